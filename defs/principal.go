@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package defs
+
+import (
+	"context"
+)
+
+// Principal describes the identity established by an authentication middleware.
+type Principal struct {
+	// Name identifies the principal, e.g. the username or the certificate identity.
+	Name string
+	// Method is the authentication method that established the principal, e.g. "basic".
+	Method string
+	// Roles contains the roles assigned to the principal.
+	Roles []string
+	// Scopes contains the scopes granted to the principal.
+	Scopes []string
+	// Claims contains further method specific information about the principal.
+	Claims map[string]any
+}
+
+// principalKey is the context key the Principal is stored under.
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx that carries the given Principal.
+func ContextWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext gets the Principal stored in ctx. If there is none, the
+// second return value is false.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+
+	return p, ok && p != nil
+}
@@ -0,0 +1,31 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package defs_test
+
+import (
+	"testing"
+
+	"github.com/AlphaOne1/midgard/defs"
+)
+
+func TestPrincipalContext(t *testing.T) {
+	t.Parallel()
+
+	if _, found := defs.PrincipalFromContext(t.Context()); found {
+		t.Errorf("expected no principal in empty context")
+	}
+
+	want := &defs.Principal{Name: "testuser", Method: "basic"}
+	ctx := defs.ContextWithPrincipal(t.Context(), want)
+
+	got, found := defs.PrincipalFromContext(ctx)
+
+	if !found || got != want {
+		t.Errorf("got principal %v but wanted %v", got, want)
+	}
+
+	if _, found := defs.PrincipalFromContext(defs.ContextWithPrincipal(t.Context(), nil)); found {
+		t.Errorf("expected nil principal not to be found")
+	}
+}
@@ -32,7 +32,8 @@ func (h *Handler) GetMWBase() *defs.MWBase {
 }
 
 // ServeHTTP implements the access logging middleware. It logs every request with its
-// correlationID, the client's address, http method and accessed path.
+// correlationID, the client's address, http method and accessed path. If an authentication
+// middleware placed before this one established a principal, its name is logged as user.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if !helper.IntroCheck(h, w, r) {
 		return
@@ -51,7 +52,11 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		entries = append(entries, slog.String("correlation_id", correlationID))
 	}
 
-	if authLine := r.Header.Get("Authorization"); authLine != "" {
+	if principal, principalFound := defs.PrincipalFromContext(r.Context()); principalFound {
+		entries = append(entries,
+			slog.String("user", principal.Name),
+			slog.String("auth_method", principal.Method))
+	} else if authLine := r.Header.Get("Authorization"); authLine != "" {
 		username, _, userFound, _ := basicauth.ExtractUserPass(authLine)
 
 		if userFound {
@@ -12,6 +12,7 @@ import (
 	"regexp"
 	"testing"
 
+	"github.com/AlphaOne1/midgard/defs"
 	"github.com/AlphaOne1/midgard/handler/accesslog"
 	"github.com/AlphaOne1/midgard/helper"
 )
@@ -116,3 +117,31 @@ func TestAccessLoggingUser(t *testing.T) {
 		t.Errorf("user not logged correctly: %v", logBuf.String())
 	}
 }
+
+//nolint:paralleltest // testing output, manipulating global log behaviour
+func TestAccessLoggingPrincipal(t *testing.T) {
+	oldLog := slog.Default()
+	defer slog.SetDefault(oldLog)
+
+	logBuf := bytes.Buffer{}
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logBuf, &slog.HandlerOptions{})))
+
+	handler := helper.Must(accesslog.New())(http.HandlerFunc(helper.DummyHandler))
+
+	req := httptest.NewRequestWithContext(
+		defs.ContextWithPrincipal(t.Context(), &defs.Principal{Name: "backend", Method: "mtls"}),
+		http.MethodGet, "/", nil)
+	req.Header.Add("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("testuser:testpass")))
+
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	slog.SetDefault(oldLog)
+
+	userMatch := regexp.MustCompile("user=backend auth_method=mtls")
+
+	if !userMatch.Match(logBuf.Bytes()) {
+		t.Errorf("principal not logged correctly: %v", logBuf.String())
+	}
+}
@@ -0,0 +1,199 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package authz implements the authorization of requests based on the principal set
+// by the authentication middlewares.
+package authz
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// ErrNilOption is returned when an option is nil.
+var ErrNilOption = errors.New("option cannot be nil")
+
+// ErrNilPolicy is returned when a given policy is nil.
+var ErrNilPolicy = errors.New("policy cannot be nil")
+
+// Handler holds the internal data of the authorization middleware.
+type Handler struct {
+	defs.MWBase
+
+	rules        []Rule // rules are evaluated in order, the first matching one decides
+	defaultAllow bool   // defaultAllow lets requests pass that no rule matches
+}
+
+// GetMWBase returns the MWBase instance of the handler.
+func (h *Handler) GetMWBase() *defs.MWBase {
+	if h == nil {
+		return nil
+	}
+
+	return &h.MWBase
+}
+
+// cleanPath gives the cleaned request path, so that e.g. "/public/../admin" cannot be
+// used to bypass the rules for "/admin".
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+
+	return path.Clean("/" + p)
+}
+
+// decide evaluates the rules for the request. It gives the reason for a rejection and
+// the index of the deciding rule, -1 if no rule matched.
+func (h *Handler) decide(r *http.Request, principal *defs.Principal) (string, int) {
+	p := cleanPath(r.URL.Path)
+
+	for i := range h.rules {
+		if h.rules[i].matches(r.Method, p) {
+			return h.rules[i].check(principal), i
+		}
+	}
+
+	if h.defaultAllow {
+		return "", -1
+	}
+
+	return "no matching rule", -1
+}
+
+// ServeHTTP implements the authorization functionality.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !helper.IntroCheck(h, w, r) {
+		return
+	}
+
+	principal, _ := defs.PrincipalFromContext(r.Context())
+	reason, rule := h.decide(r, principal)
+
+	if reason == "" {
+		h.Next().ServeHTTP(w, r)
+
+		return
+	}
+
+	name := ""
+
+	if principal != nil {
+		name = principal.Name
+	}
+
+	h.Log().Info("request not authorized",
+		slog.String("reason", reason),
+		slog.Int("rule", rule),
+		slog.String("principal", name),
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path))
+
+	helper.WriteState(w, h.Log(), http.StatusForbidden)
+}
+
+// WithRules adds rules to the end of the rule list.
+func WithRules(rules ...Rule) func(h *Handler) error {
+	return func(h *Handler) error {
+		policy := Policy{Rules: rules}
+
+		if err := policy.validate(); err != nil {
+			return err
+		}
+
+		h.rules = append(h.rules, slices.Clone(rules)...)
+
+		return nil
+	}
+}
+
+// WithDefault sets the decision for requests no rule matches, either Allow or Deny.
+// Without it, these requests are denied.
+func WithDefault(decision string) func(h *Handler) error {
+	return func(h *Handler) error {
+		policy := Policy{Default: decision}
+
+		if err := policy.validate(); err != nil {
+			return err
+		}
+
+		h.defaultAllow = decision == Allow
+
+		return nil
+	}
+}
+
+// WithPolicy adds the rules of the policy to the end of the rule list. If the policy
+// has a default, it replaces the current one.
+func WithPolicy(policy *Policy) func(h *Handler) error {
+	return func(h *Handler) error {
+		if policy == nil {
+			return ErrNilPolicy
+		}
+
+		if err := policy.validate(); err != nil {
+			return err
+		}
+
+		h.rules = append(h.rules, slices.Clone(policy.Rules)...)
+
+		if policy.Default != "" {
+			h.defaultAllow = policy.Default == Allow
+		}
+
+		return nil
+	}
+}
+
+// WithPolicyFile reads the policy from the given JSON or YAML file and applies it like
+// WithPolicy.
+func WithPolicyFile(fileName string) func(h *Handler) error {
+	return func(h *Handler) error {
+		policy, err := ReadPolicyFile(fileName)
+
+		if err != nil {
+			return err
+		}
+
+		return WithPolicy(policy)(h)
+	}
+}
+
+// WithLogger configures the logger to use.
+func WithLogger(log *slog.Logger) func(h *Handler) error {
+	return defs.WithLogger[*Handler](log)
+}
+
+// WithLogLevel configures the log level to use with the logger.
+func WithLogLevel(level slog.Level) func(h *Handler) error {
+	return defs.WithLogLevel[*Handler](level)
+}
+
+// New generates a new authorization middleware.
+func New(options ...func(handler *Handler) error) (defs.Middleware, error) {
+	handler := Handler{}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&handler); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		if err := handler.SetNext(next); err != nil {
+			return nil
+		}
+
+		return &handler
+	}, nil
+}
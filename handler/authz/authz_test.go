@@ -0,0 +1,240 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package authz_test
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/authz"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+var (
+	admin = &defs.Principal{Name: "root", Roles: []string{"admin", "user"}}
+	user  = &defs.Principal{
+		Name:   "alice",
+		Roles:  []string{"user"},
+		Scopes: []string{"reports:read"},
+		Claims: map[string]any{"tenant": "acme"},
+	}
+	other = &defs.Principal{
+		Name:   "bob",
+		Scopes: []string{"reports:read"},
+		Claims: map[string]any{"tenant": []any{"umbrella"}},
+	}
+)
+
+// policyTests are shared by the tests of the policies defined in Go and in files.
+var policyTests = []struct {
+	Method     string
+	Path       string
+	Principal  *defs.Principal
+	WantStatus int
+}{
+	{Method: http.MethodPost, Path: "/admin/users", Principal: admin, WantStatus: http.StatusOK},              // 0
+	{Method: http.MethodPost, Path: "/admin/users", Principal: user, WantStatus: http.StatusForbidden},        // 1
+	{Method: http.MethodGet, Path: "/admin/users", Principal: user, WantStatus: http.StatusOK},                // 2
+	{Method: http.MethodGet, Path: "/admin/users", Principal: nil, WantStatus: http.StatusForbidden},          // 3
+	{Method: http.MethodDelete, Path: "/admin", Principal: user, WantStatus: http.StatusForbidden},            // 4
+	{Method: http.MethodPost, Path: "/public/../admin/x", Principal: user, WantStatus: http.StatusForbidden},  // 5
+	{Method: http.MethodGet, Path: "/tenants/1/reports", Principal: user, WantStatus: http.StatusOK},          // 6
+	{Method: http.MethodGet, Path: "/tenants/1/reports", Principal: other, WantStatus: http.StatusForbidden},  // 7
+	{Method: http.MethodGet, Path: "/tenants/1/reports", Principal: admin, WantStatus: http.StatusForbidden},  // 8
+	{Method: http.MethodGet, Path: "/tenants/1/2/reports", Principal: user, WantStatus: http.StatusForbidden}, // 9
+	{Method: http.MethodGet, Path: "/internal/metrics", Principal: admin, WantStatus: http.StatusForbidden},   // 10
+	{Method: http.MethodGet, Path: "/public/index.html", Principal: nil, WantStatus: http.StatusOK},           // 11
+	{Method: http.MethodGet, Path: "/", Principal: nil, WantStatus: http.StatusOK},                            // 12
+	{Method: http.MethodGet, Path: "/other", Principal: admin, WantStatus: http.StatusForbidden},              // 13
+}
+
+// testRules is the Go definition of the policy in the test files.
+var testRules = []authz.Rule{
+	{
+		Methods: []string{http.MethodPost, http.MethodPut, http.MethodDelete},
+		Paths:   []string{"/admin/**"},
+		Roles:   []string{"admin"},
+	},
+	{Paths: []string{"/admin/**"}, Authenticated: true},
+	{
+		Paths:  []string{"/tenants/*/reports"},
+		Scopes: []string{"reports:read"},
+		Claims: map[string][]string{"tenant": {"acme", "initech"}},
+	},
+	{Paths: []string{"/internal/**"}, Deny: true},
+	{Paths: []string{"/public/**", "/"}},
+}
+
+func runPolicyTests(t *testing.T, options ...func(*authz.Handler) error) {
+	t.Helper()
+
+	handler := helper.Must(authz.New(options...))(http.HandlerFunc(helper.DummyHandler))
+
+	for k, test := range policyTests {
+		req := httptest.NewRequestWithContext(t.Context(), test.Method, "/", nil)
+		req.URL.Path = test.Path
+
+		if test.Principal != nil {
+			req = req.WithContext(defs.ContextWithPrincipal(req.Context(), test.Principal))
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.WantStatus {
+			t.Errorf("%v: got status %v but wanted %v", k, rec.Code, test.WantStatus)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	t.Parallel()
+
+	runPolicyTests(t, authz.WithRules(testRules...))
+}
+
+func TestPolicyFiles(t *testing.T) {
+	t.Parallel()
+
+	for _, fileName := range []string{"testpolicy.json", "testpolicy.yaml"} {
+		t.Run(fileName, func(t *testing.T) {
+			t.Parallel()
+
+			runPolicyTests(t, authz.WithPolicyFile(fileName))
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options    []func(*authz.Handler) error
+		WantStatus int
+	}{
+		{Options: nil, WantStatus: http.StatusForbidden},                                                   // 0
+		{Options: []func(*authz.Handler) error{authz.WithDefault(authz.Allow)}, WantStatus: http.StatusOK}, // 1
+		{ // 2
+			Options: []func(*authz.Handler) error{
+				authz.WithDefault(authz.Allow),
+				authz.WithPolicy(&authz.Policy{Default: authz.Deny}),
+			},
+			WantStatus: http.StatusForbidden,
+		},
+		{ // 3
+			Options: []func(*authz.Handler) error{
+				authz.WithDefault(authz.Allow),
+				authz.WithPolicy(&authz.Policy{Rules: []authz.Rule{{Paths: []string{"/admin/**"}, Deny: true}}}),
+			},
+			WantStatus: http.StatusOK,
+		},
+	}
+
+	for k, test := range tests {
+		handler := helper.Must(authz.New(test.Options...))(http.HandlerFunc(helper.DummyHandler))
+		req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/anything", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.WantStatus {
+			t.Errorf("%v: got status %v but wanted %v", k, rec.Code, test.WantStatus)
+		}
+	}
+}
+
+func TestMethods(t *testing.T) {
+	t.Parallel()
+
+	handler := helper.Must(authz.New(
+		authz.WithDefault(authz.Allow),
+		authz.WithRules(authz.Rule{Methods: []string{"get"}, Paths: []string{"/admin/**"}, Deny: true})))(
+		http.HandlerFunc(helper.DummyHandler))
+
+	tests := []struct {
+		Method     string
+		WantStatus int
+	}{
+		{Method: http.MethodGet, WantStatus: http.StatusForbidden},  // 0
+		{Method: http.MethodHead, WantStatus: http.StatusForbidden}, // 1 rules for GET also apply to HEAD
+		{Method: http.MethodPost, WantStatus: http.StatusOK},        // 2
+	}
+
+	for k, test := range tests {
+		req := httptest.NewRequestWithContext(t.Context(), test.Method, "/admin/users", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.WantStatus {
+			t.Errorf("%v: got status %v but wanted %v", k, rec.Code, test.WantStatus)
+		}
+	}
+}
+
+func TestReadPolicyErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Input   string
+		Format  authz.Format
+		WantErr error
+	}{
+		{Input: `{"rules": [{"paths": ["admin"]}]}`, Format: authz.FormatJSON, WantErr: authz.ErrInvalidPattern},   // 0
+		{Input: `{"rules": [{"paths": ["/a/**/b"]}]}`, Format: authz.FormatJSON, WantErr: authz.ErrInvalidPattern}, // 1
+		{Input: `{"rules": [{"paths": ["/a["]}]}`, Format: authz.FormatJSON, WantErr: authz.ErrInvalidPattern},     // 2
+		{Input: `{"default": "maybe"}`, Format: authz.FormatJSON, WantErr: authz.ErrInvalidDefault},                // 3
+		{Input: `{"rulez": []}`, Format: authz.FormatJSON, WantErr: errors.New("unknown field")},                   // 4
+		{Input: "rulez: []", Format: authz.FormatYAML, WantErr: errors.New("not found")},                           // 5
+		{Input: "rules: []", Format: "toml", WantErr: authz.ErrUnknownFormat},                                      // 6
+		{Input: "", Format: authz.FormatYAML, WantErr: nil},                                                        // 7
+		{Input: "rules:\n  - paths: [\"/x/**\"]\n", Format: authz.FormatYAML, WantErr: nil},                        // 8
+	}
+
+	for k, test := range tests {
+		_, err := authz.ReadPolicy(strings.NewReader(test.Input), test.Format)
+
+		switch {
+		case test.WantErr == nil && err != nil:
+			t.Errorf("%v: got unexpected error %v", k, err)
+		case test.WantErr == nil:
+		case err == nil:
+			t.Errorf("%v: expected error %v", k, test.WantErr)
+		case !errors.Is(err, test.WantErr) && !strings.Contains(err.Error(), test.WantErr.Error()):
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+}
+
+func TestOptionErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Option  func(*authz.Handler) error
+		WantErr error
+	}{
+		{Option: authz.WithPolicy(nil), WantErr: authz.ErrNilPolicy},                                  // 0
+		{Option: authz.WithDefault("sometimes"), WantErr: authz.ErrInvalidDefault},                    // 1
+		{Option: authz.WithRules(authz.Rule{Paths: []string{"x"}}), WantErr: authz.ErrInvalidPattern}, // 2
+		{Option: authz.WithPolicyFile("testpolicy.toml"), WantErr: authz.ErrUnknownFormat},            // 3
+		{Option: authz.WithPolicyFile("missing.json"), WantErr: fs.ErrNotExist},                       // 4
+	}
+
+	for k, test := range tests {
+		_, err := authz.New(test.Option)
+
+		if err == nil {
+			t.Errorf("%v: expected error", k)
+
+			continue
+		}
+
+		if !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package authz
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// ErrInvalidPattern is returned when a path pattern is malformed.
+var ErrInvalidPattern = errors.New("invalid path pattern")
+
+// ErrInvalidDefault is returned when the default decision of a policy is neither allow nor deny.
+var ErrInvalidDefault = errors.New("default must be allow or deny")
+
+// ErrUnknownFormat is returned when the format of a policy file cannot be determined.
+var ErrUnknownFormat = helper.ErrUnknownFormat
+
+// Format is the format of a policy description.
+type Format = helper.ConfigFormat
+
+const (
+	// FormatJSON is the JSON format.
+	FormatJSON = helper.ConfigJSON
+	// FormatYAML is the YAML format.
+	FormatYAML = helper.ConfigYAML
+)
+
+// Decision values of a policy default.
+const (
+	Allow = "allow"
+	Deny  = "deny"
+)
+
+// Rule describes the requirements for requests matching its methods and paths. All
+// given requirements have to be fulfilled.
+type Rule struct {
+	// Methods the rule applies to. If empty, it applies to all methods.
+	Methods []string `json:"methods,omitempty" yaml:"methods,omitempty"`
+	// Paths the rule applies to. The patterns use the syntax of path.Match for each
+	// segment, a final "**" segment matches any number of segments, e.g. "/admin/**".
+	// If empty, the rule applies to all paths.
+	Paths []string `json:"paths,omitempty" yaml:"paths,omitempty"`
+
+	// Deny rejects all matching requests.
+	Deny bool `json:"deny,omitempty" yaml:"deny,omitempty"`
+	// Authenticated requires a principal to be present.
+	Authenticated bool `json:"authenticated,omitempty" yaml:"authenticated,omitempty"`
+	// Roles of which the principal needs at least one.
+	Roles []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+	// Scopes that all have to be granted to the principal.
+	Scopes []string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+	// Claims that the principal needs to have, each with one of the given values.
+	Claims map[string][]string `json:"claims,omitempty" yaml:"claims,omitempty"`
+}
+
+// Policy is an ordered list of rules. The first rule matching the method and path of
+// a request decides about it. If no rule matches, the default decides.
+type Policy struct {
+	// Default is the decision for requests no rule matches, either allow or deny. If
+	// empty, the requests are denied.
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+	// Rules are evaluated in order.
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// validate checks the patterns and the default of the policy.
+func (p *Policy) validate() error {
+	if p.Default != "" && p.Default != Allow && p.Default != Deny {
+		return fmt.Errorf("%w: %v", ErrInvalidDefault, p.Default)
+	}
+
+	for _, rule := range p.Rules {
+		for _, pattern := range rule.Paths {
+			if err := validatePattern(pattern); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validatePattern checks that the path pattern is well-formed.
+func validatePattern(pattern string) error {
+	if !strings.HasPrefix(pattern, "/") {
+		return fmt.Errorf("%w: %v must start with /", ErrInvalidPattern, pattern)
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPattern, pattern)
+	}
+
+	if i := strings.Index(pattern, "**"); i >= 0 && (i != len(pattern)-2 || !strings.HasSuffix(pattern, "/**")) {
+		return fmt.Errorf("%w: %v, ** is only allowed as last segment", ErrInvalidPattern, pattern)
+	}
+
+	return nil
+}
+
+// ReadPolicy reads a policy in the given format.
+func ReadPolicy(r io.Reader, format Format) (*Policy, error) {
+	policy, err := helper.ReadConfig[Policy](r, format)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// ReadPolicyFile reads a policy from the given file. The format is determined by the
+// file extension, .json for JSON and .yaml or .yml for YAML.
+func ReadPolicyFile(fileName string) (*Policy, error) {
+	policy, err := helper.ReadConfigFile[Policy](fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// matchPath matches the path against the pattern.
+func matchPath(pattern, p string) bool {
+	patternSegments := strings.Split(pattern, "/")
+	pathSegments := strings.Split(p, "/")
+
+	for i, ps := range patternSegments {
+		// also matches the parent, e.g. "/admin/**" matches "/admin"
+		if ps == "**" && i == len(patternSegments)-1 {
+			return true
+		}
+
+		if i >= len(pathSegments) {
+			return false
+		}
+
+		if matched, _ := path.Match(ps, pathSegments[i]); !matched {
+			return false
+		}
+	}
+
+	return len(patternSegments) == len(pathSegments)
+}
+
+// matches checks if the rule applies to the given method and path. Rules for GET also
+// apply to HEAD, as HEAD gives the same response without body.
+func (rule *Rule) matches(method, p string) bool {
+	if len(rule.Methods) > 0 && !slices.ContainsFunc(rule.Methods, func(m string) bool {
+		return strings.EqualFold(m, method) ||
+			(strings.EqualFold(method, http.MethodHead) && strings.EqualFold(m, http.MethodGet))
+	}) {
+		return false
+	}
+
+	return len(rule.Paths) == 0 || slices.ContainsFunc(rule.Paths, func(pattern string) bool {
+		return matchPath(pattern, p)
+	})
+}
+
+// claimValues gives the values of the claim as strings. Lists are flattened.
+func claimValues(claim any) []string {
+	switch v := claim.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []any:
+		result := make([]string, 0, len(v))
+
+		for _, e := range v {
+			result = append(result, fmt.Sprint(e))
+		}
+
+		return result
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+// check gives the reason the principal does not fulfill the requirements of the rule,
+// or an empty string if it does.
+func (rule *Rule) check(principal *defs.Principal) string {
+	if rule.Deny {
+		return "denied by rule"
+	}
+
+	needsPrincipal := rule.Authenticated || len(rule.Roles) > 0 || len(rule.Scopes) > 0 || len(rule.Claims) > 0
+
+	if principal == nil {
+		if needsPrincipal {
+			return "not authenticated"
+		}
+
+		return ""
+	}
+
+	if len(rule.Roles) > 0 && !slices.ContainsFunc(rule.Roles, func(r string) bool {
+		return slices.Contains(principal.Roles, r)
+	}) {
+		return "missing role"
+	}
+
+	for _, s := range rule.Scopes {
+		if !slices.Contains(principal.Scopes, s) {
+			return "missing scope " + s
+		}
+	}
+
+	for name, allowed := range rule.Claims {
+		if !slices.ContainsFunc(claimValues(principal.Claims[name]), func(v string) bool {
+			return slices.Contains(allowed, v)
+		}) {
+			return "claim mismatch " + name
+		}
+	}
+
+	return ""
+}
@@ -31,6 +31,24 @@ type Authenticator interface {
 	Authenticate(username, password string) (bool, error)
 }
 
+// PrincipalAuthenticator is an optional interface of authenticators that know more
+// about a user than just the validity of its credentials, e.g. its roles. If the
+// configured authenticator implements it, the handler uses it instead of Authenticate
+// and stores the returned principal in the request context.
+type PrincipalAuthenticator interface {
+	// AuthenticatePrincipal checks the given credentials like Authenticate does and
+	// additionally gives the principal of the user, if they are allowed.
+	AuthenticatePrincipal(username, password string) (*defs.Principal, bool, error)
+}
+
+// Versioned is an optional interface of authenticators whose credentials can change
+// at runtime, e.g. by reloading a file. The version changes with every change of the
+// credentials, so that users like caches can detect them.
+type Versioned interface {
+	// Version gives the current version of the credentials.
+	Version() uint64
+}
+
 // Handler holds the internal data of the basic authentication middleware.
 type Handler struct {
 	defs.MWBase
@@ -99,7 +117,7 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hasAuth, authErr := h.auth.Authenticate(username, password)
+	principal, hasAuth, authErr := h.authenticate(username, password)
 
 	if authErr != nil {
 		h.Log().Error("authentication error",
@@ -113,9 +131,44 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	r = r.WithContext(defs.ContextWithPrincipal(r.Context(), principal))
+
 	h.Next().ServeHTTP(w, r)
 }
 
+// authenticate checks the credentials using the configured Authenticator and gives the
+// principal of the user.
+func (h *Handler) authenticate(username, password string) (*defs.Principal, bool, error) {
+	return AuthenticatePrincipal(h.auth, username, password)
+}
+
+// AuthenticatePrincipal checks the credentials using the given Authenticator and gives
+// the principal of the user. If the authenticator implements PrincipalAuthenticator,
+// its principal is used, otherwise one containing just the username.
+func AuthenticatePrincipal(auth Authenticator, username, password string) (*defs.Principal, bool, error) {
+	if pa, ok := auth.(PrincipalAuthenticator); ok {
+		principal, hasAuth, err := pa.AuthenticatePrincipal(username, password)
+
+		if !hasAuth || principal == nil {
+			return nil, false, err
+		}
+
+		if principal.Name == "" {
+			principal.Name = username
+		}
+
+		if principal.Method == "" {
+			principal.Method = "basic"
+		}
+
+		return principal, true, err
+	}
+
+	hasAuth, err := auth.Authenticate(username, password)
+
+	return &defs.Principal{Name: username, Method: "basic"}, hasAuth, err
+}
+
 // sendNoAuth sends the client that his credentials are not allowed.
 func (h *Handler) sendNoAuth(w http.ResponseWriter, r *http.Request) {
 	if len(h.redirect) > 0 {
@@ -197,3 +197,25 @@ func TestBasicAuthRedirect(t *testing.T) {
 		t.Errorf("redirect not set correctly: %v", relocHeader)
 	}
 }
+
+func TestBasicAuthPrincipal(t *testing.T) {
+	t.Parallel()
+
+	handler := helper.Must(basicauth.New(basicauth.WithAuthenticator(&AuthTest{})))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if p, found := defs.PrincipalFromContext(r.Context()); found {
+				_, _ = w.Write([]byte(p.Method + ":" + p.Name))
+			}
+		}))
+
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	req.SetBasicAuth("testuser", "testpass")
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Body.String() != "basic:testuser" {
+		t.Errorf("principal not set correctly: %v", rec.Body.String())
+	}
+}
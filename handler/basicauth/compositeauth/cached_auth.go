@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package compositeauth
+
+import (
+	"container/list"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/basicauth"
+)
+
+// ErrInvalidTTL is returned when the time to live of cache entries is not positive.
+var ErrInvalidTTL = errors.New("ttl must be greater than 0")
+
+// ErrInvalidMaxEntries is returned when the maximum number of cache entries is not positive.
+var ErrInvalidMaxEntries = errors.New("maximum entries must be greater than 0")
+
+// cacheKey is the hashed form of the credentials.
+type cacheKey [sha256.Size]byte
+
+// cacheEntry is an entry of the least recently used list.
+type cacheEntry struct {
+	key       cacheKey
+	expires   time.Time
+	principal *defs.Principal // principal is the principal given for the credentials
+}
+
+// CachedAuthenticator remembers credentials that were accepted by an authenticator.
+type CachedAuthenticator struct {
+	auth       basicauth.Authenticator
+	ttl        time.Duration
+	maxEntries int
+	salt       []byte           // salt is the key of the credentials hashing
+	now        func() time.Time // now gives the current time, replaceable for testing
+
+	mtx     sync.Mutex
+	entries map[cacheKey]*list.Element // entries maps the keys to their element in lru
+	lru     *list.List                 // lru contains the entries, most recently used first
+	version uint64                     // version is the version of auth the entries belong to
+}
+
+// Cached creates an authenticator that caches positive results of the given
+// authenticator for the time ttl. At most maxEntries credentials are cached, the least
+// recently used ones are dismissed first. The credentials are stored as salted hash
+// only. If the authenticator implements basicauth.Versioned, e.g. after reloading its
+// source, a version change clears the cache.
+func Cached(auth basicauth.Authenticator, ttl time.Duration, maxEntries int) (*CachedAuthenticator, error) {
+	if auth == nil {
+		return nil, ErrNilAuthenticator
+	}
+
+	if ttl <= 0 {
+		return nil, ErrInvalidTTL
+	}
+
+	if maxEntries <= 0 {
+		return nil, ErrInvalidMaxEntries
+	}
+
+	salt := make([]byte, sha256.Size)
+	_, _ = rand.Read(salt)
+
+	return &CachedAuthenticator{
+		auth:       auth,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		salt:       salt,
+		now:        time.Now,
+		entries:    make(map[cacheKey]*list.Element, maxEntries),
+		lru:        list.New(),
+		version:    version(auth),
+	}, nil
+}
+
+// key calculates the cache key of the given credentials.
+func (a *CachedAuthenticator) key(username, password string) cacheKey {
+	mac := hmac.New(sha256.New, a.salt)
+	_, _ = mac.Write([]byte(username))
+	_, _ = mac.Write([]byte{0})
+	_, _ = mac.Write([]byte(password))
+
+	return cacheKey(mac.Sum(nil))
+}
+
+// lookup gives the principal of the credentials, if they are cached and not expired.
+// The caller must hold the lock.
+func (a *CachedAuthenticator) lookup(key cacheKey, now time.Time) (*defs.Principal, bool) {
+	if v := version(a.auth); v != a.version {
+		a.entries = make(map[cacheKey]*list.Element, a.maxEntries)
+		a.lru.Init()
+		a.version = v
+
+		return nil, false
+	}
+
+	elem, found := a.entries[key]
+
+	if !found {
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry) //nolint:forcetypeassert // only cacheEntry stored
+
+	if !now.Before(entry.expires) {
+		a.lru.Remove(elem)
+		delete(a.entries, key)
+
+		return nil, false
+	}
+
+	a.lru.MoveToFront(elem)
+
+	return clonePrincipal(entry.principal), true
+}
+
+// store adds the credentials with their principal to the cache. The caller must hold
+// the lock.
+func (a *CachedAuthenticator) store(key cacheKey, principal *defs.Principal, now time.Time, ver uint64) {
+	// the authenticator changed while checking, so the result could be outdated
+	if ver != a.version {
+		return
+	}
+
+	if elem, found := a.entries[key]; found {
+		entry := elem.Value.(*cacheEntry) //nolint:forcetypeassert // only cacheEntry stored
+		entry.expires = now.Add(a.ttl)
+		entry.principal = clonePrincipal(principal)
+		a.lru.MoveToFront(elem)
+
+		return
+	}
+
+	for a.lru.Len() >= a.maxEntries {
+		oldest := a.lru.Back()
+		a.lru.Remove(oldest)
+		delete(a.entries, oldest.Value.(*cacheEntry).key) //nolint:forcetypeassert // only cacheEntry stored
+	}
+
+	a.entries[key] = a.lru.PushFront(&cacheEntry{
+		key:       key,
+		expires:   now.Add(a.ttl),
+		principal: clonePrincipal(principal),
+	})
+}
+
+// Authenticate checks the credentials in the cache first and asks the wrapped
+// authenticator only if they are not found.
+func (a *CachedAuthenticator) Authenticate(username, password string) (bool, error) {
+	_, ok, err := a.AuthenticatePrincipal(username, password)
+
+	return ok, err
+}
+
+// AuthenticatePrincipal checks the credentials in the cache first and asks the wrapped
+// authenticator only if they are not found. The principal given by the wrapped
+// authenticator is cached along with the credentials.
+func (a *CachedAuthenticator) AuthenticatePrincipal(username, password string) (*defs.Principal, bool, error) {
+	if a == nil {
+		return nil, false, ErrNotInitialized
+	}
+
+	key := a.key(username, password)
+
+	a.mtx.Lock()
+	principal, found := a.lookup(key, a.now())
+	ver := a.version
+	a.mtx.Unlock()
+
+	if found {
+		return principal, true, nil
+	}
+
+	principal, ok, err := basicauth.AuthenticatePrincipal(a.auth, username, password)
+
+	if ok && err == nil {
+		a.mtx.Lock()
+		a.store(key, principal, a.now(), ver)
+		a.mtx.Unlock()
+	}
+
+	return principal, ok, err
+}
+
+// Purge removes all entries from the cache.
+func (a *CachedAuthenticator) Purge() {
+	if a == nil {
+		return
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	a.entries = make(map[cacheKey]*list.Element, a.maxEntries)
+	a.lru.Init()
+}
+
+// Len gives the number of cached credentials.
+func (a *CachedAuthenticator) Len() int {
+	if a == nil {
+		return 0
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	return a.lru.Len()
+}
+
+// Version gives the version of the wrapped authenticator.
+func (a *CachedAuthenticator) Version() uint64 {
+	if a == nil {
+		return 0
+	}
+
+	return version(a.auth)
+}
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package compositeauth_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/basicauth/compositeauth"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+func TestCached(t *testing.T) {
+	t.Parallel()
+
+	backend := &CountingAuth{User: "user", Pass: "pass"}
+	cached := helper.Must(compositeauth.Cached(backend, time.Minute, 10))
+	now := time.Now()
+
+	compositeauth.TSetNow(cached, func() time.Time { return now })
+
+	for range 3 {
+		if got, err := cached.Authenticate("user", "pass"); !got || err != nil {
+			t.Errorf("expected acceptance, but got %v, %v", got, err)
+		}
+	}
+
+	if backend.Calls.Load() != 1 {
+		t.Errorf("expected one backend call, but got %v", backend.Calls.Load())
+	}
+
+	// negative results are not cached
+	for range 2 {
+		if got, _ := cached.Authenticate("user", "wrong"); got {
+			t.Errorf("expected rejection")
+		}
+	}
+
+	if backend.Calls.Load() != 3 {
+		t.Errorf("expected three backend calls, but got %v", backend.Calls.Load())
+	}
+
+	now = now.Add(2 * time.Minute)
+
+	if got, _ := cached.Authenticate("user", "pass"); !got {
+		t.Errorf("expected acceptance after expiry")
+	}
+
+	if backend.Calls.Load() != 4 {
+		t.Errorf("expected expired entry to be checked again, but got %v calls", backend.Calls.Load())
+	}
+}
+
+func TestCachedInvalidation(t *testing.T) {
+	t.Parallel()
+
+	backend := &CountingAuth{User: "user", Pass: "pass"}
+	cached := helper.Must(compositeauth.Cached(backend, time.Minute, 10))
+
+	_, _ = cached.Authenticate("user", "pass")
+
+	if cached.Len() != 1 {
+		t.Errorf("expected one cached entry, but got %v", cached.Len())
+	}
+
+	// the source reloads and the password changes
+	backend.Pass = "newpass"
+	backend.Revised.Add(1)
+
+	if got, _ := cached.Authenticate("user", "pass"); got {
+		t.Errorf("expected old password to be rejected after reload")
+	}
+
+	if cached.Len() != 0 {
+		t.Errorf("expected cache to be cleared, but got %v entries", cached.Len())
+	}
+
+	_, _ = cached.Authenticate("user", "newpass")
+	cached.Purge()
+
+	if cached.Len() != 0 {
+		t.Errorf("expected cache to be empty after purge, but got %v entries", cached.Len())
+	}
+}
+
+func TestCachedEviction(t *testing.T) {
+	t.Parallel()
+
+	backend := &CountingAuth{User: "user", Pass: "pass"}
+	cached := helper.Must(compositeauth.Cached(
+		helper.Must(compositeauth.AnyOf(backend, &CountingAuth{User: "other", Pass: "pass"},
+			&CountingAuth{User: "third", Pass: "pass"})),
+		time.Minute, 2))
+
+	_, _ = cached.Authenticate("user", "pass")
+	_, _ = cached.Authenticate("other", "pass")
+	_, _ = cached.Authenticate("user", "pass") // user is now most recently used
+	_, _ = cached.Authenticate("third", "pass")
+
+	if cached.Len() != 2 {
+		t.Errorf("expected two cached entries, but got %v", cached.Len())
+	}
+
+	before := backend.Calls.Load()
+
+	_, _ = cached.Authenticate("user", "pass")
+
+	if backend.Calls.Load() != before {
+		t.Errorf("expected most recently used entry to stay cached")
+	}
+
+	_, _ = cached.Authenticate("other", "pass")
+
+	if backend.Calls.Load() != before+1 {
+		t.Errorf("expected least recently used entry to be evicted")
+	}
+}
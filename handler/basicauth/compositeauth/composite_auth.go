@@ -0,0 +1,280 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package compositeauth implements combinators for basic auth authenticators.
+package compositeauth
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/basicauth"
+)
+
+// ErrNoAuthenticators is returned when no authenticators are given.
+var ErrNoAuthenticators = errors.New("no authenticators given")
+
+// ErrNilAuthenticator is returned when a given authenticator is nil.
+var ErrNilAuthenticator = errors.New("authenticator cannot be nil")
+
+// ErrInvalidTimeout is returned when the timeout is not positive.
+var ErrInvalidTimeout = errors.New("timeout must be greater than 0")
+
+// ErrTimeout is returned when an authenticator did not answer in time.
+var ErrTimeout = errors.New("authentication timed out")
+
+// ErrNotInitialized is returned when a combinator is used before it has been initialized.
+var ErrNotInitialized = errors.New("composite authenticator not initialized")
+
+// checkAuthenticators validates the given list of authenticators.
+func checkAuthenticators(auths []basicauth.Authenticator) error {
+	if len(auths) == 0 {
+		return ErrNoAuthenticators
+	}
+
+	if slices.Contains(auths, nil) {
+		return ErrNilAuthenticator
+	}
+
+	return nil
+}
+
+// version calculates a combined version of the given authenticators. As every single
+// version only grows, the sum changes whenever one of them changes.
+func version(auths ...basicauth.Authenticator) uint64 {
+	var result uint64
+
+	for _, a := range auths {
+		if v, ok := a.(basicauth.Versioned); ok {
+			result += v.Version()
+		}
+	}
+
+	return result
+}
+
+// clonePrincipal copies the principal, so that changes of the copy do not affect the
+// original.
+func clonePrincipal(p *defs.Principal) *defs.Principal {
+	if p == nil {
+		return nil
+	}
+
+	result := *p
+	result.Roles = slices.Clone(p.Roles)
+	result.Scopes = slices.Clone(p.Scopes)
+	result.Claims = maps.Clone(p.Claims)
+
+	return &result
+}
+
+// AnyOfAuthenticator accepts credentials, if one of its authenticators accepts them.
+type AnyOfAuthenticator struct {
+	auths []basicauth.Authenticator
+}
+
+// AnyOf creates an authenticator that asks the given authenticators in order and
+// accepts the credentials of the first one accepting them. Errors of single
+// authenticators are only reported, if no authenticator accepted the credentials.
+func AnyOf(auths ...basicauth.Authenticator) (*AnyOfAuthenticator, error) {
+	if err := checkAuthenticators(auths); err != nil {
+		return nil, err
+	}
+
+	return &AnyOfAuthenticator{auths: slices.Clone(auths)}, nil
+}
+
+// Authenticate checks the credentials against the authenticators until one accepts them.
+func (a *AnyOfAuthenticator) Authenticate(username, password string) (bool, error) {
+	_, ok, err := a.AuthenticatePrincipal(username, password)
+
+	return ok, err
+}
+
+// AuthenticatePrincipal checks the credentials against the authenticators until one
+// accepts them and gives the principal of the accepting one.
+func (a *AnyOfAuthenticator) AuthenticatePrincipal(username, password string) (*defs.Principal, bool, error) {
+	if a == nil {
+		return nil, false, ErrNotInitialized
+	}
+
+	var errs []error
+
+	for _, auth := range a.auths {
+		principal, ok, err := basicauth.AuthenticatePrincipal(auth, username, password)
+
+		if ok && err == nil {
+			return principal, true, nil
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return nil, false, errors.Join(errs...)
+}
+
+// Version gives the combined version of the authenticators.
+func (a *AnyOfAuthenticator) Version() uint64 {
+	if a == nil {
+		return 0
+	}
+
+	return version(a.auths...)
+}
+
+// AllOfAuthenticator accepts credentials, if all of its authenticators accept them.
+type AllOfAuthenticator struct {
+	auths []basicauth.Authenticator
+}
+
+// AllOf creates an authenticator that accepts credentials only if all the given
+// authenticators accept them. The authenticators are asked in order, stopping at the
+// first one rejecting the credentials.
+func AllOf(auths ...basicauth.Authenticator) (*AllOfAuthenticator, error) {
+	if err := checkAuthenticators(auths); err != nil {
+		return nil, err
+	}
+
+	return &AllOfAuthenticator{auths: slices.Clone(auths)}, nil
+}
+
+// Authenticate checks the credentials against all authenticators.
+func (a *AllOfAuthenticator) Authenticate(username, password string) (bool, error) {
+	_, ok, err := a.AuthenticatePrincipal(username, password)
+
+	return ok, err
+}
+
+// AuthenticatePrincipal checks the credentials against all authenticators. The principal
+// is that of the first authenticator, with the roles, scopes and claims of the others
+// added.
+func (a *AllOfAuthenticator) AuthenticatePrincipal(username, password string) (*defs.Principal, bool, error) {
+	if a == nil {
+		return nil, false, ErrNotInitialized
+	}
+
+	var result *defs.Principal
+
+	for _, auth := range a.auths {
+		principal, ok, err := basicauth.AuthenticatePrincipal(auth, username, password)
+
+		if !ok || err != nil {
+			return nil, false, err
+		}
+
+		if result == nil {
+			result = clonePrincipal(principal)
+
+			continue
+		}
+
+		for _, role := range principal.Roles {
+			if !slices.Contains(result.Roles, role) {
+				result.Roles = append(result.Roles, role)
+			}
+		}
+
+		for _, scope := range principal.Scopes {
+			if !slices.Contains(result.Scopes, scope) {
+				result.Scopes = append(result.Scopes, scope)
+			}
+		}
+
+		for name, value := range principal.Claims {
+			if _, found := result.Claims[name]; !found {
+				if result.Claims == nil {
+					result.Claims = make(map[string]any)
+				}
+
+				result.Claims[name] = value
+			}
+		}
+	}
+
+	return result, true, nil
+}
+
+// Version gives the combined version of the authenticators.
+func (a *AllOfAuthenticator) Version() uint64 {
+	if a == nil {
+		return 0
+	}
+
+	return version(a.auths...)
+}
+
+// TimeoutAuthenticator limits the time an authenticator may take.
+type TimeoutAuthenticator struct {
+	auth    basicauth.Authenticator
+	timeout time.Duration
+}
+
+// WithTimeout creates an authenticator that rejects the credentials with ErrTimeout, if
+// the given authenticator does not answer within the timeout. As the Authenticator
+// interface offers no way of cancellation, the late authenticator call still runs to
+// its end in the background.
+func WithTimeout(auth basicauth.Authenticator, timeout time.Duration) (*TimeoutAuthenticator, error) {
+	if auth == nil {
+		return nil, ErrNilAuthenticator
+	}
+
+	if timeout <= 0 {
+		return nil, ErrInvalidTimeout
+	}
+
+	return &TimeoutAuthenticator{auth: auth, timeout: timeout}, nil
+}
+
+// authResult holds the return values of an AuthenticatePrincipal call.
+type authResult struct {
+	principal *defs.Principal
+	ok        bool
+	err       error
+}
+
+// Authenticate checks the credentials using the wrapped authenticator.
+func (a *TimeoutAuthenticator) Authenticate(username, password string) (bool, error) {
+	_, ok, err := a.AuthenticatePrincipal(username, password)
+
+	return ok, err
+}
+
+// AuthenticatePrincipal checks the credentials using the wrapped authenticator and gives
+// its principal.
+func (a *TimeoutAuthenticator) AuthenticatePrincipal(username, password string) (*defs.Principal, bool, error) {
+	if a == nil {
+		return nil, false, ErrNotInitialized
+	}
+
+	// buffered, so the late goroutine can finish without a receiver
+	result := make(chan authResult, 1)
+
+	go func() {
+		principal, ok, err := basicauth.AuthenticatePrincipal(a.auth, username, password)
+		result <- authResult{principal: principal, ok: ok, err: err}
+	}()
+
+	timer := time.NewTimer(a.timeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-result:
+		return r.principal, r.ok, r.err
+	case <-timer.C:
+		return nil, false, ErrTimeout
+	}
+}
+
+// Version gives the version of the wrapped authenticator.
+func (a *TimeoutAuthenticator) Version() uint64 {
+	if a == nil {
+		return 0
+	}
+
+	return version(a.auth)
+}
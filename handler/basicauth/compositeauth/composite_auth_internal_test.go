@@ -0,0 +1,13 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package compositeauth
+
+import "time"
+
+// The following functions are used for internal testing and are not visible to normal library users.
+
+// TSetNow replaces the time source of the given cache.
+func TSetNow(a *CachedAuthenticator, now func() time.Time) {
+	a.now = now
+}
@@ -0,0 +1,296 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package compositeauth_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/basicauth"
+	"github.com/AlphaOne1/midgard/handler/basicauth/compositeauth"
+	"github.com/AlphaOne1/midgard/handler/basicauth/mapauth"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+var (
+	_ basicauth.PrincipalAuthenticator = (*compositeauth.AnyOfAuthenticator)(nil)
+	_ basicauth.PrincipalAuthenticator = (*compositeauth.AllOfAuthenticator)(nil)
+	_ basicauth.PrincipalAuthenticator = (*compositeauth.TimeoutAuthenticator)(nil)
+	_ basicauth.PrincipalAuthenticator = (*compositeauth.CachedAuthenticator)(nil)
+)
+
+// CountingAuth accepts a single user and counts the calls.
+type CountingAuth struct {
+	User    string
+	Pass    string
+	Err     error
+	Delay   time.Duration
+	Calls   atomic.Int64
+	Revised atomic.Uint64
+}
+
+func (a *CountingAuth) Authenticate(username, password string) (bool, error) {
+	a.Calls.Add(1)
+	time.Sleep(a.Delay)
+
+	if a.Err != nil {
+		return false, a.Err
+	}
+
+	return username == a.User && password == a.Pass, nil
+}
+
+func (a *CountingAuth) Version() uint64 {
+	return a.Revised.Load()
+}
+
+func TestAnyOf(t *testing.T) {
+	t.Parallel()
+
+	admin := helper.Must(mapauth.New(mapauth.WithAuths(map[string]string{"admin": "secret"})))
+	failing := &CountingAuth{Err: errors.New("backend down")}
+	users := &CountingAuth{User: "user", Pass: "pass"}
+
+	auth := helper.Must(compositeauth.AnyOf(admin, failing, users))
+
+	tests := []struct {
+		User      string
+		Pass      string
+		Want      bool
+		WantErr   bool
+		WantCalls int64
+	}{
+		{User: "admin", Pass: "secret", Want: true, WantErr: false, WantCalls: 0},
+		{User: "user", Pass: "pass", Want: true, WantErr: false, WantCalls: 1},
+		{User: "user", Pass: "wrong", Want: false, WantErr: true, WantCalls: 1},
+	}
+
+	for k, test := range tests {
+		before := users.Calls.Load()
+		got, err := auth.Authenticate(test.User, test.Pass)
+
+		if got != test.Want || (err != nil) != test.WantErr {
+			t.Errorf("%v: got %v, %v but wanted %v, error %v", k, got, err, test.Want, test.WantErr)
+		}
+
+		if calls := users.Calls.Load() - before; calls != test.WantCalls {
+			t.Errorf("%v: got %v calls but wanted %v", k, calls, test.WantCalls)
+		}
+	}
+}
+
+func TestAllOf(t *testing.T) {
+	t.Parallel()
+
+	first := &CountingAuth{User: "user", Pass: "pass"}
+	second := &CountingAuth{User: "user", Pass: "pass"}
+
+	auth := helper.Must(compositeauth.AllOf(first, second))
+
+	if got, err := auth.Authenticate("user", "pass"); !got || err != nil {
+		t.Errorf("expected acceptance, but got %v, %v", got, err)
+	}
+
+	if got, err := auth.Authenticate("user", "wrong"); got || err != nil {
+		t.Errorf("expected rejection, but got %v, %v", got, err)
+	}
+
+	if second.Calls.Load() != 1 {
+		t.Errorf("expected second authenticator not to be asked after rejection")
+	}
+
+	second.Err = errors.New("generated")
+
+	if got, err := auth.Authenticate("user", "pass"); got || err == nil {
+		t.Errorf("expected rejection with error, but got %v, %v", got, err)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	slow := &CountingAuth{User: "user", Pass: "pass", Delay: 200 * time.Millisecond}
+	fast := &CountingAuth{User: "user", Pass: "pass"}
+
+	if got, err := helper.Must(compositeauth.WithTimeout(slow, 10*time.Millisecond)).
+		Authenticate("user", "pass"); got || !errors.Is(err, compositeauth.ErrTimeout) {
+
+		t.Errorf("expected timeout, but got %v, %v", got, err)
+	}
+
+	if got, err := helper.Must(compositeauth.WithTimeout(fast, time.Second)).
+		Authenticate("user", "pass"); !got || err != nil {
+
+		t.Errorf("expected acceptance, but got %v, %v", got, err)
+	}
+}
+
+func TestVersionPropagation(t *testing.T) {
+	t.Parallel()
+
+	inner := &CountingAuth{}
+	timeout := helper.Must(compositeauth.WithTimeout(inner, time.Second))
+	anyOf := helper.Must(compositeauth.AnyOf(timeout, &CountingAuth{}))
+	allOf := helper.Must(compositeauth.AllOf(anyOf))
+
+	inner.Revised.Add(3)
+
+	for k, v := range []basicauth.Versioned{timeout, anyOf, allOf} {
+		if v.Version() != 3 {
+			t.Errorf("%v: version not propagated, got %v", k, v.Version())
+		}
+	}
+}
+
+func TestConstructionErrors(t *testing.T) {
+	t.Parallel()
+
+	valid := &CountingAuth{}
+
+	tests := []struct {
+		Create  func() error
+		WantErr error
+	}{
+		{ // 0
+			Create:  func() error { _, err := compositeauth.AnyOf(); return err },
+			WantErr: compositeauth.ErrNoAuthenticators,
+		},
+		{ // 1
+			Create:  func() error { _, err := compositeauth.AllOf(valid, nil); return err },
+			WantErr: compositeauth.ErrNilAuthenticator,
+		},
+		{ // 2
+			Create:  func() error { _, err := compositeauth.WithTimeout(nil, time.Second); return err },
+			WantErr: compositeauth.ErrNilAuthenticator,
+		},
+		{ // 3
+			Create:  func() error { _, err := compositeauth.WithTimeout(valid, 0); return err },
+			WantErr: compositeauth.ErrInvalidTimeout,
+		},
+		{ // 4
+			Create:  func() error { _, err := compositeauth.Cached(valid, 0, 1); return err },
+			WantErr: compositeauth.ErrInvalidTTL,
+		},
+		{ // 5
+			Create:  func() error { _, err := compositeauth.Cached(valid, time.Second, 0); return err },
+			WantErr: compositeauth.ErrInvalidMaxEntries,
+		},
+		{ // 6
+			Create:  func() error { _, err := compositeauth.Cached(nil, time.Second, 1); return err },
+			WantErr: compositeauth.ErrNilAuthenticator,
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestConstructionErrors-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			if err := test.Create(); !errors.Is(err, test.WantErr) {
+				t.Errorf("got error %v but wanted %v", err, test.WantErr)
+			}
+		})
+	}
+}
+
+func TestNilCombinators(t *testing.T) {
+	t.Parallel()
+
+	var anyOf *compositeauth.AnyOfAuthenticator
+	var allOf *compositeauth.AllOfAuthenticator
+	var timeout *compositeauth.TimeoutAuthenticator
+	var cached *compositeauth.CachedAuthenticator
+
+	for k, a := range []basicauth.Authenticator{anyOf, allOf, timeout, cached} {
+		if _, err := a.Authenticate("u", "p"); !errors.Is(err, compositeauth.ErrNotInitialized) {
+			t.Errorf("%v: expected not initialized error, but got %v", k, err)
+		}
+	}
+}
+
+// RolesAuth accepts a single user and gives its roles.
+type RolesAuth struct {
+	User  string
+	Pass  string
+	Roles []string
+	Calls atomic.Int64
+}
+
+func (a *RolesAuth) Authenticate(username, password string) (bool, error) {
+	_, ok, err := a.AuthenticatePrincipal(username, password)
+
+	return ok, err
+}
+
+func (a *RolesAuth) AuthenticatePrincipal(username, password string) (*defs.Principal, bool, error) {
+	a.Calls.Add(1)
+
+	if username != a.User || password != a.Pass {
+		return nil, false, nil
+	}
+
+	return &defs.Principal{Name: username, Method: "ldap", Roles: slices.Clone(a.Roles)}, true, nil
+}
+
+func TestPrincipalPassThrough(t *testing.T) {
+	t.Parallel()
+
+	admins := &RolesAuth{User: "user", Pass: "pass", Roles: []string{"admin"}}
+	devs := &RolesAuth{User: "user", Pass: "pass", Roles: []string{"dev", "admin"}}
+	plain := &CountingAuth{User: "user", Pass: "pass"}
+
+	tests := []struct {
+		Auth      basicauth.Authenticator
+		WantRoles []string
+	}{
+		{ // 0
+			Auth:      helper.Must(compositeauth.AnyOf(&CountingAuth{User: "other"}, admins)),
+			WantRoles: []string{"admin"},
+		},
+		{ // 1 the roles of all authenticators are combined
+			Auth:      helper.Must(compositeauth.AllOf(admins, plain, devs)),
+			WantRoles: []string{"admin", "dev"},
+		},
+		{ // 2
+			Auth:      helper.Must(compositeauth.WithTimeout(admins, time.Second)),
+			WantRoles: []string{"admin"},
+		},
+		{ // 3
+			Auth:      helper.Must(compositeauth.Cached(admins, time.Minute, 10)),
+			WantRoles: []string{"admin"},
+		},
+		{ // 4
+			Auth: helper.Must(compositeauth.Cached(
+				helper.Must(compositeauth.AnyOf(helper.Must(compositeauth.WithTimeout(devs, time.Second)))),
+				time.Minute, 10)),
+			WantRoles: []string{"dev", "admin"},
+		},
+	}
+
+	for k, test := range tests {
+		// the second round is answered from the cache by the cached authenticators
+		for round := range 2 {
+			principal, ok, err := basicauth.AuthenticatePrincipal(test.Auth, "user", "pass")
+
+			if !ok || err != nil {
+				t.Fatalf("%v/%v: expected acceptance, but got %v, %v", k, round, ok, err)
+			}
+
+			if !slices.Equal(principal.Roles, test.WantRoles) {
+				t.Errorf("%v/%v: got roles %v, wanted %v", k, round, principal.Roles, test.WantRoles)
+			}
+
+			if principal.Method != "ldap" {
+				t.Errorf("%v/%v: got method %v, wanted ldap", k, round, principal.Method)
+			}
+
+			// changes of the caller must not affect the cache
+			principal.Roles = append(principal.Roles[:0], "changed")
+		}
+	}
+}
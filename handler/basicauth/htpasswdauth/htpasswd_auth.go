@@ -10,6 +10,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 
 	"github.com/tg123/go-htpasswd"
 
@@ -22,14 +23,19 @@ var ErrEmptyInput = errors.New("input is empty")
 // ErrNotInitialized is returned when the htpasswd authenticator is not initialized.
 var ErrNotInitialized = errors.New("htpasswd auth not initialized")
 
+// ErrNoFile is returned when reloading an authenticator that was not read from a file.
+var ErrNoFile = errors.New("htpasswd auth not read from file")
+
 // HTPassWDAuth holds the htpasswd relevant data.
 type HTPassWDAuth struct {
-	auth *htpasswd.File
+	auth     *htpasswd.File
+	fileName string        // fileName is the name of the file the data was read from
+	version  atomic.Uint64 // version is incremented on every reload
 }
 
-// Authorize checks if for a given username the password hash matches the
+// Authenticate checks if for a given username the password hash matches the
 // one stored in the used htpasswd file.
-func (a *HTPassWDAuth) Authorize(username, password string) (bool, error) {
+func (a *HTPassWDAuth) Authenticate(username, password string) (bool, error) {
 	if a == nil {
 		return false, ErrNotInitialized
 	}
@@ -41,6 +47,52 @@ func (a *HTPassWDAuth) Authorize(username, password string) (bool, error) {
 	return a.auth.Match(username, password), nil
 }
 
+// Authorize checks if for a given username the password hash matches the
+// one stored in the used htpasswd file.
+//
+// Deprecated: use Authenticate, that makes HTPassWDAuth a basicauth.Authenticator.
+func (a *HTPassWDAuth) Authorize(username, password string) (bool, error) {
+	return a.Authenticate(username, password)
+}
+
+// Version gives the number of reloads done so far. It is used to detect changes of
+// the credentials, e.g. to invalidate caches.
+func (a *HTPassWDAuth) Version() uint64 {
+	if a == nil {
+		return 0
+	}
+
+	return a.version.Load()
+}
+
+// Reload reads the htpasswd file again. This is only possible, if the authenticator
+// was configured using WithAuthFile. If reading fails, the old data stays in use.
+func (a *HTPassWDAuth) Reload() error {
+	if a == nil || a.auth == nil {
+		return ErrNotInitialized
+	}
+
+	if a.fileName == "" {
+		return ErrNoFile
+	}
+
+	input, err := os.Open(a.fileName)
+
+	if err != nil {
+		return fmt.Errorf("could not open auth file: %w", err)
+	}
+
+	defer func() { _ = input.Close() }()
+
+	if err := a.auth.ReloadFromReader(input, nil); err != nil {
+		return helper.WrapIfError("could not read htpasswd input", err)
+	}
+
+	a.version.Add(1)
+
+	return nil
+}
+
 // WithAuthInput configures the htpasswd file to be read from the
 // given io.Reader.
 func WithAuthInput(in io.Reader) func(a *HTPassWDAuth) error {
@@ -51,6 +103,7 @@ func WithAuthInput(in io.Reader) func(a *HTPassWDAuth) error {
 
 		var err error
 
+		a.fileName = ""
 		a.auth, err = htpasswd.NewFromReader(in, htpasswd.DefaultSystems, nil)
 
 		return helper.WrapIfError("could not read htpasswd input", err)
@@ -73,16 +126,22 @@ func WithAuthFile(fileName string) func(a *HTPassWDAuth) error {
 
 		defer func() { _ = input.Close() }()
 
-		return WithAuthInput(input)(auth)
+		if err := WithAuthInput(input)(auth); err != nil {
+			return err
+		}
+
+		auth.fileName = filepath.Clean(fileName)
+
+		return nil
 	}
 }
 
 // New creates a new htpasswd authenticator.
 func New(options ...func(*HTPassWDAuth) error) (*HTPassWDAuth, error) {
-	auth := HTPassWDAuth{}
+	auth := &HTPassWDAuth{}
 
 	for _, opt := range options {
-		if err := opt(&auth); err != nil {
+		if err := opt(auth); err != nil {
 			return nil, err
 		}
 	}
@@ -91,5 +150,5 @@ func New(options ...func(*HTPassWDAuth) error) (*HTPassWDAuth, error) {
 		return nil, ErrEmptyInput
 	}
 
-	return &auth, nil
+	return auth, nil
 }
@@ -4,10 +4,14 @@
 package htpasswdauth_test
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/AlphaOne1/midgard/handler/basicauth"
 	"github.com/AlphaOne1/midgard/handler/basicauth/htpasswdauth"
 	"github.com/AlphaOne1/midgard/helper"
 )
@@ -104,3 +108,70 @@ func TestHtpasswdEmptyFilename(t *testing.T) {
 		t.Errorf("authorizer initialization with empty filename should give error")
 	}
 }
+
+func TestHtpasswdAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	var a basicauth.Authenticator = helper.Must(
+		htpasswdauth.New(htpasswdauth.WithAuthFile("testwd")))
+
+	if gotAuth, gotErr := a.Authenticate("user1", "pass1"); !gotAuth || gotErr != nil {
+		t.Errorf("expected authentication to succeed, but got %v, %v", gotAuth, gotErr)
+	}
+}
+
+func TestHtpasswdReload(t *testing.T) {
+	t.Parallel()
+
+	fileName := filepath.Join(t.TempDir(), "htpasswd")
+
+	if err := os.WriteFile(fileName, helper.Must(os.ReadFile("testwd")), 0o600); err != nil {
+		t.Fatalf("could not write test file: %v", err)
+	}
+
+	a := helper.Must(htpasswdauth.New(htpasswdauth.WithAuthFile(fileName)))
+
+	if gotAuth, _ := a.Authenticate("user1", "pass1"); !gotAuth {
+		t.Errorf("expected authentication to succeed before reload")
+	}
+
+	// keep only the first line, containing user0
+	content := helper.Must(os.ReadFile("testwd"))
+	content = content[:bytes.IndexByte(content, '\n')+1]
+
+	if err := os.WriteFile(fileName, content, 0o600); err != nil {
+		t.Fatalf("could not write test file: %v", err)
+	}
+
+	if err := a.Reload(); err != nil {
+		t.Fatalf("could not reload: %v", err)
+	}
+
+	if a.Version() != 1 {
+		t.Errorf("expected version 1 after reload, but got %v", a.Version())
+	}
+
+	if gotAuth, _ := a.Authenticate("user1", "pass1"); gotAuth {
+		t.Errorf("expected authentication to fail after reload")
+	}
+
+	if gotAuth, _ := a.Authenticate("user0", "pass0"); !gotAuth {
+		t.Errorf("expected authentication to succeed after reload")
+	}
+}
+
+func TestHtpasswdReloadNoFile(t *testing.T) {
+	t.Parallel()
+
+	a := helper.Must(htpasswdauth.New(htpasswdauth.WithAuthInput(strings.NewReader("user:pass"))))
+
+	if err := a.Reload(); !errors.Is(err, htpasswdauth.ErrNoFile) {
+		t.Errorf("expected no file error, but got %v", err)
+	}
+
+	var subject *htpasswdauth.HTPassWDAuth
+
+	if err := subject.Reload(); !errors.Is(err, htpasswdauth.ErrNotInitialized) {
+		t.Errorf("expected not initialized error, but got %v", err)
+	}
+}
@@ -0,0 +1,487 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package ldapauth implements the basic auth functionality using an LDAP directory.
+package ldapauth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// ErrNotInitialized is returned when the LDAP authenticator is not initialized.
+var ErrNotInitialized = errors.New("ldap auth not initialized")
+
+// ErrNoURL is returned when no server URL is configured.
+var ErrNoURL = errors.New("no ldap url configured")
+
+// ErrNoUserLookup is returned when neither a DN template nor a user search is configured.
+var ErrNoUserLookup = errors.New("neither dn template nor user search configured")
+
+// ErrAmbiguousUser is returned when the user search finds more than one entry.
+var ErrAmbiguousUser = errors.New("user search result ambiguous")
+
+// ErrInvalidPoolSize is returned when the connection pool size is negative.
+var ErrInvalidPoolSize = errors.New("pool size must not be negative")
+
+// ErrInvalidTimeout is returned when the timeout is not positive.
+var ErrInvalidTimeout = errors.New("timeout must be greater than 0")
+
+// DefaultPoolSize is the number of idle connections kept, if not configured otherwise.
+const DefaultPoolSize = 4
+
+// DefaultTimeout is the timeout of LDAP operations, if not configured otherwise.
+const DefaultTimeout = 10 * time.Second
+
+// LDAPAuth holds the LDAP relevant data.
+type LDAPAuth struct {
+	url       string      // url of the server, ldap:// or ldaps://
+	startTLS  bool        // startTLS signalizes to upgrade plain connections using StartTLS
+	tlsConfig *tls.Config // tlsConfig is used for ldaps:// and StartTLS
+	timeout   time.Duration
+
+	dnTemplate string // dnTemplate is the user DN with %s as placeholder for the username
+
+	bindDN       string // bindDN is the service account used for searches
+	bindPassword string // bindPassword is the password of the service account
+	searchBase   string // searchBase is the base DN of the user search
+	searchFilter string // searchFilter is the user search filter with %s as placeholder
+
+	groupAttribute   string              // groupAttribute of the user entry, containing the groups
+	groupBase        string              // groupBase is the base DN of the group search
+	groupFilter      string              // groupFilter is the group search filter, %s is the user DN
+	roleMapping      map[string][]string // roleMapping maps lower case group DNs to roles
+	groupNamesAsRole bool                // groupNamesAsRole uses the group DNs as roles, if not mapped
+
+	poolSize int
+	pool     chan *ldap.Conn // pool contains the idle connections
+}
+
+// timeLimit gives the time limit of searches in full seconds, as the protocol knows no
+// smaller unit.
+func (a *LDAPAuth) timeLimit() int {
+	return int(math.Ceil(a.timeout.Seconds()))
+}
+
+// dial opens a new connection to the server.
+func (a *LDAPAuth) dial() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(a.url,
+		ldap.DialWithTLSConfig(a.tlsConfig),
+		ldap.DialWithDialer(&net.Dialer{Timeout: a.timeout}))
+
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to ldap server: %w", err)
+	}
+
+	conn.SetTimeout(a.timeout)
+
+	if a.startTLS && strings.HasPrefix(strings.ToLower(a.url), "ldap://") {
+		if err := conn.StartTLS(a.startTLSConfig()); err != nil {
+			_ = conn.Close()
+
+			return nil, fmt.Errorf("could not start tls: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// startTLSConfig gives the TLS configuration for StartTLS. Unlike for ldaps://, the
+// server name is not derived from the URL automatically, so it is set here if missing.
+func (a *LDAPAuth) startTLSConfig() *tls.Config {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if a.tlsConfig != nil {
+		config = a.tlsConfig.Clone()
+	}
+
+	if config.ServerName == "" && !config.InsecureSkipVerify {
+		if u, err := url.Parse(a.url); err == nil {
+			config.ServerName = u.Hostname()
+		}
+	}
+
+	return config
+}
+
+// get takes an idle connection out of the pool or opens a new one.
+func (a *LDAPAuth) get() (*ldap.Conn, error) {
+	for {
+		select {
+		case conn := <-a.pool:
+			if !conn.IsClosing() {
+				return conn, nil
+			}
+		default:
+			return a.dial()
+		}
+	}
+}
+
+// put returns a connection to the pool. If the pool is full, the connection is closed.
+func (a *LDAPAuth) put(conn *ldap.Conn) {
+	select {
+	case a.pool <- conn:
+	default:
+		_ = conn.Close()
+	}
+}
+
+// Close closes all idle connections.
+func (a *LDAPAuth) Close() {
+	if a == nil {
+		return
+	}
+
+	for {
+		select {
+		case conn := <-a.pool:
+			_ = conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// userDN determines the DN of the user, either using the template or searching it.
+func (a *LDAPAuth) userDN(conn *ldap.Conn, username string) (string, bool, error) {
+	if a.dnTemplate != "" {
+		return fmt.Sprintf(a.dnTemplate, ldap.EscapeDN(username)), true, nil
+	}
+
+	if err := a.serviceBind(conn); err != nil {
+		return "", false, err
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.searchBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, a.timeLimit(), false,
+		fmt.Sprintf(a.searchFilter, ldap.EscapeFilter(username)),
+		[]string{"dn"}, nil))
+
+	if err != nil {
+		return "", false, fmt.Errorf("could not search user: %w", err)
+	}
+
+	switch len(result.Entries) {
+	case 0:
+		return "", false, nil
+	case 1:
+		return result.Entries[0].DN, true, nil
+	default:
+		return "", false, ErrAmbiguousUser
+	}
+}
+
+// serviceBind binds the connection as the service account. Without a configured
+// service account, the connection is bound anonymously, as a pooled connection may
+// still be bound as a former user.
+func (a *LDAPAuth) serviceBind(conn *ldap.Conn) error {
+	if a.bindDN == "" {
+		return helper.WrapIfError("could not bind anonymously", conn.UnauthenticatedBind(""))
+	}
+
+	return helper.WrapIfError("could not bind service account", conn.Bind(a.bindDN, a.bindPassword))
+}
+
+// roles determines the roles of the user with the given DN from its group memberships.
+func (a *LDAPAuth) roles(conn *ldap.Conn, userDN string) ([]string, error) {
+	var groups []string
+
+	if a.groupAttribute != "" {
+		result, err := conn.Search(ldap.NewSearchRequest(
+			userDN, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, a.timeLimit(), false,
+			"(objectClass=*)", []string{a.groupAttribute}, nil))
+
+		if err != nil {
+			return nil, fmt.Errorf("could not read groups of user: %w", err)
+		}
+
+		for _, e := range result.Entries {
+			groups = append(groups, e.GetAttributeValues(a.groupAttribute)...)
+		}
+	}
+
+	if a.groupFilter != "" {
+		result, err := conn.Search(ldap.NewSearchRequest(
+			a.groupBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, a.timeLimit(), false,
+			fmt.Sprintf(a.groupFilter, ldap.EscapeFilter(userDN)), []string{"dn"}, nil))
+
+		if err != nil {
+			return nil, fmt.Errorf("could not search groups of user: %w", err)
+		}
+
+		for _, e := range result.Entries {
+			groups = append(groups, e.DN)
+		}
+	}
+
+	var roles []string
+
+	for _, g := range groups {
+		mapped, found := a.roleMapping[strings.ToLower(g)]
+
+		switch {
+		case found:
+			roles = append(roles, mapped...)
+		case a.groupNamesAsRole:
+			roles = append(roles, g)
+		}
+	}
+
+	slices.Sort(roles)
+
+	return slices.Compact(roles), nil
+}
+
+// AuthenticatePrincipal checks the credentials by binding as the user and gives the
+// principal of the user, including the roles derived from the group memberships.
+func (a *LDAPAuth) AuthenticatePrincipal(username, password string) (*defs.Principal, bool, error) {
+	if a == nil || a.pool == nil {
+		return nil, false, ErrNotInitialized
+	}
+
+	// an empty password results in an unauthenticated bind, that always succeeds
+	if username == "" || password == "" {
+		return nil, false, nil
+	}
+
+	conn, err := a.get()
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	principal, ok, err := a.authenticate(conn, username, password)
+
+	var ldapErr *ldap.Error
+
+	// connections with protocol or network errors are not reused
+	if err != nil && (!errors.As(err, &ldapErr) || ldapErr.ResultCode >= ldap.ErrorNetwork) {
+		_ = conn.Close()
+	} else {
+		a.put(conn)
+	}
+
+	return principal, ok, err
+}
+
+// authenticate does the authentication steps on the given connection.
+func (a *LDAPAuth) authenticate(conn *ldap.Conn, username, password string) (*defs.Principal, bool, error) {
+	dn, found, err := a.userDN(conn, username)
+
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	if err := conn.Bind(dn, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, false, nil
+		}
+
+		return nil, false, fmt.Errorf("could not bind user: %w", err)
+	}
+
+	if a.groupAttribute == "" && a.groupFilter == "" {
+		return &defs.Principal{Name: username, Claims: map[string]any{"dn": dn}}, true, nil
+	}
+
+	// read the groups with the service account, if configured, as users may not see
+	// them. Otherwise, they are read as the user.
+	if a.bindDN != "" {
+		if err := a.serviceBind(conn); err != nil {
+			return nil, false, err
+		}
+	}
+
+	roles, err := a.roles(conn, dn)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &defs.Principal{Name: username, Roles: roles, Claims: map[string]any{"dn": dn}}, true, nil
+}
+
+// Authenticate checks the credentials by binding as the user.
+func (a *LDAPAuth) Authenticate(username, password string) (bool, error) {
+	_, ok, err := a.AuthenticatePrincipal(username, password)
+
+	return ok, err
+}
+
+// WithURL sets the URL of the LDAP server, e.g. ldaps://ldap.example.org:636.
+func WithURL(serverURL string) func(a *LDAPAuth) error {
+	return func(a *LDAPAuth) error {
+		if serverURL == "" {
+			return ErrNoURL
+		}
+
+		a.url = serverURL
+
+		return nil
+	}
+}
+
+// WithStartTLS configures to upgrade ldap:// connections using StartTLS.
+func WithStartTLS() func(a *LDAPAuth) error {
+	return func(a *LDAPAuth) error {
+		a.startTLS = true
+
+		return nil
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used for ldaps:// and StartTLS.
+func WithTLSConfig(config *tls.Config) func(a *LDAPAuth) error {
+	return func(a *LDAPAuth) error {
+		a.tlsConfig = config
+
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout for connecting to the server and for LDAP operations.
+// The time limit of searches on the server side is rounded up to full seconds.
+func WithTimeout(d time.Duration) func(a *LDAPAuth) error {
+	return func(a *LDAPAuth) error {
+		if d <= 0 {
+			return ErrInvalidTimeout
+		}
+
+		a.timeout = d
+
+		return nil
+	}
+}
+
+// WithDNTemplate configures the simple bind mode, where the user DN is derived from the
+// username using the given template, e.g. "uid=%s,ou=people,dc=example,dc=org".
+func WithDNTemplate(template string) func(a *LDAPAuth) error {
+	return func(a *LDAPAuth) error {
+		a.dnTemplate = template
+
+		return nil
+	}
+}
+
+// WithServiceAccount sets the account used for searches. Without it, searches are done
+// anonymously.
+func WithServiceAccount(bindDN, password string) func(a *LDAPAuth) error {
+	return func(a *LDAPAuth) error {
+		a.bindDN = bindDN
+		a.bindPassword = password
+
+		return nil
+	}
+}
+
+// WithUserSearch configures the search-then-bind mode, where the user DN is searched
+// below the base DN using the filter, e.g. "(&(objectClass=person)(uid=%s))".
+func WithUserSearch(baseDN, filter string) func(a *LDAPAuth) error {
+	return func(a *LDAPAuth) error {
+		a.searchBase = baseDN
+		a.searchFilter = filter
+
+		return nil
+	}
+}
+
+// WithGroupAttribute configures to read the groups of a user from the given attribute
+// of its entry, e.g. "memberOf".
+func WithGroupAttribute(attribute string) func(a *LDAPAuth) error {
+	return func(a *LDAPAuth) error {
+		a.groupAttribute = attribute
+
+		return nil
+	}
+}
+
+// WithGroupSearch configures to search the groups of a user below the base DN using the
+// filter, in which %s is replaced by the user DN, e.g. "(member=%s)".
+func WithGroupSearch(baseDN, filter string) func(a *LDAPAuth) error {
+	return func(a *LDAPAuth) error {
+		a.groupBase = baseDN
+		a.groupFilter = filter
+
+		return nil
+	}
+}
+
+// WithRoleMapping sets the roles assigned to the members of the given group DNs.
+// Group DNs are compared case-insensitively. If used multiple times, the mappings
+// are merged.
+func WithRoleMapping(mapping map[string][]string) func(a *LDAPAuth) error {
+	return func(a *LDAPAuth) error {
+		if a.roleMapping == nil {
+			a.roleMapping = make(map[string][]string, len(mapping))
+		}
+
+		for group, roles := range mapping {
+			key := strings.ToLower(group)
+			a.roleMapping[key] = append(a.roleMapping[key], roles...)
+		}
+
+		return nil
+	}
+}
+
+// WithGroupNamesAsRoles configures to use the DNs of groups without role mapping as roles.
+func WithGroupNamesAsRoles() func(a *LDAPAuth) error {
+	return func(a *LDAPAuth) error {
+		a.groupNamesAsRole = true
+
+		return nil
+	}
+}
+
+// WithPoolSize sets the maximum number of idle connections kept for reuse.
+func WithPoolSize(size int) func(a *LDAPAuth) error {
+	return func(a *LDAPAuth) error {
+		if size < 0 {
+			return ErrInvalidPoolSize
+		}
+
+		a.poolSize = size
+
+		return nil
+	}
+}
+
+// New creates a new LDAP authenticator. The connections to the server are established
+// on demand.
+func New(options ...func(*LDAPAuth) error) (*LDAPAuth, error) {
+	auth := LDAPAuth{
+		timeout:  DefaultTimeout,
+		poolSize: DefaultPoolSize,
+	}
+
+	for _, opt := range options {
+		if err := opt(&auth); err != nil {
+			return nil, err
+		}
+	}
+
+	if auth.url == "" {
+		return nil, ErrNoURL
+	}
+
+	if auth.dnTemplate == "" && auth.searchFilter == "" {
+		return nil, ErrNoUserLookup
+	}
+
+	auth.pool = make(chan *ldap.Conn, auth.poolSize)
+
+	return &auth, nil
+}
@@ -0,0 +1,412 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package ldapauth_test
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/basicauth"
+	"github.com/AlphaOne1/midgard/handler/basicauth/ldapauth"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+const (
+	adminsDN = "cn=admins,ou=groups,dc=example,dc=org"
+	devsDN   = "cn=devs,ou=groups,dc=example,dc=org"
+)
+
+func testEntries() []ldapEntry {
+	return []ldapEntry{
+		{
+			DN:       "cn=service,dc=example,dc=org",
+			Password: "service-secret",
+		},
+		{
+			DN:       "uid=alice,ou=people,dc=example,dc=org",
+			Password: "alice-secret",
+			Attrs: map[string][]string{
+				"uid":      {"alice"},
+				"mail":     {"alice@example.org"},
+				"memberOf": {adminsDN, devsDN},
+			},
+		},
+		{
+			DN:       "uid=bob,ou=people,dc=example,dc=org",
+			Password: "bob-secret",
+			Attrs: map[string][]string{
+				"uid":  {"bob"},
+				"mail": {"bob@example.org"},
+			},
+		},
+		{
+			DN: devsDN,
+			Attrs: map[string][]string{
+				"member": {"uid=alice,ou=people,dc=example,dc=org", "uid=bob,ou=people,dc=example,dc=org"},
+			},
+		},
+	}
+}
+
+func TestTemplateBind(t *testing.T) {
+	t.Parallel()
+
+	server, _ := startLDAPServer(t, testEntries(), false)
+
+	auth := helper.Must(ldapauth.New(
+		ldapauth.WithURL("ldap://"+server.Addr()),
+		ldapauth.WithDNTemplate("uid=%s,ou=people,dc=example,dc=org")))
+	t.Cleanup(auth.Close)
+
+	tests := []struct {
+		User string
+		Pass string
+		Want bool
+	}{
+		{User: "alice", Pass: "alice-secret", Want: true},  // 0
+		{User: "alice", Pass: "wrong", Want: false},        // 1
+		{User: "bob", Pass: "bob-secret", Want: true},      // 2
+		{User: "carol", Pass: "carol-secret", Want: false}, // 3
+		{User: "alice", Pass: "", Want: false},             // 4
+		{User: "", Pass: "", Want: false},                  // 5
+		{User: "alice,dc=x", Pass: "x", Want: false},       // 6
+	}
+
+	for k, test := range tests {
+		got, err := auth.Authenticate(test.User, test.Pass)
+
+		if err != nil {
+			t.Errorf("%v: got unexpected error: %v", k, err)
+		}
+
+		if got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+	}
+
+	if conns := server.conns.Load(); conns != 1 {
+		t.Errorf("connections should be reused, but got %v", conns)
+	}
+}
+
+func TestSearchBind(t *testing.T) {
+	t.Parallel()
+
+	server, _ := startLDAPServer(t, testEntries(), false)
+
+	tests := []struct {
+		Options   []func(*ldapauth.LDAPAuth) error
+		User      string
+		Pass      string
+		Want      bool
+		WantErr   bool
+		WantRoles []string
+	}{
+		{ // 0
+			Options: []func(*ldapauth.LDAPAuth) error{
+				ldapauth.WithUserSearch("ou=people,dc=example,dc=org", "(uid=%s)"),
+			},
+			User: "alice", Pass: "alice-secret", Want: true,
+		},
+		{ // 1
+			Options: []func(*ldapauth.LDAPAuth) error{
+				ldapauth.WithUserSearch("ou=people,dc=example,dc=org", "(mail=%s)"),
+			},
+			User: "bob@example.org", Pass: "bob-secret", Want: true,
+		},
+		{ // 2
+			Options: []func(*ldapauth.LDAPAuth) error{
+				ldapauth.WithUserSearch("ou=people,dc=example,dc=org", "(uid=%s)"),
+			},
+			User: "*", Pass: "alice-secret", Want: false,
+		},
+		{ // 3
+			Options: []func(*ldapauth.LDAPAuth) error{
+				ldapauth.WithUserSearch("ou=people,dc=example,dc=org", "(uid=*)"),
+			},
+			User: "alice", Pass: "alice-secret", Want: false, WantErr: true,
+		},
+		{ // 4
+			Options: []func(*ldapauth.LDAPAuth) error{
+				ldapauth.WithServiceAccount("cn=service,dc=example,dc=org", "service-secret"),
+				ldapauth.WithUserSearch("ou=people,dc=example,dc=org", "(uid=%s)"),
+				ldapauth.WithGroupAttribute("memberOf"),
+				ldapauth.WithRoleMapping(map[string][]string{"CN=Admins,OU=Groups,DC=example,DC=org": {"admin"}}),
+			},
+			User: "alice", Pass: "alice-secret", Want: true, WantRoles: []string{"admin"},
+		},
+		{ // 5
+			Options: []func(*ldapauth.LDAPAuth) error{
+				ldapauth.WithServiceAccount("cn=service,dc=example,dc=org", "wrong"),
+				ldapauth.WithUserSearch("ou=people,dc=example,dc=org", "(uid=%s)"),
+			},
+			User: "alice", Pass: "alice-secret", Want: false, WantErr: true,
+		},
+		{ // 6
+			Options: []func(*ldapauth.LDAPAuth) error{
+				ldapauth.WithDNTemplate("uid=%s,ou=people,dc=example,dc=org"),
+				ldapauth.WithGroupAttribute("memberOf"),
+				ldapauth.WithGroupNamesAsRoles(),
+				ldapauth.WithRoleMapping(map[string][]string{adminsDN: {"admin", "ops"}}),
+			},
+			User: "alice", Pass: "alice-secret", Want: true, WantRoles: []string{"admin", devsDN, "ops"},
+		},
+		{ // 7
+			Options: []func(*ldapauth.LDAPAuth) error{
+				ldapauth.WithDNTemplate("uid=%s,ou=people,dc=example,dc=org"),
+				ldapauth.WithGroupSearch("ou=groups,dc=example,dc=org", "(member=%s)"),
+				ldapauth.WithRoleMapping(map[string][]string{devsDN: {"developer"}}),
+			},
+			User: "bob", Pass: "bob-secret", Want: true, WantRoles: []string{"developer"},
+		},
+		{ // 8
+			Options: []func(*ldapauth.LDAPAuth) error{
+				ldapauth.WithDNTemplate("uid=%s,ou=people,dc=example,dc=org"),
+				ldapauth.WithGroupAttribute("memberOf"),
+				ldapauth.WithGroupSearch("ou=groups,dc=example,dc=org", "(member=%s)"),
+				ldapauth.WithRoleMapping(map[string][]string{
+					adminsDN: {"admin"},
+					devsDN:   {"developer"},
+				}),
+			},
+			User: "alice", Pass: "alice-secret", Want: true, WantRoles: []string{"admin", "developer"},
+		},
+	}
+
+	for k, test := range tests {
+		auth := helper.Must(ldapauth.New(append(test.Options, ldapauth.WithURL("ldap://"+server.Addr()))...))
+
+		principal, got, err := auth.AuthenticatePrincipal(test.User, test.Pass)
+		auth.Close()
+
+		if (err != nil) != test.WantErr {
+			t.Errorf("%v: got error %v but wanted error %v", k, err, test.WantErr)
+		}
+
+		if got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+
+			continue
+		}
+
+		if !got {
+			continue
+		}
+
+		if principal.Name != test.User {
+			t.Errorf("%v: got principal name %q but wanted %q", k, principal.Name, test.User)
+		}
+
+		if !slices.Equal(principal.Roles, test.WantRoles) {
+			t.Errorf("%v: got roles %v but wanted %v", k, principal.Roles, test.WantRoles)
+		}
+	}
+}
+
+func TestTLS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		LDAPS    bool
+		StartTLS bool
+		Trusted  bool
+		Want     bool
+	}{
+		{LDAPS: true, Trusted: true, Want: true},      // 0
+		{LDAPS: true, Trusted: false, Want: false},    // 1
+		{StartTLS: true, Trusted: true, Want: true},   // 2
+		{StartTLS: true, Trusted: false, Want: false}, // 3
+	}
+
+	for k, test := range tests {
+		server, pool := startLDAPServer(t, testEntries(), test.LDAPS)
+
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+		if test.Trusted {
+			tlsConfig.RootCAs = pool
+		}
+
+		options := []func(*ldapauth.LDAPAuth) error{
+			ldapauth.WithDNTemplate("uid=%s,ou=people,dc=example,dc=org"),
+			ldapauth.WithTLSConfig(tlsConfig),
+			ldapauth.WithTimeout(2 * time.Second),
+		}
+
+		if test.LDAPS {
+			options = append(options, ldapauth.WithURL("ldaps://"+server.Addr()))
+		} else {
+			options = append(options, ldapauth.WithURL("ldap://"+server.Addr()))
+		}
+
+		if test.StartTLS {
+			options = append(options, ldapauth.WithStartTLS())
+		}
+
+		auth := helper.Must(ldapauth.New(options...))
+		got, err := auth.Authenticate("alice", "alice-secret")
+		auth.Close()
+
+		if got != test.Want || (err == nil) != test.Want {
+			t.Errorf("%v: got %v, %v but wanted %v", k, got, err, test.Want)
+		}
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	t.Parallel()
+
+	server, _ := startLDAPServer(t, nil, false)
+	addr := server.Addr()
+	_ = server.listener.Close()
+
+	auth := helper.Must(ldapauth.New(
+		ldapauth.WithURL("ldap://"+addr),
+		ldapauth.WithDNTemplate("uid=%s,ou=people,dc=example,dc=org")))
+
+	if got, err := auth.Authenticate("alice", "alice-secret"); got || err == nil {
+		t.Errorf("expected error on unreachable server, but got %v, %v", got, err)
+	}
+}
+
+func TestDialTimeout(t *testing.T) {
+	t.Parallel()
+
+	// the server accepts connections, but never answers the TLS handshake
+	listener := helper.Must(net.Listen("tcp", "127.0.0.1:0"))
+	t.Cleanup(func() { _ = listener.Close() })
+
+	auth := helper.Must(ldapauth.New(
+		ldapauth.WithURL("ldaps://"+listener.Addr().String()),
+		ldapauth.WithDNTemplate("uid=%s,ou=people,dc=example,dc=org"),
+		ldapauth.WithTimeout(200*time.Millisecond)))
+	defer auth.Close()
+
+	start := time.Now()
+
+	if got, err := auth.Authenticate("alice", "alice-secret"); got || err == nil {
+		t.Errorf("expected error on hanging server, but got %v, %v", got, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("dial took %v, but the timeout is 200ms", elapsed)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options []func(*ldapauth.LDAPAuth) error
+		WantErr error
+	}{
+		{ // 0
+			Options: []func(*ldapauth.LDAPAuth) error{ldapauth.WithDNTemplate("uid=%s")},
+			WantErr: ldapauth.ErrNoURL,
+		},
+		{ // 1
+			Options: []func(*ldapauth.LDAPAuth) error{ldapauth.WithURL("")},
+			WantErr: ldapauth.ErrNoURL,
+		},
+		{ // 2
+			Options: []func(*ldapauth.LDAPAuth) error{ldapauth.WithURL("ldap://localhost")},
+			WantErr: ldapauth.ErrNoUserLookup,
+		},
+		{ // 3
+			Options: []func(*ldapauth.LDAPAuth) error{
+				ldapauth.WithURL("ldap://localhost"),
+				ldapauth.WithDNTemplate("uid=%s"),
+				ldapauth.WithPoolSize(-1),
+			},
+			WantErr: ldapauth.ErrInvalidPoolSize,
+		},
+		{ // 4
+			Options: []func(*ldapauth.LDAPAuth) error{
+				ldapauth.WithURL("ldap://localhost"),
+				ldapauth.WithUserSearch("dc=example,dc=org", "(uid=%s)"),
+				ldapauth.WithPoolSize(0),
+			},
+			WantErr: nil,
+		},
+		{ // 5
+			Options: []func(*ldapauth.LDAPAuth) error{
+				ldapauth.WithURL("ldap://localhost"),
+				ldapauth.WithDNTemplate("uid=%s"),
+				ldapauth.WithTimeout(0),
+			},
+			WantErr: ldapauth.ErrInvalidTimeout,
+		},
+	}
+
+	for k, test := range tests {
+		_, err := ldapauth.New(test.Options...)
+
+		if !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+}
+
+func TestNotInitialized(t *testing.T) {
+	t.Parallel()
+
+	var auth *ldapauth.LDAPAuth
+
+	if _, err := auth.Authenticate("alice", "alice-secret"); !errors.Is(err, ldapauth.ErrNotInitialized) {
+		t.Errorf("expected ErrNotInitialized, but got %v", err)
+	}
+
+	auth.Close()
+}
+
+func TestBasicAuthPrincipal(t *testing.T) {
+	t.Parallel()
+
+	server, _ := startLDAPServer(t, testEntries(), false)
+
+	auth := helper.Must(ldapauth.New(
+		ldapauth.WithURL("ldap://"+server.Addr()),
+		ldapauth.WithDNTemplate("uid=%s,ou=people,dc=example,dc=org"),
+		ldapauth.WithGroupAttribute("memberOf"),
+		ldapauth.WithRoleMapping(map[string][]string{adminsDN: {"admin"}})))
+	t.Cleanup(auth.Close)
+
+	var principal *defs.Principal
+
+	handler := helper.Must(basicauth.New(
+		basicauth.WithAuthenticator(auth),
+		basicauth.WithRealm("testrealm")))(
+		http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			principal, _ = defs.PrincipalFromContext(r.Context())
+		}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("alice", "alice-secret")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %v but wanted %v", rec.Code, http.StatusOK)
+	}
+
+	if principal == nil {
+		t.Fatal("principal not set")
+	}
+
+	if principal.Name != "alice" || principal.Method != "basic" || !slices.Equal(principal.Roles, []string{"admin"}) {
+		t.Errorf("got unexpected principal %+v", principal)
+	}
+
+	if principal.Claims["dn"] != "uid=alice,ou=people,dc=example,dc=org" {
+		t.Errorf("got unexpected dn claim %v", principal.Claims["dn"])
+	}
+}
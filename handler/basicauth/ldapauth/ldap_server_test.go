@@ -0,0 +1,359 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package ldapauth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// LDAP protocol operations and result codes used by the stand-in server.
+const (
+	opBindRequest       = 0
+	opBindResponse      = 1
+	opUnbindRequest     = 2
+	opSearchRequest     = 3
+	opSearchResultEntry = 4
+	opSearchResultDone  = 5
+	opExtendedRequest   = 23
+	opExtendedResponse  = 24
+
+	resultSuccess            = 0
+	resultNoSuchObject       = 32
+	resultInvalidCredentials = 49
+	resultUnwilling          = 53
+
+	oidStartTLS = "1.3.6.1.4.1.1466.20037"
+)
+
+// ldapEntry is an entry of the stand-in directory.
+type ldapEntry struct {
+	DN       string
+	Password string
+	Attrs    map[string][]string
+}
+
+// ldapServer is a minimal in-process LDAP server, supporting simple binds, searches
+// with equality, presence and boolean filters, and StartTLS.
+type ldapServer struct {
+	listener  net.Listener
+	entries   []ldapEntry
+	tlsConfig *tls.Config // tlsConfig is used for StartTLS
+	conns     atomic.Int64
+	wg        sync.WaitGroup
+}
+
+// newTestCertificate creates a self-signed server certificate for 127.0.0.1.
+func newTestCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key := helper.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ldap test"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der := helper.Must(x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key))
+	pool := x509.NewCertPool()
+	pool.AddCert(helper.Must(x509.ParseCertificate(der)))
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+// startLDAPServer starts the stand-in server. If useTLS is true, it listens for
+// LDAPS connections, otherwise it offers StartTLS.
+func startLDAPServer(t *testing.T, entries []ldapEntry, useTLS bool) (*ldapServer, *x509.CertPool) {
+	t.Helper()
+
+	cert, pool := newTestCertificate(t)
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+
+	var listener net.Listener
+
+	if useTLS {
+		listener = helper.Must(tls.Listen("tcp", "127.0.0.1:0", tlsConfig))
+	} else {
+		listener = helper.Must(net.Listen("tcp", "127.0.0.1:0"))
+	}
+
+	s := &ldapServer{listener: listener, entries: entries, tlsConfig: tlsConfig}
+
+	s.wg.Go(s.accept)
+
+	t.Cleanup(func() {
+		_ = listener.Close()
+		s.wg.Wait()
+	})
+
+	return s, pool
+}
+
+// Addr gives the address the server listens on.
+func (s *ldapServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// accept accepts connections until the listener is closed.
+func (s *ldapServer) accept() {
+	for {
+		conn, err := s.listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		s.conns.Add(1)
+		s.wg.Go(func() { s.serve(conn) })
+	}
+}
+
+// result creates an LDAPResult based response.
+func result(op ber.Tag, code int64, message string) *ber.Packet {
+	p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, op, nil, "result")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "code"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matched"))
+	p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "message"))
+
+	return p
+}
+
+// send writes a response with the given message id.
+func send(w io.Writer, id int64, op *ber.Packet) error {
+	envelope := ber.NewSequence("message")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "id"))
+	envelope.AppendChild(op)
+
+	_, err := w.Write(envelope.Bytes())
+
+	return err //nolint:wrapcheck // test code
+}
+
+// serve handles the requests of a single connection.
+func (s *ldapServer) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	var bound *ldapEntry
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+
+		id, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case opBindRequest:
+			var code int64
+
+			bound, code = s.bind(op)
+			err = send(conn, id, result(opBindResponse, code, ""))
+		case opSearchRequest:
+			err = s.search(conn, id, op, bound)
+		case opExtendedRequest:
+			if len(op.Children) == 0 || op.Children[0].Data.String() != oidStartTLS {
+				err = send(conn, id, result(opExtendedResponse, resultUnwilling, "unsupported"))
+
+				break
+			}
+
+			if err = send(conn, id, result(opExtendedResponse, resultSuccess, "")); err == nil {
+				tlsConn := tls.Server(conn, s.tlsConfig)
+				err = tlsConn.Handshake()
+				conn = tlsConn
+			}
+		case opUnbindRequest:
+			return
+		default:
+			return
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// bind checks the credentials of a simple bind request.
+func (s *ldapServer) bind(op *ber.Packet) (*ldapEntry, int64) {
+	if len(op.Children) < 3 {
+		return nil, resultInvalidCredentials
+	}
+
+	name, _ := op.Children[1].Value.(string)
+	password := op.Children[2].Data.String()
+
+	if name == "" && password == "" {
+		return nil, resultSuccess
+	}
+
+	for i := range s.entries {
+		if strings.EqualFold(s.entries[i].DN, name) && s.entries[i].Password != "" &&
+			s.entries[i].Password == password {
+
+			return &s.entries[i], resultSuccess
+		}
+	}
+
+	return nil, resultInvalidCredentials
+}
+
+// inScope checks if the DN is within the search scope.
+func inScope(dn, base string, scope int64) bool {
+	dn = strings.ToLower(dn)
+	base = strings.ToLower(base)
+
+	if scope == 0 {
+		return dn == base
+	}
+
+	return dn == base || strings.HasSuffix(dn, ","+base)
+}
+
+// matches evaluates the filter against the entry.
+func matches(e *ldapEntry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case 0: // and
+		for _, c := range filter.Children {
+			if !matches(e, c) {
+				return false
+			}
+		}
+
+		return true
+	case 1: // or
+		for _, c := range filter.Children {
+			if matches(e, c) {
+				return true
+			}
+		}
+
+		return false
+	case 2: // not
+		return len(filter.Children) == 1 && !matches(e, filter.Children[0])
+	case 3: // equality match
+		attr := strings.ToLower(filter.Children[0].Data.String())
+		value := filter.Children[1].Data.String()
+
+		if attr == "objectclass" && value == "*" {
+			return true
+		}
+
+		for k, vals := range e.Attrs {
+			if strings.EqualFold(k, attr) {
+				for _, v := range vals {
+					if strings.EqualFold(v, value) {
+						return true
+					}
+				}
+			}
+		}
+
+		return false
+	case 7: // present
+		attr := filter.Data.String()
+
+		if strings.EqualFold(attr, "objectClass") {
+			return true
+		}
+
+		for k := range e.Attrs {
+			if strings.EqualFold(k, attr) {
+				return true
+			}
+		}
+
+		return false
+	default:
+		return false
+	}
+}
+
+// search answers a search request. Only bound clients can see the memberOf attributes.
+func (s *ldapServer) search(w io.Writer, id int64, op *ber.Packet, bound *ldapEntry) error {
+	if len(op.Children) < 8 {
+		return errors.New("malformed search")
+	}
+
+	base, _ := op.Children[0].Value.(string)
+	scope, _ := op.Children[1].Value.(int64)
+	filter := op.Children[6]
+
+	var wantAttrs []string
+
+	for _, a := range op.Children[7].Children {
+		wantAttrs = append(wantAttrs, a.Data.String())
+	}
+
+	found := 0
+
+	for i := range s.entries {
+		e := &s.entries[i]
+
+		if !inScope(e.DN, base, scope) || !matches(e, filter) {
+			continue
+		}
+
+		found++
+
+		entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, opSearchResultEntry, nil, "entry")
+		entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "dn"))
+		attrs := ber.NewSequence("attributes")
+
+		for _, name := range wantAttrs {
+			vals, ok := e.Attrs[name]
+
+			if !ok || (strings.EqualFold(name, "memberOf") && bound == nil) {
+				continue
+			}
+
+			attr := ber.NewSequence("attribute")
+			attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "values")
+
+			for _, v := range vals {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+			}
+
+			attr.AppendChild(set)
+			attrs.AppendChild(attr)
+		}
+
+		entry.AppendChild(attrs)
+
+		if err := send(w, id, entry); err != nil {
+			return err
+		}
+	}
+
+	code := int64(resultSuccess)
+
+	if found == 0 && scope == 0 {
+		code = resultNoSuchObject
+	}
+
+	return send(w, id, result(opSearchResultDone, code, ""))
+}
@@ -0,0 +1,222 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package concurrencylimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNilAlgorithm is returned when the algorithm is nil.
+var ErrNilAlgorithm = errors.New("algorithm cannot be nil")
+
+// ErrInvalidLimit is returned when a concurrency limit is not greater than 0 or the
+// minimum limit exceeds the maximum.
+var ErrInvalidLimit = errors.New("invalid concurrency limit")
+
+// ErrLimitExceeded is returned when a request exceeds the current concurrency limit.
+var ErrLimitExceeded = errors.New("concurrency limit exceeded")
+
+const (
+	// DefaultInitialLimit is the default concurrency limit to start with.
+	DefaultInitialLimit = 20
+	// DefaultMinLimit is the default lower bound of the concurrency limit.
+	DefaultMinLimit = 1
+	// DefaultMaxLimit is the default upper bound of the concurrency limit.
+	DefaultMaxLimit = 1000
+)
+
+// Sample is the observation of a single request, the algorithms adjust the limit on.
+type Sample struct {
+	// Latency is the time the request took.
+	Latency time.Duration
+	// InFlight is the number of in-flight requests, when the request started, including
+	// itself.
+	InFlight int
+	// Dropped is set, when the request failed due to overload.
+	Dropped bool
+}
+
+// Algorithm calculates the concurrency limit from the observed requests. It is called
+// by the AdaptiveLimiter with its lock held, so it needs no synchronization on its own,
+// but it must not be shared between limiters.
+type Algorithm interface {
+	// Update gives the new limit, given the current one and a sample.
+	Update(limit float64, s Sample) float64
+}
+
+// AdaptiveLimiter limits the number of concurrently processed requests, adjusting the
+// limit from the observed latency and errors. Requests exceeding the limit are rejected
+// immediately, as waiting would just add to the latency the limit is derived from.
+type AdaptiveLimiter struct {
+	// Algorithm calculates the limit from the observed requests.
+	Algorithm Algorithm
+	// InitialLimit is the concurrency limit to start with.
+	InitialLimit int
+	// MinLimit is the lower bound of the concurrency limit.
+	MinLimit int
+	// MaxLimit is the upper bound of the concurrency limit.
+	MaxLimit int
+
+	mtx      sync.Mutex
+	limit    float64
+	inFlight int
+	now      func() time.Time
+}
+
+// Acquire gets a slot for a request, if the current limit permits. The outcome given to
+// the returned Release adjusts the limit, OutcomeIgnored leaves it untouched.
+func (l *AdaptiveLimiter) Acquire(ctx context.Context) (Release, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.inFlight >= int(l.limit) {
+		return nil, ErrLimitExceeded
+	}
+
+	l.inFlight++
+
+	inFlight := l.inFlight
+	start := l.now()
+
+	var once sync.Once
+
+	return func(outcome Outcome) {
+		once.Do(func() {
+			l.release(outcome, Sample{
+				Latency:  l.now().Sub(start),
+				InFlight: inFlight,
+				Dropped:  outcome == OutcomeDropped,
+			})
+		})
+	}, nil
+}
+
+// release frees a slot and adjusts the limit.
+func (l *AdaptiveLimiter) release(outcome Outcome, s Sample) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.inFlight--
+
+	if outcome == OutcomeIgnored {
+		return
+	}
+
+	l.limit = min(float64(l.MaxLimit), max(float64(l.MinLimit), l.Algorithm.Update(l.limit, s)))
+}
+
+// Limit gives the current concurrency limit.
+func (l *AdaptiveLimiter) Limit() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return int(l.limit)
+}
+
+// InFlight gives the number of requests currently processed.
+func (l *AdaptiveLimiter) InFlight() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.inFlight
+}
+
+// WithAlgorithm sets the algorithm calculating the limit.
+func WithAlgorithm(a Algorithm) func(l *AdaptiveLimiter) error {
+	return func(l *AdaptiveLimiter) error {
+		if a == nil {
+			return ErrNilAlgorithm
+		}
+
+		l.Algorithm = a
+
+		return nil
+	}
+}
+
+// WithInitialLimit sets the concurrency limit to start with. It is kept within the
+// minimum and maximum limit.
+func WithInitialLimit(n int) func(l *AdaptiveLimiter) error {
+	return func(l *AdaptiveLimiter) error {
+		if n <= 0 {
+			return ErrInvalidLimit
+		}
+
+		l.InitialLimit = n
+
+		return nil
+	}
+}
+
+// WithMinLimit sets the lower bound of the concurrency limit.
+func WithMinLimit(n int) func(l *AdaptiveLimiter) error {
+	return func(l *AdaptiveLimiter) error {
+		if n <= 0 {
+			return ErrInvalidLimit
+		}
+
+		l.MinLimit = n
+
+		return nil
+	}
+}
+
+// WithMaxLimit sets the upper bound of the concurrency limit.
+func WithMaxLimit(n int) func(l *AdaptiveLimiter) error {
+	return func(l *AdaptiveLimiter) error {
+		if n <= 0 {
+			return ErrInvalidLimit
+		}
+
+		l.MaxLimit = n
+
+		return nil
+	}
+}
+
+// NewAdaptiveLimiter creates a new adaptive concurrency limiter. Without an algorithm
+// given, the gradient algorithm with its defaults is used.
+func NewAdaptiveLimiter(options ...func(*AdaptiveLimiter) error) (*AdaptiveLimiter, error) {
+	limiter := AdaptiveLimiter{
+		InitialLimit: DefaultInitialLimit,
+		MinLimit:     DefaultMinLimit,
+		MaxLimit:     DefaultMaxLimit,
+		now:          time.Now,
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&limiter); err != nil {
+			return nil, err
+		}
+	}
+
+	if limiter.MinLimit > limiter.MaxLimit {
+		return nil, ErrInvalidLimit
+	}
+
+	if limiter.Algorithm == nil {
+		gradient, err := NewGradient()
+
+		if err != nil {
+			return nil, err
+		}
+
+		limiter.Algorithm = gradient
+	}
+
+	limiter.limit = float64(min(limiter.MaxLimit, max(limiter.MinLimit, limiter.InitialLimit)))
+
+	return &limiter, nil
+}
@@ -0,0 +1,13 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package concurrencylimit
+
+import "time"
+
+// The following functions are used for internal testing and are not visible to normal library users.
+
+// TSetNow replaces the time source of the given limiter.
+func TSetNow(l *AdaptiveLimiter, now func() time.Time) {
+	l.now = now
+}
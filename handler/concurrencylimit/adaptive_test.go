@@ -0,0 +1,265 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package concurrencylimit_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard"
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/concurrencylimit"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// recorder is an algorithm recording the samples and setting a fixed limit.
+type recorder struct {
+	samples []concurrencylimit.Sample
+	next    float64
+}
+
+func (r *recorder) Update(_ float64, s concurrencylimit.Sample) float64 {
+	r.samples = append(r.samples, s)
+
+	return r.next
+}
+
+// fakeClock is a time source advanced manually.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestAIMD(t *testing.T) {
+	t.Parallel()
+
+	aimd := helper.Must(concurrencylimit.NewAIMD(
+		concurrencylimit.WithBackoffRatio(0.5),
+		concurrencylimit.WithTimeout(100*time.Millisecond)))
+
+	tests := []struct {
+		Limit  float64
+		Sample concurrencylimit.Sample
+		Want   float64
+	}{
+		{Limit: 10, Sample: concurrencylimit.Sample{Latency: 10 * time.Millisecond, InFlight: 5}, Want: 11},               // 0
+		{Limit: 10, Sample: concurrencylimit.Sample{Latency: 10 * time.Millisecond, InFlight: 4}, Want: 10},               // 1
+		{Limit: 10, Sample: concurrencylimit.Sample{Latency: 10 * time.Millisecond, InFlight: 9, Dropped: true}, Want: 5}, // 2
+		{Limit: 10, Sample: concurrencylimit.Sample{Latency: 200 * time.Millisecond, InFlight: 9}, Want: 5},               // 3
+		{Limit: 10, Sample: concurrencylimit.Sample{Latency: 100 * time.Millisecond, InFlight: 9}, Want: 11},              // 4
+	}
+
+	for k, test := range tests {
+		if got := aimd.Update(test.Limit, test.Sample); got != test.Want {
+			t.Errorf("%v: got limit %v but wanted %v", k, got, test.Want)
+		}
+	}
+}
+
+func TestGradient(t *testing.T) {
+	t.Parallel()
+
+	gradient := helper.Must(concurrencylimit.NewGradient(
+		concurrencylimit.WithSmoothing(1),
+		concurrencylimit.WithLongWindow(3)))
+
+	tests := []struct {
+		Latency  time.Duration
+		InFlight int
+		Dropped  bool
+		Want     float64
+	}{
+		{Latency: 10 * time.Millisecond, InFlight: 20, Want: 24},    // 0 no queueing, grows by the queue size
+		{Latency: 10 * time.Millisecond, InFlight: 24, Want: 28},    // 1
+		{Latency: 60 * time.Millisecond, InFlight: 28, Want: 28.5},  // 2 latency rises, gradient 0.875
+		{Dropped: true, InFlight: 28, Want: 18.25},                  // 3 dropped, gradient 0.5
+		{Latency: 10 * time.Millisecond, InFlight: 2, Want: 18.25},  // 4 limit not used, unchanged
+		{Latency: 20 * time.Millisecond, InFlight: 18, Want: 22.25}, // 5 long-term latency decayed
+	}
+
+	limit := 20.0
+
+	for k, test := range tests {
+		limit = gradient.Update(limit, concurrencylimit.Sample{
+			Latency:  test.Latency,
+			InFlight: test.InFlight,
+			Dropped:  test.Dropped,
+		})
+
+		if math.Abs(limit-test.Want) > 1e-9 {
+			t.Errorf("%v: got limit %v but wanted %v", k, limit, test.Want)
+		}
+	}
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	t.Parallel()
+
+	clock := fakeClock{now: time.Unix(1_700_000_000, 0)}
+	algorithm := recorder{}
+
+	limiter := helper.Must(concurrencylimit.NewAdaptiveLimiter(
+		concurrencylimit.WithAlgorithm(&algorithm),
+		concurrencylimit.WithInitialLimit(2),
+		concurrencylimit.WithMaxLimit(5)))
+	concurrencylimit.TSetNow(limiter, clock.Now)
+
+	first := helper.Must(limiter.Acquire(t.Context()))
+	second := helper.Must(limiter.Acquire(t.Context()))
+
+	if _, err := limiter.Acquire(t.Context()); !errors.Is(err, concurrencylimit.ErrLimitExceeded) {
+		t.Errorf("got error %v but wanted %v", err, concurrencylimit.ErrLimitExceeded)
+	}
+
+	clock.advance(30 * time.Millisecond)
+	algorithm.next = 10
+	first(concurrencylimit.OutcomeSuccess)
+
+	if limiter.Limit() != 5 {
+		t.Errorf("got limit %v but wanted the maximum 5", limiter.Limit())
+	}
+
+	algorithm.next = 0
+	second(concurrencylimit.OutcomeIgnored)
+
+	if limiter.Limit() != 5 || limiter.InFlight() != 0 {
+		t.Errorf("got limit %v and %v in-flight but wanted 5 and 0", limiter.Limit(), limiter.InFlight())
+	}
+
+	third := helper.Must(limiter.Acquire(t.Context()))
+	clock.advance(10 * time.Millisecond)
+	third(concurrencylimit.OutcomeDropped)
+	third(concurrencylimit.OutcomeSuccess)
+
+	if limiter.Limit() != 1 {
+		t.Errorf("got limit %v but wanted the minimum 1", limiter.Limit())
+	}
+
+	want := []concurrencylimit.Sample{
+		{Latency: 30 * time.Millisecond, InFlight: 1},
+		{Latency: 10 * time.Millisecond, InFlight: 1, Dropped: true},
+	}
+
+	if !slices.Equal(algorithm.samples, want) {
+		t.Errorf("got samples %+v but wanted %+v", algorithm.samples, want)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := limiter.Acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v but wanted %v", err, context.Canceled)
+	}
+}
+
+func TestAdaptiveOutcomes(t *testing.T) {
+	t.Parallel()
+
+	algorithm := recorder{next: 10}
+	limiter := helper.Must(concurrencylimit.NewAdaptiveLimiter(concurrencylimit.WithAlgorithm(&algorithm)))
+
+	tests := []struct {
+		Status      int
+		Cancel      bool
+		WantSamples int
+		WantDropped bool
+	}{
+		{Status: http.StatusOK, WantSamples: 1, WantDropped: false},                // 0
+		{Status: http.StatusNotFound, WantSamples: 2, WantDropped: false},          // 1
+		{Status: http.StatusServiceUnavailable, WantSamples: 3, WantDropped: true}, // 2
+		{Status: http.StatusInternalServerError, Cancel: true, WantSamples: 3},     // 3 client went away
+	}
+
+	for k, test := range tests {
+		ctx, cancel := context.WithCancel(t.Context())
+
+		handler := midgard.StackMiddlewareHandler(
+			[]defs.Middleware{helper.Must(concurrencylimit.New(concurrencylimit.WithLimiter(limiter)))},
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if test.Cancel {
+					cancel()
+				}
+
+				w.WriteHeader(test.Status)
+			}))
+
+		req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		cancel()
+
+		if len(algorithm.samples) != test.WantSamples {
+			t.Errorf("%v: got %v samples but wanted %v", k, len(algorithm.samples), test.WantSamples)
+
+			continue
+		}
+
+		if got := algorithm.samples[len(algorithm.samples)-1].Dropped; !test.Cancel && got != test.WantDropped {
+			t.Errorf("%v: got dropped %v but wanted %v", k, got, test.WantDropped)
+		}
+	}
+}
+
+func TestAdaptiveOptionErrors(t *testing.T) {
+	t.Parallel()
+
+	limiterTests := []struct {
+		Options []func(*concurrencylimit.AdaptiveLimiter) error
+		WantErr error
+	}{
+		{Options: nil, WantErr: nil}, // 0
+		{Options: []func(*concurrencylimit.AdaptiveLimiter) error{nil}, WantErr: concurrencylimit.ErrNilOption},                                                                    // 1
+		{Options: []func(*concurrencylimit.AdaptiveLimiter) error{concurrencylimit.WithAlgorithm(nil)}, WantErr: concurrencylimit.ErrNilAlgorithm},                                 // 2
+		{Options: []func(*concurrencylimit.AdaptiveLimiter) error{concurrencylimit.WithInitialLimit(0)}, WantErr: concurrencylimit.ErrInvalidLimit},                                // 3
+		{Options: []func(*concurrencylimit.AdaptiveLimiter) error{concurrencylimit.WithMinLimit(0)}, WantErr: concurrencylimit.ErrInvalidLimit},                                    // 4
+		{Options: []func(*concurrencylimit.AdaptiveLimiter) error{concurrencylimit.WithMaxLimit(0)}, WantErr: concurrencylimit.ErrInvalidLimit},                                    // 5
+		{Options: []func(*concurrencylimit.AdaptiveLimiter) error{concurrencylimit.WithMinLimit(10), concurrencylimit.WithMaxLimit(5)}, WantErr: concurrencylimit.ErrInvalidLimit}, // 6
+	}
+
+	for k, test := range limiterTests {
+		if _, err := concurrencylimit.NewAdaptiveLimiter(test.Options...); !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+
+	aimdTests := []struct {
+		Options []func(*concurrencylimit.AIMD) error
+		WantErr error
+	}{
+		{Options: []func(*concurrencylimit.AIMD) error{nil}, WantErr: concurrencylimit.ErrNilOption},                                            // 0
+		{Options: []func(*concurrencylimit.AIMD) error{concurrencylimit.WithBackoffRatio(0)}, WantErr: concurrencylimit.ErrInvalidBackoffRatio}, // 1
+		{Options: []func(*concurrencylimit.AIMD) error{concurrencylimit.WithBackoffRatio(1)}, WantErr: concurrencylimit.ErrInvalidBackoffRatio}, // 2
+	}
+
+	for k, test := range aimdTests {
+		if _, err := concurrencylimit.NewAIMD(test.Options...); !errors.Is(err, test.WantErr) {
+			t.Errorf("aimd %v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+
+	gradientTests := []struct {
+		Options []func(*concurrencylimit.Gradient) error
+		WantErr error
+	}{
+		{Options: []func(*concurrencylimit.Gradient) error{nil}, WantErr: concurrencylimit.ErrNilOption},                                        // 0
+		{Options: []func(*concurrencylimit.Gradient) error{concurrencylimit.WithTolerance(0.5)}, WantErr: concurrencylimit.ErrInvalidTolerance}, // 1
+		{Options: []func(*concurrencylimit.Gradient) error{concurrencylimit.WithSmoothing(0)}, WantErr: concurrencylimit.ErrInvalidSmoothing},   // 2
+		{Options: []func(*concurrencylimit.Gradient) error{concurrencylimit.WithLongWindow(0)}, WantErr: concurrencylimit.ErrInvalidLongWindow}, // 3
+		{Options: []func(*concurrencylimit.Gradient) error{concurrencylimit.WithQueueSize(-1)}, WantErr: concurrencylimit.ErrInvalidQueueSize},  // 4
+	}
+
+	for k, test := range gradientTests {
+		if _, err := concurrencylimit.NewGradient(test.Options...); !errors.Is(err, test.WantErr) {
+			t.Errorf("gradient %v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package concurrencylimit
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidBackoffRatio is returned when the backoff ratio is not between 0 and 1.
+var ErrInvalidBackoffRatio = errors.New("backoff ratio must be greater than 0 and less than 1")
+
+const (
+	// DefaultBackoffRatio is the default factor the limit is reduced by on overload.
+	DefaultBackoffRatio = 0.9
+	// DefaultAIMDTimeout is the default latency, above which a request counts as dropped.
+	DefaultAIMDTimeout = 5 * time.Second
+)
+
+// AIMD is the additive increase, multiplicative decrease algorithm. It increases the
+// limit by one for each successful request, as long as at least half of the limit is in
+// use, and reduces it by the backoff ratio for each dropped or timed out request.
+type AIMD struct {
+	// BackoffRatio is the factor the limit is reduced by on overload.
+	BackoffRatio float64
+	// Timeout is the latency, above which a request counts as dropped. With 0, only
+	// the outcome counts.
+	Timeout time.Duration
+}
+
+// Update gives the new limit, given the current one and a sample.
+func (a *AIMD) Update(limit float64, s Sample) float64 {
+	if s.Dropped || (a.Timeout > 0 && s.Latency > a.Timeout) {
+		return limit * a.BackoffRatio
+	}
+
+	if float64(s.InFlight)*2 >= limit {
+		return limit + 1
+	}
+
+	return limit
+}
+
+// WithBackoffRatio sets the factor the limit is reduced by on overload.
+func WithBackoffRatio(ratio float64) func(a *AIMD) error {
+	return func(a *AIMD) error {
+		if ratio <= 0 || ratio >= 1 {
+			return ErrInvalidBackoffRatio
+		}
+
+		a.BackoffRatio = ratio
+
+		return nil
+	}
+}
+
+// WithTimeout sets the latency, above which a request counts as dropped.
+func WithTimeout(d time.Duration) func(a *AIMD) error {
+	return func(a *AIMD) error {
+		a.Timeout = max(0, d)
+
+		return nil
+	}
+}
+
+// NewAIMD creates a new AIMD algorithm.
+func NewAIMD(options ...func(*AIMD) error) (*AIMD, error) {
+	aimd := AIMD{
+		BackoffRatio: DefaultBackoffRatio,
+		Timeout:      DefaultAIMDTimeout,
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&aimd); err != nil {
+			return nil, err
+		}
+	}
+
+	return &aimd, nil
+}
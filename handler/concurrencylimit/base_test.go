@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package concurrencylimit_test
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/AlphaOne1/midgard/handler/concurrencylimit"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+//
+// Basic Handler
+//
+
+func TestHandlerNil(t *testing.T) {
+	t.Parallel()
+
+	var handler *concurrencylimit.Handler
+
+	if got := handler.GetMWBase(); got != nil {
+		t.Errorf("MWBase of nil must be nil, but got non-nil")
+	}
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	//goland:noinspection GoMaybeNil
+	handler.ServeHTTP(rec, req)
+
+	if rec.Result().StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected %v but got %v", http.StatusInternalServerError, rec.Result().StatusCode)
+	}
+}
+
+//
+// Generic Options
+//
+
+func TestOptionError(t *testing.T) {
+	t.Parallel()
+
+	errOpt := func( /* h */ *concurrencylimit.Handler) error {
+		return errors.New("testerror")
+	}
+
+	_, err := concurrencylimit.New(errOpt)
+
+	if err == nil {
+		t.Errorf("expected middleware creation to fail")
+	}
+}
+
+func TestOptionNil(t *testing.T) {
+	t.Parallel()
+
+	_, err := concurrencylimit.New(nil)
+
+	if err == nil {
+		t.Errorf("expected middleware creation to fail")
+	}
+}
+
+func TestHandlerNextNil(t *testing.T) {
+	t.Parallel()
+
+	h := helper.Must(concurrencylimit.New(
+		concurrencylimit.WithLogLevel(slog.LevelDebug),
+		concurrencylimit.WithLimiter(testLimiter())))(
+		nil)
+
+	if h != nil {
+		t.Errorf("expected handler to be nil")
+	}
+}
+
+//
+// WithLevel
+//
+
+func TestOptionWithLevel(t *testing.T) {
+	t.Parallel()
+
+	h := helper.Must(concurrencylimit.New(
+		concurrencylimit.WithLogLevel(slog.LevelDebug),
+		concurrencylimit.WithLimiter(testLimiter())))(
+		http.HandlerFunc(helper.DummyHandler))
+
+	val, isValid := h.(*concurrencylimit.Handler)
+
+	if !isValid {
+		t.Fatalf("wrong type")
+	}
+
+	if val.LogLevel() != slog.LevelDebug {
+		t.Errorf("wanted loglevel debug not set")
+	}
+}
+
+func TestOptionWithLevelOnNil(t *testing.T) {
+	t.Parallel()
+
+	err := concurrencylimit.WithLogLevel(slog.LevelDebug)(nil)
+
+	if err == nil {
+		t.Errorf("expected error on configuring nil handler")
+	}
+}
+
+//
+// WithLogger
+//
+
+func TestOptionWithLogger(t *testing.T) {
+	t.Parallel()
+
+	newLog := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	h := helper.Must(concurrencylimit.New(
+		concurrencylimit.WithLogger(newLog),
+		concurrencylimit.WithLimiter(testLimiter())))(
+		http.HandlerFunc(helper.DummyHandler))
+
+	val, isValid := h.(*concurrencylimit.Handler)
+
+	if !isValid {
+		t.Fatalf("wrong type")
+	}
+
+	if val.Log() != newLog {
+		t.Errorf("logger not set correctly")
+	}
+}
+
+func TestOptionWithLoggerOnNil(t *testing.T) {
+	t.Parallel()
+
+	err := concurrencylimit.WithLogger(slog.Default())(nil)
+
+	if err == nil {
+		t.Errorf("expected error on configuring nil handler")
+	}
+}
+
+func TestOptionWithNilLogger(t *testing.T) {
+	t.Parallel()
+
+	var l *slog.Logger
+	_, hErr := concurrencylimit.New(concurrencylimit.WithLogger(l))
+
+	if hErr == nil {
+		t.Errorf("expected error on configuration with nil logger")
+	}
+}
@@ -0,0 +1,167 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package concurrencylimit provides middleware limiting the number of concurrently
+// processed HTTP requests.
+package concurrencylimit
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// ErrNilLimiter is returned when the limiter is nil.
+var ErrNilLimiter = errors.New("limiter cannot be nil")
+
+// ErrNilOption is returned when an option is nil.
+var ErrNilOption = errors.New("option cannot be nil")
+
+// DefaultRetryAfter is the default time clients are asked to wait after a rejection.
+const DefaultRetryAfter = time.Second
+
+// Handler holds the internal concurrency limiter information.
+type Handler struct {
+	defs.MWBase
+
+	// Limit is the limiter deciding on the requests.
+	Limit SlotLimiter
+	// RetryAfter is the time clients are asked to wait after a rejection.
+	RetryAfter time.Duration
+}
+
+// GetMWBase returns the MWBase instance of the handler.
+func (h *Handler) GetMWBase() *defs.MWBase {
+	if h == nil {
+		return nil
+	}
+
+	return &h.MWBase
+}
+
+// ServeHTTP processes the request, if a slot is available within the queue timeout.
+// Otherwise, it responds with 503 Service Unavailable and a Retry-After header.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !helper.IntroCheck(h, w, r) {
+		return
+	}
+
+	release, err := h.Limit.Acquire(r.Context())
+
+	if err != nil {
+		h.Log().Debug("request rejected", slog.String("error", err.Error()))
+
+		w.Header().Set("Retry-After", strconv.FormatInt(int64((h.RetryAfter+time.Second-1)/time.Second), 10))
+		helper.WriteState(w, h.Log(), http.StatusServiceUnavailable)
+
+		return
+	}
+
+	sw := statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+	defer func() {
+		// a panic is recovered by net/http, so the slot has to be released anyway
+		if p := recover(); p != nil {
+			release(OutcomeDropped)
+			panic(p)
+		}
+
+		switch {
+		case r.Context().Err() != nil:
+			release(OutcomeIgnored)
+		case sw.status >= http.StatusInternalServerError:
+			release(OutcomeDropped)
+		default:
+			release(OutcomeSuccess)
+		}
+	}()
+
+	h.Next().ServeHTTP(&sw, r)
+}
+
+// statusWriter records the status code written by the next handler.
+type statusWriter struct {
+	http.ResponseWriter
+
+	status int
+}
+
+// WriteHeader records the status code and passes it on.
+func (sw *statusWriter) WriteHeader(status int) {
+	sw.status = status
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap gives the wrapped ResponseWriter, for use with http.ResponseController.
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// WithLimiter sets the limiter to use, either a static Limiter or an AdaptiveLimiter. It
+// may be shared by several handlers, to limit the requests to a common backend.
+func WithLimiter(l SlotLimiter) func(h *Handler) error {
+	return func(h *Handler) error {
+		if value := reflect.ValueOf(l); !value.IsValid() || value.IsNil() {
+			return ErrNilLimiter
+		}
+
+		h.Limit = l
+
+		return nil
+	}
+}
+
+// WithRetryAfter sets the time clients are asked to wait after a rejection. It is
+// rounded up to full seconds.
+func WithRetryAfter(d time.Duration) func(h *Handler) error {
+	return func(h *Handler) error {
+		h.RetryAfter = max(time.Second, d)
+
+		return nil
+	}
+}
+
+// WithLogger configures the logger to use.
+func WithLogger(log *slog.Logger) func(h *Handler) error {
+	return defs.WithLogger[*Handler](log)
+}
+
+// WithLogLevel configures the log level to use with the logger.
+func WithLogLevel(level slog.Level) func(h *Handler) error {
+	return defs.WithLogLevel[*Handler](level)
+}
+
+// New creates a new concurrency limiting middleware.
+func New(options ...func(*Handler) error) (defs.Middleware, error) {
+	handler := Handler{
+		RetryAfter: DefaultRetryAfter,
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&handler); err != nil {
+			return nil, err
+		}
+	}
+
+	if handler.Limit == nil {
+		return nil, ErrNilLimiter
+	}
+
+	return func(next http.Handler) http.Handler {
+		if err := handler.SetNext(next); err != nil {
+			return nil
+		}
+
+		return &handler
+	}, nil
+}
@@ -0,0 +1,278 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package concurrencylimit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard"
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/concurrencylimit"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+func testLimiter() *concurrencylimit.Limiter {
+	return helper.Must(concurrencylimit.NewLimiter(concurrencylimit.WithMaxInFlight(1)))
+}
+
+// waitFor waits until the condition is met.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	for start := time.Now(); !cond(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("condition not met in time")
+		}
+	}
+}
+
+func TestDiscipline(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Discipline concurrencylimit.Discipline
+		Want       []int
+	}{
+		{Discipline: concurrencylimit.FIFO, Want: []int{0, 1, 2}}, // 0
+		{Discipline: concurrencylimit.LIFO, Want: []int{2, 1, 0}}, // 1
+	}
+
+	for k, test := range tests {
+		limiter := helper.Must(concurrencylimit.NewLimiter(
+			concurrencylimit.WithMaxInFlight(1),
+			concurrencylimit.WithMaxQueue(3),
+			concurrencylimit.WithQueueTimeout(time.Minute),
+			concurrencylimit.WithDiscipline(test.Discipline)))
+
+		release := helper.Must(limiter.Acquire(t.Context()))
+
+		var mtx sync.Mutex
+		var order []int
+		var wg sync.WaitGroup
+
+		for i := range 3 {
+			wg.Go(func() {
+				r, err := limiter.Acquire(t.Context())
+
+				if err != nil {
+					t.Errorf("%v: got unexpected error %v", k, err)
+
+					return
+				}
+
+				mtx.Lock()
+				order = append(order, i)
+				mtx.Unlock()
+
+				r(concurrencylimit.OutcomeSuccess)
+			})
+
+			waitFor(t, func() bool { return limiter.Queued() == i+1 })
+		}
+
+		if limiter.InFlight() != 1 {
+			t.Errorf("%v: got %v in-flight but wanted 1", k, limiter.InFlight())
+		}
+
+		release(concurrencylimit.OutcomeSuccess)
+		wg.Wait()
+
+		if !slices.Equal(order, test.Want) {
+			t.Errorf("%v: got order %v but wanted %v", k, order, test.Want)
+		}
+
+		if limiter.InFlight() != 0 || limiter.Queued() != 0 {
+			t.Errorf("%v: got %v in-flight and %v queued but wanted none",
+				k, limiter.InFlight(), limiter.Queued())
+		}
+	}
+}
+
+func TestLimiterRejections(t *testing.T) {
+	t.Parallel()
+
+	limiter := helper.Must(concurrencylimit.NewLimiter(
+		concurrencylimit.WithMaxInFlight(2),
+		concurrencylimit.WithMaxQueue(1),
+		concurrencylimit.WithQueueTimeout(20*time.Millisecond)))
+
+	first := helper.Must(limiter.Acquire(t.Context()))
+	second := helper.Must(limiter.Acquire(t.Context()))
+
+	// 0 waits in the queue until the timeout
+	if _, err := limiter.Acquire(t.Context()); !errors.Is(err, concurrencylimit.ErrQueueTimeout) {
+		t.Errorf("got error %v but wanted %v", err, concurrencylimit.ErrQueueTimeout)
+	}
+
+	// 1 is rejected as the queue is full
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		if _, err := limiter.Acquire(t.Context()); !errors.Is(err, concurrencylimit.ErrQueueTimeout) {
+			t.Errorf("got error %v but wanted %v", err, concurrencylimit.ErrQueueTimeout)
+		}
+	}()
+
+	waitFor(t, func() bool { return limiter.Queued() == 1 })
+
+	if _, err := limiter.Acquire(t.Context()); !errors.Is(err, concurrencylimit.ErrQueueFull) {
+		t.Errorf("got error %v but wanted %v", err, concurrencylimit.ErrQueueFull)
+	}
+
+	<-done
+
+	// 2 gives up as its context is canceled
+	ctx, cancel := context.WithCancel(t.Context())
+
+	go func() {
+		for limiter.Queued() != 1 {
+			time.Sleep(time.Millisecond)
+		}
+
+		cancel()
+	}()
+
+	if _, err := limiter.Acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v but wanted %v", err, context.Canceled)
+	}
+
+	// 3 releasing twice frees just one slot
+	first(concurrencylimit.OutcomeSuccess)
+	first(concurrencylimit.OutcomeSuccess)
+	second(concurrencylimit.OutcomeSuccess)
+
+	if limiter.InFlight() != 0 || limiter.Queued() != 0 {
+		t.Errorf("got %v in-flight and %v queued but wanted none", limiter.InFlight(), limiter.Queued())
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	t.Parallel()
+
+	limiter := testLimiter()
+	block := make(chan struct{})
+
+	handler := midgard.StackMiddlewareHandler(
+		[]defs.Middleware{helper.Must(concurrencylimit.New(
+			concurrencylimit.WithLimiter(limiter),
+			concurrencylimit.WithRetryAfter(1500*time.Millisecond)))},
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-block
+			helper.DummyHandler(w, r)
+		}))
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	done := make(chan *httptest.ResponseRecorder)
+
+	go func() { done <- serve() }()
+
+	waitFor(t, func() bool { return limiter.InFlight() == 1 })
+
+	rec := serve()
+
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "2" {
+		t.Errorf("got status %v and Retry-After %q but wanted %v and 2",
+			rec.Code, rec.Header().Get("Retry-After"), http.StatusServiceUnavailable)
+	}
+
+	close(block)
+
+	if rec := <-done; rec.Code != http.StatusOK {
+		t.Errorf("got status %v but wanted %v", rec.Code, http.StatusOK)
+	}
+
+	if rec := serve(); rec.Code != http.StatusOK {
+		t.Errorf("got status %v but wanted %v", rec.Code, http.StatusOK)
+	}
+
+	if limiter.InFlight() != 0 {
+		t.Errorf("got %v in-flight but wanted 0", limiter.InFlight())
+	}
+}
+
+func TestConcurrencyLimitPanic(t *testing.T) {
+	t.Parallel()
+
+	limiter := testLimiter()
+
+	handler := midgard.StackMiddlewareHandler(
+		[]defs.Middleware{helper.Must(concurrencylimit.New(concurrencylimit.WithLimiter(limiter)))},
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Panic") != "" {
+				panic("test panic")
+			}
+
+			helper.DummyHandler(w, r)
+		}))
+
+	func() {
+		defer func() {
+			if p := recover(); p != "test panic" {
+				t.Errorf("got panic %v but wanted the one of the handler", p)
+			}
+		}()
+
+		req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+		req.Header.Set("X-Panic", "1")
+
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	if limiter.InFlight() != 0 {
+		t.Errorf("got %v in-flight after the panic but wanted 0", limiter.InFlight())
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("got status %v but wanted %v", rec.Code, http.StatusOK)
+	}
+}
+
+func TestOptionErrors(t *testing.T) {
+	t.Parallel()
+
+	limiterTests := []struct {
+		Options []func(*concurrencylimit.Limiter) error
+		WantErr error
+	}{
+		{Options: nil, WantErr: concurrencylimit.ErrInvalidMaxInFlight},                                                                          // 0
+		{Options: []func(*concurrencylimit.Limiter) error{nil}, WantErr: concurrencylimit.ErrNilOption},                                          // 1
+		{Options: []func(*concurrencylimit.Limiter) error{concurrencylimit.WithMaxInFlight(0)}, WantErr: concurrencylimit.ErrInvalidMaxInFlight}, // 2
+		{Options: []func(*concurrencylimit.Limiter) error{concurrencylimit.WithMaxQueue(-1)}, WantErr: concurrencylimit.ErrInvalidMaxQueue},      // 3
+		{Options: []func(*concurrencylimit.Limiter) error{concurrencylimit.WithDiscipline(3)}, WantErr: concurrencylimit.ErrInvalidDiscipline},   // 4
+	}
+
+	for k, test := range limiterTests {
+		if _, err := concurrencylimit.NewLimiter(test.Options...); !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+
+	if _, err := concurrencylimit.New(); !errors.Is(err, concurrencylimit.ErrNilLimiter) {
+		t.Errorf("got error %v but wanted %v", err, concurrencylimit.ErrNilLimiter)
+	}
+
+	if _, err := concurrencylimit.New(concurrencylimit.WithLimiter(nil)); !errors.Is(err, concurrencylimit.ErrNilLimiter) {
+		t.Errorf("got error %v but wanted %v", err, concurrencylimit.ErrNilLimiter)
+	}
+}
@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package concurrencylimit
+
+import (
+	"errors"
+)
+
+// ErrInvalidTolerance is returned when the tolerance is less than 1.
+var ErrInvalidTolerance = errors.New("tolerance must be at least 1")
+
+// ErrInvalidSmoothing is returned when the smoothing factor is not between 0 and 1.
+var ErrInvalidSmoothing = errors.New("smoothing must be greater than 0 and at most 1")
+
+// ErrInvalidLongWindow is returned when the long window is not greater than 0.
+var ErrInvalidLongWindow = errors.New("long window must be greater than 0")
+
+// ErrInvalidQueueSize is returned when the queue size is negative.
+var ErrInvalidQueueSize = errors.New("queue size cannot be negative")
+
+const (
+	// DefaultTolerance is the default factor the latency may exceed the long-term
+	// latency, before the limit is reduced.
+	DefaultTolerance = 1.5
+	// DefaultSmoothing is the default weight of a new limit.
+	DefaultSmoothing = 0.2
+	// DefaultLongWindow is the default number of samples the long-term latency averages.
+	DefaultLongWindow = 600
+	// DefaultQueueSize is the default number of requests the limit grows by.
+	DefaultQueueSize = 4
+)
+
+const (
+	// minGradient is the lower bound of the gradient, limiting the reduction per sample.
+	minGradient = 0.5
+	// driftRatio is the ratio of long-term to current latency, above which the long-term
+	// latency is considered outdated.
+	driftRatio = 2
+	// driftDecay is the factor an outdated long-term latency is reduced by per sample.
+	driftDecay = 0.95
+)
+
+// Gradient is a delay based algorithm, following the gradient algorithm of Netflix
+// concurrency-limits. It compares the latency of each request with the long-term average
+// latency. If it rises above the tolerance, queueing is assumed and the limit is reduced
+// accordingly, otherwise it grows by the queue size.
+type Gradient struct {
+	// Tolerance is the factor the latency may exceed the long-term latency, before the
+	// limit is reduced.
+	Tolerance float64
+	// Smoothing is the weight of a new limit, the rest is kept from the current one.
+	Smoothing float64
+	// LongWindow is the number of samples the long-term latency averages.
+	LongWindow int
+	// QueueSize is the number of requests the limit grows by, allowing for some queueing.
+	QueueSize float64
+
+	longRtt float64 // longRtt is the exponential moving average of the latency in ns
+}
+
+// Update gives the new limit, given the current one and a sample.
+func (g *Gradient) Update(limit float64, s Sample) float64 {
+	gradient := minGradient
+
+	if !s.Dropped {
+		shortRtt := float64(max(1, s.Latency))
+
+		if g.longRtt == 0 {
+			g.longRtt = shortRtt
+		} else {
+			g.longRtt += (shortRtt - g.longRtt) * 2 / float64(g.LongWindow+1)
+		}
+
+		// the latency dropped significantly, e.g. after an overload, so the long-term
+		// latency has to catch up faster
+		if g.longRtt/shortRtt > driftRatio {
+			g.longRtt *= driftDecay
+		}
+
+		// with less than half of the limit in use, the latency tells nothing about it
+		if float64(s.InFlight) < limit/2 {
+			return limit
+		}
+
+		gradient = max(minGradient, min(1, g.Tolerance*g.longRtt/shortRtt))
+	}
+
+	return limit*(1-g.Smoothing) + (limit*gradient+g.QueueSize)*g.Smoothing
+}
+
+// WithTolerance sets the factor the latency may exceed the long-term latency, before the
+// limit is reduced.
+func WithTolerance(tolerance float64) func(g *Gradient) error {
+	return func(g *Gradient) error {
+		if tolerance < 1 {
+			return ErrInvalidTolerance
+		}
+
+		g.Tolerance = tolerance
+
+		return nil
+	}
+}
+
+// WithSmoothing sets the weight of a new limit. With 1, the new limit is taken as is.
+func WithSmoothing(smoothing float64) func(g *Gradient) error {
+	return func(g *Gradient) error {
+		if smoothing <= 0 || smoothing > 1 {
+			return ErrInvalidSmoothing
+		}
+
+		g.Smoothing = smoothing
+
+		return nil
+	}
+}
+
+// WithLongWindow sets the number of samples the long-term latency averages.
+func WithLongWindow(n int) func(g *Gradient) error {
+	return func(g *Gradient) error {
+		if n <= 0 {
+			return ErrInvalidLongWindow
+		}
+
+		g.LongWindow = n
+
+		return nil
+	}
+}
+
+// WithQueueSize sets the number of requests the limit grows by.
+func WithQueueSize(n int) func(g *Gradient) error {
+	return func(g *Gradient) error {
+		if n < 0 {
+			return ErrInvalidQueueSize
+		}
+
+		g.QueueSize = float64(n)
+
+		return nil
+	}
+}
+
+// NewGradient creates a new gradient algorithm.
+func NewGradient(options ...func(*Gradient) error) (*Gradient, error) {
+	gradient := Gradient{
+		Tolerance:  DefaultTolerance,
+		Smoothing:  DefaultSmoothing,
+		LongWindow: DefaultLongWindow,
+		QueueSize:  DefaultQueueSize,
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&gradient); err != nil {
+			return nil, err
+		}
+	}
+
+	return &gradient, nil
+}
@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package concurrencylimit
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrInvalidMaxInFlight is returned when the maximum number of in-flight requests is not
+// greater than 0.
+var ErrInvalidMaxInFlight = errors.New("maximum in-flight requests must be greater than 0")
+
+// ErrInvalidMaxQueue is returned when the maximum queue length is negative.
+var ErrInvalidMaxQueue = errors.New("maximum queue length cannot be negative")
+
+// ErrInvalidDiscipline is returned when the queue discipline is unknown.
+var ErrInvalidDiscipline = errors.New("invalid queue discipline")
+
+// ErrQueueFull is returned when a request cannot be queued, as the queue is full.
+var ErrQueueFull = errors.New("queue full")
+
+// ErrQueueTimeout is returned when a request waited in the queue for too long.
+var ErrQueueTimeout = errors.New("queue timeout")
+
+// DefaultQueueTimeout is the default maximum time a request waits in the queue.
+const DefaultQueueTimeout = time.Second
+
+// Discipline determines which queued request gets the next free slot.
+type Discipline int
+
+const (
+	// FIFO serves the queued requests in the order of their arrival.
+	FIFO Discipline = iota
+	// LIFO serves the most recent request first. Under overload, this serves the requests
+	// whose clients most likely still wait for the response, while the old ones time out.
+	LIFO
+)
+
+// Outcome is the result of a request, reported when releasing its slot.
+type Outcome int
+
+const (
+	// OutcomeSuccess is a request processed successfully.
+	OutcomeSuccess Outcome = iota
+	// OutcomeDropped is a request that failed, e.g. with a server error or a timeout. It
+	// indicates an overloaded backend.
+	OutcomeDropped
+	// OutcomeIgnored is a request that says nothing about the load of the backend, e.g.
+	// as the client went away.
+	OutcomeIgnored
+)
+
+// Release frees the slot of a request, reporting its outcome. Calling it more than once
+// has no effect.
+type Release func(outcome Outcome)
+
+// SlotLimiter is the interface of the limiters used by the handler.
+type SlotLimiter interface {
+	// Acquire gets a slot for a request. The returned Release has to be called, when
+	// the request is done.
+	Acquire(ctx context.Context) (Release, error)
+}
+
+// waiter is a request waiting in the queue.
+type waiter struct {
+	ready   chan struct{} // ready is closed, when the waiter got a slot
+	granted bool          // granted is set, when the waiter got a slot
+}
+
+// Limiter limits the number of concurrently processed requests. Requests exceeding the
+// limit wait in a bounded queue for a free slot.
+type Limiter struct {
+	// MaxInFlight is the maximum number of concurrently processed requests.
+	MaxInFlight int
+	// MaxQueue is the maximum number of waiting requests.
+	MaxQueue int
+	// QueueTimeout is the maximum time a request waits in the queue.
+	QueueTimeout time.Duration
+	// Discipline determines which queued request gets the next free slot.
+	Discipline Discipline
+
+	mtx      sync.Mutex
+	inFlight int
+	queue    *list.List // queue holds the waiters, the oldest at the front
+}
+
+// Acquire gets a slot for a request, waiting in the queue if necessary. The returned
+// Release has to be called, when the request is done. The outcome is not used.
+func (l *Limiter) Acquire(ctx context.Context) (Release, error) {
+	l.mtx.Lock()
+
+	if l.inFlight < l.MaxInFlight && l.queue.Len() == 0 {
+		l.inFlight++
+		l.mtx.Unlock()
+
+		return l.releaser(), nil
+	}
+
+	if l.queue.Len() >= l.MaxQueue {
+		l.mtx.Unlock()
+
+		return nil, ErrQueueFull
+	}
+
+	w := &waiter{ready: make(chan struct{})}
+	elem := l.queue.PushBack(w)
+	l.mtx.Unlock()
+
+	timer := time.NewTimer(l.QueueTimeout)
+	defer timer.Stop()
+
+	var err error
+
+	select {
+	case <-w.ready:
+		return l.releaser(), nil
+	case <-timer.C:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if w.granted {
+		// the slot was handed over while giving up, so it is used anyway
+		return l.releaser(), nil
+	}
+
+	l.queue.Remove(elem)
+
+	return nil, err
+}
+
+// releaser gives the function releasing a slot.
+func (l *Limiter) releaser() Release {
+	var once sync.Once
+
+	return func(Outcome) {
+		once.Do(l.release)
+	}
+}
+
+// release hands the slot over to the next waiter or frees it.
+func (l *Limiter) release() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	var elem *list.Element
+
+	if l.Discipline == LIFO {
+		elem = l.queue.Back()
+	} else {
+		elem = l.queue.Front()
+	}
+
+	if elem == nil {
+		l.inFlight--
+
+		return
+	}
+
+	w := l.queue.Remove(elem).(*waiter) //nolint:forcetypeassert // only waiters are queued
+	w.granted = true
+	close(w.ready)
+}
+
+// InFlight gives the number of requests currently processed.
+func (l *Limiter) InFlight() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.inFlight
+}
+
+// Queued gives the number of requests currently waiting in the queue.
+func (l *Limiter) Queued() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.queue.Len()
+}
+
+// WithMaxInFlight sets the maximum number of concurrently processed requests.
+func WithMaxInFlight(n int) func(l *Limiter) error {
+	return func(l *Limiter) error {
+		if n <= 0 {
+			return ErrInvalidMaxInFlight
+		}
+
+		l.MaxInFlight = n
+
+		return nil
+	}
+}
+
+// WithMaxQueue sets the maximum number of waiting requests. With 0, requests exceeding
+// the in-flight limit are rejected immediately.
+func WithMaxQueue(n int) func(l *Limiter) error {
+	return func(l *Limiter) error {
+		if n < 0 {
+			return ErrInvalidMaxQueue
+		}
+
+		l.MaxQueue = n
+
+		return nil
+	}
+}
+
+// WithQueueTimeout sets the maximum time a request waits in the queue.
+func WithQueueTimeout(d time.Duration) func(l *Limiter) error {
+	return func(l *Limiter) error {
+		l.QueueTimeout = max(0, d)
+
+		return nil
+	}
+}
+
+// WithDiscipline sets which queued request gets the next free slot.
+func WithDiscipline(d Discipline) func(l *Limiter) error {
+	return func(l *Limiter) error {
+		if d != FIFO && d != LIFO {
+			return ErrInvalidDiscipline
+		}
+
+		l.Discipline = d
+
+		return nil
+	}
+}
+
+// NewLimiter creates a new concurrency limiter. The maximum number of in-flight requests
+// has to be given.
+func NewLimiter(options ...func(*Limiter) error) (*Limiter, error) {
+	limiter := Limiter{
+		QueueTimeout: DefaultQueueTimeout,
+		Discipline:   FIFO,
+		queue:        list.New(),
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&limiter); err != nil {
+			return nil, err
+		}
+	}
+
+	if limiter.MaxInFlight <= 0 {
+		return nil, ErrInvalidMaxInFlight
+	}
+
+	return &limiter, nil
+}
@@ -6,10 +6,14 @@ package cors
 
 import (
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"regexp"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/AlphaOne1/midgard/defs"
 	"github.com/AlphaOne1/midgard/helper"
@@ -24,6 +28,23 @@ var ErrNoOrigin = errors.New("no origin in header")
 // ErrOriginNotAllowed is returned when the origin is not allowed.
 var ErrOriginNotAllowed = errors.New("origin not allowed")
 
+// ErrMethodNotAllowed is returned when the method of a preflight request is not allowed.
+var ErrMethodNotAllowed = errors.New("method not allowed")
+
+// ErrHeaderNotAllowed is returned when a header of a preflight request is not allowed.
+var ErrHeaderNotAllowed = errors.New("header not allowed")
+
+// ErrWildcardWithCredentials is returned when credentials are allowed together with a
+// wildcard, that the clients do not accept in this case.
+var ErrWildcardWithCredentials = errors.New("wildcard cannot be used with credentials")
+
+// ErrWildcardWithPrivateNetwork is returned when private network access is allowed
+// together with the wildcard origin, that would open the private network to all websites.
+var ErrWildcardWithPrivateNetwork = errors.New("wildcard origin cannot be used with private network access")
+
+// ErrInvalidMaxAge is returned when the maximum age is negative.
+var ErrInvalidMaxAge = errors.New("maximum age cannot be negative")
+
 // Handler is a middleware that sets up the cross-site scripting circumvention headers.
 type Handler struct {
 	defs.MWBase
@@ -40,6 +61,20 @@ type Handler struct {
 	MethodsReturn string
 	// Origins contains the allowed origins
 	Origins []string
+	// OriginRegexps contains the regular expressions of further allowed origins
+	OriginRegexps []*regexp.Regexp
+	// ExposeHeadersReturn contains the comma-concatenated headers the client may read
+	// from the responses, as returned in the expose-headers header
+	ExposeHeadersReturn string
+	// Credentials allows the client to send credentials, like cookies
+	Credentials bool
+	// MaxAge is the time the client may cache the results of preflight requests
+	MaxAge time.Duration
+	// PrivateNetwork allows the allowed origins to access the service in a private network
+	PrivateNetwork bool
+
+	origins  *originMatcher  // origins decides on the allowed origins
+	resolver *originResolver // resolver decides on the origins not allowed statically
 }
 
 // GetMWBase returns the MWBase instance of the handler.
@@ -51,10 +86,11 @@ func (h *Handler) GetMWBase() *defs.MWBase {
 	return &h.MWBase
 }
 
-// relevantOrigin gets the origin that the client matches with the allowed origins.
-// If there is no match or there are no origins set, an error is returned.
-func relevantOrigin(origin string, allowed []string) (string, error) {
-	if len(allowed) == 1 && allowed[0] == "*" {
+// relevantOrigin gets the origin that the client matches with the allowed origins or,
+// failing that, the origin resolver. If there is no match or there are no origins set,
+// an error is returned.
+func (h *Handler) relevantOrigin(r *http.Request, origin string) (string, error) {
+	if h.origins.all {
 		return "*", nil
 	}
 
@@ -62,11 +98,25 @@ func relevantOrigin(origin string, allowed []string) (string, error) {
 		return "", ErrNoOrigin
 	}
 
-	if slices.Contains(allowed, origin) {
+	if h.origins.match(origin) {
 		return origin, nil
 	}
 
-	return "", ErrOriginNotAllowed
+	if h.resolver == nil {
+		return "", ErrOriginNotAllowed
+	}
+
+	allowed, err := h.resolver.allowOrigin(r.Context(), origin)
+
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrOriginNotAllowed, err)
+	}
+
+	if !allowed {
+		return "", ErrOriginNotAllowed
+	}
+
+	return origin, nil
 }
 
 // ServeHTTP sets up the client with the appropriate headers.
@@ -77,7 +127,11 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	origin := r.Header.Get("Origin")
 
-	relevantOrigin, roErr := relevantOrigin(origin, h.Origins)
+	// all responses depend on the origin, also those for requests without or with a
+	// disallowed origin, so caches must not share them
+	w.Header().Add("Vary", "Origin")
+
+	relevantOrigin, roErr := h.relevantOrigin(r, origin)
 
 	// no (relevant) origin found in the request
 	switch {
@@ -89,17 +143,21 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.Log().Info("origin not allowed",
 			slog.String("origin", origin),
 			slog.String("path", r.URL.Path),
-			slog.String("method", r.Method))
+			slog.String("method", r.Method),
+			slog.String("error", roErr.Error()))
 
 		helper.WriteState(w, h.Log(), http.StatusForbidden)
 
 		return
 	}
 
-	w.Header().Set("Access-Control-Allow-Origin", relevantOrigin)
-	w.Header().Add("Vary", "Origin")
-
 	if r.Method != http.MethodOptions {
+		h.setOriginHeaders(w, relevantOrigin)
+
+		if h.ExposeHeadersReturn != "" {
+			w.Header().Set("Access-Control-Expose-Headers", h.ExposeHeadersReturn)
+		}
+
 		h.Next().ServeHTTP(w, r)
 
 		return
@@ -107,28 +165,37 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// on OPTIONS request, just give the possible methods and headers
 
-	if h.MethodsReturn != "" {
-		w.Header().Set("Access-Control-Allow-Methods", h.MethodsReturn)
-	} else {
-		methods := r.Header.Get("Access-Control-Request-Method")
+	if err := h.checkPreflight(r); err != nil {
+		h.Log().Info("preflight not allowed",
+			slog.String("origin", origin),
+			slog.String("path", r.URL.Path),
+			slog.String("error", err.Error()))
+
+		helper.WriteState(w, h.Log(), http.StatusForbidden)
 
-		if methods == "" {
-			methods = "*"
-		}
+		return
+	}
+
+	h.setOriginHeaders(w, relevantOrigin)
 
+	if methods := h.allowedMethods(w, r); methods != "" {
 		w.Header().Set("Access-Control-Allow-Methods", methods)
 	}
 
-	if h.HeadersReturn != "" {
-		w.Header().Set("Access-Control-Allow-Headers", h.HeadersReturn)
-	} else {
-		headers := r.Header.Get("Access-Control-Request-Headers")
+	if headers := h.allowedHeaders(w, r); headers != "" {
+		w.Header().Set("Access-Control-Allow-Headers", headers)
+	}
+
+	if h.PrivateNetwork {
+		w.Header().Add("Vary", "Access-Control-Request-Private-Network")
 
-		if headers == "" {
-			headers = "*"
+		if r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+			w.Header().Set("Access-Control-Allow-Private-Network", "true")
 		}
+	}
 
-		w.Header().Set("Access-Control-Allow-Headers", headers)
+	if h.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.FormatInt(int64(h.MaxAge/time.Second), 10))
 	}
 
 	w.WriteHeader(http.StatusNoContent)
@@ -170,15 +237,92 @@ func WithMethods(methods []string) func(handler *Handler) error {
 
 // WithOrigins sets the allowed origins. If later a comes from and origin that are not
 // contained in this list, it will be denied the service. A special origin is "*", that
-// is the wildcard for "all" origins.
+// is the wildcard for "all" origins. Origins may contain wildcards for the subdomains or
+// the port, like https://*.example.com or http://localhost:*.
 func WithOrigins(origins []string) func(handler *Handler) error {
 	return func(handler *Handler) error {
+		for _, o := range origins {
+			if o != "*" && strings.Contains(o, "*") {
+				if _, err := parseOriginPattern(o); err != nil {
+					return err
+				}
+			}
+		}
+
 		handler.Origins = origins
 
 		return nil
 	}
 }
 
+// WithOriginRegexps sets regular expressions of further allowed origins. They have to
+// match the whole origin in its normalized form, that is scheme://host[:port] in lower
+// case and without the default port of the scheme.
+func WithOriginRegexps(patterns []string) func(handler *Handler) error {
+	return func(handler *Handler) error {
+		regexps := make([]*regexp.Regexp, 0, len(patterns))
+
+		for _, p := range patterns {
+			re, err := regexp.Compile("^(?:" + p + ")$")
+
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidOriginPattern, err)
+			}
+
+			regexps = append(regexps, re)
+		}
+
+		handler.OriginRegexps = regexps
+
+		return nil
+	}
+}
+
+// WithExposeHeaders sets the headers, besides the CORS-safelisted ones, that the client
+// may read from the responses.
+func WithExposeHeaders(headers []string) func(handler *Handler) error {
+	return func(handler *Handler) error {
+		handler.ExposeHeadersReturn = strings.Join(headers, ", ")
+
+		return nil
+	}
+}
+
+// WithCredentials allows the client to send credentials, like cookies or the
+// Authorization header, and to read the responses to such requests. It cannot be used
+// with the wildcard origin "*".
+func WithCredentials(allow bool) func(handler *Handler) error {
+	return func(handler *Handler) error {
+		handler.Credentials = allow
+
+		return nil
+	}
+}
+
+// WithMaxAge sets the time the client may cache the results of preflight requests.
+func WithMaxAge(d time.Duration) func(handler *Handler) error {
+	return func(handler *Handler) error {
+		if d < 0 {
+			return ErrInvalidMaxAge
+		}
+
+		handler.MaxAge = d
+
+		return nil
+	}
+}
+
+// WithPrivateNetwork allows the allowed origins to access the service in a private
+// network, answering the Private Network Access preflight requests of the clients. It
+// cannot be used with the wildcard origin "*".
+func WithPrivateNetwork(allow bool) func(handler *Handler) error {
+	return func(handler *Handler) error {
+		handler.PrivateNetwork = allow
+
+		return nil
+	}
+}
+
 // WithLogger configures the logger to use.
 func WithLogger(log *slog.Logger) func(h *Handler) error {
 	return defs.WithLogger[*Handler](log)
@@ -208,10 +352,30 @@ func New(options ...func(handler *Handler) error) (defs.Middleware, error) {
 
 	// if no origins are specified or one of the specified allowed origins is *
 	// just set the origins to *
-	if len(handler.Origins) == 0 || slices.Contains(handler.Origins, "*") {
+	if (len(handler.Origins) == 0 && len(handler.OriginRegexps) == 0 && handler.resolver == nil) ||
+		slices.Contains(handler.Origins, "*") {
 		_ = WithOrigins([]string{"*"})(&handler)
 	}
 
+	origins, err := newOriginMatcher(handler.Origins, handler.OriginRegexps)
+
+	if err != nil {
+		return nil, err
+	}
+
+	handler.origins = origins
+
+	// the clients take the wildcards literally with credentials
+	exposeAll := slices.Contains(strings.Split(handler.ExposeHeadersReturn, ", "), "*")
+
+	if handler.Credentials && (origins.all || exposeAll) {
+		return nil, ErrWildcardWithCredentials
+	}
+
+	if handler.PrivateNetwork && origins.all {
+		return nil, ErrWildcardWithPrivateNetwork
+	}
+
 	return func(next http.Handler) http.Handler {
 		if err := handler.SetNext(next); err != nil {
 			return nil
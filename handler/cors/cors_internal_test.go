@@ -0,0 +1,18 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package cors
+
+import "time"
+
+// The following functions are used for internal testing and are not visible to normal library users.
+
+// TWithResolverNow is an option replacing the time source of the origin resolver cache.
+// It has to follow WithOriginResolver.
+func TWithResolverNow(now func() time.Time) func(handler *Handler) error {
+	return func(handler *Handler) error {
+		handler.resolver.now = now
+
+		return nil
+	}
+}
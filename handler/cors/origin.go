@@ -0,0 +1,226 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidOriginPattern is returned when an allowed origin cannot be parsed.
+var ErrInvalidOriginPattern = errors.New("invalid origin pattern")
+
+// anyPort is the port of patterns matching all ports.
+const anyPort = "*"
+
+// origin is an origin split into its parts. The scheme and host are lower case, the
+// port is set to the default port of the scheme if not given.
+type origin struct {
+	scheme string
+	host   string
+	port   string
+}
+
+// defaultPort gives the default port of the scheme, if known.
+func defaultPort(scheme string) string {
+	switch scheme {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	default:
+		return ""
+	}
+}
+
+// validPort checks that the port is a number in the valid range.
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+
+	return err == nil && n > 0 && n <= 65535 && port[0] != '+'
+}
+
+// parseOrigin parses the origin of a request, that is scheme://host[:port] without
+// user, path, query or fragment.
+func parseOrigin(s string) (origin, bool) {
+	u, err := url.Parse(s)
+
+	if err != nil ||
+		u.Scheme == "" || u.Host == "" || u.Opaque != "" || u.User != nil ||
+		u.Path != "" || u.RawQuery != "" || u.ForceQuery || u.Fragment != "" {
+		return origin{}, false
+	}
+
+	result := origin{
+		scheme: strings.ToLower(u.Scheme),
+		host:   strings.ToLower(u.Hostname()),
+		port:   u.Port(),
+	}
+
+	if result.host == "" {
+		return origin{}, false
+	}
+
+	if result.port == "" {
+		result.port = defaultPort(result.scheme)
+	} else if !validPort(result.port) {
+		return origin{}, false
+	}
+
+	return result, true
+}
+
+// String gives the normalized origin.
+func (o origin) String() string {
+	host := o.host
+
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	if o.port == "" || o.port == defaultPort(o.scheme) {
+		return o.scheme + "://" + host
+	}
+
+	return o.scheme + "://" + host + ":" + o.port
+}
+
+// originPattern is an allowed origin containing wildcards, like https://*.example.com
+// or http://localhost:*. A wildcard host matches all subdomains of the given domain,
+// but not the domain itself. A wildcard port matches all ports.
+type originPattern struct {
+	scheme     string
+	host       string // host is the host, or the domain with leading dot for subdomains
+	subdomains bool
+	port       string // port is the port, anyPort to match all
+}
+
+// parseOriginPattern parses an origin pattern.
+func parseOriginPattern(s string) (originPattern, error) {
+	scheme, rest, found := strings.Cut(s, "://")
+
+	if !found || scheme == "" || strings.Contains(scheme, "*") || strings.ContainsAny(rest, "/?#@") {
+		return originPattern{}, fmt.Errorf("%w: %v", ErrInvalidOriginPattern, s)
+	}
+
+	result := originPattern{scheme: strings.ToLower(scheme)}
+	host := rest
+
+	if i := strings.LastIndex(rest, ":"); i >= 0 && !strings.HasSuffix(rest, "]") {
+		host, result.port = rest[:i], rest[i+1:]
+
+		if result.port != anyPort && !validPort(result.port) {
+			return originPattern{}, fmt.Errorf("%w: %v", ErrInvalidOriginPattern, s)
+		}
+	}
+
+	if result.port == "" {
+		result.port = defaultPort(result.scheme)
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"))
+
+	if domain, ok := strings.CutPrefix(host, "*."); ok {
+		result.subdomains = true
+		host = "." + domain
+	}
+
+	if strings.Trim(host, ".") == "" || strings.Contains(host, "*") {
+		return originPattern{}, fmt.Errorf("%w: %v", ErrInvalidOriginPattern, s)
+	}
+
+	result.host = host
+
+	return result, nil
+}
+
+// match checks, if the origin matches the pattern.
+func (p originPattern) match(o origin) bool {
+	if o.scheme != p.scheme || (p.port != anyPort && o.port != p.port) {
+		return false
+	}
+
+	if !p.subdomains {
+		return o.host == p.host
+	}
+
+	sub, found := strings.CutSuffix(o.host, p.host)
+
+	return found && sub != "" && !strings.HasPrefix(sub, ".") && !strings.HasSuffix(sub, ".")
+}
+
+// originMatcher decides, if an origin is allowed. It is compiled once from the allowed
+// origins.
+type originMatcher struct {
+	all      bool            // all is set, if all origins are allowed
+	exact    map[string]bool // exact holds the origins without wildcards
+	patterns []originPattern
+	regexps  []*regexp.Regexp
+}
+
+// newOriginMatcher compiles the allowed origins and regular expressions. An origin of
+// "*" allows all origins.
+func newOriginMatcher(origins []string, regexps []*regexp.Regexp) (*originMatcher, error) {
+	result := originMatcher{
+		exact:   make(map[string]bool, len(origins)),
+		regexps: regexps,
+	}
+
+	for _, o := range origins {
+		switch {
+		case o == "*":
+			result.all = true
+		case strings.Contains(o, "*"):
+			p, err := parseOriginPattern(o)
+
+			if err != nil {
+				return nil, err
+			}
+
+			result.patterns = append(result.patterns, p)
+		default:
+			result.exact[o] = true
+		}
+	}
+
+	return &result, nil
+}
+
+// match checks, if the origin is allowed. Patterns and regular expressions only match
+// well-formed origins, the regular expressions being applied to the normalized form.
+func (m *originMatcher) match(s string) bool {
+	if m.all || m.exact[s] {
+		return true
+	}
+
+	if len(m.patterns) == 0 && len(m.regexps) == 0 {
+		return false
+	}
+
+	o, ok := parseOrigin(s)
+
+	if !ok {
+		return false
+	}
+
+	for _, p := range m.patterns {
+		if p.match(o) {
+			return true
+		}
+	}
+
+	normalized := o.String()
+
+	for _, re := range m.regexps {
+		if re.MatchString(normalized) {
+			return true
+		}
+	}
+
+	return false
+}
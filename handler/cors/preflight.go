@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package cors
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// safelistedMethods are the methods the clients allow without being listed in the
+// allowed methods.
+var safelistedMethods = map[string]bool{
+	http.MethodGet:  true,
+	http.MethodHead: true,
+	http.MethodPost: true,
+}
+
+// requestedHeaders gives the lower case names of the headers requested by a preflight
+// request.
+func requestedHeaders(r *http.Request) []string {
+	var result []string
+
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for name := range strings.SplitSeq(value, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				result = append(result, name)
+			}
+		}
+	}
+
+	return result
+}
+
+// checkPreflight checks the method and the headers requested by a preflight request
+// against the allowed ones. Without allowed methods or headers, all are allowed.
+func (h *Handler) checkPreflight(r *http.Request) error {
+	method := r.Header.Get("Access-Control-Request-Method")
+
+	if method != "" && len(h.Methods) > 0 && !h.Methods["*"] &&
+		!h.Methods[method] && !safelistedMethods[method] {
+		return fmt.Errorf("%w: %v", ErrMethodNotAllowed, method)
+	}
+
+	if len(h.Headers) == 0 || h.Headers["*"] {
+		return nil
+	}
+
+	for _, name := range requestedHeaders(r) {
+		if !h.Headers[name] {
+			return fmt.Errorf("%w: %v", ErrHeaderNotAllowed, name)
+		}
+	}
+
+	return nil
+}
+
+// setOriginHeaders sets the headers allowing the origin and, if configured, the
+// credentials.
+func (h *Handler) setOriginHeaders(w http.ResponseWriter, origin string) {
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+
+	if h.Credentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// allowedMethods gives the allowed methods for the preflight request. If all methods are
+// allowed, the requested one is echoed, as the clients do not accept the wildcard with
+// credentials.
+func (h *Handler) allowedMethods(w http.ResponseWriter, r *http.Request) string {
+	if len(h.Methods) > 0 && !h.Methods["*"] {
+		return h.MethodsReturn
+	}
+
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+
+	if method := r.Header.Get("Access-Control-Request-Method"); method != "" || h.Credentials {
+		return method
+	}
+
+	return "*"
+}
+
+// allowedHeaders gives the allowed headers for the preflight request. If all headers are
+// allowed, the requested ones are echoed, as the clients do not accept the wildcard with
+// credentials nor for the Authorization header.
+func (h *Handler) allowedHeaders(w http.ResponseWriter, r *http.Request) string {
+	if len(h.Headers) > 0 && !h.Headers["*"] {
+		return h.HeadersReturn
+	}
+
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	if headers := requestedHeaders(r); len(headers) > 0 || h.Credentials {
+		return strings.Join(headers, ", ")
+	}
+
+	return "*"
+}
@@ -0,0 +1,339 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package cors_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/cors"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+const testOrigin = "https://app.example.com"
+
+func TestConformance(t *testing.T) {
+	t.Parallel()
+
+	credentials := []func(*cors.Handler) error{
+		cors.WithOrigins([]string{testOrigin}),
+		cors.WithCredentials(true),
+	}
+
+	tests := []struct {
+		Options  []func(*cors.Handler) error
+		Method   string
+		Header   map[string]string
+		WantCode int
+		// WantHeader are the expected response headers, empty values must be absent
+		WantHeader map[string]string
+	}{
+		{ // 0 simple request with credentials
+			Options: append(credentials, cors.WithExposeHeaders([]string{"X-Total", "X-Page"})),
+			Method:  http.MethodGet,
+			Header:  map[string]string{"Origin": testOrigin},
+			WantHeader: map[string]string{
+				"Access-Control-Allow-Origin":      testOrigin,
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Expose-Headers":    "X-Total, X-Page",
+				"Vary":                             "Origin",
+				"Access-Control-Max-Age":           "",
+			},
+			WantCode: http.StatusOK,
+		},
+		{ // 1 simple request without credentials
+			Options: []func(*cors.Handler) error{cors.WithOrigins([]string{testOrigin})},
+			Method:  http.MethodGet,
+			Header:  map[string]string{"Origin": testOrigin},
+			WantHeader: map[string]string{
+				"Access-Control-Allow-Origin":      testOrigin,
+				"Access-Control-Allow-Credentials": "",
+				"Access-Control-Expose-Headers":    "",
+			},
+			WantCode: http.StatusOK,
+		},
+		{ // 2 preflight with allowed method
+			Options: []func(*cors.Handler) error{
+				cors.WithOrigins([]string{testOrigin}),
+				cors.WithMethods([]string{http.MethodPut, http.MethodDelete}),
+				cors.WithMaxAge(10 * time.Minute),
+				cors.WithExposeHeaders([]string{"X-Total"}),
+			},
+			Method: http.MethodOptions,
+			Header: map[string]string{
+				"Origin":                        testOrigin,
+				"Access-Control-Request-Method": http.MethodDelete,
+			},
+			WantHeader: map[string]string{
+				"Access-Control-Allow-Origin":   testOrigin,
+				"Access-Control-Allow-Methods":  "PUT, DELETE",
+				"Access-Control-Max-Age":        "600",
+				"Access-Control-Expose-Headers": "",
+			},
+			WantCode: http.StatusNoContent,
+		},
+		{ // 3 preflight with disallowed method
+			Options: []func(*cors.Handler) error{
+				cors.WithOrigins([]string{testOrigin}),
+				cors.WithMethods([]string{http.MethodPut}),
+			},
+			Method: http.MethodOptions,
+			Header: map[string]string{
+				"Origin":                        testOrigin,
+				"Access-Control-Request-Method": http.MethodDelete,
+			},
+			WantHeader: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+			WantCode: http.StatusForbidden,
+		},
+		{ // 4 methods are case-sensitive
+			Options: []func(*cors.Handler) error{
+				cors.WithOrigins([]string{testOrigin}),
+				cors.WithMethods([]string{http.MethodPatch}),
+			},
+			Method: http.MethodOptions,
+			Header: map[string]string{
+				"Origin":                        testOrigin,
+				"Access-Control-Request-Method": "patch",
+			},
+			WantCode: http.StatusForbidden,
+		},
+		{ // 5 safelisted methods need not be listed
+			Options: []func(*cors.Handler) error{
+				cors.WithOrigins([]string{testOrigin}),
+				cors.WithMethods([]string{http.MethodPut}),
+			},
+			Method: http.MethodOptions,
+			Header: map[string]string{
+				"Origin":                        testOrigin,
+				"Access-Control-Request-Method": http.MethodPost,
+			},
+			WantCode: http.StatusNoContent,
+		},
+		{ // 6 preflight with allowed headers, case-insensitive
+			Options: []func(*cors.Handler) error{
+				cors.WithOrigins([]string{testOrigin}),
+				cors.WithHeaders([]string{"X-Custom", "Content-Type"}),
+			},
+			Method: http.MethodOptions,
+			Header: map[string]string{
+				"Origin":                         testOrigin,
+				"Access-Control-Request-Method":  http.MethodPut,
+				"Access-Control-Request-Headers": "x-custom,content-type",
+			},
+			WantHeader: map[string]string{
+				"Access-Control-Allow-Headers": "X-Custom, Content-Type",
+				"Access-Control-Allow-Methods": http.MethodPut,
+			},
+			WantCode: http.StatusNoContent,
+		},
+		{ // 7 preflight with disallowed header
+			Options: []func(*cors.Handler) error{
+				cors.WithOrigins([]string{testOrigin}),
+				cors.WithHeaders([]string{"X-Custom"}),
+			},
+			Method: http.MethodOptions,
+			Header: map[string]string{
+				"Origin":                         testOrigin,
+				"Access-Control-Request-Method":  http.MethodGet,
+				"Access-Control-Request-Headers": "x-custom, authorization",
+			},
+			WantHeader: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Headers": "",
+			},
+			WantCode: http.StatusForbidden,
+		},
+		{ // 8 with credentials, the requested method and headers are echoed
+			Options: credentials,
+			Method:  http.MethodOptions,
+			Header: map[string]string{
+				"Origin":                         testOrigin,
+				"Access-Control-Request-Method":  http.MethodPatch,
+				"Access-Control-Request-Headers": "Authorization, X-Custom",
+			},
+			WantHeader: map[string]string{
+				"Access-Control-Allow-Origin":      testOrigin,
+				"Access-Control-Allow-Credentials": "true",
+				"Access-Control-Allow-Methods":     http.MethodPatch,
+				"Access-Control-Allow-Headers":     "authorization, x-custom",
+			},
+			WantCode: http.StatusNoContent,
+		},
+		{ // 9 with credentials, no wildcard is sent
+			Options: credentials,
+			Method:  http.MethodOptions,
+			Header:  map[string]string{"Origin": testOrigin},
+			WantHeader: map[string]string{
+				"Access-Control-Allow-Methods": "",
+				"Access-Control-Allow-Headers": "",
+			},
+			WantCode: http.StatusNoContent,
+		},
+		{ // 10 without credentials, the wildcard is sent
+			Options: nil,
+			Method:  http.MethodOptions,
+			Header:  map[string]string{"Origin": testOrigin},
+			WantHeader: map[string]string{
+				"Access-Control-Allow-Origin":  "*",
+				"Access-Control-Allow-Methods": "*",
+				"Access-Control-Allow-Headers": "*",
+			},
+			WantCode: http.StatusNoContent,
+		},
+		{ // 11 private network access
+			Options: []func(*cors.Handler) error{
+				cors.WithOrigins([]string{testOrigin}),
+				cors.WithPrivateNetwork(true),
+			},
+			Method: http.MethodOptions,
+			Header: map[string]string{
+				"Origin":                                 testOrigin,
+				"Access-Control-Request-Method":          http.MethodGet,
+				"Access-Control-Request-Private-Network": "true",
+			},
+			WantHeader: map[string]string{
+				"Access-Control-Allow-Origin":          testOrigin,
+				"Access-Control-Allow-Private-Network": "true",
+			},
+			WantCode: http.StatusNoContent,
+		},
+		{ // 12 private network access is opt-in
+			Options: []func(*cors.Handler) error{cors.WithOrigins([]string{testOrigin})},
+			Method:  http.MethodOptions,
+			Header: map[string]string{
+				"Origin":                                 testOrigin,
+				"Access-Control-Request-Method":          http.MethodGet,
+				"Access-Control-Request-Private-Network": "true",
+			},
+			WantHeader: map[string]string{
+				"Access-Control-Allow-Private-Network": "",
+			},
+			WantCode: http.StatusNoContent,
+		},
+		{ // 13 private network access only for the allowed origins
+			Options: []func(*cors.Handler) error{
+				cors.WithOrigins([]string{testOrigin}),
+				cors.WithPrivateNetwork(true),
+			},
+			Method: http.MethodOptions,
+			Header: map[string]string{
+				"Origin":                                 "https://evil.example.com",
+				"Access-Control-Request-Method":          http.MethodGet,
+				"Access-Control-Request-Private-Network": "true",
+			},
+			WantHeader: map[string]string{
+				"Access-Control-Allow-Private-Network": "",
+			},
+			WantCode: http.StatusForbidden,
+		},
+		{ // 14 only on preflight requests
+			Options: []func(*cors.Handler) error{
+				cors.WithOrigins([]string{testOrigin}),
+				cors.WithPrivateNetwork(true),
+			},
+			Method: http.MethodGet,
+			Header: map[string]string{
+				"Origin":                                 testOrigin,
+				"Access-Control-Request-Private-Network": "true",
+			},
+			WantHeader: map[string]string{
+				"Access-Control-Allow-Private-Network": "",
+			},
+			WantCode: http.StatusOK,
+		},
+		{ // 15 disallowed origins are rejected before the preflight
+			Options: credentials,
+			Method:  http.MethodOptions,
+			Header: map[string]string{
+				"Origin":                        "https://evil.example.com",
+				"Access-Control-Request-Method": http.MethodGet,
+			},
+			WantHeader: map[string]string{
+				"Access-Control-Allow-Origin":      "",
+				"Access-Control-Allow-Credentials": "",
+			},
+			WantCode: http.StatusForbidden,
+		},
+	}
+
+	for k, test := range tests {
+		handler := helper.Must(cors.New(test.Options...))(http.HandlerFunc(helper.DummyHandler))
+
+		req := httptest.NewRequestWithContext(t.Context(), test.Method, "/", nil)
+
+		for name, value := range test.Header {
+			req.Header.Set(name, value)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.WantCode {
+			t.Errorf("%v: got status %v, wanted %v", k, rec.Code, test.WantCode)
+		}
+
+		for name, want := range test.WantHeader {
+			if got := rec.Header().Get(name); got != want {
+				t.Errorf("%v: got header %v: %q, wanted %q", k, name, got, want)
+			}
+		}
+	}
+}
+
+func TestConformanceOptionErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options []func(*cors.Handler) error
+		Want    error
+	}{
+		{ // 0 all origins are the default
+			Options: []func(*cors.Handler) error{cors.WithCredentials(true)},
+			Want:    cors.ErrWildcardWithCredentials,
+		},
+		{ // 1
+			Options: []func(*cors.Handler) error{
+				cors.WithOrigins([]string{testOrigin, "*"}),
+				cors.WithCredentials(true),
+			},
+			Want: cors.ErrWildcardWithCredentials,
+		},
+		{ // 2
+			Options: []func(*cors.Handler) error{
+				cors.WithOrigins([]string{testOrigin}),
+				cors.WithExposeHeaders([]string{"*"}),
+				cors.WithCredentials(true),
+			},
+			Want: cors.ErrWildcardWithCredentials,
+		},
+		{ // 3
+			Options: []func(*cors.Handler) error{cors.WithMaxAge(-time.Second)},
+			Want:    cors.ErrInvalidMaxAge,
+		},
+		{ // 4
+			Options: []func(*cors.Handler) error{cors.WithPrivateNetwork(true)},
+			Want:    cors.ErrWildcardWithPrivateNetwork,
+		},
+		{ // 5
+			Options: []func(*cors.Handler) error{
+				cors.WithOrigins([]string{"https://*.example.com"}),
+				cors.WithPrivateNetwork(true),
+				cors.WithCredentials(true),
+			},
+			Want: nil,
+		},
+	}
+
+	for k, test := range tests {
+		if _, err := cors.New(test.Options...); !errors.Is(err, test.Want) {
+			t.Errorf("%v: got error %v, wanted %v", k, err, test.Want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package cors
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ErrNilResolver is returned when the origin resolver is nil.
+var ErrNilResolver = errors.New("origin resolver cannot be nil")
+
+// ErrInvalidTTL is returned when the time to live of the cached decisions is negative.
+var ErrInvalidTTL = errors.New("ttl cannot be negative")
+
+// maxCachedOrigins is the maximum number of cached decisions of the origin resolver.
+const maxCachedOrigins = 10_000
+
+// OriginResolver decides at runtime, if an origin is allowed, e.g. looking it up in a
+// database of tenants.
+type OriginResolver interface {
+	// AllowOrigin gives true, if the origin is allowed. An error is treated as not
+	// allowed, without caching it.
+	AllowOrigin(ctx context.Context, origin string) (bool, error)
+}
+
+// OriginResolverFunc is a function implementing OriginResolver.
+type OriginResolverFunc func(ctx context.Context, origin string) (bool, error)
+
+// AllowOrigin calls the function.
+func (f OriginResolverFunc) AllowOrigin(ctx context.Context, origin string) (bool, error) {
+	return f(ctx, origin)
+}
+
+// cachedOrigin is a cached decision of the origin resolver.
+type cachedOrigin struct {
+	allowed bool
+	expires time.Time
+}
+
+// originResolver caches the decisions of an OriginResolver.
+type originResolver struct {
+	resolver OriginResolver
+	ttl      time.Duration
+
+	mtx     sync.Mutex
+	entries map[string]cachedOrigin
+	now     func() time.Time
+}
+
+// allowOrigin gives the decision of the resolver, cached for the ttl.
+func (c *originResolver) allowOrigin(ctx context.Context, origin string) (bool, error) {
+	now := c.now()
+
+	c.mtx.Lock()
+	entry, found := c.entries[origin]
+	c.mtx.Unlock()
+
+	if found && now.Before(entry.expires) {
+		return entry.allowed, nil
+	}
+
+	allowed, err := c.resolver.AllowOrigin(ctx, origin)
+
+	if err != nil || c.ttl == 0 {
+		return allowed && err == nil, err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if len(c.entries) >= maxCachedOrigins {
+		for key, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, key)
+			}
+		}
+	}
+
+	if len(c.entries) >= maxCachedOrigins {
+		// requests with arbitrary origins must not grow the cache without limit
+		clear(c.entries)
+	}
+
+	c.entries[origin] = cachedOrigin{allowed: allowed, expires: now.Add(c.ttl)}
+
+	return allowed, nil
+}
+
+// WithOriginResolver sets a resolver deciding on the origins not allowed by WithOrigins
+// or WithOriginRegexps. Its decisions are cached for the given ttl, 0 disables caching.
+func WithOriginResolver(resolver OriginResolver, ttl time.Duration) func(handler *Handler) error {
+	return func(handler *Handler) error {
+		if value := reflect.ValueOf(resolver); !value.IsValid() ||
+			((value.Kind() == reflect.Func || value.Kind() == reflect.Pointer) && value.IsNil()) {
+			return ErrNilResolver
+		}
+
+		if ttl < 0 {
+			return ErrInvalidTTL
+		}
+
+		handler.resolver = &originResolver{
+			resolver: resolver,
+			ttl:      ttl,
+			entries:  make(map[string]cachedOrigin),
+			now:      time.Now,
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package cors_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/cors"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// tenants is an origin resolver backed by a changing set of tenant origins.
+type tenants struct {
+	mtx     sync.Mutex
+	origins map[string]bool
+	calls   int
+	err     error
+}
+
+func (d *tenants) AllowOrigin(_ context.Context, origin string) (bool, error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.calls++
+
+	return d.origins[origin], d.err
+}
+
+func (d *tenants) set(origin string, allowed bool, err error) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	d.origins[origin] = allowed
+	d.err = err
+}
+
+func TestOriginResolver(t *testing.T) {
+	t.Parallel()
+
+	db := &tenants{origins: map[string]bool{"https://tenant.example.com": true}}
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	handler := helper.Must(cors.New(
+		cors.WithOrigins([]string{"https://static.example.com"}),
+		cors.WithOriginResolver(db, time.Minute),
+		cors.TWithResolverNow(func() time.Time { return now }),
+	))(http.HandlerFunc(helper.DummyHandler))
+
+	tests := []struct {
+		Change    func()
+		Origin    string
+		WantCode  int
+		WantCalls int
+	}{
+		{ // 0
+			Origin:    "https://tenant.example.com",
+			WantCode:  http.StatusOK,
+			WantCalls: 1,
+		},
+		{ // 1 the decision is cached
+			Origin:    "https://tenant.example.com",
+			WantCode:  http.StatusOK,
+			WantCalls: 1,
+		},
+		{ // 2 the static origins do not need the resolver
+			Origin:    "https://static.example.com",
+			WantCode:  http.StatusOK,
+			WantCalls: 1,
+		},
+		{ // 3 changes apply after the ttl
+			Change:    func() { db.set("https://tenant.example.com", false, nil) },
+			Origin:    "https://tenant.example.com",
+			WantCode:  http.StatusOK,
+			WantCalls: 1,
+		},
+		{ // 4
+			Change:    func() { now = now.Add(time.Minute) },
+			Origin:    "https://tenant.example.com",
+			WantCode:  http.StatusForbidden,
+			WantCalls: 2,
+		},
+		{ // 5 rejections are cached as well
+			Origin:    "https://tenant.example.com",
+			WantCode:  http.StatusForbidden,
+			WantCalls: 2,
+		},
+		{ // 6 errors are not cached
+			Change:    func() { db.set("https://new.example.com", true, errors.New("database down")) },
+			Origin:    "https://new.example.com",
+			WantCode:  http.StatusForbidden,
+			WantCalls: 3,
+		},
+		{ // 7
+			Change:    func() { db.set("https://new.example.com", true, nil) },
+			Origin:    "https://new.example.com",
+			WantCode:  http.StatusOK,
+			WantCalls: 4,
+		},
+		{ // 8 requests without origin pass without asking the resolver
+			Origin:    "",
+			WantCode:  http.StatusOK,
+			WantCalls: 4,
+		},
+	}
+
+	for k, test := range tests {
+		if test.Change != nil {
+			test.Change()
+		}
+
+		req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+
+		if test.Origin != "" {
+			req.Header.Set("Origin", test.Origin)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.WantCode {
+			t.Errorf("%v: got status %v, wanted %v", k, rec.Code, test.WantCode)
+		}
+
+		if db.calls != test.WantCalls {
+			t.Errorf("%v: got %v calls of the resolver, wanted %v", k, db.calls, test.WantCalls)
+		}
+
+		// the responses differ by origin, also the rejections
+		if !slices.Contains(rec.Header().Values("Vary"), "Origin") {
+			t.Errorf("%v: response does not vary by origin", k)
+		}
+
+		wantOrigin := ""
+
+		if test.WantCode == http.StatusOK {
+			wantOrigin = test.Origin
+		}
+
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != wantOrigin {
+			t.Errorf("%v: got allowed origin %q, wanted %q", k, got, wantOrigin)
+		}
+	}
+}
+
+func TestOriginResolverFunc(t *testing.T) {
+	t.Parallel()
+
+	type ctxKey struct{}
+
+	calls := 0
+
+	handler := helper.Must(cors.New(
+		cors.WithOriginResolver(cors.OriginResolverFunc(func(ctx context.Context, origin string) (bool, error) {
+			calls++
+
+			return ctx.Value(ctxKey{}) == origin, nil
+		}), 0),
+	))(http.HandlerFunc(helper.DummyHandler))
+
+	for k, origin := range []string{"https://a.example.com", "https://a.example.com"} {
+		ctx := context.WithValue(t.Context(), ctxKey{}, origin)
+		req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+		req.Header.Set("Origin", origin)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("%v: got status %v, wanted %v", k, rec.Code, http.StatusOK)
+		}
+
+		// without ttl, every request asks the resolver
+		if calls != k+1 {
+			t.Errorf("%v: got %v calls of the resolver, wanted %v", k, calls, k+1)
+		}
+	}
+}
+
+func TestOriginResolverErrors(t *testing.T) {
+	t.Parallel()
+
+	var nilFunc cors.OriginResolverFunc
+
+	tests := []struct {
+		Option func(*cors.Handler) error
+		Want   error
+	}{
+		{Option: cors.WithOriginResolver(nil, time.Minute), Want: cors.ErrNilResolver},             // 0
+		{Option: cors.WithOriginResolver(nilFunc, time.Minute), Want: cors.ErrNilResolver},         // 1
+		{Option: cors.WithOriginResolver((*tenants)(nil), time.Minute), Want: cors.ErrNilResolver}, // 2
+		{Option: cors.WithOriginResolver(&tenants{}, -time.Second), Want: cors.ErrInvalidTTL},      // 3
+	}
+
+	for k, test := range tests {
+		if _, err := cors.New(test.Option); !errors.Is(err, test.Want) {
+			t.Errorf("%v: got error %v, wanted %v", k, err, test.Want)
+		}
+	}
+}
@@ -0,0 +1,446 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package digestauth implements the HTTP digest authentication as described in RFC 7616.
+package digestauth
+
+import (
+	"crypto/md5" //nolint:gosec // MD5 is required by RFC 7616 for legacy clients
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// ErrNilOption is returned when an option is nil.
+var ErrNilOption = errors.New("option cannot be nil")
+
+// ErrNoSecrets is returned when there is no secret provider configured.
+var ErrNoSecrets = errors.New("no secret provider configured")
+
+// ErrUnsupportedAlgorithm is returned when an unknown digest algorithm is configured.
+var ErrUnsupportedAlgorithm = errors.New("unsupported digest algorithm")
+
+// ErrInvalidLifetime is returned when the nonce lifetime is not positive.
+var ErrInvalidLifetime = errors.New("nonce lifetime must be greater than 0")
+
+// ErrMalformedHeader is returned when the authorization header cannot be parsed.
+var ErrMalformedHeader = errors.New("malformed authorization header")
+
+// Algorithm is a hash algorithm usable for digest authentication.
+type Algorithm string
+
+const (
+	// AlgorithmMD5 is the MD5 algorithm, kept for compatibility with legacy clients.
+	AlgorithmMD5 Algorithm = "MD5"
+	// AlgorithmSHA256 is the SHA-256 algorithm.
+	AlgorithmSHA256 Algorithm = "SHA-256"
+)
+
+// DefaultNonceLifetime is the time a nonce is valid, if not configured otherwise.
+const DefaultNonceLifetime = 5 * time.Minute
+
+// newHash gives the hash function for the algorithm.
+func (a Algorithm) newHash() (hash.Hash, error) {
+	switch a {
+	case AlgorithmMD5:
+		return md5.New(), nil //nolint:gosec // see import
+	case AlgorithmSHA256:
+		return sha256.New(), nil
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+}
+
+// Hash calculates the hex encoded hash of the given parts, joined by colons, as used
+// throughout the digest calculations.
+func (a Algorithm) Hash(parts ...string) (string, error) {
+	h, err := a.newHash()
+
+	if err != nil {
+		return "", err
+	}
+
+	_, _ = h.Write([]byte(strings.Join(parts, ":")))
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SecretProvider is an interface the digest auth handler uses to get the stored
+// credential hashes of a user.
+type SecretProvider interface {
+	// HA1 gets the hex encoded hash of "username:realm:password" for the given
+	// algorithm. If there is no such entry, found is false.
+	HA1(username, realm string, algorithm Algorithm) (ha1 string, found bool, err error)
+}
+
+// Handler holds the internal data of the digest authentication middleware.
+type Handler struct {
+	defs.MWBase
+
+	secrets       SecretProvider // secrets holds the SecretProvider used
+	realm         string         // realm to report to the client
+	algorithms    []Algorithm    // algorithms offered to the client, in order of preference
+	nonceLifetime time.Duration  // nonceLifetime is the time a nonce stays valid
+	nonces        *nonceStore    // nonces issues and checks the server nonces
+	opaque        string         // opaque is the value the client has to send back unchanged
+}
+
+// GetMWBase returns the MWBase instance of the handler.
+func (h *Handler) GetMWBase() *defs.MWBase {
+	if h == nil {
+		return nil
+	}
+
+	return &h.MWBase
+}
+
+// Credentials holds the parameters a client sends in a digest Authorization header.
+type Credentials struct {
+	Username  string
+	Realm     string
+	Nonce     string
+	URI       string
+	Response  string
+	Algorithm Algorithm
+	CNonce    string
+	NC        string
+	QOP       string
+	Opaque    string
+}
+
+// ParseParams parses a comma separated list of auth-params, e.g. `a="b", c=d`, into a map.
+// The parameter names are converted to lower case.
+func ParseParams(s string) (map[string]string, error) {
+	result := make(map[string]string)
+
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		name, rest, found := strings.Cut(s, "=")
+
+		if !found {
+			return nil, ErrMalformedHeader
+		}
+
+		name = strings.ToLower(strings.TrimSpace(name))
+		rest = strings.TrimLeft(rest, " \t")
+
+		var value string
+
+		if strings.HasPrefix(rest, `"`) {
+			var b strings.Builder
+
+			i := 1
+
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+
+				b.WriteByte(rest[i])
+			}
+
+			if i >= len(rest) {
+				return nil, ErrMalformedHeader
+			}
+
+			value = b.String()
+			rest = rest[i+1:]
+		} else {
+			end := strings.IndexByte(rest, ',')
+
+			if end < 0 {
+				end = len(rest)
+			}
+
+			value = strings.TrimSpace(rest[:end])
+			rest = rest[end:]
+		}
+
+		result[name] = value
+
+		rest = strings.TrimLeft(rest, " \t")
+
+		if rest != "" && !strings.HasPrefix(rest, ",") {
+			return nil, ErrMalformedHeader
+		}
+
+		s = strings.TrimPrefix(rest, ",")
+	}
+
+	return result, nil
+}
+
+// ExtractCredentials extracts the digest credentials out of the given header value
+// for Authorization. It signalizes if the desired information exists or an error,
+// when the auth string is unprocessable.
+func ExtractCredentials(auth string) (cred Credentials, found bool, err error) {
+	authInfo, headerPrefixOK := strings.CutPrefix(auth, "Digest ")
+
+	if !headerPrefixOK {
+		return Credentials{}, false, nil
+	}
+
+	params, parseErr := ParseParams(authInfo)
+
+	if parseErr != nil {
+		return Credentials{}, false, parseErr
+	}
+
+	cred = Credentials{
+		Username:  params["username"],
+		Realm:     params["realm"],
+		Nonce:     params["nonce"],
+		URI:       params["uri"],
+		Response:  params["response"],
+		Algorithm: Algorithm(params["algorithm"]),
+		CNonce:    params["cnonce"],
+		NC:        params["nc"],
+		QOP:       params["qop"],
+		Opaque:    params["opaque"],
+	}
+
+	if cred.Algorithm == "" {
+		cred.Algorithm = AlgorithmMD5
+	}
+
+	if cred.Username == "" || cred.Nonce == "" || cred.URI == "" || cred.Response == "" {
+		return Credentials{}, false, nil
+	}
+
+	return cred, true, nil
+}
+
+// ServeHTTP implements the digest auth functionality.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !helper.IntroCheck(h, w, r) {
+		return
+	}
+
+	authInfo := r.Header.Get("Authorization")
+	cred, credFound, credErr := ExtractCredentials(authInfo)
+
+	if credErr != nil {
+		h.Log().Debug("could not process auth info",
+			slog.String("error", credErr.Error()),
+			slog.String("authInfo", authInfo))
+	}
+
+	if !credFound {
+		h.sendNoAuth(w, false)
+
+		return
+	}
+
+	if !h.validParams(&cred, r) {
+		h.Log().Debug("invalid digest parameters",
+			slog.String("user", cred.Username))
+		h.sendNoAuth(w, false)
+
+		return
+	}
+
+	count, countErr := strconv.ParseUint(cred.NC, 16, 64)
+
+	if countErr != nil {
+		h.sendNoAuth(w, false)
+
+		return
+	}
+
+	ha1, userFound, secretErr := h.secrets.HA1(cred.Username, h.realm, cred.Algorithm)
+
+	if secretErr != nil {
+		h.Log().Error("authentication error",
+			slog.String("error", secretErr.Error()),
+			slog.String("user", cred.Username))
+	}
+
+	if !userFound || secretErr != nil {
+		h.sendNoAuth(w, false)
+
+		return
+	}
+
+	ha2, _ := cred.Algorithm.Hash(r.Method, cred.URI)
+	want, _ := cred.Algorithm.Hash(ha1, cred.Nonce, cred.NC, cred.CNonce, cred.QOP, ha2)
+
+	if subtle.ConstantTimeCompare([]byte(want), []byte(strings.ToLower(cred.Response))) != 1 {
+		h.sendNoAuth(w, false)
+
+		return
+	}
+
+	// the nonce is only checked for a correct response, so that only authenticated
+	// clients can make the server remember nonces
+	switch h.nonces.check(cred.Nonce, count) {
+	case nonceValid:
+	case nonceStale:
+		h.sendNoAuth(w, true)
+
+		return
+	case nonceReplay:
+		h.Log().Info("replayed digest nonce",
+			slog.String("user", cred.Username),
+			slog.String("client", r.RemoteAddr))
+		h.sendNoAuth(w, false)
+
+		return
+	default:
+		h.sendNoAuth(w, false)
+
+		return
+	}
+
+	rspHA2, _ := cred.Algorithm.Hash("", cred.URI)
+	rspAuth, _ := cred.Algorithm.Hash(ha1, cred.Nonce, cred.NC, cred.CNonce, cred.QOP, rspHA2)
+
+	w.Header().Set("Authentication-Info",
+		fmt.Sprintf(`qop=auth, rspauth="%s", cnonce="%s", nc=%s`, rspAuth, quoteEscape(cred.CNonce), cred.NC))
+
+	r = r.WithContext(defs.ContextWithPrincipal(r.Context(), &defs.Principal{Name: cred.Username, Method: "digest"}))
+
+	h.Next().ServeHTTP(w, r)
+}
+
+// validParams checks that the parameters sent by the client match the ones of the
+// challenge and the current request.
+func (h *Handler) validParams(cred *Credentials, r *http.Request) bool {
+	return cred.Realm == h.realm &&
+		cred.Opaque == h.opaque &&
+		cred.QOP == "auth" &&
+		cred.CNonce != "" &&
+		slices.Contains(h.algorithms, cred.Algorithm) &&
+		cred.URI == r.RequestURI
+}
+
+// quoteEscape escapes the characters that cannot be used verbatim in a quoted string.
+func quoteEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// sendNoAuth sends the client the challenges for all configured algorithms.
+func (h *Handler) sendNoAuth(w http.ResponseWriter, stale bool) {
+	nonce := h.nonces.issue()
+
+	for _, alg := range h.algorithms {
+		challenge := fmt.Sprintf(`Digest realm="%s", qop="auth", algorithm=%s, nonce="%s", opaque="%s"`,
+			quoteEscape(h.realm), alg, nonce, h.opaque)
+
+		if stale {
+			challenge += ", stale=true"
+		}
+
+		w.Header().Add("WWW-Authenticate", challenge+`, charset=UTF-8`)
+	}
+
+	helper.WriteState(w, h.Log(), http.StatusUnauthorized)
+}
+
+// WithSecrets sets the SecretProvider to use.
+func WithSecrets(secrets SecretProvider) func(h *Handler) error {
+	return func(h *Handler) error {
+		h.secrets = secrets
+
+		return nil
+	}
+}
+
+// WithRealm sets the realm to use.
+func WithRealm(realm string) func(h *Handler) error {
+	return func(h *Handler) error {
+		h.realm = realm
+
+		return nil
+	}
+}
+
+// WithAlgorithms sets the algorithms offered to the clients, in the order of preference.
+func WithAlgorithms(algorithms ...Algorithm) func(h *Handler) error {
+	return func(h *Handler) error {
+		for _, a := range algorithms {
+			if _, err := a.newHash(); err != nil {
+				return fmt.Errorf("%w: %v", err, a)
+			}
+		}
+
+		h.algorithms = slices.Clone(algorithms)
+
+		return nil
+	}
+}
+
+// WithNonceLifetime sets the time a nonce stays valid. After that, clients are asked to
+// authenticate again with a fresh nonce.
+func WithNonceLifetime(d time.Duration) func(h *Handler) error {
+	return func(h *Handler) error {
+		if d <= 0 {
+			return ErrInvalidLifetime
+		}
+
+		h.nonceLifetime = d
+
+		return nil
+	}
+}
+
+// WithLogger configures the logger to use.
+func WithLogger(log *slog.Logger) func(h *Handler) error {
+	return defs.WithLogger[*Handler](log)
+}
+
+// WithLogLevel configures the log level to use with the logger.
+func WithLogLevel(level slog.Level) func(h *Handler) error {
+	return defs.WithLogLevel[*Handler](level)
+}
+
+// New generates a new digest authentication middleware.
+func New(options ...func(handler *Handler) error) (defs.Middleware, error) {
+	handler := Handler{
+		algorithms:    []Algorithm{AlgorithmSHA256, AlgorithmMD5},
+		nonceLifetime: DefaultNonceLifetime,
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&handler); err != nil {
+			return nil, err
+		}
+	}
+
+	if handler.secrets == nil {
+		return nil, ErrNoSecrets
+	}
+
+	if len(handler.algorithms) == 0 {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	if handler.realm == "" {
+		handler.realm = "Restricted"
+	}
+
+	handler.nonces = newNonceStore(handler.nonceLifetime)
+	handler.opaque = handler.nonces.issue()
+
+	return func(next http.Handler) http.Handler {
+		if err := handler.SetNext(next); err != nil {
+			return nil
+		}
+
+		return &handler
+	}, nil
+}
@@ -0,0 +1,394 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package digestauth_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/digestauth"
+	"github.com/AlphaOne1/midgard/handler/digestauth/htdigestauth"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+type SecretsTest struct{}
+
+func (s *SecretsTest) HA1(username, realm string, algorithm digestauth.Algorithm) (string, bool, error) {
+	switch username {
+	case "testuser":
+		ha1, err := algorithm.Hash(username, realm, "testpass")
+
+		return ha1, true, err
+	case "generr":
+		return "", false, errors.New("generated")
+	default:
+		return "", false, nil
+	}
+}
+
+// challengeParams gets the parameters of the first challenge for the given algorithm.
+func challengeParams(t *testing.T, h http.Header, alg digestauth.Algorithm) map[string]string {
+	t.Helper()
+
+	for _, c := range h.Values("WWW-Authenticate") {
+		params := helper.Must(digestauth.ParseParams(strings.TrimPrefix(c, "Digest ")))
+
+		if params["algorithm"] == string(alg) {
+			return params
+		}
+	}
+
+	t.Fatalf("no challenge for algorithm %v found", alg)
+
+	return nil
+}
+
+// authorization calculates the Authorization header value answering the given challenge.
+func authorization(
+	challenge map[string]string,
+	alg digestauth.Algorithm,
+	method, uri, user, pass string,
+	nc int) string {
+
+	ncStr := fmt.Sprintf("%08x", nc)
+	ha1, _ := alg.Hash(user, challenge["realm"], pass)
+	ha2, _ := alg.Hash(method, uri)
+	response, _ := alg.Hash(ha1, challenge["nonce"], ncStr, "clientnonce", "auth", ha2)
+
+	return fmt.Sprintf(
+		`Digest username="%s", realm="%s", nonce="%s", uri="%s", algorithm=%s, `+
+			`qop=auth, nc=%s, cnonce="clientnonce", response="%s", opaque="%s"`,
+		user, challenge["realm"], challenge["nonce"], uri, alg, ncStr, response, challenge["opaque"])
+}
+
+// doRequest sends a request with the given authorization header to the handler.
+func doRequest(t *testing.T, handler http.Handler, uri, auth string) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, uri, nil)
+	rec := httptest.NewRecorder()
+
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	handler.ServeHTTP(rec, req)
+
+	return rec.Result()
+}
+
+func TestDigestAuth(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Algorithm digestauth.Algorithm
+		User      string
+		Pass      string
+		URI       string
+		WantState int
+	}{
+		{ // 0
+			Algorithm: digestauth.AlgorithmSHA256,
+			User:      "testuser",
+			Pass:      "testpass",
+			URI:       "/",
+			WantState: http.StatusOK,
+		},
+		{ // 1
+			Algorithm: digestauth.AlgorithmMD5,
+			User:      "testuser",
+			Pass:      "testpass",
+			URI:       "/",
+			WantState: http.StatusOK,
+		},
+		{ // 2
+			Algorithm: digestauth.AlgorithmSHA256,
+			User:      "testuser",
+			Pass:      "testwrong",
+			URI:       "/",
+			WantState: http.StatusUnauthorized,
+		},
+		{ // 3
+			Algorithm: digestauth.AlgorithmSHA256,
+			User:      "unknown",
+			Pass:      "testpass",
+			URI:       "/",
+			WantState: http.StatusUnauthorized,
+		},
+		{ // 4
+			Algorithm: digestauth.AlgorithmSHA256,
+			User:      "generr",
+			Pass:      "testpass",
+			URI:       "/",
+			WantState: http.StatusUnauthorized,
+		},
+		{ // 5
+			Algorithm: digestauth.AlgorithmSHA256,
+			User:      "testuser",
+			Pass:      "testpass",
+			URI:       "/other",
+			WantState: http.StatusUnauthorized,
+		},
+	}
+
+	handler := helper.Must(digestauth.New(
+		digestauth.WithSecrets(&SecretsTest{}),
+		digestauth.WithRealm("testrealm")))(
+		http.HandlerFunc(helper.DummyHandler))
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestDigestAuth-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			challenge := challengeParams(t, doRequest(t, handler, "/", "").Header, test.Algorithm)
+			res := doRequest(t, handler, "/",
+				authorization(challenge, test.Algorithm, http.MethodGet, test.URI, test.User, test.Pass, 1))
+
+			if res.StatusCode != test.WantState {
+				t.Errorf("got state %v but wanted %v", res.StatusCode, test.WantState)
+			}
+
+			if test.WantState == http.StatusOK && !strings.Contains(res.Header.Get("Authentication-Info"), "rspauth=") {
+				t.Errorf("expected authentication info, but got %v", res.Header.Get("Authentication-Info"))
+			}
+		})
+	}
+}
+
+func TestDigestAuthChallenge(t *testing.T) {
+	t.Parallel()
+
+	handler := helper.Must(digestauth.New(digestauth.WithSecrets(&SecretsTest{})))(
+		http.HandlerFunc(helper.DummyHandler))
+
+	res := doRequest(t, handler, "/", "")
+
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got state %v but wanted %v", res.StatusCode, http.StatusUnauthorized)
+	}
+
+	challenges := res.Header.Values("WWW-Authenticate")
+
+	if len(challenges) != 2 {
+		t.Fatalf("expected 2 challenges, but got %v", challenges)
+	}
+
+	if !strings.Contains(challenges[0], "algorithm=SHA-256") || !strings.Contains(challenges[1], "algorithm=MD5") {
+		t.Errorf("challenges not in preference order: %v", challenges)
+	}
+
+	if !strings.Contains(challenges[0], `realm="Restricted"`) {
+		t.Errorf("default realm not set correctly: %v", challenges[0])
+	}
+}
+
+func TestDigestAuthReplay(t *testing.T) {
+	t.Parallel()
+
+	handler := helper.Must(digestauth.New(
+		digestauth.WithSecrets(&SecretsTest{}),
+		digestauth.WithAlgorithms(digestauth.AlgorithmSHA256)))(
+		http.HandlerFunc(helper.DummyHandler))
+
+	challenge := challengeParams(t, doRequest(t, handler, "/", "").Header, digestauth.AlgorithmSHA256)
+
+	tests := []struct {
+		NC        int
+		WantState int
+	}{
+		{NC: 1, WantState: http.StatusOK},
+		{NC: 1, WantState: http.StatusUnauthorized},
+		{NC: 2, WantState: http.StatusOK},
+		{NC: 2, WantState: http.StatusUnauthorized},
+		{NC: 5, WantState: http.StatusOK},
+		{NC: 3, WantState: http.StatusUnauthorized},
+	}
+
+	for k, test := range tests {
+		res := doRequest(t, handler, "/",
+			authorization(challenge, digestauth.AlgorithmSHA256, http.MethodGet, "/", "testuser", "testpass", test.NC))
+
+		if res.StatusCode != test.WantState {
+			t.Errorf("%v: got state %v but wanted %v", k, res.StatusCode, test.WantState)
+		}
+	}
+}
+
+func TestDigestAuthStale(t *testing.T) {
+	t.Parallel()
+
+	var now atomic.Int64
+
+	now.Store(time.Now().UnixNano())
+
+	handler := helper.Must(digestauth.New(
+		digestauth.WithSecrets(&SecretsTest{}),
+		digestauth.WithNonceLifetime(time.Minute)))(
+		http.HandlerFunc(helper.DummyHandler))
+
+	digestauth.TSetNow(handler.(*digestauth.Handler), func() time.Time { return time.Unix(0, now.Load()) })
+
+	challenge := challengeParams(t, doRequest(t, handler, "/", "").Header, digestauth.AlgorithmSHA256)
+	auth := authorization(challenge, digestauth.AlgorithmSHA256, http.MethodGet, "/", "testuser", "testpass", 1)
+
+	now.Add(int64(2 * time.Minute))
+
+	res := doRequest(t, handler, "/", auth)
+
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got state %v but wanted %v", res.StatusCode, http.StatusUnauthorized)
+	}
+
+	if !strings.Contains(res.Header.Get("WWW-Authenticate"), "stale=true") {
+		t.Errorf("expected stale marker, but got %v", res.Header.Get("WWW-Authenticate"))
+	}
+}
+
+func TestDigestAuthInvalidNonce(t *testing.T) {
+	t.Parallel()
+
+	handler := helper.Must(digestauth.New(digestauth.WithSecrets(&SecretsTest{})))(
+		http.HandlerFunc(helper.DummyHandler))
+
+	challenge := challengeParams(t, doRequest(t, handler, "/", "").Header, digestauth.AlgorithmSHA256)
+	challenge["nonce"] = "forged" + challenge["nonce"]
+
+	res := doRequest(t, handler, "/",
+		authorization(challenge, digestauth.AlgorithmSHA256, http.MethodGet, "/", "testuser", "testpass", 1))
+
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Errorf("got state %v but wanted %v", res.StatusCode, http.StatusUnauthorized)
+	}
+
+	if strings.Contains(res.Header.Get("WWW-Authenticate"), "stale=true") {
+		t.Errorf("forged nonce must not be reported stale")
+	}
+}
+
+func TestDigestAuthHTDigest(t *testing.T) {
+	t.Parallel()
+
+	handler := helper.Must(digestauth.New(
+		digestauth.WithSecrets(helper.Must(htdigestauth.New(htdigestauth.WithAuthFile("htdigestauth/testdigest")))),
+		digestauth.WithRealm("testrealm")))(
+		http.HandlerFunc(helper.DummyHandler))
+
+	challenge := challengeParams(t, doRequest(t, handler, "/", "").Header, digestauth.AlgorithmMD5)
+	res := doRequest(t, handler, "/",
+		authorization(challenge, digestauth.AlgorithmMD5, http.MethodGet, "/", "user1", "pass1", 1))
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("got state %v but wanted %v", res.StatusCode, http.StatusOK)
+	}
+}
+
+func TestDigestAuthMalformed(t *testing.T) {
+	t.Parallel()
+
+	handler := helper.Must(digestauth.New(digestauth.WithSecrets(&SecretsTest{})))(
+		http.HandlerFunc(helper.DummyHandler))
+
+	tests := []string{
+		"Basic dGVzdHVzZXI6dGVzdHBhc3M=",
+		`Digest username="testuser", nonce="unterminated`,
+		`Digest username="testuser"`,
+		`Digest username="testuser", nonce="x", uri="/", response="x", nc=zz, qop=auth, cnonce="c"`,
+	}
+
+	for k, test := range tests {
+		if res := doRequest(t, handler, "/", test); res.StatusCode != http.StatusUnauthorized {
+			t.Errorf("%v: got state %v but wanted %v", k, res.StatusCode, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		In      string
+		Want    map[string]string
+		WantErr bool
+	}{
+		{ // 0
+			In:   `a="b", C=d`,
+			Want: map[string]string{"a": "b", "c": "d"},
+		},
+		{ // 1
+			In:   `a="with \"quote\", and comma",b=x`,
+			Want: map[string]string{"a": `with "quote", and comma`, "b": "x"},
+		},
+		{ // 2
+			In:   ``,
+			Want: map[string]string{},
+		},
+		{ // 3
+			In:      `a`,
+			WantErr: true,
+		},
+		{ // 4
+			In:      `a="b" c=d`,
+			WantErr: true,
+		},
+	}
+
+	for k, test := range tests {
+		got, err := digestauth.ParseParams(test.In)
+
+		if (err != nil) != test.WantErr {
+			t.Errorf("%v: got error %v, wanted error %v", k, err, test.WantErr)
+		}
+
+		if fmt.Sprint(got) != fmt.Sprint(test.Want) && !test.WantErr {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+	}
+}
+
+func TestDigestAuthConfiguration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options []func(*digestauth.Handler) error
+		WantErr error
+	}{
+		{ // 0
+			Options: nil,
+			WantErr: digestauth.ErrNoSecrets,
+		},
+		{ // 1
+			Options: []func(*digestauth.Handler) error{
+				digestauth.WithSecrets(&SecretsTest{}),
+				digestauth.WithAlgorithms("SHA-1"),
+			},
+			WantErr: digestauth.ErrUnsupportedAlgorithm,
+		},
+		{ // 2
+			Options: []func(*digestauth.Handler) error{
+				digestauth.WithSecrets(&SecretsTest{}),
+				digestauth.WithAlgorithms(),
+			},
+			WantErr: digestauth.ErrUnsupportedAlgorithm,
+		},
+		{ // 3
+			Options: []func(*digestauth.Handler) error{
+				digestauth.WithSecrets(&SecretsTest{}),
+				digestauth.WithNonceLifetime(0),
+			},
+			WantErr: digestauth.ErrInvalidLifetime,
+		},
+	}
+
+	for k, test := range tests {
+		if _, err := digestauth.New(test.Options...); !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package htdigestauth implements the digest auth secret lookup using a htdigest file.
+package htdigestauth
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlphaOne1/midgard/handler/digestauth"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// ErrEmptyInput is returned when the input is empty.
+var ErrEmptyInput = errors.New("input is empty")
+
+// ErrNotInitialized is returned when the htdigest authenticator is not initialized.
+var ErrNotInitialized = errors.New("htdigest auth not initialized")
+
+// ErrMalformedLine is returned when a line of the htdigest input cannot be processed.
+var ErrMalformedLine = errors.New("malformed htdigest line")
+
+// entryKey identifies an entry of the htdigest file.
+type entryKey struct {
+	username  string
+	realm     string
+	algorithm digestauth.Algorithm
+}
+
+// HTDigestAuth holds the htdigest relevant data.
+type HTDigestAuth struct {
+	entries map[entryKey]string
+}
+
+// HA1 gets the stored hash of "username:realm:password" for the given algorithm.
+// The classic htdigest format only contains MD5 hashes. To also support SHA-256, lines
+// with a 64 hex digits long hash are interpreted as SHA-256 entries.
+func (a *HTDigestAuth) HA1(username, realm string, algorithm digestauth.Algorithm) (string, bool, error) {
+	if a == nil || a.entries == nil {
+		return "", false, ErrNotInitialized
+	}
+
+	ha1, found := a.entries[entryKey{username: username, realm: realm, algorithm: algorithm}]
+
+	return ha1, found, nil
+}
+
+// parse reads the htdigest lines out of the given reader.
+func parse(in io.Reader) (map[entryKey]string, error) {
+	entries := make(map[entryKey]string)
+	scanner := bufio.NewScanner(in)
+
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, ":")
+
+		if len(fields) != 3 || fields[0] == "" {
+			return nil, fmt.Errorf("%w: line %d", ErrMalformedLine, lineNo)
+		}
+
+		if _, err := hex.DecodeString(fields[2]); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrMalformedLine, lineNo, err)
+		}
+
+		key := entryKey{username: fields[0], realm: fields[1]}
+
+		switch len(fields[2]) {
+		case hex.EncodedLen(16): //nolint:mnd // MD5 size
+			key.algorithm = digestauth.AlgorithmMD5
+		case hex.EncodedLen(32): //nolint:mnd // SHA-256 size
+			key.algorithm = digestauth.AlgorithmSHA256
+		default:
+			return nil, fmt.Errorf("%w: line %d: unknown hash length", ErrMalformedLine, lineNo)
+		}
+
+		entries[key] = strings.ToLower(fields[2])
+	}
+
+	return entries, helper.WrapIfError("could not read htdigest input", scanner.Err())
+}
+
+// WithAuthInput configures the htdigest file to be read from the
+// given io.Reader.
+func WithAuthInput(in io.Reader) func(a *HTDigestAuth) error {
+	return func(a *HTDigestAuth) error {
+		if in == nil {
+			return ErrEmptyInput
+		}
+
+		entries, err := parse(in)
+
+		if err != nil {
+			return err
+		}
+
+		a.entries = entries
+
+		return nil
+	}
+}
+
+// WithAuthFile configures the htdigest file to be read from the
+// filesystem with the given name.
+func WithAuthFile(fileName string) func(a *HTDigestAuth) error {
+	return func(auth *HTDigestAuth) error {
+		if len(fileName) == 0 {
+			return ErrEmptyInput
+		}
+
+		input, err := os.Open(filepath.Clean(fileName))
+
+		if err != nil {
+			return fmt.Errorf("could not open auth file: %w", err)
+		}
+
+		defer func() { _ = input.Close() }()
+
+		return WithAuthInput(input)(auth)
+	}
+}
+
+// New creates a new htdigest secret provider.
+func New(options ...func(*HTDigestAuth) error) (*HTDigestAuth, error) {
+	auth := HTDigestAuth{}
+
+	for _, opt := range options {
+		if err := opt(&auth); err != nil {
+			return nil, err
+		}
+	}
+
+	if auth.entries == nil {
+		return nil, ErrEmptyInput
+	}
+
+	return &auth, nil
+}
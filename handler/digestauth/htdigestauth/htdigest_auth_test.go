@@ -0,0 +1,161 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package htdigestauth_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AlphaOne1/midgard/handler/digestauth"
+	"github.com/AlphaOne1/midgard/handler/digestauth/htdigestauth"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+func TestHtdigestAuth(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Username  string
+		Password  string
+		Algorithm digestauth.Algorithm
+		Found     bool
+	}{
+		{
+			Username:  "user0",
+			Password:  "pass0",
+			Algorithm: digestauth.AlgorithmMD5,
+			Found:     true,
+		},
+		{
+			Username:  "user0",
+			Password:  "pass0",
+			Algorithm: digestauth.AlgorithmSHA256,
+			Found:     true,
+		},
+		{
+			Username:  "user1",
+			Password:  "pass1",
+			Algorithm: digestauth.AlgorithmMD5,
+			Found:     true,
+		},
+		{
+			Username:  "user1",
+			Algorithm: digestauth.AlgorithmSHA256,
+			Found:     false,
+		},
+		{
+			Username:  "user2",
+			Algorithm: digestauth.AlgorithmMD5,
+			Found:     false,
+		},
+	}
+
+	a := helper.Must(
+		htdigestauth.New(
+			htdigestauth.WithAuthFile(
+				filepath.Join(helper.Must(os.Getwd()), "/testdigest"))))
+
+	for k, v := range tests {
+		gotHA1, gotFound, gotErr := a.HA1(v.Username, "testrealm", v.Algorithm)
+
+		if gotErr != nil {
+			t.Errorf("%v: got error, but did not expect any: %v", k, gotErr)
+		}
+
+		if gotFound != v.Found {
+			t.Errorf("%v: got found %v but wanted %v", k, gotFound, v.Found)
+		}
+
+		if !v.Found {
+			continue
+		}
+
+		if want, _ := v.Algorithm.Hash(v.Username, "testrealm", v.Password); gotHA1 != want {
+			t.Errorf("%v: got ha1 %v but wanted %v", k, gotHA1, want)
+		}
+	}
+}
+
+func TestHtdigestMalformed(t *testing.T) {
+	t.Parallel()
+
+	tests := []string{
+		"user0:testrealm",
+		"user0:testrealm:nohex",
+		"user0:testrealm:abcdef",
+		":testrealm:55471e43d827e96a089f3bd3dfb62cc1",
+	}
+
+	for k, v := range tests {
+		_, err := htdigestauth.New(htdigestauth.WithAuthInput(strings.NewReader(v)))
+
+		if !errors.Is(err, htdigestauth.ErrMalformedLine) {
+			t.Errorf("%v: expected malformed line error, but got %v", k, err)
+		}
+	}
+}
+
+func TestHtdigestComments(t *testing.T) {
+	t.Parallel()
+
+	a := helper.Must(htdigestauth.New(htdigestauth.WithAuthInput(strings.NewReader(
+		"# comment\n\nuser0:testrealm:55471e43d827e96a089f3bd3dfb62cc1\n"))))
+
+	if _, found, _ := a.HA1("user0", "testrealm", digestauth.AlgorithmMD5); !found {
+		t.Errorf("expected entry to be found")
+	}
+}
+
+func TestHtdigestNil(t *testing.T) {
+	t.Parallel()
+
+	var subject *htdigestauth.HTDigestAuth
+
+	if _, _, err := subject.HA1("u", "r", digestauth.AlgorithmMD5); err == nil {
+		t.Errorf("lookup on nil provider should give error")
+	}
+}
+
+func TestHtdigestNonExistingFile(t *testing.T) {
+	t.Parallel()
+
+	_, err := htdigestauth.New(htdigestauth.WithAuthFile("IDoNotExistNowhereInThisWorldForgetIt"))
+
+	if err == nil {
+		t.Errorf("provider initialization with non-existent file should give error")
+	}
+}
+
+func TestHtdigestNoOptions(t *testing.T) {
+	t.Parallel()
+
+	_, err := htdigestauth.New()
+
+	if err == nil {
+		t.Errorf("provider initialization without options should give error")
+	}
+}
+
+func TestHtdigestWrongReader(t *testing.T) {
+	t.Parallel()
+
+	_, err := htdigestauth.New(htdigestauth.WithAuthInput(nil))
+
+	if err == nil {
+		t.Errorf("provider initialization nil reader should give error")
+	}
+}
+
+func TestHtdigestEmptyFilename(t *testing.T) {
+	t.Parallel()
+
+	_, err := htdigestauth.New(htdigestauth.WithAuthFile(""))
+
+	if err == nil {
+		t.Errorf("provider initialization with empty filename should give error")
+	}
+}
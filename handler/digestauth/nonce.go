@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package digestauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// nonceResult is the outcome of a nonce check.
+type nonceResult int
+
+const (
+	nonceValid   nonceResult = iota // nonceValid signalizes a usable nonce
+	nonceInvalid                    // nonceInvalid signalizes a nonce not issued by this server
+	nonceStale                      // nonceStale signalizes an issued, but expired nonce
+	nonceReplay                     // nonceReplay signalizes a nonce count that was already used
+)
+
+const (
+	nonceTimeLen   = 8                             // nonceTimeLen is the length of the issue timestamp
+	nonceRandomLen = 16                            // nonceRandomLen is the length of the random part
+	nonceMACLen    = sha256.Size                   // nonceMACLen is the length of the signature
+	nonceDataLen   = nonceTimeLen + nonceRandomLen // nonceDataLen is the length of the signed data
+)
+
+// nonceState holds the replay relevant information of a nonce already in use.
+type nonceState struct {
+	expires   time.Time // expires is the time the nonce becomes stale
+	lastCount uint64    // lastCount is the highest nonce count seen so far
+}
+
+// nonceStore issues and validates server nonces. Nonces are signed with a process local
+// key, so that issuing them does not need any state. Only nonces that were used in
+// successfully authenticated requests are remembered, to detect replayed nonce counts.
+type nonceStore struct {
+	key       []byte                // key is used to sign the nonces
+	lifetime  time.Duration         // lifetime is the time a nonce is valid after issuing
+	now       func() time.Time      // now gives the current time, replaceable for testing
+	mtx       sync.Mutex            // mtx protects used and lastPurge
+	used      map[string]nonceState // used contains the nonces in use
+	lastPurge time.Time             // lastPurge is the time expired nonces were last removed
+}
+
+// newNonceStore creates a new nonceStore with a random signing key.
+func newNonceStore(lifetime time.Duration) *nonceStore {
+	key := make([]byte, sha256.Size)
+	_, _ = rand.Read(key)
+
+	return &nonceStore{
+		key:      key,
+		lifetime: lifetime,
+		now:      time.Now,
+		used:     make(map[string]nonceState),
+	}
+}
+
+// sign calculates the signature over the given nonce data.
+func (s *nonceStore) sign(data []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	_, _ = mac.Write(data)
+
+	return mac.Sum(nil)
+}
+
+// issue generates a new nonce.
+func (s *nonceStore) issue() string {
+	nonce := make([]byte, nonceDataLen, nonceDataLen+nonceMACLen)
+
+	binary.BigEndian.PutUint64(nonce, uint64(s.now().UnixNano())) //nolint:gosec // time is positive
+	_, _ = rand.Read(nonce[nonceTimeLen:])
+
+	return base64.RawURLEncoding.EncodeToString(append(nonce, s.sign(nonce)...))
+}
+
+// check validates the given nonce and marks the given count as used. The count has to
+// be greater than all counts seen before for the same nonce.
+func (s *nonceStore) check(nonce string, count uint64) nonceResult {
+	raw, decodeErr := base64.RawURLEncoding.DecodeString(nonce)
+
+	if decodeErr != nil || len(raw) != nonceDataLen+nonceMACLen {
+		return nonceInvalid
+	}
+
+	if !hmac.Equal(raw[nonceDataLen:], s.sign(raw[:nonceDataLen])) {
+		return nonceInvalid
+	}
+
+	now := s.now()
+	expires := time.Unix(0, int64(binary.BigEndian.Uint64(raw))).Add(s.lifetime) //nolint:gosec // signed by us
+
+	if !now.Before(expires) {
+		return nonceStale
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.purge(now)
+
+	state, found := s.used[nonce]
+
+	if found && count <= state.lastCount {
+		return nonceReplay
+	}
+
+	s.used[nonce] = nonceState{expires: expires, lastCount: count}
+
+	return nonceValid
+}
+
+// purge removes the expired nonces from the used map. To keep the cost low, it does so
+// at most once per nonce lifetime. The caller must hold the lock.
+func (s *nonceStore) purge(now time.Time) {
+	if now.Sub(s.lastPurge) < s.lifetime {
+		return
+	}
+
+	for k, v := range s.used {
+		if !now.Before(v.expires) {
+			delete(s.used, k)
+		}
+	}
+
+	s.lastPurge = now
+}
@@ -0,0 +1,423 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package hmacauth implements the verification of requests signed with a shared secret.
+package hmacauth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// ErrNilOption is returned when an option is nil.
+var ErrNilOption = errors.New("option cannot be nil")
+
+// ErrNoSecrets is returned when there is no secret provider configured.
+var ErrNoSecrets = errors.New("no secret provider configured")
+
+// ErrNilCanonicalizer is returned when the configured canonicalizer is nil.
+var ErrNilCanonicalizer = errors.New("canonicalizer cannot be nil")
+
+// ErrInvalidSkew is returned when the maximum clock skew is not positive.
+var ErrInvalidSkew = errors.New("clock skew must be greater than 0")
+
+// ErrInvalidSize is returned when a configured size is not positive.
+var ErrInvalidSize = errors.New("size must be greater than 0")
+
+// Names of the headers carrying the signature information.
+const (
+	HeaderKeyID     = "X-Signature-Key-Id"
+	HeaderTimestamp = "X-Signature-Timestamp"
+	HeaderNonce     = "X-Signature-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+// DefaultMaxSkew is the maximum difference between the timestamp of a request and the
+// server time, if not configured otherwise.
+const DefaultMaxSkew = 5 * time.Minute
+
+// DefaultMaxBodySize is the maximum size of signed request bodies, if not configured otherwise.
+const DefaultMaxBodySize = 10 << 20
+
+// DefaultNonceCacheSize is the maximum number of remembered nonces, if not configured otherwise.
+const DefaultNonceCacheSize = 100_000
+
+// SecretProvider is an interface the handler uses to get the shared secret of a key.
+type SecretProvider interface {
+	// Secret gets the secret of the key with the given id. If the key is unknown, found
+	// is false.
+	Secret(keyID string) (secret []byte, found bool, err error)
+}
+
+// SecretMap is a SecretProvider holding the secrets in a map of key ids to secrets.
+type SecretMap map[string][]byte
+
+// Secret gets the secret of the key with the given id.
+func (m SecretMap) Secret(keyID string) ([]byte, bool, error) {
+	secret, found := m[keyID]
+
+	return secret, found, nil
+}
+
+// SigningInput contains the parts of a request covered by the signature.
+type SigningInput struct {
+	Method     string   // Method is the request method
+	Target     string   // Target is the escaped path with the query
+	Headers    []string // Headers are the signed headers as lowercase name, colon and value
+	Timestamp  string   // Timestamp is the unix time of the signature in seconds
+	Nonce      string   // Nonce is the unique value of the request
+	BodyDigest string   // BodyDigest is the hex encoded SHA-256 digest of the body
+}
+
+// Canonicalizer creates the string to sign from the parts of a request.
+type Canonicalizer func(in *SigningInput) string
+
+// DefaultCanonicalizer joins the method, target, signed headers, timestamp, nonce and
+// body digest, each on its own line.
+func DefaultCanonicalizer(in *SigningInput) string {
+	parts := make([]string, 0, 5+len(in.Headers))
+	parts = append(parts, in.Method, in.Target)
+	parts = append(parts, in.Headers...)
+	parts = append(parts, in.Timestamp, in.Nonce, in.BodyDigest)
+
+	return strings.Join(parts, "\n")
+}
+
+// Signature calculates the hex encoded HMAC-SHA256 of the canonical string.
+func Signature(secret []byte, canonical string) string {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(canonical))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signedHeaders gives the values of the named headers in the form used in SigningInput.
+// Multiple values of a header are joined with commas.
+func signedHeaders(r *http.Request, names []string) []string {
+	result := make([]string, 0, len(names))
+
+	for _, name := range names {
+		name = strings.ToLower(name)
+		value := r.Host
+
+		if name != "host" {
+			values := make([]string, 0, 1)
+
+			for _, v := range r.Header.Values(name) {
+				values = append(values, strings.TrimSpace(v))
+			}
+
+			value = strings.Join(values, ",")
+		}
+
+		result = append(result, name+":"+value)
+	}
+
+	return result
+}
+
+// bodyDigest calculates the hex encoded SHA-256 digest of the body.
+func bodyDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// SignRequest signs the request using the default canonicalization, as expected by the
+// handler if no other canonicalizer is configured. It sets the key id, timestamp, nonce
+// and signature headers. The headers given in signed are covered by the signature and
+// have to be set before.
+func SignRequest(r *http.Request, keyID string, secret []byte, signed []string) error {
+	var body []byte
+
+	if r.Body != nil && r.Body != http.NoBody {
+		var err error
+
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return fmt.Errorf("could not read body: %w", err)
+		}
+
+		_ = r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+
+	r.Header.Set(HeaderKeyID, keyID)
+	r.Header.Set(HeaderTimestamp, strconv.FormatInt(time.Now().Unix(), 10))
+	r.Header.Set(HeaderNonce, hex.EncodeToString(nonce))
+
+	r.Header.Set(HeaderSignature, Signature(secret, DefaultCanonicalizer(&SigningInput{
+		Method:     r.Method,
+		Target:     r.URL.RequestURI(),
+		Headers:    signedHeaders(r, signed),
+		Timestamp:  r.Header.Get(HeaderTimestamp),
+		Nonce:      r.Header.Get(HeaderNonce),
+		BodyDigest: bodyDigest(body),
+	})))
+
+	return nil
+}
+
+// Handler holds the internal data of the signature verification middleware.
+type Handler struct {
+	defs.MWBase
+
+	secrets        SecretProvider   // secrets gives the shared secrets of the keys
+	signed         []string         // signed are the names of the headers covered by the signature
+	canonicalize   Canonicalizer    // canonicalize creates the string to sign
+	maxSkew        time.Duration    // maxSkew is the maximum allowed clock difference
+	maxBodySize    int64            // maxBodySize is the maximum size of request bodies
+	nonceCacheSize int              // nonceCacheSize is the maximum number of remembered nonces
+	now            func() time.Time // now gives the current time, replaceable for testing
+
+	nonces *nonceCache
+}
+
+// GetMWBase returns the MWBase instance of the handler.
+func (h *Handler) GetMWBase() *defs.MWBase {
+	if h == nil {
+		return nil
+	}
+
+	return &h.MWBase
+}
+
+// reject logs the reason and sends it to the client as problem details.
+func (h *Handler) reject(w http.ResponseWriter, r *http.Request, status int, reason string) {
+	h.Log().Info("request signature rejected",
+		slog.String("reason", reason),
+		slog.String("key", r.Header.Get(HeaderKeyID)),
+		slog.String("client", r.RemoteAddr))
+	helper.WriteProblem(w, h.Log(), status, reason)
+}
+
+// ServeHTTP implements the signature verification.
+//
+//nolint:funlen // the verification steps are easier to follow in one place
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !helper.IntroCheck(h, w, r) {
+		return
+	}
+
+	keyID := r.Header.Get(HeaderKeyID)
+	timestamp := r.Header.Get(HeaderTimestamp)
+	nonce := r.Header.Get(HeaderNonce)
+	signature, decodeErr := hex.DecodeString(r.Header.Get(HeaderSignature))
+
+	if keyID == "" || timestamp == "" || nonce == "" || len(signature) == 0 || decodeErr != nil {
+		h.reject(w, r, http.StatusUnauthorized, "missing or malformed signature headers")
+
+		return
+	}
+
+	unix, tsErr := strconv.ParseInt(timestamp, 10, 64)
+	signedAt := time.Unix(unix, 0)
+	now := h.now()
+
+	if tsErr != nil || signedAt.Before(now.Add(-h.maxSkew)) || signedAt.After(now.Add(h.maxSkew)) {
+		h.reject(w, r, http.StatusUnauthorized, "timestamp outside of allowed clock skew")
+
+		return
+	}
+
+	secret, found, secretErr := h.secrets.Secret(keyID)
+
+	if secretErr != nil {
+		h.Log().Error("could not get secret", slog.String("error", secretErr.Error()), slog.String("key", keyID))
+		helper.WriteProblem(w, h.Log(), http.StatusServiceUnavailable, "")
+
+		return
+	}
+
+	if !found {
+		h.reject(w, r, http.StatusUnauthorized, "unknown key")
+
+		return
+	}
+
+	body, complete, bodyErr := helper.ReadBody(r, h.maxBodySize)
+
+	if bodyErr != nil {
+		h.reject(w, r, http.StatusBadRequest, "could not read body")
+
+		return
+	}
+
+	if !complete {
+		h.reject(w, r, http.StatusRequestEntityTooLarge, "body too large")
+
+		return
+	}
+
+	expected := Signature(secret, h.canonicalize(&SigningInput{
+		Method:     r.Method,
+		Target:     r.URL.RequestURI(),
+		Headers:    signedHeaders(r, h.signed),
+		Timestamp:  timestamp,
+		Nonce:      nonce,
+		BodyDigest: bodyDigest(body),
+	}))
+
+	if !hmac.Equal([]byte(expected), []byte(hex.EncodeToString(signature))) {
+		h.reject(w, r, http.StatusUnauthorized, "invalid signature")
+
+		return
+	}
+
+	switch h.nonces.add(keyID, nonce, signedAt.Add(h.maxSkew), now) {
+	case nonceReplayed:
+		h.reject(w, r, http.StatusUnauthorized, "nonce already used")
+
+		return
+	case nonceCacheFull:
+		h.Log().Error("nonce cache full, cannot protect against replays")
+		helper.WriteProblem(w, h.Log(), http.StatusServiceUnavailable, "")
+
+		return
+	case nonceAdded:
+	}
+
+	r = r.WithContext(defs.ContextWithPrincipal(r.Context(), &defs.Principal{
+		Name:   keyID,
+		Method: "hmac",
+		Claims: map[string]any{"signed_at": signedAt, "nonce": nonce},
+	}))
+
+	h.Next().ServeHTTP(w, r)
+}
+
+// WithSecrets sets the SecretProvider to use.
+func WithSecrets(secrets SecretProvider) func(h *Handler) error {
+	return func(h *Handler) error {
+		h.secrets = secrets
+
+		return nil
+	}
+}
+
+// WithSignedHeaders sets the headers covered by the signature, in the order they are
+// canonicalized. The pseudo header "host" stands for the requested host.
+func WithSignedHeaders(names ...string) func(h *Handler) error {
+	return func(h *Handler) error {
+		h.signed = append(h.signed, names...)
+
+		return nil
+	}
+}
+
+// WithCanonicalizer sets the function creating the string to sign. Without it, the
+// DefaultCanonicalizer is used.
+func WithCanonicalizer(c Canonicalizer) func(h *Handler) error {
+	return func(h *Handler) error {
+		if c == nil {
+			return ErrNilCanonicalizer
+		}
+
+		h.canonicalize = c
+
+		return nil
+	}
+}
+
+// WithMaxSkew sets the maximum difference between the timestamp of a request and the
+// server time. It also determines how long nonces are remembered.
+func WithMaxSkew(d time.Duration) func(h *Handler) error {
+	return func(h *Handler) error {
+		if d <= 0 {
+			return ErrInvalidSkew
+		}
+
+		h.maxSkew = d
+
+		return nil
+	}
+}
+
+// WithMaxBodySize sets the maximum size of request bodies. Larger requests are rejected,
+// as the body has to be held in memory for the verification.
+func WithMaxBodySize(size int64) func(h *Handler) error {
+	return func(h *Handler) error {
+		if size <= 0 {
+			return ErrInvalidSize
+		}
+
+		h.maxBodySize = size
+
+		return nil
+	}
+}
+
+// WithNonceCacheSize sets the maximum number of remembered nonces. If the cache is full,
+// requests are rejected until nonces expire.
+func WithNonceCacheSize(size int) func(h *Handler) error {
+	return func(h *Handler) error {
+		if size <= 0 {
+			return ErrInvalidSize
+		}
+
+		h.nonceCacheSize = size
+
+		return nil
+	}
+}
+
+// WithLogger configures the logger to use.
+func WithLogger(log *slog.Logger) func(h *Handler) error {
+	return defs.WithLogger[*Handler](log)
+}
+
+// WithLogLevel configures the log level to use with the logger.
+func WithLogLevel(level slog.Level) func(h *Handler) error {
+	return defs.WithLogLevel[*Handler](level)
+}
+
+// New generates a new signature verification middleware.
+func New(options ...func(handler *Handler) error) (defs.Middleware, error) {
+	handler := Handler{
+		canonicalize:   DefaultCanonicalizer,
+		maxSkew:        DefaultMaxSkew,
+		maxBodySize:    DefaultMaxBodySize,
+		nonceCacheSize: DefaultNonceCacheSize,
+		now:            time.Now,
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&handler); err != nil {
+			return nil, err
+		}
+	}
+
+	if handler.secrets == nil {
+		return nil, ErrNoSecrets
+	}
+
+	handler.nonces = newNonceCache(handler.nonceCacheSize)
+
+	return func(next http.Handler) http.Handler {
+		if err := handler.SetNext(next); err != nil {
+			return nil
+		}
+
+		return &handler
+	}, nil
+}
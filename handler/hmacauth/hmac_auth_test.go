@@ -0,0 +1,344 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package hmacauth_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/hmacauth"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+var testKeys = hmacauth.SecretMap{
+	"client-a": []byte("secret-a"),
+	"client-b": []byte("secret-b"),
+}
+
+// testSigned are the headers covered by the signature.
+var testSigned = []string{"host", "content-type"}
+
+// failingSecrets is a SecretProvider that cannot reach its backend.
+type failingSecrets struct{}
+
+func (failingSecrets) Secret(string) ([]byte, bool, error) {
+	return nil, false, errors.New("backend down")
+}
+
+// received records what the next handler got.
+type received struct {
+	body      string
+	principal *defs.Principal
+}
+
+func newHandler(t *testing.T, rec *received, offset time.Duration,
+	options ...func(*hmacauth.Handler) error) *hmacauth.Handler {
+
+	t.Helper()
+
+	options = append([]func(*hmacauth.Handler) error{
+		hmacauth.WithSecrets(testKeys),
+		hmacauth.WithSignedHeaders(testSigned...),
+		hmacauth.WithMaxSkew(time.Minute),
+		hmacauth.WithMaxBodySize(64),
+	}, options...)
+
+	h := helper.Must(hmacauth.New(options...))(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.body = string(body)
+		rec.principal, _ = defs.PrincipalFromContext(r.Context())
+	}))
+
+	handler, isHandler := h.(*hmacauth.Handler)
+
+	if !isHandler {
+		t.Fatalf("wrong handler type")
+	}
+
+	hmacauth.TSetNow(handler, func() time.Time { return time.Now().Add(offset) })
+
+	return handler
+}
+
+func newRequest(t *testing.T, method, target, body string) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(t.Context(), method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	return req
+}
+
+func TestVerification(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Name       string
+		Prepare    func(r *http.Request) // Prepare is called on the signed request
+		Offset     time.Duration
+		Key        string
+		Secret     string
+		WantStatus int
+		WantDetail string
+	}{
+		{
+			Name: "valid", Key: "client-a", Secret: "secret-a",
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "wrong secret", Key: "client-a", Secret: "secret-b",
+			WantStatus: http.StatusUnauthorized, WantDetail: "invalid signature",
+		},
+		{
+			Name: "unknown key", Key: "client-x", Secret: "secret-a",
+			WantStatus: http.StatusUnauthorized, WantDetail: "unknown key",
+		},
+		{
+			Name: "tampered body", Key: "client-a", Secret: "secret-a",
+			Prepare:    func(r *http.Request) { r.Body = io.NopCloser(strings.NewReader(`{"amount":1000}`)) },
+			WantStatus: http.StatusUnauthorized, WantDetail: "invalid signature",
+		},
+		{
+			Name: "tampered query", Key: "client-a", Secret: "secret-a",
+			Prepare:    func(r *http.Request) { r.URL.RawQuery = "account=2" },
+			WantStatus: http.StatusUnauthorized, WantDetail: "invalid signature",
+		},
+		{
+			Name: "tampered method", Key: "client-a", Secret: "secret-a",
+			Prepare:    func(r *http.Request) { r.Method = http.MethodPut },
+			WantStatus: http.StatusUnauthorized, WantDetail: "invalid signature",
+		},
+		{
+			Name: "tampered signed header", Key: "client-a", Secret: "secret-a",
+			Prepare:    func(r *http.Request) { r.Header.Set("Content-Type", "text/plain") },
+			WantStatus: http.StatusUnauthorized, WantDetail: "invalid signature",
+		},
+		{
+			Name: "tampered host", Key: "client-a", Secret: "secret-a",
+			Prepare:    func(r *http.Request) { r.Host = "other.example" },
+			WantStatus: http.StatusUnauthorized, WantDetail: "invalid signature",
+		},
+		{
+			Name: "unsigned header", Key: "client-a", Secret: "secret-a",
+			Prepare:    func(r *http.Request) { r.Header.Set("X-Other", "x") },
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "tampered timestamp", Key: "client-a", Secret: "secret-a",
+			Prepare: func(r *http.Request) {
+				ts, _ := strconv.ParseInt(r.Header.Get(hmacauth.HeaderTimestamp), 10, 64)
+				r.Header.Set(hmacauth.HeaderTimestamp, strconv.FormatInt(ts+1, 10))
+			},
+			WantStatus: http.StatusUnauthorized, WantDetail: "invalid signature",
+		},
+		{
+			Name: "clock ahead", Key: "client-a", Secret: "secret-a", Offset: 2 * time.Minute,
+			WantStatus: http.StatusUnauthorized, WantDetail: "timestamp outside of allowed clock skew",
+		},
+		{
+			Name: "clock behind", Key: "client-a", Secret: "secret-a", Offset: -2 * time.Minute,
+			WantStatus: http.StatusUnauthorized, WantDetail: "timestamp outside of allowed clock skew",
+		},
+		{
+			Name: "small skew", Key: "client-a", Secret: "secret-a", Offset: 30 * time.Second,
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "missing signature", Key: "client-a", Secret: "secret-a",
+			Prepare:    func(r *http.Request) { r.Header.Del(hmacauth.HeaderSignature) },
+			WantStatus: http.StatusUnauthorized, WantDetail: "missing or malformed signature headers",
+		},
+		{
+			Name: "malformed signature", Key: "client-a", Secret: "secret-a",
+			Prepare:    func(r *http.Request) { r.Header.Set(hmacauth.HeaderSignature, "not hex") },
+			WantStatus: http.StatusUnauthorized, WantDetail: "missing or malformed signature headers",
+		},
+		{
+			Name: "body too large", Key: "client-a", Secret: "secret-a",
+			Prepare:    func(r *http.Request) { r.Body = io.NopCloser(strings.NewReader(strings.Repeat("x", 65))) },
+			WantStatus: http.StatusRequestEntityTooLarge, WantDetail: "body too large",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+
+			var rec received
+
+			handler := newHandler(t, &rec, test.Offset)
+			req := newRequest(t, http.MethodPost, "/transfer?account=1", `{"amount":10}`)
+
+			if err := hmacauth.SignRequest(req, test.Key, []byte(test.Secret), testSigned); err != nil {
+				t.Fatalf("could not sign request: %v", err)
+			}
+
+			if test.Prepare != nil {
+				test.Prepare(req)
+			}
+
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+
+			if resp.Code != test.WantStatus {
+				t.Errorf("got status %v but wanted %v", resp.Code, test.WantStatus)
+			}
+
+			if test.WantStatus == http.StatusOK {
+				if rec.body != `{"amount":10}` {
+					t.Errorf("body not passed on, got %q", rec.body)
+				}
+
+				if rec.principal == nil || rec.principal.Name != test.Key || rec.principal.Method != "hmac" {
+					t.Errorf("got unexpected principal %+v", rec.principal)
+				}
+
+				return
+			}
+
+			var problem helper.Problem
+
+			if err := json.Unmarshal(resp.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("could not decode problem details: %v", err)
+			}
+
+			if problem.Status != test.WantStatus || problem.Detail != test.WantDetail {
+				t.Errorf("got problem %+v but wanted status %v, detail %q", problem, test.WantStatus, test.WantDetail)
+			}
+
+			if ct := resp.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("got content type %q", ct)
+			}
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	var rec received
+
+	handler := newHandler(t, &rec, 0)
+	req := newRequest(t, http.MethodPost, "/transfer", `{"amount":10}`)
+
+	if err := hmacauth.SignRequest(req, "client-a", []byte("secret-a"), testSigned); err != nil {
+		t.Fatalf("could not sign request: %v", err)
+	}
+
+	wantStatus := []int{http.StatusOK, http.StatusUnauthorized}
+
+	for k, want := range wantStatus {
+		replay := req.Clone(t.Context())
+		replay.Body = io.NopCloser(strings.NewReader(`{"amount":10}`))
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, replay)
+
+		if resp.Code != want {
+			t.Errorf("%v: got status %v but wanted %v", k, resp.Code, want)
+		}
+	}
+}
+
+func TestNonceCacheFull(t *testing.T) {
+	t.Parallel()
+
+	var rec received
+
+	handler := newHandler(t, &rec, 0, hmacauth.WithNonceCacheSize(1))
+	wantStatus := []int{http.StatusOK, http.StatusServiceUnavailable}
+
+	for k, want := range wantStatus {
+		req := newRequest(t, http.MethodGet, "/", "")
+
+		if err := hmacauth.SignRequest(req, "client-a", []byte("secret-a"), testSigned); err != nil {
+			t.Fatalf("could not sign request: %v", err)
+		}
+
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+
+		if resp.Code != want {
+			t.Errorf("%v: got status %v but wanted %v", k, resp.Code, want)
+		}
+	}
+}
+
+func TestCanonicalizer(t *testing.T) {
+	t.Parallel()
+
+	// a canonicalization without the body, e.g. for streaming clients
+	noBody := func(in *hmacauth.SigningInput) string {
+		return in.Method + " " + in.Target + " " + in.Timestamp + " " + in.Nonce
+	}
+
+	var rec received
+
+	handler := newHandler(t, &rec, 0, hmacauth.WithCanonicalizer(noBody))
+	req := newRequest(t, http.MethodPost, "/upload", "anything")
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set(hmacauth.HeaderKeyID, "client-b")
+	req.Header.Set(hmacauth.HeaderTimestamp, ts)
+	req.Header.Set(hmacauth.HeaderNonce, "n1")
+	req.Header.Set(hmacauth.HeaderSignature, hmacauth.Signature([]byte("secret-b"), "POST /upload "+ts+" n1"))
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Errorf("got status %v but wanted %v: %v", resp.Code, http.StatusOK, resp.Body.String())
+	}
+}
+
+func TestSecretError(t *testing.T) {
+	t.Parallel()
+
+	var rec received
+
+	handler := newHandler(t, &rec, 0, hmacauth.WithSecrets(failingSecrets{}))
+	req := newRequest(t, http.MethodGet, "/", "")
+
+	if err := hmacauth.SignRequest(req, "client-a", []byte("secret-a"), testSigned); err != nil {
+		t.Fatalf("could not sign request: %v", err)
+	}
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %v but wanted %v", resp.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestOptionErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options []func(*hmacauth.Handler) error
+		WantErr error
+	}{
+		{Options: nil, WantErr: hmacauth.ErrNoSecrets}, // 0
+		{Options: []func(*hmacauth.Handler) error{testSecrets, hmacauth.WithCanonicalizer(nil)}, WantErr: hmacauth.ErrNilCanonicalizer}, // 1
+		{Options: []func(*hmacauth.Handler) error{testSecrets, hmacauth.WithMaxSkew(0)}, WantErr: hmacauth.ErrInvalidSkew},              // 2
+		{Options: []func(*hmacauth.Handler) error{testSecrets, hmacauth.WithMaxBodySize(0)}, WantErr: hmacauth.ErrInvalidSize},          // 3
+		{Options: []func(*hmacauth.Handler) error{testSecrets, hmacauth.WithNonceCacheSize(-1)}, WantErr: hmacauth.ErrInvalidSize},      // 4
+	}
+
+	for k, test := range tests {
+		_, err := hmacauth.New(test.Options...)
+
+		if !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package hmacauth
+
+import (
+	"sync"
+	"time"
+)
+
+// nonceCache remembers the nonces of verified requests, so that they cannot be replayed.
+// Nonces only need to be remembered as long as their timestamp is within the allowed
+// clock skew, older requests are rejected anyway.
+type nonceCache struct {
+	size    int
+	mtx     sync.Mutex
+	entries map[string]time.Time // entries maps key id and nonce to the time they can be forgotten
+}
+
+// newNonceCache creates a cache for at most size nonces.
+func newNonceCache(size int) *nonceCache {
+	return &nonceCache{
+		size:    size,
+		entries: make(map[string]time.Time),
+	}
+}
+
+// nonceResult is the result of adding a nonce to the cache.
+type nonceResult int
+
+const (
+	nonceAdded nonceResult = iota
+	nonceReplayed
+	nonceCacheFull
+)
+
+// add remembers the nonce until the given time. It reports if the nonce was already
+// used. If the cache is full, even after removing the expired entries, the nonce is not
+// added, as the request could not be protected against replays.
+func (c *nonceCache) add(keyID, nonce string, until, now time.Time) nonceResult {
+	key := keyID + "\x00" + nonce
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if expires, found := c.entries[key]; found && now.Before(expires) {
+		return nonceReplayed
+	}
+
+	if len(c.entries) >= c.size {
+		for k, expires := range c.entries {
+			if !now.Before(expires) {
+				delete(c.entries, k)
+			}
+		}
+
+		if len(c.entries) >= c.size {
+			return nonceCacheFull
+		}
+	}
+
+	c.entries[key] = until
+
+	return nonceAdded
+}
@@ -0,0 +1,239 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package httpsig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Algorithm is the name of a signature algorithm, as registered in the HTTP Signature
+// Algorithms registry.
+type Algorithm string
+
+const (
+	// AlgHMACSHA256 is HMAC using SHA-256. Its key is the shared secret as []byte.
+	AlgHMACSHA256 Algorithm = "hmac-sha256"
+	// AlgEd25519 is EdDSA using curve edwards25519. Its keys are of type ed25519.PublicKey
+	// and ed25519.PrivateKey.
+	AlgEd25519 Algorithm = "ed25519"
+	// AlgECDSAP256SHA256 is ECDSA using curve P-256 and SHA-256. Its keys are of type
+	// *ecdsa.PublicKey and *ecdsa.PrivateKey.
+	AlgECDSAP256SHA256 Algorithm = "ecdsa-p256-sha256"
+	// AlgRSAPSSSHA512 is RSASSA-PSS using SHA-512. Its keys are of type *rsa.PublicKey and
+	// *rsa.PrivateKey.
+	AlgRSAPSSSHA512 Algorithm = "rsa-pss-sha512"
+)
+
+// ErrUnsupportedAlgorithm is returned for algorithms not implemented by this package.
+var ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+
+// ErrInvalidKey is returned if a key does not fit its algorithm.
+var ErrInvalidKey = errors.New("key does not fit algorithm")
+
+// ErrInvalidSignature is returned if a signature does not match.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Key is a key together with the algorithm it is used with. Binding keys to a single
+// algorithm prevents attackers from choosing a weaker one. For verification, private
+// keys are accepted in place of the public keys.
+type Key struct {
+	Algorithm Algorithm
+	Key       any
+}
+
+// ecdsaP256Size is the size of each of the two integers of a P-256 signature.
+const ecdsaP256Size = 32
+
+// rsaPSSOptions are the options of RSASSA-PSS as required by RFC 9421.
+var rsaPSSOptions = rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512}
+
+// sign signs the signature base with the given key.
+func (k Key) sign(base []byte) ([]byte, error) {
+	switch k.Algorithm {
+	case AlgHMACSHA256:
+		secret, isSecret := k.Key.([]byte)
+
+		if !isSecret || len(secret) == 0 {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, k.Algorithm)
+		}
+
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(base)
+
+		return mac.Sum(nil), nil
+	case AlgEd25519:
+		key, isKey := k.Key.(ed25519.PrivateKey)
+
+		if !isKey || len(key) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, k.Algorithm)
+		}
+
+		return ed25519.Sign(key, base), nil
+	case AlgECDSAP256SHA256:
+		key, isKey := k.Key.(*ecdsa.PrivateKey)
+
+		if !isKey || key == nil || key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, k.Algorithm)
+		}
+
+		digest := sha256.Sum256(base)
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+
+		if err != nil {
+			return nil, fmt.Errorf("could not sign: %w", err)
+		}
+
+		signature := make([]byte, 2*ecdsaP256Size)
+		r.FillBytes(signature[:ecdsaP256Size])
+		s.FillBytes(signature[ecdsaP256Size:])
+
+		return signature, nil
+	case AlgRSAPSSSHA512:
+		key, isKey := k.Key.(*rsa.PrivateKey)
+
+		if !isKey || key == nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKey, k.Algorithm)
+		}
+
+		digest := sha512.Sum512(base)
+		signature, err := rsa.SignPSS(rand.Reader, key, crypto.SHA512, digest[:], &rsaPSSOptions)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not sign: %w", err)
+		}
+
+		return signature, nil
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedAlgorithm, k.Algorithm)
+	}
+}
+
+// verify checks the signature of the signature base with the given key.
+func (k Key) verify(base, signature []byte) error {
+	switch k.Algorithm {
+	case AlgHMACSHA256:
+		expected, err := k.sign(base)
+
+		if err != nil {
+			return err
+		}
+
+		if !hmac.Equal(expected, signature) {
+			return ErrInvalidSignature
+		}
+
+		return nil
+	case AlgEd25519:
+		key, err := ed25519PublicKey(k.Key)
+
+		if err != nil {
+			return err
+		}
+
+		if !ed25519.Verify(key, base, signature) {
+			return ErrInvalidSignature
+		}
+
+		return nil
+	case AlgECDSAP256SHA256:
+		key, err := ecdsaPublicKey(k.Key)
+
+		if err != nil {
+			return err
+		}
+
+		if len(signature) != 2*ecdsaP256Size {
+			return ErrInvalidSignature
+		}
+
+		digest := sha256.Sum256(base)
+		r := new(big.Int).SetBytes(signature[:ecdsaP256Size])
+		s := new(big.Int).SetBytes(signature[ecdsaP256Size:])
+
+		if !ecdsa.Verify(key, digest[:], r, s) {
+			return ErrInvalidSignature
+		}
+
+		return nil
+	case AlgRSAPSSSHA512:
+		key, err := rsaPublicKey(k.Key)
+
+		if err != nil {
+			return err
+		}
+
+		digest := sha512.Sum512(base)
+
+		if rsa.VerifyPSS(key, crypto.SHA512, digest[:], signature, &rsaPSSOptions) != nil {
+			return ErrInvalidSignature
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("%w: %v", ErrUnsupportedAlgorithm, k.Algorithm)
+	}
+}
+
+// ed25519PublicKey gives the public key of an ed25519.PublicKey or ed25519.PrivateKey.
+func ed25519PublicKey(key any) (ed25519.PublicKey, error) {
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		if len(k) == ed25519.PublicKeySize {
+			return k, nil
+		}
+	case ed25519.PrivateKey:
+		if len(k) == ed25519.PrivateKeySize {
+			return k.Public().(ed25519.PublicKey), nil //nolint:forcetypeassert // always true
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %v", ErrInvalidKey, AlgEd25519)
+}
+
+// ecdsaPublicKey gives the public key of an *ecdsa.PublicKey or *ecdsa.PrivateKey on P-256.
+func ecdsaPublicKey(key any) (*ecdsa.PublicKey, error) {
+	var result *ecdsa.PublicKey
+
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		result = k
+	case *ecdsa.PrivateKey:
+		if k != nil {
+			result = &k.PublicKey
+		}
+	}
+
+	if result == nil || result.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, AlgECDSAP256SHA256)
+	}
+
+	return result, nil
+}
+
+// rsaPublicKey gives the public key of an *rsa.PublicKey or *rsa.PrivateKey.
+func rsaPublicKey(key any) (*rsa.PublicKey, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if k != nil {
+			return k, nil
+		}
+	case *rsa.PrivateKey:
+		if k != nil {
+			return &k.PublicKey, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %v", ErrInvalidKey, AlgRSAPSSSHA512)
+}
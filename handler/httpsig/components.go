@@ -0,0 +1,305 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package httpsig
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalidComponent is returned for component identifiers that are malformed or not
+// supported.
+var ErrInvalidComponent = errors.New("invalid component")
+
+// ErrMissingComponent is returned if a covered component is not present in the request.
+var ErrMissingComponent = errors.New("missing component")
+
+// ErrDuplicateComponent is returned if a component is covered more than once.
+var ErrDuplicateComponent = errors.New("duplicate component")
+
+// ErrContentDigest is returned if the Content-Digest header does not match the body.
+var ErrContentDigest = errors.New("content digest mismatch")
+
+// Derived components supported by this package.
+const (
+	ComponentMethod        = "@method"
+	ComponentTargetURI     = "@target-uri"
+	ComponentAuthority     = "@authority"
+	ComponentScheme        = "@scheme"
+	ComponentRequestTarget = "@request-target"
+	ComponentPath          = "@path"
+	ComponentQuery         = "@query"
+	ComponentQueryParam    = "@query-param"
+	ComponentContentDigest = "content-digest"
+)
+
+// signatureParams is the name of the last line of the signature base.
+const signatureParams = "@signature-params"
+
+// component is a parsed component identifier, i.e. the name of a derived component or a
+// lower case header field name, and its parameters.
+type component struct {
+	name   string
+	params sfParams
+}
+
+// String gives the serialized component identifier.
+func (c component) String() string {
+	return serializeString(c.name) + c.params.serialize()
+}
+
+// parseComponent parses a component as given in the options, e.g. "content-type" or
+// `@query-param;name="id"`.
+func parseComponent(s string) (component, error) {
+	name, rawParams, hasParams := strings.Cut(s, ";")
+
+	var params sfParams
+
+	if hasParams {
+		var err error
+
+		if params, err = parseParams(";" + rawParams); err != nil {
+			return component{}, fmt.Errorf("%w: %q: %w", ErrInvalidComponent, s, err)
+		}
+	}
+
+	return newComponent(strings.ToLower(strings.TrimSpace(name)), params)
+}
+
+// componentFromItem converts an item of the Signature-Input header to a component.
+func componentFromItem(item sfItem) (component, error) {
+	name, isString := item.value.(string)
+
+	if !isString || name != strings.ToLower(name) {
+		return component{}, fmt.Errorf("%w: %v", ErrInvalidComponent, serializeBareItem(item.value))
+	}
+
+	return newComponent(name, item.params)
+}
+
+// newComponent checks that the component and its parameters are supported.
+func newComponent(name string, params sfParams) (component, error) {
+	c := component{name: name, params: params}
+
+	if name == "" || name == signatureParams {
+		return c, fmt.Errorf("%w: %q", ErrInvalidComponent, name)
+	}
+
+	if strings.HasPrefix(name, "@") {
+		switch name {
+		case ComponentMethod, ComponentTargetURI, ComponentAuthority, ComponentScheme,
+			ComponentRequestTarget, ComponentPath, ComponentQuery:
+		case ComponentQueryParam:
+			if paramName, _ := firstValue(params, "name").(string); paramName == "" || len(params) != 1 {
+				return c, fmt.Errorf("%w: %v needs exactly one name parameter", ErrInvalidComponent, name)
+			}
+
+			return c, nil
+		default:
+			return c, fmt.Errorf("%w: %q", ErrInvalidComponent, name)
+		}
+	}
+
+	if len(params) > 0 {
+		return c, fmt.Errorf("%w: parameters of %v not supported", ErrInvalidComponent, c)
+	}
+
+	return c, nil
+}
+
+// firstValue returns the value of the given parameter or nil.
+func firstValue(params sfParams, key string) any {
+	value, _ := params.get(key)
+
+	return value
+}
+
+// scheme gives the lower case scheme of the request. On the server side it is derived
+// from the connection.
+func scheme(r *http.Request) string {
+	if r.URL.Scheme != "" {
+		return strings.ToLower(r.URL.Scheme)
+	}
+
+	if r.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+// authority gives the normalized host of the request, without the default port.
+func authority(r *http.Request) string {
+	host := r.Host
+
+	if host == "" {
+		host = r.URL.Host
+	}
+
+	host = strings.ToLower(host)
+
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if (port == "80" && scheme(r) == "http") || (port == "443" && scheme(r) == "https") {
+			host = h
+
+			if strings.Contains(h, ":") {
+				host = "[" + h + "]"
+			}
+		}
+	}
+
+	return host
+}
+
+// queryEscape percent-encodes query parameter names and values as required by RFC 9421.
+func queryEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// value gives the component value of the request.
+//
+//nolint:cyclop // one case per component
+func (c component) value(r *http.Request) (string, error) {
+	switch c.name {
+	case ComponentMethod:
+		return r.Method, nil
+	case ComponentTargetURI:
+		return scheme(r) + "://" + authority(r) + r.URL.RequestURI(), nil
+	case ComponentAuthority:
+		return authority(r), nil
+	case ComponentScheme:
+		return scheme(r), nil
+	case ComponentRequestTarget:
+		return r.URL.RequestURI(), nil
+	case ComponentPath:
+		if p := r.URL.EscapedPath(); p != "" {
+			return p, nil
+		}
+
+		return "/", nil
+	case ComponentQuery:
+		return "?" + r.URL.RawQuery, nil
+	case ComponentQueryParam:
+		// the name is given percent-encoded like the value
+		name, _ := firstValue(c.params, "name").(string)
+
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+
+		values := r.URL.Query()[name]
+
+		if len(values) != 1 {
+			return "", fmt.Errorf("%w: %v must occur exactly once", ErrMissingComponent, c)
+		}
+
+		return queryEscape(values[0]), nil
+	}
+
+	values := r.Header.Values(c.name)
+
+	if len(values) == 0 {
+		return "", fmt.Errorf("%w: %v", ErrMissingComponent, c)
+	}
+
+	trimmed := make([]string, 0, len(values))
+
+	for _, v := range values {
+		trimmed = append(trimmed, strings.Trim(v, " \t"))
+	}
+
+	return strings.Join(trimmed, ", "), nil
+}
+
+// signatureBase creates the signature base of the request for the given components and
+// signature parameters, as well as the serialized signature parameters.
+func signatureBase(r *http.Request, components []component, params sfParams) ([]byte, string, error) {
+	var base strings.Builder
+
+	items := make([]sfItem, 0, len(components))
+	seen := make(map[string]struct{}, len(components))
+
+	for _, c := range components {
+		id := c.String()
+
+		if _, found := seen[id]; found {
+			return nil, "", fmt.Errorf("%w: %v", ErrDuplicateComponent, id)
+		}
+
+		seen[id] = struct{}{}
+
+		value, err := c.value(r)
+
+		if err != nil {
+			return nil, "", err
+		}
+
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, "", fmt.Errorf("%w: %v contains line breaks", ErrInvalidComponent, id)
+		}
+
+		base.WriteString(id + ": " + value + "\n")
+		items = append(items, sfItem{value: c.name, params: c.params})
+	}
+
+	serializedParams := serializeInnerList(items, params)
+	base.WriteString(serializeString(signatureParams) + ": " + serializedParams)
+
+	return []byte(base.String()), serializedParams, nil
+}
+
+// contentDigest gives the Content-Digest header value (RFC 9530) of the body.
+func contentDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+
+	return "sha-256=" + serializeBareItem(sum[:])
+}
+
+// checkContentDigest checks the Content-Digest header against the body. All digests
+// with supported algorithms have to match, and there has to be at least one of them.
+func checkContentDigest(header string, body []byte) error {
+	members, err := parseDictionary(header)
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrContentDigest, err)
+	}
+
+	checked := 0
+
+	for _, member := range members {
+		var expected []byte
+
+		switch member.key {
+		case "sha-256":
+			sum := sha256.Sum256(body)
+			expected = sum[:]
+		case "sha-512":
+			sum := sha512.Sum512(body)
+			expected = sum[:]
+		default:
+			continue
+		}
+
+		digest, isBytes := member.value.([]byte)
+
+		if !isBytes || subtle.ConstantTimeCompare(digest, expected) != 1 {
+			return fmt.Errorf("%w: %v", ErrContentDigest, member.key)
+		}
+
+		checked++
+	}
+
+	if checked == 0 {
+		return fmt.Errorf("%w: no supported algorithm", ErrContentDigest)
+	}
+
+	return nil
+}
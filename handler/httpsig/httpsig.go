@@ -0,0 +1,451 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package httpsig implements HTTP Message Signatures (RFC 9421). It contains a middleware
+// verifying signed requests and a http.RoundTripper signing outgoing requests.
+package httpsig
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// ErrNilOption is returned when an option is nil.
+var ErrNilOption = errors.New("option cannot be nil")
+
+// ErrNoKeys is returned when there is no key provider configured.
+var ErrNoKeys = errors.New("no key provider configured")
+
+// ErrInvalidDuration is returned for durations that are not greater than 0.
+var ErrInvalidDuration = errors.New("duration must be greater than 0")
+
+// ErrInvalidSize is returned for sizes that are not greater than 0.
+var ErrInvalidSize = errors.New("size must be greater than 0")
+
+// ErrInvalidLabel is returned for labels that are no valid dictionary keys.
+var ErrInvalidLabel = errors.New("invalid label")
+
+// Header names used by HTTP Message Signatures.
+const (
+	HeaderSignatureInput = "Signature-Input"
+	HeaderSignature      = "Signature"
+	HeaderContentDigest  = "Content-Digest"
+)
+
+const (
+	// DefaultMaxAge is the default maximum age of a signature.
+	DefaultMaxAge = 5 * time.Minute
+	// DefaultMaxSkew is the default tolerance for clocks of signers running ahead.
+	DefaultMaxSkew = 30 * time.Second
+	// DefaultMaxBodySize is the default maximum size of bodies checked against their
+	// Content-Digest.
+	DefaultMaxBodySize = 10 << 20
+)
+
+// DefaultRequiredComponents are the components a signature has to cover, if not
+// configured otherwise.
+var DefaultRequiredComponents = []string{ComponentMethod, ComponentAuthority, ComponentPath}
+
+// KeyProvider gives the keys used to verify signatures.
+type KeyProvider interface {
+	// Key gives the key with the given key id. found is false if the key is unknown, an
+	// error is reserved for failures of the provider itself.
+	Key(keyID string) (key Key, found bool, err error)
+}
+
+// KeyMap is a static KeyProvider.
+type KeyMap map[string]Key
+
+// Key gives the key with the given id.
+func (m KeyMap) Key(keyID string) (Key, bool, error) {
+	key, found := m[keyID]
+
+	return key, found, nil
+}
+
+// Handler holds the internal data of the signature verification middleware.
+type Handler struct {
+	defs.MWBase
+
+	keys        KeyProvider      // keys gives the verification keys
+	required    []string         // required are the names of the components that have to be covered
+	label       string           // label restricts the verification to the signature with this label
+	tag         string           // tag is the required tag parameter
+	maxAge      time.Duration    // maxAge is the maximum age of a signature
+	maxSkew     time.Duration    // maxSkew is the tolerance for clocks running ahead
+	maxBodySize int64            // maxBodySize is the maximum size of bodies checked for their digest
+	now         func() time.Time // now gives the current time
+}
+
+// GetMWBase returns the MWBase of the handler.
+func (h *Handler) GetMWBase() *defs.MWBase {
+	if h == nil {
+		return nil
+	}
+
+	return &h.MWBase
+}
+
+// rejection describes why a request was rejected.
+type rejection struct {
+	status int
+	reason string
+}
+
+// verified is the result of a successful signature verification.
+type verified struct {
+	label      string
+	keyID      string
+	alg        Algorithm
+	created    time.Time
+	components []string
+}
+
+// verify checks a single signature of the request.
+//
+//nolint:cyclop,funlen // the checks are easier to follow in one place
+func (h *Handler) verify(r *http.Request, input, signature sfMember) (*verified, *rejection) {
+	malformed := &rejection{status: http.StatusUnauthorized, reason: "malformed signature " + input.key}
+	items, isList := input.value.([]sfItem)
+	sig, isBytes := signature.value.([]byte)
+
+	if !isList || !isBytes {
+		return nil, malformed
+	}
+
+	result := verified{label: input.key, components: make([]string, 0, len(items))}
+	components := make([]component, 0, len(items))
+
+	for _, item := range items {
+		c, err := componentFromItem(item)
+
+		if err != nil {
+			return nil, &rejection{status: http.StatusUnauthorized, reason: err.Error()}
+		}
+
+		components = append(components, c)
+		result.components = append(result.components, c.name)
+	}
+
+	for _, name := range h.required {
+		if !slices.Contains(result.components, name) {
+			return nil, &rejection{status: http.StatusUnauthorized, reason: "component " + name + " not covered"}
+		}
+	}
+
+	created, hasCreated := input.params.get("created")
+	createdUnix, isInt := created.(int64)
+
+	if !hasCreated || !isInt {
+		return nil, &rejection{status: http.StatusUnauthorized, reason: "missing creation time"}
+	}
+
+	now := h.now()
+	result.created = time.Unix(createdUnix, 0)
+
+	if result.created.After(now.Add(h.maxSkew)) || result.created.Before(now.Add(-h.maxAge)) {
+		return nil, &rejection{status: http.StatusUnauthorized, reason: "signature too old or created in the future"}
+	}
+
+	if expires, hasExpires := input.params.get("expires"); hasExpires {
+		expiresUnix, isInt := expires.(int64)
+
+		if !isInt {
+			return nil, malformed
+		}
+
+		if now.Add(-h.maxSkew).After(time.Unix(expiresUnix, 0)) {
+			return nil, &rejection{status: http.StatusUnauthorized, reason: "signature expired"}
+		}
+	}
+
+	if h.tag != "" {
+		if tag, _ := input.params.get("tag"); tag != h.tag {
+			return nil, &rejection{status: http.StatusUnauthorized, reason: "unexpected tag"}
+		}
+	}
+
+	keyID, _ := firstValue(input.params, "keyid").(string)
+
+	if keyID == "" {
+		return nil, &rejection{status: http.StatusUnauthorized, reason: "missing key id"}
+	}
+
+	result.keyID = keyID
+	key, found, keyErr := h.keys.Key(keyID)
+
+	if keyErr != nil {
+		h.Log().Error("could not get key", slog.String("error", keyErr.Error()), slog.String("key", keyID))
+
+		return nil, &rejection{status: http.StatusServiceUnavailable}
+	}
+
+	if !found {
+		return nil, &rejection{status: http.StatusUnauthorized, reason: "unknown key"}
+	}
+
+	result.alg = key.Algorithm
+
+	if alg, hasAlg := input.params.get("alg"); hasAlg && alg != string(key.Algorithm) {
+		return nil, &rejection{status: http.StatusUnauthorized, reason: "algorithm does not match key"}
+	}
+
+	base, _, baseErr := signatureBase(r, components, input.params)
+
+	if baseErr != nil {
+		return nil, &rejection{status: http.StatusUnauthorized, reason: baseErr.Error()}
+	}
+
+	if err := key.verify(base, sig); err != nil {
+		if !errors.Is(err, ErrInvalidSignature) {
+			h.Log().Error("could not verify signature", slog.String("error", err.Error()), slog.String("key", keyID))
+		}
+
+		return nil, &rejection{status: http.StatusUnauthorized, reason: "invalid signature"}
+	}
+
+	return &result, nil
+}
+
+// checkDigest checks the Content-Digest header against the body, if it is covered by the
+// signature.
+func (h *Handler) checkDigest(r *http.Request, v *verified) *rejection {
+	if !slices.Contains(v.components, ComponentContentDigest) {
+		return nil
+	}
+
+	body, complete, err := helper.ReadBody(r, h.maxBodySize)
+
+	if err != nil {
+		return &rejection{status: http.StatusBadRequest, reason: "could not read body"}
+	}
+
+	if !complete {
+		return &rejection{status: http.StatusRequestEntityTooLarge, reason: "body too large"}
+	}
+
+	if err := checkContentDigest(strings.Join(r.Header.Values(HeaderContentDigest), ","), body); err != nil {
+		return &rejection{status: http.StatusUnauthorized, reason: err.Error()}
+	}
+
+	return nil
+}
+
+// reject logs the reason and sends it to the client as problem details.
+func (h *Handler) reject(w http.ResponseWriter, r *http.Request, rej *rejection) {
+	if rej.reason != "" {
+		h.Log().Info("request signature rejected",
+			slog.String("reason", rej.reason),
+			slog.String("client", r.RemoteAddr))
+	}
+
+	helper.WriteProblem(w, h.Log(), rej.status, rej.reason)
+}
+
+// ServeHTTP implements the signature verification. Without a configured label, the
+// request is accepted if any of its signatures is valid.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !helper.IntroCheck(h, w, r) {
+		return
+	}
+
+	inputs, inputErr := parseDictionary(strings.Join(r.Header.Values(HeaderSignatureInput), ","))
+	signatures, sigErr := parseDictionary(strings.Join(r.Header.Values(HeaderSignature), ","))
+
+	if inputErr != nil || sigErr != nil {
+		h.reject(w, r, &rejection{status: http.StatusUnauthorized, reason: "malformed signature headers"})
+
+		return
+	}
+
+	var (
+		result   *verified
+		rejected = &rejection{status: http.StatusUnauthorized, reason: "missing signature"}
+	)
+
+	for _, input := range inputs {
+		if h.label != "" && input.key != h.label {
+			continue
+		}
+
+		i := indexMember(signatures, input.key)
+
+		if i < 0 {
+			continue
+		}
+
+		var rej *rejection
+
+		if result, rej = h.verify(r, input, signatures[i]); result != nil {
+			break
+		}
+
+		if rej.status != http.StatusUnauthorized {
+			h.reject(w, r, rej)
+
+			return
+		}
+
+		rejected = rej
+	}
+
+	if result == nil {
+		h.reject(w, r, rejected)
+
+		return
+	}
+
+	if rej := h.checkDigest(r, result); rej != nil {
+		h.reject(w, r, rej)
+
+		return
+	}
+
+	r = r.WithContext(defs.ContextWithPrincipal(r.Context(), &defs.Principal{
+		Name:   result.keyID,
+		Method: "httpsig",
+		Claims: map[string]any{
+			"label":      result.label,
+			"alg":        string(result.alg),
+			"created":    result.created,
+			"components": result.components,
+		},
+	}))
+
+	h.Next().ServeHTTP(w, r)
+}
+
+// WithKeys sets the KeyProvider to use.
+func WithKeys(keys KeyProvider) func(h *Handler) error {
+	return func(h *Handler) error {
+		h.keys = keys
+
+		return nil
+	}
+}
+
+// WithRequiredComponents sets the names of the components a signature has to cover,
+// replacing the DefaultRequiredComponents. If "content-digest" is required, the body is
+// checked against the Content-Digest header.
+func WithRequiredComponents(names ...string) func(h *Handler) error {
+	return func(h *Handler) error {
+		h.required = slices.Clone(names)
+
+		return nil
+	}
+}
+
+// WithLabel restricts the verification to the signature with the given label.
+func WithLabel(label string) func(h *Handler) error {
+	return func(h *Handler) error {
+		if !isKey(label) {
+			return fmt.Errorf("%w: %q", ErrInvalidLabel, label)
+		}
+
+		h.label = label
+
+		return nil
+	}
+}
+
+// WithTag requires the signatures to carry the given tag parameter, identifying the
+// application protocol they were created for.
+func WithTag(tag string) func(h *Handler) error {
+	return func(h *Handler) error {
+		h.tag = tag
+
+		return nil
+	}
+}
+
+// WithMaxAge sets the maximum age of a signature, as given by its created parameter.
+func WithMaxAge(d time.Duration) func(h *Handler) error {
+	return func(h *Handler) error {
+		if d <= 0 {
+			return ErrInvalidDuration
+		}
+
+		h.maxAge = d
+
+		return nil
+	}
+}
+
+// WithMaxSkew sets the tolerance for signatures created in the future or expired shortly
+// before, caused by clocks that are not exactly synchronized.
+func WithMaxSkew(d time.Duration) func(h *Handler) error {
+	return func(h *Handler) error {
+		if d <= 0 {
+			return ErrInvalidDuration
+		}
+
+		h.maxSkew = d
+
+		return nil
+	}
+}
+
+// WithMaxBodySize sets the maximum size of request bodies checked against their
+// Content-Digest. Larger requests are rejected, as the body has to be held in memory.
+func WithMaxBodySize(size int64) func(h *Handler) error {
+	return func(h *Handler) error {
+		if size <= 0 {
+			return ErrInvalidSize
+		}
+
+		h.maxBodySize = size
+
+		return nil
+	}
+}
+
+// WithLogger configures the logger to use.
+func WithLogger(log *slog.Logger) func(h *Handler) error {
+	return defs.WithLogger[*Handler](log)
+}
+
+// WithLogLevel configures the log level to use with the logger.
+func WithLogLevel(level slog.Level) func(h *Handler) error {
+	return defs.WithLogLevel[*Handler](level)
+}
+
+// New generates a new signature verification middleware.
+func New(options ...func(handler *Handler) error) (defs.Middleware, error) {
+	handler := Handler{
+		required:    slices.Clone(DefaultRequiredComponents),
+		maxAge:      DefaultMaxAge,
+		maxSkew:     DefaultMaxSkew,
+		maxBodySize: DefaultMaxBodySize,
+		now:         time.Now,
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&handler); err != nil {
+			return nil, err
+		}
+	}
+
+	if handler.keys == nil {
+		return nil, ErrNoKeys
+	}
+
+	return func(next http.Handler) http.Handler {
+		if err := handler.SetNext(next); err != nil {
+			return nil
+		}
+
+		return &handler
+	}, nil
+}
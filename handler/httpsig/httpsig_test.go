@@ -0,0 +1,610 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package httpsig_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/httpsig"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// rfcCreated is the creation time used in the examples of RFC 9421.
+var rfcCreated = time.Unix(1618884473, 0)
+
+// rfcSharedSecret is the key test-shared-secret of RFC 9421, Appendix B.1.4.
+var rfcSharedSecret = helper.Must(base64.StdEncoding.DecodeString(
+	"uzvJfB4u3N0Jy4T7NZ75MDVcr8zSTInedJtkgcu46YW4XByzNJjxBdtjUkdJPBtbmHhIDi6pcl8jsasjlTMtDQ=="))
+
+// rfcEd25519 is the key test-key-ed25519 of RFC 9421, Appendix B.1.4.
+var rfcEd25519 = ed25519.NewKeyFromSeed(helper.Must(base64.RawURLEncoding.DecodeString(
+	"n4Ni-HpISpVObnQMW0wOhCKROaIKqKtW_2ZYb2p9KcU")))
+
+// rfcRequest creates the example request of RFC 9421, Appendix B.2.
+func rfcRequest(t *testing.T) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodPost,
+		"/foo?param=Value&Pet=dog", strings.NewReader(`{"hello": "world"}`))
+	req.Host = "example.com"
+	req.Header.Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Digest",
+		"sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:")
+	req.Header.Set("Content-Length", "18")
+
+	return req
+}
+
+// newVerifier creates a verification handler recording the principal of accepted requests.
+func newVerifier(t *testing.T, now time.Time, principal **defs.Principal,
+	options ...func(*httpsig.Handler) error) *httpsig.Handler {
+
+	t.Helper()
+
+	h := helper.Must(httpsig.New(options...))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if principal != nil {
+			*principal, _ = defs.PrincipalFromContext(r.Context())
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+
+	handler, isHandler := h.(*httpsig.Handler)
+
+	if !isHandler {
+		t.Fatalf("wrong handler type")
+	}
+
+	if !now.IsZero() {
+		httpsig.TSetNow(handler, func() time.Time { return now })
+	}
+
+	return handler
+}
+
+func TestRFCExamples(t *testing.T) {
+	t.Parallel()
+
+	keys := httpsig.KeyMap{
+		"test-shared-secret": {Algorithm: httpsig.AlgHMACSHA256, Key: rfcSharedSecret},
+		"test-key-ed25519":   {Algorithm: httpsig.AlgEd25519, Key: rfcEd25519.Public()},
+	}
+
+	tests := []struct {
+		Name       string
+		Input      string
+		Signature  string
+		Required   []string
+		WantStatus int
+	}{
+		{
+			Name:       "B.2.5 HMAC",
+			Input:      `sig-b25=("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"`,
+			Signature:  `sig-b25=:pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=:`,
+			Required:   []string{"@authority"},
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "B.2.6 Ed25519",
+			Input: `sig-b26=("date" "@method" "@path" "@authority" "content-type" "content-length")` +
+				`;created=1618884473;keyid="test-key-ed25519"`,
+			Signature: `sig-b26=:wqcAqbmYJ2ji2glfAMaRy4gruYYnx2nEFN2HN6jrnDnQCK1u02Gb04v9EDgwUPiu4A0w6vuQv5lIp5WP` +
+				`pBKRCw==:`,
+			Required:   httpsig.DefaultRequiredComponents,
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name:       "B.2.5 HMAC missing required",
+			Input:      `sig-b25=("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"`,
+			Signature:  `sig-b25=:pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=:`,
+			Required:   httpsig.DefaultRequiredComponents,
+			WantStatus: http.StatusUnauthorized,
+		},
+		{
+			Name: "B.2.6 Ed25519 modified parameters",
+			Input: `sig-b26=("date" "@method" "@path" "@authority" "content-type" "content-length")` +
+				`;created=1618884474;keyid="test-key-ed25519"`,
+			Signature: `sig-b26=:wqcAqbmYJ2ji2glfAMaRy4gruYYnx2nEFN2HN6jrnDnQCK1u02Gb04v9EDgwUPiu4A0w6vuQv5lIp5WP` +
+				`pBKRCw==:`,
+			Required:   httpsig.DefaultRequiredComponents,
+			WantStatus: http.StatusUnauthorized,
+		},
+		{
+			Name: "B.2.6 Ed25519 with content digest",
+			Input: `sig-b26=("date" "@method" "@path" "@authority" "content-type" "content-length")` +
+				`;created=1618884473;keyid="test-key-ed25519"`,
+			Signature: `sig-b26=:wqcAqbmYJ2ji2glfAMaRy4gruYYnx2nEFN2HN6jrnDnQCK1u02Gb04v9EDgwUPiu4A0w6vuQv5lIp5WP` +
+				`pBKRCw==:`,
+			Required:   []string{"content-digest"},
+			WantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+
+			var principal *defs.Principal
+
+			handler := newVerifier(t, rfcCreated, &principal,
+				httpsig.WithKeys(keys),
+				httpsig.WithRequiredComponents(test.Required...))
+
+			req := rfcRequest(t)
+			req.Header.Set("Signature-Input", test.Input)
+			req.Header.Set("Signature", test.Signature)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != test.WantStatus {
+				t.Errorf("got status %v but wanted %v: %v", rec.Code, test.WantStatus, rec.Body.String())
+			}
+
+			if test.WantStatus == http.StatusOK && (principal == nil || principal.Method != "httpsig") {
+				t.Errorf("got unexpected principal %+v", principal)
+			}
+		})
+	}
+}
+
+// roundTripFunc is a http.RoundTripper implemented by a function.
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// testKeyPairs creates signing and verification keys of all supported algorithms.
+func testKeyPairs(t *testing.T) map[httpsig.Algorithm][2]httpsig.Key {
+	t.Helper()
+
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	ecPrivate, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaPrivate, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	return map[httpsig.Algorithm][2]httpsig.Key{
+		httpsig.AlgHMACSHA256: {
+			{Algorithm: httpsig.AlgHMACSHA256, Key: []byte("shared secret")},
+			{Algorithm: httpsig.AlgHMACSHA256, Key: []byte("shared secret")},
+		},
+		httpsig.AlgEd25519: {
+			{Algorithm: httpsig.AlgEd25519, Key: edPrivate},
+			{Algorithm: httpsig.AlgEd25519, Key: edPublic},
+		},
+		httpsig.AlgECDSAP256SHA256: {
+			{Algorithm: httpsig.AlgECDSAP256SHA256, Key: ecPrivate},
+			{Algorithm: httpsig.AlgECDSAP256SHA256, Key: &ecPrivate.PublicKey},
+		},
+		httpsig.AlgRSAPSSSHA512: {
+			{Algorithm: httpsig.AlgRSAPSSSHA512, Key: rsaPrivate},
+			{Algorithm: httpsig.AlgRSAPSSSHA512, Key: &rsaPrivate.PublicKey},
+		},
+	}
+}
+
+func TestAlgorithms(t *testing.T) {
+	t.Parallel()
+
+	for alg, pair := range testKeyPairs(t) {
+		t.Run(string(alg), func(t *testing.T) {
+			t.Parallel()
+
+			var principal *defs.Principal
+
+			server := httptest.NewTLSServer(newVerifier(t, time.Time{}, &principal,
+				httpsig.WithKeys(httpsig.KeyMap{"client": pair[1]}),
+				httpsig.WithRequiredComponents("@method", "@target-uri", "content-digest")))
+			defer server.Close()
+
+			transport := helper.Must(httpsig.NewTransport("client", pair[0],
+				httpsig.WithBaseTransport(server.Client().Transport)))
+
+			req, _ := http.NewRequestWithContext(t.Context(), http.MethodPost,
+				server.URL+"/orders?id=1", strings.NewReader(`{"amount":10}`))
+			resp, err := (&http.Client{Transport: transport}).Do(req)
+
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK || string(body) != `{"amount":10}` {
+				t.Errorf("got status %v and body %q", resp.StatusCode, body)
+			}
+
+			if principal == nil || principal.Name != "client" || principal.Claims["alg"] != string(alg) {
+				t.Errorf("got unexpected principal %+v", principal)
+			}
+		})
+	}
+}
+
+func TestVerification(t *testing.T) {
+	t.Parallel()
+
+	secret := httpsig.Key{Algorithm: httpsig.AlgHMACSHA256, Key: []byte("shared secret")}
+	otherSecret := httpsig.Key{Algorithm: httpsig.AlgHMACSHA256, Key: []byte("other secret")}
+	edPublic, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		Name       string
+		KeyID      string
+		Key        httpsig.Key
+		Verify     httpsig.Key
+		Signing    []func(*httpsig.Transport) error
+		Options    []func(*httpsig.Handler) error
+		Prepare    func(r *http.Request) // Prepare is called on the signed request
+		Offset     time.Duration         // Offset is added to the time of the verifier
+		WantStatus int
+		WantDetail string
+	}{
+		{
+			Name: "valid", KeyID: "client", Key: secret, Verify: secret,
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "wrong key", KeyID: "client", Key: otherSecret, Verify: secret,
+			WantStatus: http.StatusUnauthorized, WantDetail: "invalid signature",
+		},
+		{
+			Name: "unknown key", KeyID: "someone", Key: secret, Verify: secret,
+			WantStatus: http.StatusUnauthorized, WantDetail: "unknown key",
+		},
+		{
+			Name: "algorithm mismatch", KeyID: "client", Key: secret,
+			Verify:     httpsig.Key{Algorithm: httpsig.AlgEd25519, Key: edPublic},
+			WantStatus: http.StatusUnauthorized, WantDetail: "algorithm does not match key",
+		},
+		{
+			Name: "ed25519", KeyID: "client", Verify: httpsig.Key{Algorithm: httpsig.AlgEd25519, Key: edPublic},
+			Key:        httpsig.Key{Algorithm: httpsig.AlgEd25519, Key: edPrivate},
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "tampered path", KeyID: "client", Key: secret, Verify: secret,
+			Prepare:    func(r *http.Request) { r.URL.Path = "/admin" },
+			WantStatus: http.StatusUnauthorized, WantDetail: "invalid signature",
+		},
+		{
+			Name: "tampered method", KeyID: "client", Key: secret, Verify: secret,
+			Prepare:    func(r *http.Request) { r.Method = http.MethodPut },
+			WantStatus: http.StatusUnauthorized, WantDetail: "invalid signature",
+		},
+		{
+			Name: "tampered body", KeyID: "client", Key: secret, Verify: secret,
+			Prepare: func(r *http.Request) {
+				r.Body = io.NopCloser(strings.NewReader(`{"amount":99}`))
+				r.GetBody = nil
+			},
+			WantStatus: http.StatusUnauthorized, WantDetail: "content digest mismatch: sha-256",
+		},
+		{
+			Name: "tampered digest", KeyID: "client", Key: secret, Verify: secret,
+			Prepare:    func(r *http.Request) { r.Header.Set("Content-Digest", "sha-256=:AAAA:") },
+			WantStatus: http.StatusUnauthorized, WantDetail: "invalid signature",
+		},
+		{
+			Name: "required content digest", KeyID: "client", Key: secret, Verify: secret,
+			Options:    []func(*httpsig.Handler) error{httpsig.WithRequiredComponents("content-digest")},
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "missing signature", KeyID: "client", Key: secret, Verify: secret,
+			Prepare: func(r *http.Request) {
+				r.Header.Del("Signature")
+				r.Header.Del("Signature-Input")
+			},
+			WantStatus: http.StatusUnauthorized, WantDetail: "missing signature",
+		},
+		{
+			Name: "malformed signature input", KeyID: "client", Key: secret, Verify: secret,
+			Prepare:    func(r *http.Request) { r.Header.Set("Signature-Input", `sig1=("@method"`) },
+			WantStatus: http.StatusUnauthorized, WantDetail: "malformed signature headers",
+		},
+		{
+			Name: "malformed signature", KeyID: "client", Key: secret, Verify: secret,
+			Prepare:    func(r *http.Request) { r.Header.Set("Signature", `sig1="not bytes"`) },
+			WantStatus: http.StatusUnauthorized, WantDetail: "malformed signature sig1",
+		},
+		{
+			Name: "uncovered required component", KeyID: "client", Key: secret, Verify: secret,
+			Signing:    []func(*httpsig.Transport) error{httpsig.WithSigningComponents("@method", "@path")},
+			WantStatus: http.StatusUnauthorized, WantDetail: "component @authority not covered",
+		},
+		{
+			Name: "too old", KeyID: "client", Key: secret, Verify: secret, Offset: 6 * time.Minute,
+			WantStatus: http.StatusUnauthorized, WantDetail: "signature too old or created in the future",
+		},
+		{
+			Name: "created in the future", KeyID: "client", Key: secret, Verify: secret, Offset: -time.Minute,
+			WantStatus: http.StatusUnauthorized, WantDetail: "signature too old or created in the future",
+		},
+		{
+			Name: "expired", KeyID: "client", Key: secret, Verify: secret, Offset: 2 * time.Minute,
+			Signing:    []func(*httpsig.Transport) error{httpsig.WithSigningExpiry(time.Minute)},
+			WantStatus: http.StatusUnauthorized, WantDetail: "signature expired",
+		},
+		{
+			Name: "not yet expired", KeyID: "client", Key: secret, Verify: secret, Offset: 50 * time.Second,
+			Signing:    []func(*httpsig.Transport) error{httpsig.WithSigningExpiry(time.Minute), httpsig.WithSigningNonce()},
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "tag", KeyID: "client", Key: secret, Verify: secret,
+			Signing:    []func(*httpsig.Transport) error{httpsig.WithSigningTag("partner-api")},
+			Options:    []func(*httpsig.Handler) error{httpsig.WithTag("partner-api")},
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "wrong tag", KeyID: "client", Key: secret, Verify: secret,
+			Signing:    []func(*httpsig.Transport) error{httpsig.WithSigningTag("other-api")},
+			Options:    []func(*httpsig.Handler) error{httpsig.WithTag("partner-api")},
+			WantStatus: http.StatusUnauthorized, WantDetail: "unexpected tag",
+		},
+		{
+			Name: "label", KeyID: "client", Key: secret, Verify: secret,
+			Signing:    []func(*httpsig.Transport) error{httpsig.WithSigningLabel("partner")},
+			Options:    []func(*httpsig.Handler) error{httpsig.WithLabel("partner")},
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "other label", KeyID: "client", Key: secret, Verify: secret,
+			Options:    []func(*httpsig.Handler) error{httpsig.WithLabel("partner")},
+			WantStatus: http.StatusUnauthorized, WantDetail: "missing signature",
+		},
+		{
+			Name: "additional invalid signature", KeyID: "client", Key: secret, Verify: secret,
+			Prepare: func(r *http.Request) {
+				r.Header.Set("Signature-Input", `proxy=("@method");created=1;keyid="proxy", `+r.Header.Get("Signature-Input"))
+				r.Header.Set("Signature", `proxy=:AAAA:, `+r.Header.Get("Signature"))
+			},
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "query parameter", KeyID: "client", Key: secret, Verify: secret,
+			Signing: []func(*httpsig.Transport) error{
+				httpsig.WithSigningComponents("@method", "@authority", "@path", `@query-param;name="id"`),
+			},
+			Prepare:    func(r *http.Request) { r.URL.RawQuery += "&other=2" },
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "tampered query parameter", KeyID: "client", Key: secret, Verify: secret,
+			Signing: []func(*httpsig.Transport) error{
+				httpsig.WithSigningComponents("@method", "@authority", "@path", `@query-param;name="id"`),
+			},
+			Prepare:    func(r *http.Request) { r.URL.RawQuery = "id=2" },
+			WantStatus: http.StatusUnauthorized, WantDetail: "invalid signature",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+
+			server := httptest.NewServer(newVerifier(t, time.Now().Add(test.Offset), nil,
+				append([]func(*httpsig.Handler) error{
+					httpsig.WithKeys(httpsig.KeyMap{"client": test.Verify}),
+				}, test.Options...)...))
+			defer server.Close()
+
+			prepare := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				if test.Prepare != nil {
+					test.Prepare(r)
+				}
+
+				return server.Client().Transport.RoundTrip(r) //nolint:wrapcheck // test
+			})
+
+			transport := helper.Must(httpsig.NewTransport(test.KeyID, test.Key,
+				append([]func(*httpsig.Transport) error{httpsig.WithBaseTransport(prepare)}, test.Signing...)...))
+
+			req, _ := http.NewRequestWithContext(t.Context(), http.MethodPost,
+				server.URL+"/orders?id=1", strings.NewReader(`{"amount":10}`))
+			resp, err := transport.RoundTrip(req)
+
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+
+			defer func() { _ = resp.Body.Close() }()
+
+			if resp.StatusCode != test.WantStatus {
+				t.Errorf("got status %v but wanted %v", resp.StatusCode, test.WantStatus)
+			}
+
+			if test.WantStatus == http.StatusOK {
+				return
+			}
+
+			var problem helper.Problem
+
+			if err := json.NewDecoder(resp.Body).Decode(&problem); err != nil {
+				t.Fatalf("could not decode problem details: %v", err)
+			}
+
+			if problem.Detail != test.WantDetail {
+				t.Errorf("got detail %q but wanted %q", problem.Detail, test.WantDetail)
+			}
+		})
+	}
+}
+
+// failingKeys is a KeyProvider that cannot reach its backend.
+type failingKeys struct{}
+
+func (failingKeys) Key(string) (httpsig.Key, bool, error) {
+	return httpsig.Key{}, false, errors.New("backend down")
+}
+
+func TestKeyProviderError(t *testing.T) {
+	t.Parallel()
+
+	handler := newVerifier(t, rfcCreated, nil, httpsig.WithKeys(failingKeys{}),
+		httpsig.WithRequiredComponents())
+
+	req := rfcRequest(t)
+	req.Header.Set("Signature-Input",
+		`sig-b25=("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"`)
+	req.Header.Set("Signature", `sig-b25=:pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=:`)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %v but wanted %v", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestTransportHeaders(t *testing.T) {
+	t.Parallel()
+
+	var got *http.Request
+
+	capture := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		got = r
+
+		return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody, Request: r}, nil
+	})
+
+	transport := helper.Must(httpsig.NewTransport("test-key-ed25519",
+		httpsig.Key{Algorithm: httpsig.AlgEd25519, Key: rfcEd25519},
+		httpsig.WithBaseTransport(capture),
+		httpsig.WithSigningLabel("sig-b26"),
+		httpsig.WithSigningTag("app")))
+
+	httpsig.TSetTransportNow(transport, func() time.Time { return rfcCreated })
+
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodPost,
+		"https://example.com:443/foo?param=Value&Pet=dog", strings.NewReader(`{"hello": "world"}`))
+	resp, err := transport.RoundTrip(req)
+
+	if err != nil {
+		t.Fatalf("could not send request: %v", err)
+	}
+
+	_ = resp.Body.Close()
+
+	if req.Header.Get("Signature") != "" {
+		t.Errorf("original request must not be modified")
+	}
+
+	wantInput := `sig-b26=("@method" "@target-uri" "@authority" "@path" "content-digest");created=1618884473` +
+		`;keyid="test-key-ed25519";alg="ed25519";tag="app"`
+
+	if input := got.Header.Get("Signature-Input"); input != wantInput {
+		t.Errorf("got signature input\n%v\nbut wanted\n%v", input, wantInput)
+	}
+
+	if digest := got.Header.Get("Content-Digest"); digest != "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:" {
+		t.Errorf("got unexpected content digest %v", digest)
+	}
+
+	// Ed25519 signatures are deterministic, so the signature is checked by verifying it
+	verifier := newVerifier(t, rfcCreated, nil,
+		httpsig.WithKeys(httpsig.KeyMap{"test-key-ed25519": {Algorithm: httpsig.AlgEd25519, Key: rfcEd25519.Public()}}),
+		httpsig.WithRequiredComponents("@target-uri", "content-digest"))
+
+	body, _ := got.GetBody()
+	check := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/foo?param=Value&Pet=dog", body)
+	check.Host = "example.com"
+	check.TLS = got.TLS
+	check.Header = got.Header
+	check.URL.Scheme = "https"
+
+	rec := httptest.NewRecorder()
+	verifier.ServeHTTP(rec, check)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("got status %v but wanted %v: %v", rec.Code, http.StatusOK, rec.Body.String())
+	}
+}
+
+func TestOptionErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options []func(*httpsig.Handler) error
+		WantErr error
+	}{
+		{Options: nil, WantErr: httpsig.ErrNoKeys}, // 0
+		{Options: []func(*httpsig.Handler) error{testKeys, httpsig.WithLabel("")}, WantErr: httpsig.ErrInvalidLabel},      // 1
+		{Options: []func(*httpsig.Handler) error{testKeys, httpsig.WithLabel("A b")}, WantErr: httpsig.ErrInvalidLabel},   // 2
+		{Options: []func(*httpsig.Handler) error{testKeys, httpsig.WithMaxAge(0)}, WantErr: httpsig.ErrInvalidDuration},   // 3
+		{Options: []func(*httpsig.Handler) error{testKeys, httpsig.WithMaxSkew(-1)}, WantErr: httpsig.ErrInvalidDuration}, // 4
+		{Options: []func(*httpsig.Handler) error{testKeys, httpsig.WithMaxBodySize(0)}, WantErr: httpsig.ErrInvalidSize},  // 5
+	}
+
+	for k, test := range tests {
+		_, err := httpsig.New(test.Options...)
+
+		if !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+}
+
+func TestTransportOptionErrors(t *testing.T) {
+	t.Parallel()
+
+	secret := httpsig.Key{Algorithm: httpsig.AlgHMACSHA256, Key: []byte("secret")}
+
+	tests := []struct {
+		KeyID   string
+		Key     httpsig.Key
+		Options []func(*httpsig.Transport) error
+		WantErr error
+	}{
+		{KeyID: "", Key: secret, WantErr: httpsig.ErrNoKeyID},                                                                                                     // 0
+		{KeyID: "k", Key: httpsig.Key{Algorithm: "rsa-v1_5-sha256"}, WantErr: httpsig.ErrUnsupportedAlgorithm},                                                    // 1
+		{KeyID: "k", Key: httpsig.Key{Algorithm: httpsig.AlgEd25519, Key: []byte("x")}, WantErr: httpsig.ErrInvalidKey},                                           // 2
+		{KeyID: "k", Key: secret, Options: []func(*httpsig.Transport) error{nil}, WantErr: httpsig.ErrNilOption},                                                  // 3
+		{KeyID: "k", Key: secret, Options: []func(*httpsig.Transport) error{httpsig.WithBaseTransport(nil)}, WantErr: httpsig.ErrNilTransport},                    // 4
+		{KeyID: "k", Key: secret, Options: []func(*httpsig.Transport) error{httpsig.WithSigningLabel("")}, WantErr: httpsig.ErrInvalidLabel},                      // 5
+		{KeyID: "k", Key: secret, Options: []func(*httpsig.Transport) error{httpsig.WithSigningComponents("@status")}, WantErr: httpsig.ErrInvalidComponent},      // 6
+		{KeyID: "k", Key: secret, Options: []func(*httpsig.Transport) error{httpsig.WithSigningComponents("@query-param")}, WantErr: httpsig.ErrInvalidComponent}, // 7
+		{KeyID: "k", Key: secret, Options: []func(*httpsig.Transport) error{httpsig.WithSigningExpiry(0)}, WantErr: httpsig.ErrInvalidDuration},                   // 8
+	}
+
+	for k, test := range tests {
+		_, err := httpsig.NewTransport(test.KeyID, test.Key, test.Options...)
+
+		if !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+}
+
+func TestTransportMissingComponent(t *testing.T) {
+	t.Parallel()
+
+	transport := helper.Must(httpsig.NewTransport("k",
+		httpsig.Key{Algorithm: httpsig.AlgHMACSHA256, Key: []byte("secret")},
+		httpsig.WithSigningComponents("@method", "x-missing")))
+
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "http://example.com/", nil)
+
+	if _, err := transport.RoundTrip(req); !errors.Is(err, httpsig.ErrMissingComponent) {
+		t.Errorf("got error %v but wanted %v", err, httpsig.ErrMissingComponent)
+	}
+}
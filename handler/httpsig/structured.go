@@ -0,0 +1,504 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package httpsig
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// This file contains the subset of structured field values (RFC 8941) needed for the
+// Signature-Input, Signature and Content-Digest headers: dictionaries, inner lists,
+// parameters and the bare item types.
+
+// errMalformed is the base of all parsing errors.
+var errMalformed = errors.New("malformed structured field")
+
+// sfToken is a token item, to be distinguished from a string item.
+type sfToken string
+
+// sfParam is a single parameter of an item or inner list.
+type sfParam struct {
+	key   string
+	value any
+}
+
+// sfParams are the ordered parameters of an item or inner list.
+type sfParams []sfParam
+
+// get returns the value of the parameter with the given key.
+func (p sfParams) get(key string) (any, bool) {
+	for _, param := range p {
+		if param.key == key {
+			return param.value, true
+		}
+	}
+
+	return nil, false
+}
+
+// set sets the value of a parameter, keeping the position of an existing one.
+func (p sfParams) set(key string, value any) sfParams {
+	for i := range p {
+		if p[i].key == key {
+			p[i].value = value
+
+			return p
+		}
+	}
+
+	return append(p, sfParam{key: key, value: value})
+}
+
+// sfItem is a bare item with its parameters.
+type sfItem struct {
+	value  any
+	params sfParams
+}
+
+// sfMember is a member of a dictionary. Its value is either a bare item or, for inner
+// lists, a []sfItem.
+type sfMember struct {
+	key    string
+	value  any
+	params sfParams
+}
+
+// sfParser holds the state of the parsing of a single field value.
+type sfParser struct {
+	input string
+	pos   int
+}
+
+// parseDictionary parses a dictionary field value. Duplicate keys overwrite the value of
+// the former member.
+func parseDictionary(input string) ([]sfMember, error) {
+	p := sfParser{input: input}
+	members := make([]sfMember, 0, 1)
+
+	p.skipSP()
+
+	for !p.done() {
+		key, err := p.parseKey()
+
+		if err != nil {
+			return nil, err
+		}
+
+		member := sfMember{key: key, value: true}
+
+		if p.peek() == '=' {
+			p.pos++
+
+			if member.value, member.params, err = p.parseItemOrInnerList(); err != nil {
+				return nil, err
+			}
+		} else if member.params, err = p.parseParams(); err != nil {
+			return nil, err
+		}
+
+		if i := indexMember(members, key); i >= 0 {
+			members[i] = member
+		} else {
+			members = append(members, member)
+		}
+
+		p.skipOWS()
+
+		if p.done() {
+			break
+		}
+
+		if p.peek() != ',' {
+			return nil, fmt.Errorf("%w: expected comma at %v", errMalformed, p.pos)
+		}
+
+		p.pos++
+		p.skipOWS()
+
+		if p.done() {
+			return nil, fmt.Errorf("%w: trailing comma", errMalformed)
+		}
+	}
+
+	return members, nil
+}
+
+// indexMember returns the index of the member with the given key, or -1.
+func indexMember(members []sfMember, key string) int {
+	for i := range members {
+		if members[i].key == key {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// parseParams parses parameters as a standalone value, as used for component identifiers.
+func parseParams(input string) (sfParams, error) {
+	p := sfParser{input: input}
+	params, err := p.parseParams()
+
+	if err == nil && !p.done() {
+		err = fmt.Errorf("%w: unexpected character at %v", errMalformed, p.pos)
+	}
+
+	return params, err
+}
+
+// isKey checks if s is a valid dictionary or parameter key.
+func isKey(s string) bool {
+	p := sfParser{input: s}
+	_, err := p.parseKey()
+
+	return err == nil && p.done()
+}
+
+func (p *sfParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *sfParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+
+	return p.input[p.pos]
+}
+
+func (p *sfParser) skipSP() {
+	for p.peek() == ' ' {
+		p.pos++
+	}
+}
+
+func (p *sfParser) skipOWS() {
+	for p.peek() == ' ' || p.peek() == '\t' {
+		p.pos++
+	}
+}
+
+func (p *sfParser) parseItemOrInnerList() (any, sfParams, error) {
+	if p.peek() != '(' {
+		item, err := p.parseItem()
+
+		return item.value, item.params, err
+	}
+
+	p.pos++
+	items := make([]sfItem, 0, 4)
+
+	for !p.done() {
+		p.skipSP()
+
+		if p.peek() == ')' {
+			p.pos++
+			params, err := p.parseParams()
+
+			return items, params, err
+		}
+
+		item, err := p.parseItem()
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		items = append(items, item)
+
+		if c := p.peek(); c != ' ' && c != ')' {
+			return nil, nil, fmt.Errorf("%w: unexpected character in inner list at %v", errMalformed, p.pos)
+		}
+	}
+
+	return nil, nil, fmt.Errorf("%w: unterminated inner list", errMalformed)
+}
+
+func (p *sfParser) parseItem() (sfItem, error) {
+	value, err := p.parseBareItem()
+
+	if err != nil {
+		return sfItem{}, err
+	}
+
+	params, err := p.parseParams()
+
+	return sfItem{value: value, params: params}, err
+}
+
+func (p *sfParser) parseParams() (sfParams, error) {
+	var params sfParams
+
+	for p.peek() == ';' {
+		p.pos++
+		p.skipSP()
+
+		key, err := p.parseKey()
+
+		if err != nil {
+			return nil, err
+		}
+
+		var value any = true
+
+		if p.peek() == '=' {
+			p.pos++
+
+			if value, err = p.parseBareItem(); err != nil {
+				return nil, err
+			}
+		}
+
+		params = params.set(key, value)
+	}
+
+	return params, nil
+}
+
+func (p *sfParser) parseKey() (string, error) {
+	start := p.pos
+
+	if c := p.peek(); !isLCAlpha(c) && c != '*' {
+		return "", fmt.Errorf("%w: invalid key at %v", errMalformed, p.pos)
+	}
+
+	for !p.done() {
+		c := p.peek()
+
+		if !isLCAlpha(c) && !isDigit(c) && !strings.ContainsRune("_-.*", rune(c)) {
+			break
+		}
+
+		p.pos++
+	}
+
+	return p.input[start:p.pos], nil
+}
+
+func (p *sfParser) parseBareItem() (any, error) {
+	switch c := p.peek(); {
+	case c == '-' || isDigit(c):
+		return p.parseNumber()
+	case c == '"':
+		return p.parseString()
+	case c == '*' || isAlpha(c):
+		return p.parseToken(), nil
+	case c == ':':
+		return p.parseByteSequence()
+	case c == '?':
+		return p.parseBoolean()
+	default:
+		return nil, fmt.Errorf("%w: unexpected character at %v", errMalformed, p.pos)
+	}
+}
+
+func (p *sfParser) parseNumber() (any, error) {
+	start := p.pos
+
+	if p.peek() == '-' {
+		p.pos++
+	}
+
+	decimal := false
+
+	for !p.done() && (isDigit(p.peek()) || (p.peek() == '.' && !decimal)) {
+		decimal = decimal || p.peek() == '.'
+		p.pos++
+	}
+
+	number := p.input[start:p.pos]
+
+	if decimal {
+		value, err := strconv.ParseFloat(number, 64)
+
+		if err != nil || len(number) > 17 || strings.HasSuffix(number, ".") {
+			return nil, fmt.Errorf("%w: invalid decimal %q", errMalformed, number)
+		}
+
+		return value, nil
+	}
+
+	value, err := strconv.ParseInt(number, 10, 64)
+
+	if err != nil || len(strings.TrimPrefix(number, "-")) > 15 {
+		return nil, fmt.Errorf("%w: invalid integer %q", errMalformed, number)
+	}
+
+	return value, nil
+}
+
+func (p *sfParser) parseString() (string, error) {
+	var result strings.Builder
+
+	p.pos++
+
+	for !p.done() {
+		c := p.input[p.pos]
+		p.pos++
+
+		switch {
+		case c == '"':
+			return result.String(), nil
+		case c == '\\':
+			if n := p.peek(); n != '"' && n != '\\' {
+				return "", fmt.Errorf("%w: invalid escape at %v", errMalformed, p.pos)
+			}
+
+			result.WriteByte(p.input[p.pos])
+			p.pos++
+		case c < 0x20 || c > 0x7e:
+			return "", fmt.Errorf("%w: invalid character in string at %v", errMalformed, p.pos)
+		default:
+			result.WriteByte(c)
+		}
+	}
+
+	return "", fmt.Errorf("%w: unterminated string", errMalformed)
+}
+
+func (p *sfParser) parseToken() sfToken {
+	start := p.pos
+
+	for !p.done() {
+		if c := p.peek(); !isTChar(c) && c != ':' && c != '/' {
+			break
+		}
+
+		p.pos++
+	}
+
+	return sfToken(p.input[start:p.pos])
+}
+
+func (p *sfParser) parseByteSequence() ([]byte, error) {
+	p.pos++
+	end := strings.IndexByte(p.input[p.pos:], ':')
+
+	if end < 0 {
+		return nil, fmt.Errorf("%w: unterminated byte sequence", errMalformed)
+	}
+
+	encoded := p.input[p.pos : p.pos+end]
+	p.pos += end + 1
+
+	value, err := base64.StdEncoding.DecodeString(encoded)
+
+	if err != nil {
+		value, err = base64.RawStdEncoding.DecodeString(encoded)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid byte sequence: %w", errMalformed, err)
+	}
+
+	return value, nil
+}
+
+func (p *sfParser) parseBoolean() (bool, error) {
+	p.pos++
+
+	switch p.peek() {
+	case '1':
+		p.pos++
+
+		return true, nil
+	case '0':
+		p.pos++
+
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: invalid boolean at %v", errMalformed, p.pos)
+	}
+}
+
+func isLCAlpha(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+func isAlpha(c byte) bool {
+	return isLCAlpha(c) || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isTChar(c byte) bool {
+	return isAlpha(c) || isDigit(c) || strings.ContainsRune("!#$%&'*+-.^_`|~", rune(c))
+}
+
+// serializeString serializes a string item.
+func serializeString(s string) string {
+	var result strings.Builder
+
+	result.WriteByte('"')
+
+	for i := range len(s) {
+		if s[i] == '"' || s[i] == '\\' {
+			result.WriteByte('\\')
+		}
+
+		result.WriteByte(s[i])
+	}
+
+	result.WriteByte('"')
+
+	return result.String()
+}
+
+// serializeBareItem serializes a bare item of any supported type.
+func serializeBareItem(value any) string {
+	switch v := value.(type) {
+	case string:
+		return serializeString(v)
+	case sfToken:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []byte:
+		return ":" + base64.StdEncoding.EncodeToString(v) + ":"
+	case bool:
+		if v {
+			return "?1"
+		}
+
+		return "?0"
+	default:
+		return serializeString(fmt.Sprint(v))
+	}
+}
+
+// serialize serializes the parameters, including their leading semicolons.
+func (p sfParams) serialize() string {
+	var result strings.Builder
+
+	for _, param := range p {
+		result.WriteByte(';')
+		result.WriteString(param.key)
+
+		if v, isBool := param.value.(bool); !isBool || !v {
+			result.WriteByte('=')
+			result.WriteString(serializeBareItem(param.value))
+		}
+	}
+
+	return result.String()
+}
+
+// serializeInnerList serializes an inner list with its parameters.
+func serializeInnerList(items []sfItem, params sfParams) string {
+	parts := make([]string, 0, len(items))
+
+	for _, item := range items {
+		parts = append(parts, serializeBareItem(item.value)+item.params.serialize())
+	}
+
+	return "(" + strings.Join(parts, " ") + ")" + params.serialize()
+}
@@ -0,0 +1,254 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package httpsig
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// ErrNilTransport is returned when the base transport is nil.
+var ErrNilTransport = errors.New("transport cannot be nil")
+
+// ErrNoKeyID is returned when the key id of the signing key is empty.
+var ErrNoKeyID = errors.New("key id cannot be empty")
+
+// DefaultLabel is the label of the signatures created by the Transport.
+const DefaultLabel = "sig1"
+
+// DefaultSigningComponents are the components covered by the signatures created by the
+// Transport, if not configured otherwise. Requests with a body additionally cover
+// their Content-Digest.
+var DefaultSigningComponents = []string{ComponentMethod, ComponentTargetURI, ComponentAuthority, ComponentPath}
+
+// Transport is a http.RoundTripper that signs the requests before passing them to its
+// base transport.
+type Transport struct {
+	base       http.RoundTripper // base sends the signed requests
+	keyID      string            // keyID identifies the key at the verifier
+	key        Key               // key signs the requests
+	label      string            // label is the label of the signature
+	components []component       // components are the covered components
+	explicit   bool              // explicit is true if the components were configured
+	tag        string            // tag is the optional tag parameter
+	expiry     time.Duration     // expiry is the validity of the signatures, 0 for none
+	nonce      bool              // nonce indicates if a random nonce is added
+	now        func() time.Time  // now gives the current time
+}
+
+// WithBaseTransport sets the transport sending the signed requests. Without it,
+// http.DefaultTransport is used.
+func WithBaseTransport(base http.RoundTripper) func(t *Transport) error {
+	return func(t *Transport) error {
+		if base == nil {
+			return ErrNilTransport
+		}
+
+		t.base = base
+
+		return nil
+	}
+}
+
+// WithSigningLabel sets the label of the signature, instead of DefaultLabel.
+func WithSigningLabel(label string) func(t *Transport) error {
+	return func(t *Transport) error {
+		if !isKey(label) {
+			return fmt.Errorf("%w: %q", ErrInvalidLabel, label)
+		}
+
+		t.label = label
+
+		return nil
+	}
+}
+
+// WithSigningComponents sets the components to cover, instead of the
+// DefaultSigningComponents. Header fields are given by their name, query parameters as
+// `@query-param;name="id"`. If "content-digest" is given, the Content-Digest header is
+// calculated if not already present.
+func WithSigningComponents(components ...string) func(t *Transport) error {
+	return func(t *Transport) error {
+		parsed := make([]component, 0, len(components))
+
+		for _, s := range components {
+			c, err := parseComponent(s)
+
+			if err != nil {
+				return err
+			}
+
+			parsed = append(parsed, c)
+		}
+
+		t.components = parsed
+		t.explicit = true
+
+		return nil
+	}
+}
+
+// WithSigningTag sets the tag parameter of the signatures.
+func WithSigningTag(tag string) func(t *Transport) error {
+	return func(t *Transport) error {
+		t.tag = tag
+
+		return nil
+	}
+}
+
+// WithSigningExpiry adds an expires parameter to the signatures, the given duration
+// after their creation.
+func WithSigningExpiry(d time.Duration) func(t *Transport) error {
+	return func(t *Transport) error {
+		if d <= 0 {
+			return ErrInvalidDuration
+		}
+
+		t.expiry = d
+
+		return nil
+	}
+}
+
+// WithSigningNonce adds a random nonce parameter to the signatures.
+func WithSigningNonce() func(t *Transport) error {
+	return func(t *Transport) error {
+		t.nonce = true
+
+		return nil
+	}
+}
+
+// NewTransport creates a new signing transport using the given key.
+func NewTransport(keyID string, key Key, options ...func(t *Transport) error) (*Transport, error) {
+	if keyID == "" {
+		return nil, ErrNoKeyID
+	}
+
+	// check early that key and algorithm fit together
+	if _, err := key.sign(nil); err != nil {
+		return nil, err
+	}
+
+	t := Transport{
+		base:  http.DefaultTransport,
+		keyID: keyID,
+		key:   key,
+		label: DefaultLabel,
+		now:   time.Now,
+	}
+
+	for _, c := range DefaultSigningComponents {
+		t.components = append(t.components, component{name: c})
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&t); err != nil {
+			return nil, err
+		}
+	}
+
+	return &t, nil
+}
+
+// hasBody checks if the request has a body.
+func hasBody(r *http.Request) bool {
+	return r.Body != nil && r.Body != http.NoBody
+}
+
+// sign adds the signature headers to the request.
+func (t *Transport) sign(r *http.Request) error {
+	components := slices.Clone(t.components)
+	covers := slices.ContainsFunc(components, func(c component) bool { return c.name == ComponentContentDigest })
+
+	if !t.explicit && hasBody(r) && !covers {
+		components = append(components, component{name: ComponentContentDigest})
+		covers = true
+	}
+
+	if covers && r.Header.Get(HeaderContentDigest) == "" {
+		var body []byte
+
+		if hasBody(r) {
+			var err error
+
+			body, err = io.ReadAll(r.Body)
+			_ = r.Body.Close()
+
+			if err != nil {
+				return fmt.Errorf("could not read body: %w", err)
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+		}
+
+		r.Header.Set(HeaderContentDigest, contentDigest(body))
+	}
+
+	created := t.now()
+	params := sfParams{{key: "created", value: created.Unix()}}
+
+	if t.expiry > 0 {
+		params = append(params, sfParam{key: "expires", value: created.Add(t.expiry).Unix()})
+	}
+
+	if t.nonce {
+		nonce := make([]byte, 16)
+		_, _ = rand.Read(nonce)
+		params = append(params, sfParam{key: "nonce", value: base64.RawURLEncoding.EncodeToString(nonce)})
+	}
+
+	params = append(params,
+		sfParam{key: "keyid", value: t.keyID},
+		sfParam{key: "alg", value: string(t.key.Algorithm)})
+
+	if t.tag != "" {
+		params = append(params, sfParam{key: "tag", value: t.tag})
+	}
+
+	base, serializedParams, err := signatureBase(r, components, params)
+
+	if err != nil {
+		return err
+	}
+
+	signature, err := t.key.sign(base)
+
+	if err != nil {
+		return err
+	}
+
+	r.Header.Add(HeaderSignatureInput, t.label+"="+serializedParams)
+	r.Header.Add(HeaderSignature, t.label+"="+serializeBareItem(signature))
+
+	return nil
+}
+
+// RoundTrip signs a copy of the request and sends it using the base transport.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	signed := r.Clone(r.Context())
+
+	if err := t.sign(signed); err != nil {
+		if hasBody(r) {
+			_ = r.Body.Close()
+		}
+
+		return nil, fmt.Errorf("could not sign request: %w", err)
+	}
+
+	return t.base.RoundTrip(signed) //nolint:wrapcheck // errors of the base transport are passed unchanged
+}
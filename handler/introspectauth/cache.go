@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package introspectauth
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// cacheEntry is a cached introspection result.
+type cacheEntry struct {
+	result  *Introspection
+	expires time.Time
+}
+
+// tokenCache holds the introspection results of active tokens. The tokens are stored
+// as hashes only.
+type tokenCache struct {
+	size    int
+	mtx     sync.Mutex
+	entries map[[sha256.Size]byte]cacheEntry
+}
+
+// newTokenCache creates a new cache holding at most size entries.
+func newTokenCache(size int) *tokenCache {
+	return &tokenCache{
+		size:    size,
+		entries: make(map[[sha256.Size]byte]cacheEntry, min(size, DefaultCacheSize)),
+	}
+}
+
+// get gives the cached result of the token, if it is present and not expired.
+func (c *tokenCache) get(token string, now time.Time) (*Introspection, bool) {
+	if c.size == 0 {
+		return nil, false
+	}
+
+	key := sha256.Sum256([]byte(token))
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	entry, found := c.entries[key]
+
+	if !found {
+		return nil, false
+	}
+
+	if !now.Before(entry.expires) {
+		delete(c.entries, key)
+
+		return nil, false
+	}
+
+	return entry.result, true
+}
+
+// put stores the result of the token until the given expiry. If the cache is full,
+// expired entries are removed first. If there are none, an arbitrary entry is dropped.
+func (c *tokenCache) put(token string, result *Introspection, expires, now time.Time) {
+	if c.size == 0 {
+		return
+	}
+
+	key := sha256.Sum256([]byte(token))
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if _, found := c.entries[key]; !found && len(c.entries) >= c.size {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+
+		for k := range c.entries {
+			if len(c.entries) < c.size {
+				break
+			}
+
+			delete(c.entries, k)
+		}
+	}
+
+	c.entries[key] = cacheEntry{result: result, expires: expires}
+}
+
+// len gives the number of cached results.
+func (c *tokenCache) len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return len(c.entries)
+}
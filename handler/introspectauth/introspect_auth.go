@@ -0,0 +1,419 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package introspectauth implements the authentication of bearer tokens using OAuth 2.0
+// token introspection as described in RFC 7662.
+package introspectauth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// ErrNilOption is returned when an option is nil.
+var ErrNilOption = errors.New("option cannot be nil")
+
+// ErrNoEndpoint is returned when no introspection endpoint is configured.
+var ErrNoEndpoint = errors.New("no introspection endpoint configured")
+
+// ErrNilClient is returned when the configured HTTP client is nil.
+var ErrNilClient = errors.New("http client cannot be nil")
+
+// ErrInvalidCacheSize is returned when the cache size is negative.
+var ErrInvalidCacheSize = errors.New("cache size must not be negative")
+
+// ErrMalformedHeader is returned when the authorization header does not contain a valid bearer token.
+var ErrMalformedHeader = errors.New("malformed bearer authorization header")
+
+// ErrIntrospectionFailed is returned when the introspection endpoint does not give a valid answer.
+var ErrIntrospectionFailed = errors.New("token introspection failed")
+
+// DefaultCacheSize is the maximum number of cached introspection results, if not configured otherwise.
+const DefaultCacheSize = 10_000
+
+// DefaultTimeout is the timeout of the default HTTP client.
+const DefaultTimeout = 10 * time.Second
+
+// maxResponseSize limits the size of the introspection responses read.
+const maxResponseSize = 1 << 20
+
+// Introspection holds the standard members of an introspection response (RFC 7662,
+// section 2.2). All members, including extensions, are available in Claims.
+type Introspection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"` //nolint:tagliatelle // defined by RFC 7662
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"` //nolint:tagliatelle // defined by RFC 7662
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Nbf       int64  `json:"nbf,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	JTI       string `json:"jti,omitempty"`
+
+	Claims map[string]any `json:"-"`
+}
+
+// Scopes gives the scopes of the token.
+func (i *Introspection) Scopes() []string {
+	return strings.Fields(i.Scope)
+}
+
+// validAt checks if the token is active at the given time, considering exp and nbf.
+func (i *Introspection) validAt(now time.Time) bool {
+	if !i.Active {
+		return false
+	}
+
+	if i.Exp != 0 && !now.Before(time.Unix(i.Exp, 0)) {
+		return false
+	}
+
+	return i.Nbf == 0 || !now.Before(time.Unix(i.Nbf, 0))
+}
+
+// principal creates the principal of the token owner. The name is the username, if
+// given, otherwise the subject or, for client credential tokens, the client id.
+func (i *Introspection) principal() *defs.Principal {
+	name := i.Username
+
+	for _, alt := range []string{i.Sub, i.ClientID} {
+		if name == "" {
+			name = alt
+		}
+	}
+
+	return &defs.Principal{
+		Name:   name,
+		Method: "bearer",
+		Scopes: i.Scopes(),
+		Claims: maps.Clone(i.Claims),
+	}
+}
+
+// Handler holds the internal data of the token introspection middleware.
+type Handler struct {
+	defs.MWBase
+
+	endpoint       string           // endpoint is the URL of the introspection endpoint
+	clientID       string           // clientID to authenticate at the introspection endpoint
+	clientSecret   string           // clientSecret to authenticate at the introspection endpoint
+	client         *http.Client     // client used for the introspection requests
+	realm          string           // realm reported in the challenges
+	requiredScopes []string         // requiredScopes all have to be granted to the token
+	cacheSize      int              // cacheSize is the maximum number of cached results
+	cacheMaxTTL    time.Duration    // cacheMaxTTL limits the caching time, 0 caches until expiry
+	now            func() time.Time // now gives the current time, replaceable for testing
+
+	cache *tokenCache
+}
+
+// GetMWBase returns the MWBase instance of the handler.
+func (h *Handler) GetMWBase() *defs.MWBase {
+	if h == nil {
+		return nil
+	}
+
+	return &h.MWBase
+}
+
+// ExtractToken gets the bearer token from the given authorization header value. If
+// the header does not use the bearer scheme, found is false.
+func ExtractToken(auth string) (token string, found bool, err error) {
+	scheme, token, _ := strings.Cut(strings.TrimSpace(auth), " ")
+
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", false, nil
+	}
+
+	token = strings.TrimSpace(token)
+
+	if token == "" || strings.ContainsAny(token, " \t,") {
+		return "", true, ErrMalformedHeader
+	}
+
+	return token, true, nil
+}
+
+// introspect asks the introspection endpoint about the token.
+func (h *Handler) introspect(r *http.Request, token string) (*Introspection, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+
+	req, reqErr := http.NewRequestWithContext(r.Context(), http.MethodPost, h.endpoint,
+		strings.NewReader(form.Encode()))
+
+	if reqErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIntrospectionFailed, reqErr)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if h.clientID != "" {
+		// RFC 6749, section 2.3.1 requires the credentials to be form encoded first
+		req.SetBasicAuth(url.QueryEscape(h.clientID), url.QueryEscape(h.clientSecret))
+	}
+
+	resp, respErr := h.client.Do(req)
+
+	if respErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIntrospectionFailed, respErr)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: endpoint answered %v", ErrIntrospectionFailed, resp.StatusCode)
+	}
+
+	body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+
+	if readErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIntrospectionFailed, readErr)
+	}
+
+	var result Introspection
+
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIntrospectionFailed, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&result.Claims); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrIntrospectionFailed, err)
+	}
+
+	return &result, nil
+}
+
+// lookup gives the introspection result of the token, either from the cache or from
+// the introspection endpoint.
+func (h *Handler) lookup(r *http.Request, token string) (*Introspection, error) {
+	now := h.now()
+
+	if result, found := h.cache.get(token, now); found {
+		return result, nil
+	}
+
+	result, err := h.introspect(r, token)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// only active tokens with a known expiry are cached, others could stay valid forever
+	if result.validAt(now) && result.Exp != 0 {
+		expires := time.Unix(result.Exp, 0)
+
+		if h.cacheMaxTTL > 0 && now.Add(h.cacheMaxTTL).Before(expires) {
+			expires = now.Add(h.cacheMaxTTL)
+		}
+
+		h.cache.put(token, result, expires, now)
+	}
+
+	return result, nil
+}
+
+// ServeHTTP implements the token introspection functionality.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !helper.IntroCheck(h, w, r) {
+		return
+	}
+
+	token, found, tokenErr := ExtractToken(r.Header.Get("Authorization"))
+
+	if !found {
+		h.sendChallenge(w, http.StatusUnauthorized, "", "")
+
+		return
+	}
+
+	if tokenErr != nil {
+		h.Log().Debug("could not process bearer token", slog.String("error", tokenErr.Error()))
+		h.sendChallenge(w, http.StatusBadRequest, "invalid_request", "")
+
+		return
+	}
+
+	result, lookupErr := h.lookup(r, token)
+
+	if lookupErr != nil {
+		h.Log().Error("token introspection error", slog.String("error", lookupErr.Error()))
+		helper.WriteState(w, h.Log(), http.StatusServiceUnavailable)
+
+		return
+	}
+
+	if !result.validAt(h.now()) {
+		h.sendChallenge(w, http.StatusUnauthorized, "invalid_token", "")
+
+		return
+	}
+
+	scopes := result.Scopes()
+
+	for _, s := range h.requiredScopes {
+		if !slices.Contains(scopes, s) {
+			h.sendChallenge(w, http.StatusForbidden, "insufficient_scope", strings.Join(h.requiredScopes, " "))
+
+			return
+		}
+	}
+
+	r = r.WithContext(defs.ContextWithPrincipal(r.Context(), result.principal()))
+
+	h.Next().ServeHTTP(w, r)
+}
+
+// sendChallenge sends the bearer challenge (RFC 6750, section 3) with the given error.
+func (h *Handler) sendChallenge(w http.ResponseWriter, status int, errCode, scope string) {
+	challenge := `Bearer realm="` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(h.realm) + `"`
+
+	if errCode != "" {
+		challenge += `, error="` + errCode + `"`
+	}
+
+	if scope != "" {
+		challenge += `, scope="` + scope + `"`
+	}
+
+	w.Header().Set("WWW-Authenticate", challenge)
+	helper.WriteState(w, h.Log(), status)
+}
+
+// WithEndpoint sets the URL of the introspection endpoint of the authorization server.
+func WithEndpoint(endpoint string) func(h *Handler) error {
+	return func(h *Handler) error {
+		if endpoint == "" {
+			return ErrNoEndpoint
+		}
+
+		h.endpoint = endpoint
+
+		return nil
+	}
+}
+
+// WithClientCredentials sets the credentials used to authenticate at the introspection
+// endpoint using HTTP basic authentication.
+func WithClientCredentials(clientID, clientSecret string) func(h *Handler) error {
+	return func(h *Handler) error {
+		h.clientID = clientID
+		h.clientSecret = clientSecret
+
+		return nil
+	}
+}
+
+// WithHTTPClient sets the HTTP client used for the introspection requests.
+func WithHTTPClient(client *http.Client) func(h *Handler) error {
+	return func(h *Handler) error {
+		if client == nil {
+			return ErrNilClient
+		}
+
+		h.client = client
+
+		return nil
+	}
+}
+
+// WithRealm sets the realm to use.
+func WithRealm(realm string) func(h *Handler) error {
+	return func(h *Handler) error {
+		h.realm = realm
+
+		return nil
+	}
+}
+
+// WithRequiredScopes sets scopes that all have to be granted to a token. Tokens
+// missing one of them are rejected with the insufficient_scope error.
+func WithRequiredScopes(scopes ...string) func(h *Handler) error {
+	return func(h *Handler) error {
+		h.requiredScopes = append(h.requiredScopes, scopes...)
+
+		return nil
+	}
+}
+
+// WithCache configures the cache of active introspection results. Results are cached
+// until the token expires, but at most for maxTTL, if it is greater than 0. A size of
+// 0 disables the cache. As revoked tokens stay valid while they are cached, maxTTL
+// limits the time until a revocation takes effect.
+func WithCache(size int, maxTTL time.Duration) func(h *Handler) error {
+	return func(h *Handler) error {
+		if size < 0 {
+			return ErrInvalidCacheSize
+		}
+
+		h.cacheSize = size
+		h.cacheMaxTTL = max(0, maxTTL)
+
+		return nil
+	}
+}
+
+// WithLogger configures the logger to use.
+func WithLogger(log *slog.Logger) func(h *Handler) error {
+	return defs.WithLogger[*Handler](log)
+}
+
+// WithLogLevel configures the log level to use with the logger.
+func WithLogLevel(level slog.Level) func(h *Handler) error {
+	return defs.WithLogLevel[*Handler](level)
+}
+
+// New generates a new token introspection middleware.
+func New(options ...func(handler *Handler) error) (defs.Middleware, error) {
+	handler := Handler{
+		client:    &http.Client{Timeout: DefaultTimeout},
+		cacheSize: DefaultCacheSize,
+		now:       time.Now,
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&handler); err != nil {
+			return nil, err
+		}
+	}
+
+	if handler.endpoint == "" {
+		return nil, ErrNoEndpoint
+	}
+
+	if handler.realm == "" {
+		handler.realm = "Restricted"
+	}
+
+	handler.cache = newTokenCache(handler.cacheSize)
+
+	return func(next http.Handler) http.Handler {
+		if err := handler.SetNext(next); err != nil {
+			return nil
+		}
+
+		return &handler
+	}, nil
+}
@@ -0,0 +1,18 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package introspectauth
+
+import "time"
+
+// The following functions are used for internal testing and are not visible to normal library users.
+
+// TSetNow replaces the time source of the given handler.
+func TSetNow(h *Handler, now func() time.Time) {
+	h.now = now
+}
+
+// TCacheLen gives the number of cached introspection results of the given handler.
+func TCacheLen(h *Handler) int {
+	return h.cache.len()
+}
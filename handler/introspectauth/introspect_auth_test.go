@@ -0,0 +1,464 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package introspectauth_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/introspectauth"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// testNow is the fixed point in time the tests run at.
+var testNow = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// authServer is a stand-in for the introspection endpoint of an authorization server.
+type authServer struct {
+	*httptest.Server
+
+	tokens map[string]map[string]any
+	calls  atomic.Int64
+}
+
+func newAuthServer(t *testing.T) *authServer {
+	t.Helper()
+
+	s := &authServer{
+		tokens: map[string]map[string]any{
+			"user-token": {
+				"active":   true,
+				"scope":    "read write",
+				"username": "alice",
+				"sub":      "user-1",
+				"exp":      testNow.Add(time.Hour).Unix(),
+				"tenant":   "acme",
+			},
+			"client-token": {
+				"active":    true,
+				"scope":     "read",
+				"client_id": "batch-job",
+				"exp":       testNow.Add(time.Hour).Unix(),
+			},
+			"expired-token": {
+				"active":   true,
+				"username": "alice",
+				"exp":      testNow.Add(-time.Minute).Unix(),
+			},
+			"future-token": {
+				"active":   true,
+				"username": "alice",
+				"nbf":      testNow.Add(time.Minute).Unix(),
+				"exp":      testNow.Add(time.Hour).Unix(),
+			},
+			"eternal-token": {
+				"active":   true,
+				"username": "bob",
+			},
+			"short-token": {
+				"active":   true,
+				"username": "bob",
+				"exp":      testNow.Add(time.Minute).Unix(),
+			},
+		},
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.calls.Add(1)
+
+		id, secret, hasAuth := r.BasicAuth()
+
+		if !hasAuth || id != "resource%3Aserver" || secret != "s%26cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+
+			return
+		}
+
+		if r.Method != http.MethodPost || r.PostFormValue("token_type_hint") != "access_token" {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		switch token := r.PostFormValue("token"); token {
+		case "broken-token":
+			_, _ = w.Write([]byte("{"))
+		case "error-token":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			claims, found := s.tokens[token]
+
+			if !found {
+				claims = map[string]any{"active": false}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(claims)
+		}
+	}))
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// newHandler creates the middleware under test, calling next with the principal found.
+func newHandler(t *testing.T, server *authServer, principal **defs.Principal,
+	options ...func(*introspectauth.Handler) error) *introspectauth.Handler {
+
+	t.Helper()
+
+	options = append([]func(*introspectauth.Handler) error{
+		introspectauth.WithEndpoint(server.URL),
+		introspectauth.WithClientCredentials("resource:server", "s&cret"),
+		introspectauth.WithRealm("api"),
+	}, options...)
+
+	h := helper.Must(introspectauth.New(options...))(
+		http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			*principal, _ = defs.PrincipalFromContext(r.Context())
+		}))
+
+	handler, isHandler := h.(*introspectauth.Handler)
+
+	if !isHandler {
+		t.Fatalf("wrong handler type")
+	}
+
+	introspectauth.TSetNow(handler, func() time.Time { return testNow })
+
+	return handler
+}
+
+func TestIntrospection(t *testing.T) {
+	t.Parallel()
+
+	server := newAuthServer(t)
+
+	tests := []struct {
+		Auth          string
+		WantStatus    int
+		WantChallenge string
+		WantName      string
+		WantScopes    []string
+	}{
+		{ // 0
+			Auth:       "Bearer user-token",
+			WantStatus: http.StatusOK,
+			WantName:   "alice",
+			WantScopes: []string{"read", "write"},
+		},
+		{ // 1
+			Auth:       "bearer client-token",
+			WantStatus: http.StatusOK,
+			WantName:   "batch-job",
+			WantScopes: []string{"read"},
+		},
+		{ // 2
+			Auth:          "",
+			WantStatus:    http.StatusUnauthorized,
+			WantChallenge: `Bearer realm="api"`,
+		},
+		{ // 3
+			Auth:          "Basic dXNlcjpwYXNz",
+			WantStatus:    http.StatusUnauthorized,
+			WantChallenge: `Bearer realm="api"`,
+		},
+		{ // 4
+			Auth:          "Bearer ",
+			WantStatus:    http.StatusBadRequest,
+			WantChallenge: `Bearer realm="api", error="invalid_request"`,
+		},
+		{ // 5
+			Auth:          "Bearer unknown-token",
+			WantStatus:    http.StatusUnauthorized,
+			WantChallenge: `Bearer realm="api", error="invalid_token"`,
+		},
+		{ // 6
+			Auth:          "Bearer expired-token",
+			WantStatus:    http.StatusUnauthorized,
+			WantChallenge: `Bearer realm="api", error="invalid_token"`,
+		},
+		{ // 7
+			Auth:          "Bearer future-token",
+			WantStatus:    http.StatusUnauthorized,
+			WantChallenge: `Bearer realm="api", error="invalid_token"`,
+		},
+		{ // 8
+			Auth:       "Bearer broken-token",
+			WantStatus: http.StatusServiceUnavailable,
+		},
+		{ // 9
+			Auth:       "Bearer error-token",
+			WantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for k, test := range tests {
+		var principal *defs.Principal
+
+		handler := newHandler(t, server, &principal)
+
+		req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+
+		if test.Auth != "" {
+			req.Header.Set("Authorization", test.Auth)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.WantStatus {
+			t.Errorf("%v: got status %v but wanted %v", k, rec.Code, test.WantStatus)
+		}
+
+		if got := rec.Header().Get("WWW-Authenticate"); got != test.WantChallenge {
+			t.Errorf("%v: got challenge %q but wanted %q", k, got, test.WantChallenge)
+		}
+
+		if test.WantStatus != http.StatusOK {
+			continue
+		}
+
+		if principal == nil {
+			t.Errorf("%v: principal not set", k)
+
+			continue
+		}
+
+		if principal.Name != test.WantName || principal.Method != "bearer" {
+			t.Errorf("%v: got principal %v/%v but wanted %v/bearer", k, principal.Name, principal.Method, test.WantName)
+		}
+
+		if !slices.Equal(principal.Scopes, test.WantScopes) {
+			t.Errorf("%v: got scopes %v but wanted %v", k, principal.Scopes, test.WantScopes)
+		}
+	}
+}
+
+func TestClaims(t *testing.T) {
+	t.Parallel()
+
+	server := newAuthServer(t)
+
+	var principal *defs.Principal
+
+	handler := newHandler(t, server, &principal)
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer user-token")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if principal == nil {
+		t.Fatalf("principal not set")
+	}
+
+	if principal.Claims["tenant"] != "acme" || principal.Claims["sub"] != "user-1" {
+		t.Errorf("got unexpected claims %v", principal.Claims)
+	}
+}
+
+func TestRequiredScopes(t *testing.T) {
+	t.Parallel()
+
+	server := newAuthServer(t)
+
+	tests := []struct {
+		Token      string
+		Scopes     []string
+		WantStatus int
+	}{
+		{Token: "user-token", Scopes: []string{"read"}, WantStatus: http.StatusOK},           // 0
+		{Token: "user-token", Scopes: []string{"read", "write"}, WantStatus: http.StatusOK},  // 1
+		{Token: "client-token", Scopes: []string{"write"}, WantStatus: http.StatusForbidden}, // 2
+		{Token: "client-token", Scopes: nil, WantStatus: http.StatusOK},                      // 3
+	}
+
+	for k, test := range tests {
+		var principal *defs.Principal
+
+		handler := newHandler(t, server, &principal, introspectauth.WithRequiredScopes(test.Scopes...))
+
+		req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+test.Token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.WantStatus {
+			t.Errorf("%v: got status %v but wanted %v", k, rec.Code, test.WantStatus)
+		}
+
+		want := `Bearer realm="api", error="insufficient_scope", scope="write"`
+
+		if test.WantStatus == http.StatusForbidden && rec.Header().Get("WWW-Authenticate") != want {
+			t.Errorf("%v: got challenge %q but wanted %q", k, rec.Header().Get("WWW-Authenticate"), want)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Token     string
+		Options   []func(*introspectauth.Handler) error
+		After     time.Duration
+		WantCalls int64
+		WantCache int
+		WantOK    bool
+	}{
+		{ // 0: cached until expiry
+			Token: "user-token", After: 30 * time.Minute,
+			WantCalls: 1, WantCache: 1, WantOK: true,
+		},
+		{ // 1: expired in the cache
+			Token: "short-token", After: 2 * time.Minute,
+			WantCalls: 2, WantCache: 0, WantOK: false,
+		},
+		{ // 2: limited cache time
+			Token:   "user-token",
+			Options: []func(*introspectauth.Handler) error{introspectauth.WithCache(10, time.Minute)},
+			After:   2 * time.Minute, WantCalls: 2, WantCache: 1, WantOK: true,
+		},
+		{ // 3: disabled cache
+			Token:   "user-token",
+			Options: []func(*introspectauth.Handler) error{introspectauth.WithCache(0, 0)},
+			After:   time.Second, WantCalls: 2, WantCache: 0, WantOK: true,
+		},
+		{ // 4: tokens without expiry are not cached
+			Token: "eternal-token", After: time.Second,
+			WantCalls: 2, WantCache: 0, WantOK: true,
+		},
+		{ // 5: inactive tokens are not cached
+			Token: "unknown-token", After: time.Second,
+			WantCalls: 2, WantCache: 0, WantOK: false,
+		},
+	}
+
+	for k, test := range tests {
+		server := newAuthServer(t)
+
+		var principal *defs.Principal
+
+		handler := newHandler(t, server, &principal, test.Options...)
+		now := testNow
+
+		introspectauth.TSetNow(handler, func() time.Time { return now })
+
+		var rec *httptest.ResponseRecorder
+
+		for range 2 {
+			req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+test.Token)
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			now = now.Add(test.After)
+		}
+
+		if calls := server.calls.Load(); calls != test.WantCalls {
+			t.Errorf("%v: got %v introspection calls but wanted %v", k, calls, test.WantCalls)
+		}
+
+		if size := introspectauth.TCacheLen(handler); size != test.WantCache {
+			t.Errorf("%v: got %v cached results but wanted %v", k, size, test.WantCache)
+		}
+
+		if (rec.Code == http.StatusOK) != test.WantOK {
+			t.Errorf("%v: got status %v but wanted success %v", k, rec.Code, test.WantOK)
+		}
+	}
+}
+
+func TestCacheSize(t *testing.T) {
+	t.Parallel()
+
+	server := newAuthServer(t)
+
+	var principal *defs.Principal
+
+	handler := newHandler(t, server, &principal, introspectauth.WithCache(1, 0))
+
+	for _, token := range []string{"user-token", "client-token", "user-token"} {
+		req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if size := introspectauth.TCacheLen(handler); size != 1 {
+		t.Errorf("got %v cached results but wanted 1", size)
+	}
+
+	if calls := server.calls.Load(); calls != 3 {
+		t.Errorf("got %v introspection calls but wanted 3", calls)
+	}
+}
+
+func TestExtractToken(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Auth      string
+		WantToken string
+		WantFound bool
+		WantErr   error
+	}{
+		{Auth: "Bearer abc", WantToken: "abc", WantFound: true},                           // 0
+		{Auth: "BEARER  abc ", WantToken: "abc", WantFound: true},                         // 1
+		{Auth: "Basic abc", WantFound: false},                                             // 2
+		{Auth: "", WantFound: false},                                                      // 3
+		{Auth: "Bearer", WantFound: true, WantErr: introspectauth.ErrMalformedHeader},     // 4
+		{Auth: "Bearer a b", WantFound: true, WantErr: introspectauth.ErrMalformedHeader}, // 5
+	}
+
+	for k, test := range tests {
+		token, found, err := introspectauth.ExtractToken(test.Auth)
+
+		if token != test.WantToken || found != test.WantFound || !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got %q, %v, %v but wanted %q, %v, %v",
+				k, token, found, err, test.WantToken, test.WantFound, test.WantErr)
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options []func(*introspectauth.Handler) error
+		WantErr error
+	}{
+		{Options: nil, WantErr: introspectauth.ErrNoEndpoint}, // 0
+		{ // 1
+			Options: []func(*introspectauth.Handler) error{introspectauth.WithEndpoint("")},
+			WantErr: introspectauth.ErrNoEndpoint,
+		},
+		{ // 2
+			Options: []func(*introspectauth.Handler) error{testEndpoint, introspectauth.WithHTTPClient(nil)},
+			WantErr: introspectauth.ErrNilClient,
+		},
+		{ // 3
+			Options: []func(*introspectauth.Handler) error{testEndpoint, introspectauth.WithCache(-1, 0)},
+			WantErr: introspectauth.ErrInvalidCacheSize,
+		},
+		{ // 4
+			Options: []func(*introspectauth.Handler) error{testEndpoint, introspectauth.WithHTTPClient(http.DefaultClient)},
+			WantErr: nil,
+		},
+	}
+
+	for k, test := range tests {
+		_, err := introspectauth.New(test.Options...)
+
+		if !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+}
@@ -6,8 +6,11 @@ package methodfilter
 
 import (
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/AlphaOne1/midgard/defs"
 	"github.com/AlphaOne1/midgard/helper"
@@ -16,12 +19,36 @@ import (
 // ErrNilOption is returned when an option is nil.
 var ErrNilOption = errors.New("option cannot be nil")
 
+// ErrInvalidPattern is returned when a path pattern cannot be used.
+var ErrInvalidPattern = errors.New("invalid path pattern")
+
+// methodSet is a set of allowed methods together with its Allow header.
+type methodSet struct {
+	methods map[string]bool
+	allow   string
+}
+
+// allows checks, if the method is allowed. HEAD is allowed wherever GET is.
+func (s methodSet) allows(method string) bool {
+	return s.methods[method] || (method == http.MethodHead && s.methods[http.MethodGet])
+}
+
 // Handler only lets configured HTTP methods pass.
 type Handler struct {
 	defs.MWBase
 
 	// Methods contains methods whitelist for the endpoint.
 	Methods map[string]bool
+	// PathMethods contains the methods whitelists per path pattern, overriding Methods
+	// for the matching requests.
+	PathMethods map[string]map[string]bool
+	// AnswerOptions lets the handler answer OPTIONS requests with the allowed methods,
+	// if OPTIONS is not allowed itself.
+	AnswerOptions bool
+
+	global   methodSet            // global is the set of Methods
+	paths    *http.ServeMux       // paths matches the path patterns
+	pathSets map[string]methodSet // pathSets are the sets of the path patterns
 }
 
 // GetMWBase returns the MWBase instance of the handler.
@@ -33,21 +60,58 @@ func (h *Handler) GetMWBase() *defs.MWBase {
 	return &h.MWBase
 }
 
-// ServeHTTP denies access (405) if the method is not in the whitelist.
+// methodSet gives the set of allowed methods for the request, that of the most specific
+// matching path pattern or, if none matches, the global one.
+func (h *Handler) methodSet(r *http.Request) methodSet {
+	if h.paths != nil {
+		if _, pattern := h.paths.Handler(r); pattern != "" {
+			return h.pathSets[pattern]
+		}
+	}
+
+	return h.global
+}
+
+// newMethodSet creates the set of the given methods.
+func (h *Handler) newMethodSet(methods map[string]bool) methodSet {
+	allow := make([]string, 0, len(methods)+2)
+
+	for m := range methods {
+		allow = append(allow, m)
+	}
+
+	if methods[http.MethodGet] && !methods[http.MethodHead] {
+		allow = append(allow, http.MethodHead)
+	}
+
+	if h.AnswerOptions && !methods[http.MethodOptions] {
+		allow = append(allow, http.MethodOptions)
+	}
+
+	slices.Sort(allow)
+
+	return methodSet{methods: methods, allow: strings.Join(allow, ", ")}
+}
+
+// ServeHTTP denies access (405) if the method is not in the whitelist, informing about
+// the allowed methods in the Allow header.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if !helper.IntroCheck(h, w, r) {
 		return
 	}
 
-	if h.Methods == nil {
-		h.Log().Error("method filter not initialized")
-		helper.WriteState(w, h.Log(), http.StatusServiceUnavailable)
+	set := h.methodSet(r)
+
+	if set.allows(r.Method) {
+		h.Next().ServeHTTP(w, r)
 
 		return
 	}
 
-	if h.Methods[r.Method] {
-		h.Next().ServeHTTP(w, r)
+	w.Header().Set("Allow", set.allow)
+
+	if r.Method == http.MethodOptions && h.AnswerOptions {
+		w.WriteHeader(http.StatusNoContent)
 
 		return
 	}
@@ -75,6 +139,43 @@ func WithMethods(methods []string) func(m *Handler) error {
 	}
 }
 
+// WithPathMethods allows the given methods for the requests matching the path pattern,
+// instead of those of WithMethods. The patterns are those of http.ServeMux without
+// method, e.g. "/api/" or "/users/{id}", the most specific matching one applies. If used
+// multiple times with the same pattern, the allowed methods of the different calls are
+// all enabled.
+func WithPathMethods(pattern string, methods []string) func(m *Handler) error {
+	return func(m *Handler) error {
+		if strings.ContainsAny(pattern, " \t") {
+			return fmt.Errorf("%w: %v: methods are given separately", ErrInvalidPattern, pattern)
+		}
+
+		if m.PathMethods == nil {
+			m.PathMethods = make(map[string]map[string]bool)
+		}
+
+		if m.PathMethods[pattern] == nil {
+			m.PathMethods[pattern] = make(map[string]bool, len(methods))
+		}
+
+		for _, v := range methods {
+			m.PathMethods[pattern][v] = true
+		}
+
+		return nil
+	}
+}
+
+// WithAnswerOptions lets the handler answer OPTIONS requests with the allowed methods in
+// the Allow header, if OPTIONS is not allowed itself.
+func WithAnswerOptions(answer bool) func(m *Handler) error {
+	return func(m *Handler) error {
+		m.AnswerOptions = answer
+
+		return nil
+	}
+}
+
 // WithLogger configures the logger to use.
 func WithLogger(log *slog.Logger) func(h *Handler) error {
 	return defs.WithLogger[*Handler](log)
@@ -85,7 +186,21 @@ func WithLogLevel(level slog.Level) func(h *Handler) error {
 	return defs.WithLogLevel[*Handler](level)
 }
 
+// register registers the pattern with the ServeMux, that panics on invalid patterns.
+func register(mux *http.ServeMux, pattern string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrInvalidPattern, r)
+		}
+	}()
+
+	mux.Handle(pattern, http.NotFoundHandler())
+
+	return nil
+}
+
 // New sets up the method filter middleware. Its parameters are functions manipulating an internal Config variable.
+// Without allowed methods, all requests are denied.
 func New(options ...func(m *Handler) error) (defs.Middleware, error) {
 	handler := Handler{}
 
@@ -99,6 +214,25 @@ func New(options ...func(m *Handler) error) (defs.Middleware, error) {
 		}
 	}
 
+	if handler.Methods == nil {
+		handler.Methods = make(map[string]bool)
+	}
+
+	handler.global = handler.newMethodSet(handler.Methods)
+
+	if len(handler.PathMethods) > 0 {
+		handler.paths = http.NewServeMux()
+		handler.pathSets = make(map[string]methodSet, len(handler.PathMethods))
+
+		for pattern, methods := range handler.PathMethods {
+			if err := register(handler.paths, pattern); err != nil {
+				return nil, err
+			}
+
+			handler.pathSets[pattern] = handler.newMethodSet(methods)
+		}
+	}
+
 	return func(next http.Handler) http.Handler {
 		if err := handler.SetNext(next); err != nil {
 			return nil
@@ -86,8 +86,13 @@ func TestMethodFilterUninitialized(t *testing.T) {
 
 	mw.ServeHTTP(rec, req)
 
-	if rec.Code != http.StatusServiceUnavailable {
-		t.Errorf("method filter did not work as expected, wanted %v but got %v", http.StatusServiceUnavailable, rec.Code)
+	// without configured methods, nothing is allowed
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("method filter did not work as expected, wanted %v but got %v", http.StatusMethodNotAllowed, rec.Code)
+	}
+
+	if allow, found := rec.Header()["Allow"]; !found || allow[0] != "" {
+		t.Errorf("wanted empty Allow header, but got %v", allow)
 	}
 }
 
@@ -109,8 +114,11 @@ func FuzzMethodFilter(f *testing.F) {
 
 		mw.ServeHTTP(rec, req)
 
-		if activeFilter[method] && rec.Code != http.StatusOK ||
-			!activeFilter[method] && rec.Code != http.StatusMethodNotAllowed {
+		// HEAD is allowed together with GET
+		allowed := activeFilter[method] || method == http.MethodHead
+
+		if allowed && rec.Code != http.StatusOK ||
+			!allowed && rec.Code != http.StatusMethodNotAllowed {
 
 			t.Errorf("method filter did not work as expected, method %v got %v", method, rec.Code)
 		}
@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package methodfilter_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlphaOne1/midgard/handler/methodfilter"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+func TestPathMethods(t *testing.T) {
+	t.Parallel()
+
+	mw := helper.Must(methodfilter.New(
+		methodfilter.WithMethods([]string{http.MethodGet}),
+		methodfilter.WithPathMethods("/api/", []string{http.MethodGet, http.MethodPost}),
+		methodfilter.WithPathMethods("/api/users/{id}", []string{http.MethodPut, http.MethodDelete}),
+		methodfilter.WithPathMethods("/api/users/{id}", []string{http.MethodGet}),
+		methodfilter.WithPathMethods("/upload", []string{http.MethodPut, http.MethodOptions}),
+		methodfilter.WithAnswerOptions(true),
+	))(http.HandlerFunc(helper.DummyHandler))
+
+	tests := []struct {
+		Method    string
+		Path      string
+		WantCode  int
+		WantAllow string
+	}{
+		{ // 0 global methods
+			Method:   http.MethodGet,
+			Path:     "/",
+			WantCode: http.StatusOK,
+		},
+		{ // 1
+			Method:    http.MethodPost,
+			Path:      "/",
+			WantCode:  http.StatusMethodNotAllowed,
+			WantAllow: "GET, HEAD, OPTIONS",
+		},
+		{ // 2 HEAD is allowed wherever GET is
+			Method:   http.MethodHead,
+			Path:     "/",
+			WantCode: http.StatusOK,
+		},
+		{ // 3 OPTIONS is answered by the filter
+			Method:    http.MethodOptions,
+			Path:      "/",
+			WantCode:  http.StatusNoContent,
+			WantAllow: "GET, HEAD, OPTIONS",
+		},
+		{ // 4 path methods
+			Method:   http.MethodPost,
+			Path:     "/api/orders",
+			WantCode: http.StatusOK,
+		},
+		{ // 5
+			Method:    http.MethodDelete,
+			Path:      "/api/orders",
+			WantCode:  http.StatusMethodNotAllowed,
+			WantAllow: "GET, HEAD, OPTIONS, POST",
+		},
+		{ // 6 the most specific pattern applies
+			Method:   http.MethodDelete,
+			Path:     "/api/users/42",
+			WantCode: http.StatusOK,
+		},
+		{ // 7
+			Method:    http.MethodPost,
+			Path:      "/api/users/42",
+			WantCode:  http.StatusMethodNotAllowed,
+			WantAllow: "DELETE, GET, HEAD, OPTIONS, PUT",
+		},
+		{ // 8
+			Method:    http.MethodOptions,
+			Path:      "/api/users/42",
+			WantCode:  http.StatusNoContent,
+			WantAllow: "DELETE, GET, HEAD, OPTIONS, PUT",
+		},
+		{ // 9 explicitly allowed OPTIONS is passed on
+			Method:   http.MethodOptions,
+			Path:     "/upload",
+			WantCode: http.StatusOK,
+		},
+		{ // 10 paths are matched exactly without trailing slash
+			Method:    http.MethodGet,
+			Path:      "/upload",
+			WantCode:  http.StatusMethodNotAllowed,
+			WantAllow: "OPTIONS, PUT",
+		},
+		{ // 11
+			Method:   http.MethodGet,
+			Path:     "/upload/file",
+			WantCode: http.StatusOK,
+		},
+	}
+
+	for k, test := range tests {
+		req := httptest.NewRequestWithContext(t.Context(), test.Method, test.Path, nil)
+		rec := httptest.NewRecorder()
+
+		mw.ServeHTTP(rec, req)
+
+		if rec.Code != test.WantCode {
+			t.Errorf("%v: got status %v, wanted %v", k, rec.Code, test.WantCode)
+		}
+
+		if got := rec.Header().Get("Allow"); got != test.WantAllow {
+			t.Errorf("%v: got Allow %q, wanted %q", k, got, test.WantAllow)
+		}
+	}
+}
+
+func TestOptionsNotAnswered(t *testing.T) {
+	t.Parallel()
+
+	mw := helper.Must(methodfilter.New(
+		methodfilter.WithMethods([]string{http.MethodPost})))(http.HandlerFunc(helper.DummyHandler))
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodOptions, "/", nil)
+	rec := httptest.NewRecorder()
+
+	mw.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status %v, wanted %v", rec.Code, http.StatusMethodNotAllowed)
+	}
+
+	if got := rec.Header().Get("Allow"); got != http.MethodPost {
+		t.Errorf("got Allow %q, wanted %q", got, http.MethodPost)
+	}
+}
+
+func TestPathMethodsErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options []func(*methodfilter.Handler) error
+	}{
+		{ // 0 methods are given separately
+			Options: []func(*methodfilter.Handler) error{
+				methodfilter.WithPathMethods("GET /api/", []string{http.MethodGet}),
+			},
+		},
+		{ // 1
+			Options: []func(*methodfilter.Handler) error{
+				methodfilter.WithPathMethods("", []string{http.MethodGet}),
+			},
+		},
+		{ // 2
+			Options: []func(*methodfilter.Handler) error{
+				methodfilter.WithPathMethods("/{unclosed", []string{http.MethodGet}),
+			},
+		},
+		{ // 3 conflicting patterns
+			Options: []func(*methodfilter.Handler) error{
+				methodfilter.WithPathMethods("/users/{id}", []string{http.MethodGet}),
+				methodfilter.WithPathMethods("/users/{name}", []string{http.MethodPost}),
+			},
+		},
+	}
+
+	for k, test := range tests {
+		if _, err := methodfilter.New(test.Options...); !errors.Is(err, methodfilter.ErrInvalidPattern) {
+			t.Errorf("%v: got error %v, wanted %v", k, err, methodfilter.ErrInvalidPattern)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package mtlsauth implements the authentication of clients using TLS client certificates.
+package mtlsauth
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// ErrNilOption is returned when an option is nil.
+var ErrNilOption = errors.New("option cannot be nil")
+
+// ErrNilRule is returned when a rule is nil.
+var ErrNilRule = errors.New("rule cannot be nil")
+
+// ErrNoCertificate is returned when the request does not contain a client certificate.
+var ErrNoCertificate = errors.New("no client certificate")
+
+// Rule decides if a verified client certificate is allowed to pass.
+type Rule func(cert *x509.Certificate) bool
+
+// Handler holds the internal data of the client certificate authentication middleware.
+type Handler struct {
+	defs.MWBase
+
+	rules     []Rule         // rules of which at least one has to match
+	clientCAs *x509.CertPool // clientCAs to verify the certificates against, if not done by the server
+}
+
+// GetMWBase returns the MWBase instance of the handler.
+func (h *Handler) GetMWBase() *defs.MWBase {
+	if h == nil {
+		return nil
+	}
+
+	return &h.MWBase
+}
+
+// Fingerprint calculates the hex encoded SHA-256 fingerprint of the given certificate.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// SPIFFEID gets the SPIFFE ID of the given certificate. As defined by the SPIFFE
+// specification, it is the only URI SAN with the spiffe scheme. Certificates with
+// several of them have no valid SPIFFE ID, so an empty string is returned.
+func SPIFFEID(cert *x509.Certificate) string {
+	result := ""
+
+	for _, u := range cert.URIs {
+		if u.Scheme != "spiffe" {
+			continue
+		}
+
+		if result != "" {
+			return ""
+		}
+
+		result = u.String()
+	}
+
+	return result
+}
+
+// Identity gets the name identifying the owner of the certificate. It is the SPIFFE
+// ID, if present, otherwise the common name of the subject.
+func Identity(cert *x509.Certificate) string {
+	if id := SPIFFEID(cert); id != "" {
+		return id
+	}
+
+	return cert.Subject.CommonName
+}
+
+// verifiedCertificate gets the verified leaf certificate of the request.
+func (h *Handler) verifiedCertificate(r *http.Request) (*x509.Certificate, error) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil, ErrNoCertificate
+	}
+
+	if h.clientCAs == nil {
+		if len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			return nil, ErrNoCertificate
+		}
+
+		return r.TLS.VerifiedChains[0][0], nil
+	}
+
+	intermediates := x509.NewCertPool()
+
+	for _, c := range r.TLS.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+
+	_, verifyErr := r.TLS.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         h.clientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	if verifyErr != nil {
+		return nil, helper.WrapIfError("could not verify client certificate", verifyErr)
+	}
+
+	return r.TLS.PeerCertificates[0], nil
+}
+
+// ServeHTTP implements the client certificate authentication.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !helper.IntroCheck(h, w, r) {
+		return
+	}
+
+	cert, certErr := h.verifiedCertificate(r)
+
+	if certErr != nil {
+		h.Log().Info("client certificate rejected",
+			slog.String("error", certErr.Error()),
+			slog.String("client", r.RemoteAddr))
+		helper.WriteState(w, h.Log(), http.StatusForbidden)
+
+		return
+	}
+
+	if len(h.rules) > 0 && !slices.ContainsFunc(h.rules, func(rule Rule) bool { return rule(cert) }) {
+		h.Log().Info("client certificate not allowed",
+			slog.String("subject", cert.Subject.String()),
+			slog.String("client", r.RemoteAddr))
+		helper.WriteState(w, h.Log(), http.StatusForbidden)
+
+		return
+	}
+
+	claims := map[string]any{
+		"subject":     cert.Subject.String(),
+		"issuer":      cert.Issuer.String(),
+		"fingerprint": Fingerprint(cert),
+		"dns_names":   cert.DNSNames,
+	}
+
+	if id := SPIFFEID(cert); id != "" {
+		claims["spiffe_id"] = id
+	}
+
+	r = r.WithContext(defs.ContextWithPrincipal(r.Context(), &defs.Principal{
+		Name:   Identity(cert),
+		Method: "mtls",
+		Claims: claims,
+	}))
+
+	h.Next().ServeHTTP(w, r)
+}
+
+// MatchSubject creates a Rule matching certificates whose subject, in the
+// RFC 2253 form as given by pkix.Name.String, equals one of the given names.
+func MatchSubject(subjects ...string) Rule {
+	return func(cert *x509.Certificate) bool {
+		return slices.Contains(subjects, cert.Subject.String())
+	}
+}
+
+// MatchCommonName creates a Rule matching certificates whose subject common name
+// equals one of the given names.
+func MatchCommonName(names ...string) Rule {
+	return func(cert *x509.Certificate) bool {
+		return slices.Contains(names, cert.Subject.CommonName)
+	}
+}
+
+// MatchDNSName creates a Rule matching certificates that have a DNS SAN matching one
+// of the given patterns. A pattern can start with "*." to match exactly one label.
+func MatchDNSName(patterns ...string) Rule {
+	return func(cert *x509.Certificate) bool {
+		for _, name := range cert.DNSNames {
+			for _, p := range patterns {
+				if matchDNS(p, name) {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+}
+
+// matchDNS matches the DNS name against the pattern.
+func matchDNS(pattern, name string) bool {
+	pattern = strings.ToLower(pattern)
+	name = strings.ToLower(name)
+
+	if suffix, found := strings.CutPrefix(pattern, "*."); found {
+		label, rest, ok := strings.Cut(name, ".")
+
+		return ok && label != "" && rest == suffix
+	}
+
+	return pattern == name
+}
+
+// MatchEmail creates a Rule matching certificates that have one of the given email SANs.
+func MatchEmail(emails ...string) Rule {
+	return func(cert *x509.Certificate) bool {
+		return slices.ContainsFunc(cert.EmailAddresses, func(e string) bool {
+			return slices.ContainsFunc(emails, func(want string) bool { return strings.EqualFold(want, e) })
+		})
+	}
+}
+
+// MatchSPIFFEID creates a Rule matching certificates with a SPIFFE ID matching one
+// of the given patterns. The patterns use the syntax of path.Match on the whole ID,
+// e.g. "spiffe://example.org/ns/*/sa/backend".
+func MatchSPIFFEID(patterns ...string) Rule {
+	return func(cert *x509.Certificate) bool {
+		id := SPIFFEID(cert)
+
+		if id == "" {
+			return false
+		}
+
+		return slices.ContainsFunc(patterns, func(p string) bool {
+			matched, err := path.Match(p, id)
+
+			return err == nil && matched
+		})
+	}
+}
+
+// MatchFingerprint creates a Rule matching certificates with one of the given SHA-256
+// fingerprints. The fingerprints are hex encoded, colons are ignored.
+func MatchFingerprint(fingerprints ...string) Rule {
+	normalized := make([]string, 0, len(fingerprints))
+
+	for _, f := range fingerprints {
+		normalized = append(normalized, strings.ToLower(strings.ReplaceAll(f, ":", "")))
+	}
+
+	return func(cert *x509.Certificate) bool {
+		return slices.Contains(normalized, Fingerprint(cert))
+	}
+}
+
+// MatchAll creates a Rule matching if all the given rules match.
+func MatchAll(rules ...Rule) Rule {
+	return func(cert *x509.Certificate) bool {
+		for _, rule := range rules {
+			if !rule(cert) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// WithRules adds rules the client certificates are checked against. A certificate is
+// allowed, if at least one rule matches. Without rules, every verified certificate is
+// allowed.
+func WithRules(rules ...Rule) func(h *Handler) error {
+	return func(h *Handler) error {
+		if slices.ContainsFunc(rules, func(rule Rule) bool { return rule == nil }) {
+			return ErrNilRule
+		}
+
+		h.rules = append(h.rules, rules...)
+
+		return nil
+	}
+}
+
+// WithClientCAs sets the certificate authorities the client certificates are verified
+// against. This is only needed, if the server does not verify the client certificates
+// itself, e.g. when using tls.RequestClientCert. Otherwise, the chains verified by the
+// server are used.
+func WithClientCAs(pool *x509.CertPool) func(h *Handler) error {
+	return func(h *Handler) error {
+		h.clientCAs = pool
+
+		return nil
+	}
+}
+
+// WithLogger configures the logger to use.
+func WithLogger(log *slog.Logger) func(h *Handler) error {
+	return defs.WithLogger[*Handler](log)
+}
+
+// WithLogLevel configures the log level to use with the logger.
+func WithLogLevel(level slog.Level) func(h *Handler) error {
+	return defs.WithLogLevel[*Handler](level)
+}
+
+// New generates a new client certificate authentication middleware.
+func New(options ...func(handler *Handler) error) (defs.Middleware, error) {
+	handler := Handler{}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&handler); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		if err := handler.SetNext(next); err != nil {
+			return nil
+		}
+
+		return &handler
+	}, nil
+}
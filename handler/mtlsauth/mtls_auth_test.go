@@ -0,0 +1,270 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package mtlsauth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/mtlsauth"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// testPKI contains a certificate authority and certificates issued by it.
+type testPKI struct {
+	ca      *x509.Certificate
+	pool    *x509.CertPool
+	backend *x509.Certificate
+	client  *x509.Certificate
+	foreign *x509.Certificate
+}
+
+// issue creates a certificate from the template, signed by the parent. If parent is nil,
+// the certificate is self-signed.
+func issue(t *testing.T, template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key := helper.Must(ecdsa.GenerateKey(elliptic.P256(), rand.Reader))
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	if parent == nil {
+		parent = template
+		parentKey = key
+	}
+
+	der := helper.Must(x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey))
+
+	return helper.Must(x509.ParseCertificate(der)), key
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+
+	ca, caKey := issue(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+
+	backend, _ := issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "backend", Organization: []string{"Example"}},
+		DNSNames:    []string{"backend.svc.example.org"},
+		URIs:        []*url.URL{helper.Must(url.Parse("spiffe://example.org/ns/prod/sa/backend"))},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+
+	client, _ := issue(t, &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "client"},
+		EmailAddresses: []string{"client@example.org"},
+		ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+
+	foreign, _ := issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "backend"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, nil, nil)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	return &testPKI{ca: ca, pool: pool, backend: backend, client: client, foreign: foreign}
+}
+
+// tlsState creates the connection state for a peer with the given certificate. If verified
+// is true, the state contains the verified chain as set up by a verifying server.
+func (p *testPKI) tlsState(cert *x509.Certificate, verified bool) *tls.ConnectionState {
+	state := tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+
+	if verified {
+		state.VerifiedChains = [][]*x509.Certificate{{cert, p.ca}}
+	}
+
+	return &state
+}
+
+// principalHandler writes the name of the principal found in the request.
+func principalHandler(w http.ResponseWriter, r *http.Request) {
+	p, found := defs.PrincipalFromContext(r.Context())
+
+	if !found {
+		_, _ = w.Write([]byte("none"))
+
+		return
+	}
+
+	_, _ = w.Write([]byte(p.Name))
+}
+
+func TestMTLSAuth(t *testing.T) {
+	t.Parallel()
+
+	pki := newTestPKI(t)
+
+	tests := []struct {
+		Rules     []mtlsauth.Rule
+		CAs       *x509.CertPool
+		State     *tls.ConnectionState
+		WantState int
+		WantName  string
+	}{
+		{ // 0
+			State:     nil,
+			WantState: http.StatusForbidden,
+		},
+		{ // 1
+			State:     pki.tlsState(pki.backend, false),
+			WantState: http.StatusForbidden,
+		},
+		{ // 2
+			State:     pki.tlsState(pki.backend, true),
+			WantState: http.StatusOK,
+			WantName:  "spiffe://example.org/ns/prod/sa/backend",
+		},
+		{ // 3
+			State:     pki.tlsState(pki.client, true),
+			WantState: http.StatusOK,
+			WantName:  "client",
+		},
+		{ // 4
+			Rules:     []mtlsauth.Rule{mtlsauth.MatchSPIFFEID("spiffe://example.org/ns/*/sa/backend")},
+			State:     pki.tlsState(pki.backend, true),
+			WantState: http.StatusOK,
+			WantName:  "spiffe://example.org/ns/prod/sa/backend",
+		},
+		{ // 5
+			Rules:     []mtlsauth.Rule{mtlsauth.MatchSPIFFEID("spiffe://example.org/ns/*/sa/backend")},
+			State:     pki.tlsState(pki.client, true),
+			WantState: http.StatusForbidden,
+		},
+		{ // 6
+			Rules:     []mtlsauth.Rule{mtlsauth.MatchDNSName("*.example.org")},
+			State:     pki.tlsState(pki.backend, true),
+			WantState: http.StatusForbidden,
+		},
+		{ // 7
+			Rules:     []mtlsauth.Rule{mtlsauth.MatchDNSName("*.Example.org", "backend.svc.example.org")},
+			State:     pki.tlsState(pki.backend, true),
+			WantState: http.StatusOK,
+			WantName:  "spiffe://example.org/ns/prod/sa/backend",
+		},
+		{ // 8
+			Rules:     []mtlsauth.Rule{mtlsauth.MatchSubject("CN=backend,O=Example")},
+			State:     pki.tlsState(pki.backend, true),
+			WantState: http.StatusOK,
+			WantName:  "spiffe://example.org/ns/prod/sa/backend",
+		},
+		{ // 9
+			Rules:     []mtlsauth.Rule{mtlsauth.MatchFingerprint(mtlsauth.Fingerprint(pki.client))},
+			State:     pki.tlsState(pki.client, true),
+			WantState: http.StatusOK,
+			WantName:  "client",
+		},
+		{ // 10
+			Rules:     []mtlsauth.Rule{mtlsauth.MatchFingerprint(mtlsauth.Fingerprint(pki.client))},
+			State:     pki.tlsState(pki.backend, true),
+			WantState: http.StatusForbidden,
+		},
+		{ // 11
+			Rules: []mtlsauth.Rule{
+				mtlsauth.MatchAll(mtlsauth.MatchCommonName("client"), mtlsauth.MatchEmail("CLIENT@example.org")),
+			},
+			State:     pki.tlsState(pki.client, true),
+			WantState: http.StatusOK,
+			WantName:  "client",
+		},
+		{ // 12
+			CAs:       pki.pool,
+			State:     pki.tlsState(pki.client, false),
+			WantState: http.StatusOK,
+			WantName:  "client",
+		},
+		{ // 13
+			CAs:       pki.pool,
+			Rules:     []mtlsauth.Rule{mtlsauth.MatchCommonName("backend")},
+			State:     pki.tlsState(pki.foreign, false),
+			WantState: http.StatusForbidden,
+		},
+	}
+
+	for k, test := range tests {
+		t.Run(fmt.Sprintf("TestMTLSAuth-%d", k), func(t *testing.T) {
+			t.Parallel()
+
+			handler := helper.Must(mtlsauth.New(
+				mtlsauth.WithRules(test.Rules...),
+				mtlsauth.WithClientCAs(test.CAs)))(
+				http.HandlerFunc(principalHandler))
+
+			req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+			req.TLS = test.State
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != test.WantState {
+				t.Errorf("got state %v but wanted %v", rec.Code, test.WantState)
+			}
+
+			if test.WantState == http.StatusOK && rec.Body.String() != test.WantName {
+				t.Errorf("got principal %v but wanted %v", rec.Body.String(), test.WantName)
+			}
+		})
+	}
+}
+
+func TestSPIFFEID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		URIs []string
+		Want string
+	}{
+		{URIs: nil, Want: ""}, // 0
+		{ // 1
+			URIs: []string{"https://example.org", "spiffe://example.org/sa/backend"},
+			Want: "spiffe://example.org/sa/backend",
+		},
+		{ // 2 several SPIFFE IDs are not valid
+			URIs: []string{"spiffe://example.org/sa/backend", "spiffe://example.org/sa/admin"},
+			Want: "",
+		},
+	}
+
+	for k, test := range tests {
+		cert := x509.Certificate{}
+
+		for _, u := range test.URIs {
+			cert.URIs = append(cert.URIs, helper.Must(url.Parse(u)))
+		}
+
+		if got := mtlsauth.SPIFFEID(&cert); got != test.Want {
+			t.Errorf("%v: got %q but wanted %q", k, got, test.Want)
+		}
+	}
+}
+
+func TestMTLSAuthNilRule(t *testing.T) {
+	t.Parallel()
+
+	if _, err := mtlsauth.New(mtlsauth.WithRules(nil)); !errors.Is(err, mtlsauth.ErrNilRule) {
+		t.Errorf("expected nil rule error, but got %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrNilCostFunc is returned when the cost function is nil.
+var ErrNilCostFunc = errors.New("cost function cannot be nil")
+
+// ErrInvalidMaxCost is returned when the maximum cost is not greater than 0.
+var ErrInvalidMaxCost = errors.New("maximum cost must be greater than 0")
+
+// DefaultMaxCost is the default maximum cost of a request. Requests costing more are
+// rejected without asking the limiter.
+const DefaultMaxCost = 10_000
+
+// ErrNoCostLimiter is returned when a cost function is used with a limiter that cannot
+// account for costs.
+var ErrNoCostLimiter = errors.New("limiter does not support costs")
+
+// CostFunc gives the cost of a request in cost units, the limits are expressed in. Cheap
+// requests may cost 1, while expensive ones, like exports or searches, cost more.
+type CostFunc func(r *http.Request) int64
+
+// CostLimiter is a Limiter that can account for several units at once.
+type CostLimiter interface {
+	Limiter
+
+	// LimitN gives true, if n units may pass, otherwise false. If they may not pass,
+	// none of them is accounted for.
+	LimitN(n int64) bool
+}
+
+// CostQuotaLimiter is a CostLimiter that also reports its quota.
+type CostQuotaLimiter interface {
+	CostLimiter
+	QuotaLimiter
+
+	// LimitNQuota gives true, if n units may pass, and the quota after the decision.
+	LimitNQuota(n int64) (bool, Quota)
+}
+
+// RequestCostLimiter is a RequestLimiter that can account for the cost of a request.
+type RequestCostLimiter interface {
+	RequestLimiter
+
+	// LimitRequestCost gives true, if the request of the given cost may pass, and the
+	// quota after the decision. If no quota is known for the request, ok is false.
+	LimitRequestCost(r *http.Request, cost int64) (allowed bool, quota Quota, ok bool)
+}
+
+// limitCost asks the limiter, if n units may pass. Requests without cost always pass,
+// limiters that cannot account for several units reject all others not costing
+// exactly 1. If the limiter reports its
+// quota, it is returned as well.
+func limitCost(l Limiter, n int64) (bool, Quota, bool) {
+	if n <= 0 {
+		return true, Quota{}, false
+	}
+
+	if n == 1 {
+		if q, ok := l.(QuotaLimiter); ok {
+			allowed, quota := q.LimitQuota()
+
+			return allowed, quota, true
+		}
+
+		return l.Limit(), Quota{}, false
+	}
+
+	switch c := l.(type) {
+	case CostQuotaLimiter:
+		allowed, quota := c.LimitNQuota(n)
+
+		return allowed, quota, true
+	case CostLimiter:
+		return c.LimitN(n), Quota{}, false
+	default:
+		return false, Quota{}, false
+	}
+}
+
+// CostByHeader uses the integer value of the given header as cost, e.g. set by an
+// upstream router. Requests without a valid, non-negative value cost fallback, values
+// above maximum are clamped to it. As clients could set the header themselves, it
+// should be removed at the edge.
+func CostByHeader(name string, fallback, maximum int64) CostFunc {
+	name = http.CanonicalHeaderKey(name)
+
+	return func(r *http.Request) int64 {
+		cost, err := strconv.ParseInt(strings.TrimSpace(r.Header.Get(name)), 10, 64)
+
+		if err != nil || cost < 0 {
+			return fallback
+		}
+
+		return min(cost, maximum)
+	}
+}
+
+// CostByRoute gives the cost of the pattern of the route matched by http.ServeMux.
+// Requests of other routes cost fallback. As with KeyByRoute, the pattern is only known
+// to middlewares registered for single routes.
+func CostByRoute(costs map[string]int64, fallback int64) CostFunc {
+	return func(r *http.Request) int64 {
+		if cost, ok := costs[r.Pattern]; ok {
+			return cost
+		}
+
+		return fallback
+	}
+}
+
+// CostByMethod gives the cost of the request method. Requests of other methods cost
+// fallback.
+func CostByMethod(costs map[string]int64, fallback int64) CostFunc {
+	return func(r *http.Request) int64 {
+		if cost, ok := costs[r.Method]; ok {
+			return cost
+		}
+
+		return fallback
+	}
+}
+
+// CostByQuerySize gives a cost of 1 plus 1 per started bytesPerUnit of the raw query, so
+// that requests with complex queries cost more.
+func CostByQuerySize(bytesPerUnit int64) CostFunc {
+	bytesPerUnit = max(1, bytesPerUnit)
+
+	return func(r *http.Request) int64 {
+		return 1 + (int64(len(r.URL.RawQuery))+bytesPerUnit-1)/bytesPerUnit
+	}
+}
+
+// CostSum adds the costs of all given functions, e.g. a base cost per route and the
+// cost of the query size.
+func CostSum(costs ...CostFunc) CostFunc {
+	return func(r *http.Request) int64 {
+		var sum int64
+
+		for _, cost := range costs {
+			sum += cost(r)
+		}
+
+		return sum
+	}
+}
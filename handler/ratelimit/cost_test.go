@@ -0,0 +1,236 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package ratelimit_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlphaOne1/midgard"
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+	"github.com/AlphaOne1/midgard/handler/ratelimit/gcra"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// passRequests is a RequestLimiter passing all requests, without cost support.
+type passRequests struct{}
+
+func (passRequests) LimitRequest(*http.Request) bool {
+	return true
+}
+
+func TestCostFuncs(t *testing.T) {
+	t.Parallel()
+
+	routes := map[string]int64{"GET /export": 50, "GET /search": 10}
+	methods := map[string]int64{http.MethodPost: 5}
+
+	tests := []struct {
+		Cost    ratelimit.CostFunc
+		Method  string
+		Target  string
+		Pattern string
+		Header  string
+		Want    int64
+	}{
+		{Cost: ratelimit.CostByHeader("x-cost", 1, 100), Header: "7", Want: 7},                     // 0
+		{Cost: ratelimit.CostByHeader("x-cost", 1, 100), Header: "", Want: 1},                      // 1
+		{Cost: ratelimit.CostByHeader("x-cost", 2, 100), Header: "-3", Want: 2},                    // 2
+		{Cost: ratelimit.CostByHeader("x-cost", 2, 100), Header: "many", Want: 2},                  // 3
+		{Cost: ratelimit.CostByHeader("x-cost", 1, 100), Header: "9223372036854775807", Want: 100}, // 4 clamped
+		{Cost: ratelimit.CostByRoute(routes, 1), Pattern: "GET /export", Want: 50},                 // 5
+		{Cost: ratelimit.CostByRoute(routes, 1), Pattern: "GET /", Want: 1},                        // 6
+		{Cost: ratelimit.CostByMethod(methods, 1), Method: http.MethodPost, Want: 5},               // 7
+		{Cost: ratelimit.CostByMethod(methods, 1), Method: http.MethodGet, Want: 1},                // 8
+		{Cost: ratelimit.CostByQuerySize(10), Target: "/?q=abc", Want: 2},                          // 9
+		{Cost: ratelimit.CostByQuerySize(10), Target: "/?q=abcdefghijk", Want: 3},                  // 10
+		{Cost: ratelimit.CostByQuerySize(10), Target: "/", Want: 1},                                // 11
+		{Cost: ratelimit.CostByQuerySize(0), Target: "/?ab", Want: 3},                              // 12
+		{Cost: ratelimit.CostSum(), Want: 0},                                                       // 13
+		{
+			Cost:    ratelimit.CostSum(ratelimit.CostByRoute(routes, 1), ratelimit.CostByQuerySize(10)),
+			Target:  "/search?q=abc",
+			Pattern: "GET /search",
+			Want:    12,
+		}, // 14
+	}
+
+	for k, test := range tests {
+		method := test.Method
+
+		if method == "" {
+			method = http.MethodGet
+		}
+
+		target := test.Target
+
+		if target == "" {
+			target = "/"
+		}
+
+		req := httptest.NewRequestWithContext(t.Context(), method, target, nil)
+		req.Pattern = test.Pattern
+
+		if test.Header != "" {
+			req.Header.Set("X-Cost", test.Header)
+		}
+
+		if got := test.Cost(req); got != test.Want {
+			t.Errorf("%v: got cost %v but wanted %v", k, got, test.Want)
+		}
+	}
+}
+
+func TestCostLimit(t *testing.T) {
+	t.Parallel()
+
+	handler := midgard.StackMiddlewareHandler(
+		[]defs.Middleware{helper.Must(ratelimit.New(
+			ratelimit.WithLimiter(helper.Must(gcra.New(gcra.WithRate(1), gcra.WithBurst(10)))),
+			ratelimit.WithCostFunc(ratelimit.CostByHeader("X-Cost", 1, 100))))},
+		http.HandlerFunc(helper.DummyHandler))
+
+	tests := []struct {
+		Cost           string
+		WantStatus     int
+		WantRateLimit  string
+		WantRetryAfter string
+	}{
+		{Cost: "4", WantStatus: http.StatusOK, WantRateLimit: `"default";r=6;t=4`},                                    // 0
+		{Cost: "5", WantStatus: http.StatusOK, WantRateLimit: `"default";r=1;t=9`},                                    // 1
+		{Cost: "2", WantStatus: http.StatusTooManyRequests, WantRateLimit: `"default";r=1;t=9`, WantRetryAfter: "1"},  // 2
+		{Cost: "", WantStatus: http.StatusOK, WantRateLimit: `"default";r=0;t=10`},                                    // 3
+		{Cost: "0", WantStatus: http.StatusOK},                                                                        // 4 free requests are not limited
+		{Cost: "5", WantStatus: http.StatusTooManyRequests, WantRateLimit: `"default";r=0;t=10`, WantRetryAfter: "5"}, // 5
+		{Cost: "11", WantStatus: http.StatusTooManyRequests, WantRateLimit: `"default";r=0;t=10`},                     // 6 never passes
+	}
+
+	for k, test := range tests {
+		req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+		req.Header.Set("X-Cost", test.Cost)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.WantStatus {
+			t.Errorf("%v: got status %v but wanted %v", k, rec.Code, test.WantStatus)
+		}
+
+		if got := rec.Header().Get("RateLimit"); got != test.WantRateLimit {
+			t.Errorf("%v: got RateLimit %q but wanted %q", k, got, test.WantRateLimit)
+		}
+
+		if got := rec.Header().Get("Retry-After"); got != test.WantRetryAfter {
+			t.Errorf("%v: got Retry-After %q but wanted %q", k, got, test.WantRetryAfter)
+		}
+
+		if got := rec.Header().Get("RateLimit-Policy"); test.WantRateLimit != "" && got != `"default";q=10;w=10` {
+			t.Errorf("%v: got RateLimit-Policy %q", k, got)
+		}
+	}
+}
+
+func TestMaxCost(t *testing.T) {
+	t.Parallel()
+
+	limiter := helper.Must(gcra.New(gcra.WithRate(1), gcra.WithBurst(10)))
+	handler := midgard.StackMiddlewareHandler(
+		[]defs.Middleware{helper.Must(ratelimit.New(
+			ratelimit.WithLimiter(limiter),
+			ratelimit.WithCostFunc(ratelimit.CostByHeader("X-Cost", 1, 100)),
+			ratelimit.WithMaxCost(5)))},
+		http.HandlerFunc(helper.DummyHandler))
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+	req.Header.Set("X-Cost", "6")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("got status %v but wanted %v", rec.Code, http.StatusTooManyRequests)
+	}
+
+	if got := rec.Header().Get("Retry-After"); got != "" {
+		t.Errorf("got Retry-After %q but wanted none", got)
+	}
+
+	if got := limiter.Remaining(); got != 10 {
+		t.Errorf("got %v remaining, but the limiter should not have been asked", got)
+	}
+}
+
+func TestKeyedCostLimit(t *testing.T) {
+	t.Parallel()
+
+	limiter := helper.Must(ratelimit.NewKeyed(
+		func(string) (ratelimit.Limiter, error) { return fixedQuota{allowed: true}, nil },
+		ratelimit.WithKeyFunc(ratelimit.KeyByHeader("X-Api-Key"))))
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+	req.Header.Set("X-Api-Key", "a")
+
+	// limiters without cost support only pass requests costing 1
+	if allowed, _, _ := limiter.LimitRequestCost(req, 1); !allowed {
+		t.Errorf("request costing 1 should pass")
+	}
+
+	if allowed, _, _ := limiter.LimitRequestCost(req, 2); allowed {
+		t.Errorf("request costing 2 should be rejected")
+	}
+}
+
+func TestCostOptionErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options []func(*ratelimit.Handler) error
+		WantErr error
+	}{
+		{ // 0
+			Options: []func(*ratelimit.Handler) error{
+				ratelimit.WithLimiter(fixedQuota{allowed: true}),
+				ratelimit.WithCostFunc(nil),
+			},
+			WantErr: ratelimit.ErrNilCostFunc,
+		},
+		{ // 1
+			Options: []func(*ratelimit.Handler) error{
+				ratelimit.WithLimiter(fixedQuota{allowed: true}),
+				ratelimit.WithCostFunc(ratelimit.CostByQuerySize(100)),
+			},
+			WantErr: ratelimit.ErrNoCostLimiter,
+		},
+		{ // 2
+			Options: []func(*ratelimit.Handler) error{
+				ratelimit.WithRequestLimiter(passRequests{}),
+				ratelimit.WithCostFunc(ratelimit.CostByQuerySize(100)),
+			},
+			WantErr: ratelimit.ErrNoCostLimiter,
+		},
+		{ // 3
+			Options: []func(*ratelimit.Handler) error{
+				ratelimit.WithLimiter(helper.Must(gcra.New())),
+				ratelimit.WithCostFunc(ratelimit.CostByQuerySize(100)),
+			},
+			WantErr: nil,
+		},
+		{ // 4
+			Options: []func(*ratelimit.Handler) error{
+				ratelimit.WithLimiter(helper.Must(gcra.New())),
+				ratelimit.WithMaxCost(0),
+			},
+			WantErr: ratelimit.ErrInvalidMaxCost,
+		},
+	}
+
+	for k, test := range tests {
+		if _, err := ratelimit.New(test.Options...); !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+}
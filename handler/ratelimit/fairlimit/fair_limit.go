@@ -0,0 +1,452 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package fairlimit provides a request limiter dividing a shared capacity fairly among
+// the active clients, so that a single client cannot starve the others.
+package fairlimit
+
+import (
+	"container/list"
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+)
+
+// ErrNilOption is returned when an option is nil.
+var ErrNilOption = errors.New("option cannot be nil")
+
+// ErrZeroRate is returned when the rate is not greater than 0.
+var ErrZeroRate = errors.New("rate must be greater than 0")
+
+// ErrZeroBurst is returned when the burst is not greater than 0.
+var ErrZeroBurst = errors.New("burst must be greater than 0")
+
+// ErrZeroQuantum is returned when the quantum is not greater than 0.
+var ErrZeroQuantum = errors.New("quantum must be greater than 0")
+
+// ErrInvalidMaxQueue is returned when the maximum queue length is not greater than 0.
+var ErrInvalidMaxQueue = errors.New("maximum queue length must be greater than 0")
+
+// ErrNilWeightFunc is returned when the weight function is nil.
+var ErrNilWeightFunc = errors.New("weight function cannot be nil")
+
+const (
+	// DefaultQuantum is the default number of cost units a key of weight 1 may consume
+	// per round.
+	DefaultQuantum = 1
+	// DefaultMaxQueue is the default maximum number of waiting requests per key.
+	DefaultMaxQueue = 100
+	// DefaultQueueTimeout is the default maximum time a request waits in the queue.
+	DefaultQueueTimeout = time.Second
+)
+
+// WeightFunc gives the weight of a key. A key of weight 2 gets twice the capacity of a
+// key of weight 1, if both have requests waiting.
+type WeightFunc func(key string) float64
+
+// waiter is a request waiting in the queue of its key.
+type waiter struct {
+	cost    int64
+	ready   chan struct{} // ready is closed, when the waiter was granted
+	granted bool
+}
+
+// tenant holds the queue of a key with waiting requests.
+type tenant struct {
+	key     string
+	weight  float64
+	deficit float64       // deficit is the cost the tenant may still consume in this round
+	queue   *list.List    // queue holds the waiters, the oldest at the front
+	elem    *list.Element // elem is the element of the tenant in the active list
+}
+
+// FairLimiter is a ratelimit.RequestLimiter sharing a capacity of Rate cost units per
+// second among the keys of the requests. While the capacity suffices, requests pass
+// immediately. Otherwise, they wait in a queue per key, and the queues are served by
+// deficit round robin: each round, every key with waiting requests may consume its
+// quantum times its weight. So each active key gets its share of the capacity, no
+// matter how many requests it sends.
+type FairLimiter struct {
+	// Rate is the capacity in cost units per second.
+	Rate float64
+	// Burst is the capacity that may be used at once.
+	Burst int64
+	// Quantum is the number of cost units a key of weight 1 may consume per round.
+	Quantum int64
+	// MaxQueue is the maximum number of waiting requests per key.
+	MaxQueue int
+	// QueueTimeout is the maximum time a request waits in the queue.
+	QueueTimeout time.Duration
+
+	mtx      sync.Mutex
+	key      ratelimit.KeyFunc
+	weight   WeightFunc
+	tokens   float64            // tokens is the capacity available at last
+	last     time.Time          // last is the time tokens was calculated
+	tenants  map[string]*tenant // tenants hold the keys with waiting requests
+	active   *list.List         // active holds the tenants in round robin order
+	credited bool               // credited is set, when the front tenant got its quantum
+	stop     func() bool        // stop cancels the pending dispatch
+
+	now       func() time.Time
+	afterFunc func(d time.Duration, f func()) func() bool
+}
+
+// LimitRequest gives true, if the request may pass, waiting for its share of the
+// capacity if necessary.
+func (l *FairLimiter) LimitRequest(r *http.Request) bool {
+	allowed, _, _ := l.LimitRequestCost(r, 1)
+
+	return allowed
+}
+
+// LimitRequestQuota is LimitRequest for the use with the rate limiter middleware. The
+// limiter reports no quota, as the share of a key depends on the other keys.
+func (l *FairLimiter) LimitRequestQuota(r *http.Request) (bool, ratelimit.Quota, bool) {
+	return l.LimitRequestCost(r, 1)
+}
+
+// LimitRequestCost gives true, if the request of the given cost may pass, waiting for
+// its share of the capacity if necessary. Requests costing more than the burst are
+// rejected. The limiter reports no quota.
+func (l *FairLimiter) LimitRequestCost(r *http.Request, cost int64) (bool, ratelimit.Quota, bool) {
+	if cost <= 0 {
+		return true, ratelimit.Quota{}, false
+	}
+
+	if cost > l.Burst {
+		return false, ratelimit.Quota{}, false
+	}
+
+	key := l.key(r)
+
+	l.mtx.Lock()
+
+	now := l.now()
+	l.refill(now)
+
+	if l.active.Len() == 0 && l.tokens >= float64(cost) {
+		l.tokens -= float64(cost)
+		l.mtx.Unlock()
+
+		return true, ratelimit.Quota{}, false
+	}
+
+	t := l.tenant(key)
+
+	if t.queue.Len() >= l.MaxQueue {
+		l.mtx.Unlock()
+
+		return false, ratelimit.Quota{}, false
+	}
+
+	w := &waiter{cost: cost, ready: make(chan struct{})}
+	elem := t.queue.PushBack(w)
+	l.dispatch(now)
+
+	if w.granted {
+		l.mtx.Unlock()
+
+		return true, ratelimit.Quota{}, false
+	}
+
+	timeout := make(chan struct{})
+	stop := l.afterFunc(l.QueueTimeout, func() { close(timeout) })
+	l.mtx.Unlock()
+
+	defer stop()
+
+	select {
+	case <-w.ready:
+		return true, ratelimit.Quota{}, false
+	case <-timeout:
+	case <-r.Context().Done():
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if w.granted {
+		// the capacity was granted while giving up, so it is used anyway
+		return true, ratelimit.Quota{}, false
+	}
+
+	l.remove(t, elem)
+	l.dispatch(l.now())
+
+	return false, ratelimit.Quota{}, false
+}
+
+// tenant gives the tenant of the key, adding it to the end of the round if it has no
+// waiting requests yet.
+func (l *FairLimiter) tenant(key string) *tenant {
+	if t, found := l.tenants[key]; found {
+		return t
+	}
+
+	weight := l.weight(key)
+
+	if weight <= 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+		weight = 1
+	}
+
+	t := &tenant{key: key, weight: weight, queue: list.New()}
+	t.elem = l.active.PushBack(t)
+	l.tenants[key] = t
+
+	return t
+}
+
+// remove removes the waiter from the queue of the tenant, dropping the tenant if it has
+// no waiting requests left.
+func (l *FairLimiter) remove(t *tenant, elem *list.Element) {
+	t.queue.Remove(elem)
+
+	if t.queue.Len() > 0 {
+		return
+	}
+
+	if l.active.Front() == t.elem {
+		l.credited = false
+	}
+
+	l.active.Remove(t.elem)
+	delete(l.tenants, t.key)
+}
+
+// refill adds the capacity restored since the last calculation.
+func (l *FairLimiter) refill(now time.Time) {
+	if elapsed := now.Sub(l.last).Seconds(); elapsed > 0 {
+		l.tokens = min(float64(l.Burst), l.tokens+elapsed*l.Rate)
+	}
+
+	l.last = now
+}
+
+// dispatch grants the waiting requests by deficit round robin, as long as the capacity
+// suffices. If it runs out, the next dispatch is scheduled for when the capacity
+// suffices for the next request.
+func (l *FairLimiter) dispatch(now time.Time) {
+	l.refill(now)
+
+	for l.active.Len() > 0 {
+		elem := l.active.Front()
+		t := elem.Value.(*tenant) //nolint:forcetypeassert // only tenants are active
+
+		if !l.credited {
+			t.deficit += float64(l.Quantum) * t.weight
+			l.credited = true
+		}
+
+		for t.queue.Len() > 0 {
+			w := t.queue.Front().Value.(*waiter) //nolint:forcetypeassert // only waiters are queued
+			cost := float64(w.cost)
+
+			if cost > t.deficit {
+				break
+			}
+
+			if cost > l.tokens {
+				l.schedule(cost - l.tokens)
+
+				return
+			}
+
+			l.tokens -= cost
+			t.deficit -= cost
+			t.queue.Remove(t.queue.Front())
+			w.granted = true
+			close(w.ready)
+		}
+
+		l.credited = false
+
+		if t.queue.Len() == 0 {
+			l.active.Remove(elem)
+			delete(l.tenants, t.key)
+
+			continue
+		}
+
+		l.active.MoveToBack(elem)
+	}
+}
+
+// schedule schedules the next dispatch for when the missing capacity is restored.
+func (l *FairLimiter) schedule(missing float64) {
+	if l.stop != nil {
+		l.stop()
+	}
+
+	wait := time.Duration(math.Ceil(missing / l.Rate * float64(time.Second)))
+
+	l.stop = l.afterFunc(wait, func() {
+		l.mtx.Lock()
+		defer l.mtx.Unlock()
+
+		l.stop = nil
+		l.dispatch(l.now())
+	})
+}
+
+// Queued gives the number of waiting requests.
+func (l *FairLimiter) Queued() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	n := 0
+
+	for _, t := range l.tenants {
+		n += t.queue.Len()
+	}
+
+	return n
+}
+
+// WithRate sets the capacity in cost units per second.
+func WithRate(r float64) func(l *FairLimiter) error {
+	return func(l *FairLimiter) error {
+		if r <= 0 {
+			return ErrZeroRate
+		}
+
+		l.Rate = r
+
+		return nil
+	}
+}
+
+// WithBurst sets the capacity that may be used at once.
+func WithBurst(n int64) func(l *FairLimiter) error {
+	return func(l *FairLimiter) error {
+		if n <= 0 {
+			return ErrZeroBurst
+		}
+
+		l.Burst = n
+
+		return nil
+	}
+}
+
+// WithQuantum sets the number of cost units a key of weight 1 may consume per round. It
+// should be at least the cost of typical requests.
+func WithQuantum(n int64) func(l *FairLimiter) error {
+	return func(l *FairLimiter) error {
+		if n <= 0 {
+			return ErrZeroQuantum
+		}
+
+		l.Quantum = n
+
+		return nil
+	}
+}
+
+// WithMaxQueue sets the maximum number of waiting requests per key.
+func WithMaxQueue(n int) func(l *FairLimiter) error {
+	return func(l *FairLimiter) error {
+		if n <= 0 {
+			return ErrInvalidMaxQueue
+		}
+
+		l.MaxQueue = n
+
+		return nil
+	}
+}
+
+// WithQueueTimeout sets the maximum time a request waits in the queue.
+func WithQueueTimeout(d time.Duration) func(l *FairLimiter) error {
+	return func(l *FairLimiter) error {
+		l.QueueTimeout = max(0, d)
+
+		return nil
+	}
+}
+
+// WithKeyFunc sets the function extracting the key of the requests, e.g. the tenant.
+// Without it, the requests are keyed by their client address. Requests without key
+// share one queue.
+func WithKeyFunc(key ratelimit.KeyFunc) func(l *FairLimiter) error {
+	return func(l *FairLimiter) error {
+		if key == nil {
+			return ratelimit.ErrNilKeyFunc
+		}
+
+		l.key = key
+
+		return nil
+	}
+}
+
+// WithWeightFunc sets the function giving the weight of a key. Without it, all keys
+// have the weight 1. Weights not greater than 0 are taken as 1.
+func WithWeightFunc(weight WeightFunc) func(l *FairLimiter) error {
+	return func(l *FairLimiter) error {
+		if weight == nil {
+			return ErrNilWeightFunc
+		}
+
+		l.weight = weight
+
+		return nil
+	}
+}
+
+// WithWeights sets the weights of single keys, as given by the key function, e.g.
+// "header:X-Tenant:acme". Other keys have the weight 1.
+func WithWeights(weights map[string]float64) func(l *FairLimiter) error {
+	return WithWeightFunc(func(key string) float64 {
+		if w, found := weights[key]; found {
+			return w
+		}
+
+		return 1
+	})
+}
+
+// New creates a new fair limiter. The rate has to be given, the burst defaults to the
+// rate.
+func New(options ...func(*FairLimiter) error) (*FairLimiter, error) {
+	limiter := FairLimiter{
+		Quantum:      DefaultQuantum,
+		MaxQueue:     DefaultMaxQueue,
+		QueueTimeout: DefaultQueueTimeout,
+		key:          ratelimit.KeyByIP(),
+		weight:       func(string) float64 { return 1 },
+		tenants:      make(map[string]*tenant),
+		active:       list.New(),
+		now:          time.Now,
+		afterFunc: func(d time.Duration, f func()) func() bool {
+			return time.AfterFunc(d, f).Stop
+		},
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&limiter); err != nil {
+			return nil, err
+		}
+	}
+
+	if limiter.Rate <= 0 {
+		return nil, ErrZeroRate
+	}
+
+	if limiter.Burst <= 0 {
+		limiter.Burst = max(1, int64(math.Ceil(limiter.Rate)))
+	}
+
+	limiter.tokens = float64(limiter.Burst)
+	limiter.last = limiter.now()
+
+	return &limiter, nil
+}
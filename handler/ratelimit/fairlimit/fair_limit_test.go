@@ -0,0 +1,338 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package fairlimit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+	"github.com/AlphaOne1/midgard/handler/ratelimit/fairlimit"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+var _ ratelimit.RequestCostLimiter = (*fairlimit.FairLimiter)(nil)
+
+// timer is a timer of the clock.
+type timer struct {
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+// clock is a time source with timers, advanced manually.
+type clock struct {
+	mtx    sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+func (c *clock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.now
+}
+
+func (c *clock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	t := &timer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+
+	return func() bool {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+
+		active := !t.stopped
+		t.stopped = true
+
+		return active
+	}
+}
+
+// advance advances the clock, firing the timers due in their order.
+func (c *clock) advance(d time.Duration) {
+	c.mtx.Lock()
+	target := c.now.Add(d)
+
+	for {
+		var next *timer
+
+		for _, t := range c.timers {
+			if !t.stopped && !t.at.After(target) && (next == nil || t.at.Before(next.at)) {
+				next = t
+			}
+		}
+
+		if next == nil {
+			break
+		}
+
+		next.stopped = true
+		c.now = next.at
+		c.mtx.Unlock()
+		next.f()
+		c.mtx.Lock()
+	}
+
+	c.now = target
+	c.mtx.Unlock()
+}
+
+// waitFor waits until the condition is met.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	for start := time.Now(); !cond(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("condition not met in time")
+		}
+	}
+}
+
+// newLimiter creates a limiter keyed by the tenant header using the given clock.
+func newLimiter(t *testing.T, c *clock, options ...func(*fairlimit.FairLimiter) error) *fairlimit.FairLimiter {
+	t.Helper()
+
+	return helper.Must(fairlimit.New(append([]func(*fairlimit.FairLimiter) error{
+		fairlimit.WithRate(10),
+		fairlimit.WithBurst(1),
+		fairlimit.WithKeyFunc(ratelimit.KeyByHeader("X-Tenant")),
+		fairlimit.TWithClock(c.Now, c.AfterFunc),
+	}, options...)...))
+}
+
+func newRequest(ctx context.Context, tenant string) *http.Request {
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	req.Header.Set("X-Tenant", tenant)
+
+	return req
+}
+
+func TestFairShare(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Weights  map[string]float64
+		Requests map[string]int
+		Want     map[string]int64
+	}{
+		{ // 0 the noisy tenant gets the same share as the quiet ones
+			Requests: map[string]int{"a": 50, "b": 5, "c": 5, "d": 5, "e": 5},
+			Want:     map[string]int64{"a": 4, "b": 4, "c": 4, "d": 4, "e": 4},
+		},
+		{ // 1 the shares follow the weights
+			Weights:  map[string]float64{"header:X-Tenant:b": 3},
+			Requests: map[string]int{"a": 40, "b": 40},
+			Want:     map[string]int64{"a": 5, "b": 15},
+		},
+		{ // 2 the share of idle tenants goes to the active ones
+			Requests: map[string]int{"a": 50, "b": 2},
+			Want:     map[string]int64{"a": 18, "b": 2},
+		},
+		{ // 3 many tenants
+			Requests: map[string]int{
+				"a": 100, "b": 2, "c": 2, "d": 2, "e": 2, "f": 2, "g": 2, "h": 2, "i": 2, "j": 2,
+			},
+			Want: map[string]int64{
+				"a": 2, "b": 2, "c": 2, "d": 2, "e": 2, "f": 2, "g": 2, "h": 2, "i": 2, "j": 2,
+			},
+		},
+	}
+
+	for k, test := range tests {
+		t.Run("", func(t *testing.T) {
+			t.Parallel()
+
+			c := &clock{now: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+			limiter := newLimiter(t, c,
+				fairlimit.WithWeights(test.Weights),
+				fairlimit.WithMaxQueue(1000),
+				fairlimit.WithQueueTimeout(time.Hour))
+
+			// use up the burst, so all following requests have to queue
+			if !limiter.LimitRequest(newRequest(t.Context(), "z")) {
+				t.Fatalf("%v: first request was rejected", k)
+			}
+
+			ctx, cancel := context.WithCancel(t.Context())
+			granted := make(map[string]*atomic.Int64, len(test.Requests))
+			total := 0
+
+			var wg sync.WaitGroup
+
+			for tenant, n := range test.Requests {
+				granted[tenant] = &atomic.Int64{}
+				total += n
+
+				for range n {
+					wg.Go(func() {
+						if limiter.LimitRequest(newRequest(ctx, tenant)) {
+							granted[tenant].Add(1)
+						}
+					})
+				}
+			}
+
+			waitFor(t, func() bool { return limiter.Queued() == total })
+
+			// a capacity of 20 requests
+			c.advance(2050 * time.Millisecond)
+
+			waitFor(t, func() bool { return limiter.Queued() == total-20 })
+
+			cancel()
+			wg.Wait()
+
+			for tenant, want := range test.Want {
+				if got := granted[tenant].Load(); got != want {
+					t.Errorf("%v: tenant %v got %v requests, wanted %v", k, tenant, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFairCost(t *testing.T) {
+	t.Parallel()
+
+	c := &clock{now: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+	limiter := newLimiter(t, c, fairlimit.WithBurst(5), fairlimit.WithQuantum(5))
+
+	tests := []struct {
+		Cost int64
+		Want bool
+	}{
+		{Cost: 0, Want: true},  // 0
+		{Cost: 6, Want: false}, // 1 more than the burst
+		{Cost: 5, Want: true},  // 2
+	}
+
+	for k, test := range tests {
+		got, _, hasQuota := limiter.LimitRequestCost(newRequest(t.Context(), "a"), test.Cost)
+
+		if got != test.Want {
+			t.Errorf("%v: got %v, wanted %v", k, got, test.Want)
+		}
+
+		if hasQuota {
+			t.Errorf("%v: got a quota, but the limiter has none", k)
+		}
+	}
+}
+
+func TestFairQueue(t *testing.T) {
+	t.Parallel()
+
+	c := &clock{now: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+	limiter := newLimiter(t, c,
+		fairlimit.WithRate(0.5),
+		fairlimit.WithMaxQueue(1),
+		fairlimit.WithQueueTimeout(time.Second))
+
+	if !limiter.LimitRequest(newRequest(t.Context(), "a")) {
+		t.Fatalf("first request was rejected")
+	}
+
+	var timedOut, canceled atomic.Bool
+
+	var wg sync.WaitGroup
+
+	wg.Go(func() { timedOut.Store(!limiter.LimitRequest(newRequest(t.Context(), "a"))) })
+	waitFor(t, func() bool { return limiter.Queued() == 1 })
+
+	if limiter.LimitRequest(newRequest(t.Context(), "a")) {
+		t.Errorf("request exceeding the queue was not rejected")
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+
+	wg.Go(func() { canceled.Store(!limiter.LimitRequest(newRequest(ctx, "b"))) })
+	waitFor(t, func() bool { return limiter.Queued() == 2 })
+
+	cancel()
+	waitFor(t, func() bool { return limiter.Queued() == 1 })
+
+	// the timeout strikes before the capacity suffices for the request of a
+	c.advance(time.Second)
+	wg.Wait()
+
+	if !timedOut.Load() {
+		t.Errorf("request was not rejected after the queue timeout")
+	}
+
+	if !canceled.Load() {
+		t.Errorf("request was not rejected after cancellation")
+	}
+
+	if got := limiter.Queued(); got != 0 {
+		t.Errorf("got %v queued requests, wanted 0", got)
+	}
+}
+
+func TestFairOptionErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options []func(*fairlimit.FairLimiter) error
+		Want    error
+	}{
+		{ // 0
+			Options: []func(*fairlimit.FairLimiter) error{nil},
+			Want:    fairlimit.ErrNilOption,
+		},
+		{ // 1
+			Options: []func(*fairlimit.FairLimiter) error{},
+			Want:    fairlimit.ErrZeroRate,
+		},
+		{ // 2
+			Options: []func(*fairlimit.FairLimiter) error{fairlimit.WithRate(0)},
+			Want:    fairlimit.ErrZeroRate,
+		},
+		{ // 3
+			Options: []func(*fairlimit.FairLimiter) error{fairlimit.WithBurst(0)},
+			Want:    fairlimit.ErrZeroBurst,
+		},
+		{ // 4
+			Options: []func(*fairlimit.FairLimiter) error{fairlimit.WithQuantum(0)},
+			Want:    fairlimit.ErrZeroQuantum,
+		},
+		{ // 5
+			Options: []func(*fairlimit.FairLimiter) error{fairlimit.WithMaxQueue(0)},
+			Want:    fairlimit.ErrInvalidMaxQueue,
+		},
+		{ // 6
+			Options: []func(*fairlimit.FairLimiter) error{fairlimit.WithKeyFunc(nil)},
+			Want:    ratelimit.ErrNilKeyFunc,
+		},
+		{ // 7
+			Options: []func(*fairlimit.FairLimiter) error{fairlimit.WithWeightFunc(nil)},
+			Want:    fairlimit.ErrNilWeightFunc,
+		},
+		{ // 8
+			Options: []func(*fairlimit.FairLimiter) error{fairlimit.WithRate(2.5)},
+			Want:    nil,
+		},
+	}
+
+	for k, test := range tests {
+		limiter, err := fairlimit.New(test.Options...)
+
+		if !errors.Is(err, test.Want) {
+			t.Errorf("%v: got error %v, wanted %v", k, err, test.Want)
+		}
+
+		if err == nil && limiter.Burst != 3 {
+			t.Errorf("%v: got burst %v, wanted the rounded up rate", k, limiter.Burst)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package fairlimit
+
+import "time"
+
+// The following functions are used for internal testing and are not visible to normal library users.
+
+// TWithClock is an option replacing the time source and the timers of the limiter. The
+// afterFunc calls f after d and gives a function stopping the timer.
+func TWithClock(now func() time.Time, afterFunc func(d time.Duration, f func()) func() bool) func(l *FairLimiter) error {
+	return func(l *FairLimiter) error {
+		l.now = now
+		l.afterFunc = afterFunc
+
+		return nil
+	}
+}
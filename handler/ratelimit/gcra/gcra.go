@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package gcra provides a process-local rate limiter using the generic cell rate
+// algorithm, that works without background go routines.
+package gcra
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+)
+
+// ErrZeroRate is returned when the rate is 0.
+var ErrZeroRate = errors.New("rate must be greater than 0")
+
+// ErrZeroBurst is returned when the burst is 0.
+var ErrZeroBurst = errors.New("burst must be greater than 0")
+
+// GCRA is an instance local request limiter using the generic cell rate algorithm.
+// It keeps the theoretical arrival time of the next request, if requests arrived
+// exactly at the configured Rate. A request passes, if it is not earlier than that
+// time minus the tolerance given by Burst.
+type GCRA struct {
+	// Rate is the number of requests allowed per second.
+	Rate float64
+
+	// Burst is the number of requests that may pass at once after a period without
+	// requests.
+	Burst int64
+
+	// interval is the emission interval, the time in nanoseconds between two requests
+	// at the configured rate.
+	interval int64
+	// tat is the theoretical arrival time in nanoseconds since start.
+	tat atomic.Int64
+	// start is the reference time of tat.
+	start time.Time
+	// now gives the current time.
+	now func() time.Time
+}
+
+// elapsed gives the nanoseconds passed since the start of the limiter.
+func (g *GCRA) elapsed() int64 {
+	return g.now().Sub(g.start).Nanoseconds()
+}
+
+// Limit gives true, if the rate limit is not yet exceeded, otherwise false.
+func (g *GCRA) Limit() bool {
+	return g.LimitN(1)
+}
+
+// LimitN gives true, if n requests may pass, otherwise false. If they may not pass,
+// none of them is accounted for.
+func (g *GCRA) LimitN(n int64) bool {
+	return g.limitN(g.elapsed(), n)
+}
+
+// LimitQuota gives true, if the rate limit is not yet exceeded, and the quota after
+// the decision.
+func (g *GCRA) LimitQuota() (bool, ratelimit.Quota) {
+	return g.LimitNQuota(1)
+}
+
+// LimitNQuota gives true, if n requests may pass, and the quota after the decision.
+// The RetryAfter of the quota is the time until n requests may pass.
+func (g *GCRA) LimitNQuota(n int64) (bool, ratelimit.Quota) {
+	now := g.elapsed()
+	allowed := g.limitN(now, n)
+
+	return allowed, g.quota(now, n)
+}
+
+// limitN accounts for n requests at the given time, if they may pass.
+func (g *GCRA) limitN(now, n int64) bool {
+	if n <= 0 {
+		return true
+	}
+
+	if n > g.Burst {
+		// never passes, also keeps the following calculations from overflowing
+		return false
+	}
+
+	limit := now + g.Burst*g.interval
+
+	for {
+		tat := g.tat.Load()
+		next := max(tat, now) + n*g.interval
+
+		if next > limit {
+			return false
+		}
+
+		if g.tat.CompareAndSwap(tat, next) {
+			return true
+		}
+	}
+}
+
+// Remaining gives the number of requests that may currently pass.
+func (g *GCRA) Remaining() int64 {
+	now := g.elapsed()
+
+	return (now + g.Burst*g.interval - max(g.tat.Load(), now)) / g.interval
+}
+
+// quota gives the quota of the limiter at the given time, with the time until n
+// requests may pass. If n exceeds the burst, they never pass and no retry time is given.
+func (g *GCRA) quota(now, n int64) ratelimit.Quota {
+	tat := max(g.tat.Load(), now)
+	tolerance := g.Burst * g.interval
+	quota := ratelimit.Quota{
+		Limit:     g.Burst,
+		Window:    time.Duration(tolerance),
+		Remaining: (now + tolerance - tat) / g.interval,
+		Reset:     time.Duration(tat - now),
+	}
+
+	if n <= g.Burst {
+		quota.RetryAfter = time.Duration(max(0, tat+n*g.interval-tolerance-now))
+	}
+
+	return quota
+}
+
+// WithRate sets the number of requests allowed per second.
+func WithRate(r float64) func(g *GCRA) error {
+	return func(g *GCRA) error {
+		if r <= 0 {
+			return ErrZeroRate
+		}
+
+		g.Rate = r
+
+		return nil
+	}
+}
+
+// WithBurst sets the number of requests that may pass at once.
+func WithBurst(n int64) func(g *GCRA) error {
+	return func(g *GCRA) error {
+		if n <= 0 {
+			return ErrZeroBurst
+		}
+
+		g.Burst = n
+
+		return nil
+	}
+}
+
+// New creates a new GCRA rate limiter.
+func New(options ...func(*GCRA) error) (*GCRA, error) {
+	limiter := GCRA{
+		Rate:  1,
+		Burst: 1,
+		start: time.Now(),
+		now:   time.Now,
+	}
+
+	for _, opt := range options {
+		if err := opt(&limiter); err != nil {
+			return nil, err
+		}
+	}
+
+	limiter.interval = max(1, int64(math.Round(float64(time.Second)/limiter.Rate)))
+
+	return &limiter, nil
+}
@@ -0,0 +1,15 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package gcra
+
+import "time"
+
+// The following functions are used for internal testing and are not visible to normal library users.
+
+// TSetNow replaces the time source of the given limiter and restarts it at the current
+// time of the new source.
+func TSetNow(g *GCRA, now func() time.Time) {
+	g.now = now
+	g.start = now()
+}
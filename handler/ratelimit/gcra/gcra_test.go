@@ -0,0 +1,232 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package gcra_test
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+	"github.com/AlphaOne1/midgard/handler/ratelimit/gcra"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+func TestGCRA(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	limiter := helper.Must(gcra.New(gcra.WithRate(10), gcra.WithBurst(3)))
+	gcra.TSetNow(limiter, func() time.Time { return now })
+
+	tests := []struct {
+		Advance       time.Duration
+		N             int64
+		Want          bool
+		WantRemaining int64
+	}{
+		{Advance: 0, N: 1, Want: true, WantRemaining: 2},                       // 0
+		{Advance: 0, N: 2, Want: true, WantRemaining: 0},                       // 1
+		{Advance: 0, N: 1, Want: false, WantRemaining: 0},                      // 2
+		{Advance: 50 * time.Millisecond, N: 1, Want: false, WantRemaining: 0},  // 3
+		{Advance: 50 * time.Millisecond, N: 1, Want: true, WantRemaining: 0},   // 4
+		{Advance: 150 * time.Millisecond, N: 2, Want: false, WantRemaining: 1}, // 5 not enough, nothing accounted
+		{Advance: 0, N: 1, Want: true, WantRemaining: 0},                       // 6
+		{Advance: time.Hour, N: 4, Want: false, WantRemaining: 3},              // 7 capped at burst
+		{Advance: 0, N: 3, Want: true, WantRemaining: 0},                       // 8
+		{Advance: 0, N: 0, Want: true, WantRemaining: 0},                       // 9
+	}
+
+	for k, test := range tests {
+		now = now.Add(test.Advance)
+
+		if got := limiter.LimitN(test.N); got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+
+		if got := limiter.Remaining(); got != test.WantRemaining {
+			t.Errorf("%v: got %v remaining but wanted %v", k, got, test.WantRemaining)
+		}
+	}
+}
+
+func TestGCRAConcurrent(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	limiter := helper.Must(gcra.New(gcra.WithRate(1), gcra.WithBurst(100)))
+	gcra.TSetNow(limiter, func() time.Time { return now })
+
+	var passed atomic.Int64
+	var wg sync.WaitGroup
+
+	for range 16 {
+		wg.Go(func() {
+			for range 50 {
+				if limiter.Limit() {
+					passed.Add(1)
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if passed.Load() != 100 {
+		t.Errorf("got %v passed requests but wanted 100", passed.Load())
+	}
+}
+
+func TestGCRAOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options   []func(*gcra.GCRA) error
+		WantErr   error
+		WantRate  float64
+		WantBurst int64
+	}{
+		{Options: nil, WantRate: 1, WantBurst: 1}, // 0
+		{ // 1
+			Options:   []func(*gcra.GCRA) error{gcra.WithRate(2.5), gcra.WithBurst(7)},
+			WantRate:  2.5,
+			WantBurst: 7,
+		},
+		{Options: []func(*gcra.GCRA) error{gcra.WithRate(-1)}, WantErr: gcra.ErrZeroRate},  // 2
+		{Options: []func(*gcra.GCRA) error{gcra.WithBurst(0)}, WantErr: gcra.ErrZeroBurst}, // 3
+	}
+
+	for k, test := range tests {
+		limiter, err := gcra.New(test.Options...)
+
+		if !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+
+		if err == nil && (limiter.Rate != test.WantRate || limiter.Burst != test.WantBurst) {
+			t.Errorf("%v: got rate %v burst %v but wanted %v and %v",
+				k, limiter.Rate, limiter.Burst, test.WantRate, test.WantBurst)
+		}
+	}
+}
+
+func TestGCRAQuota(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	limiter := helper.Must(gcra.New(gcra.WithRate(10), gcra.WithBurst(3)))
+	gcra.TSetNow(limiter, func() time.Time { return now })
+
+	tests := []struct {
+		Advance   time.Duration
+		Want      bool
+		WantQuota ratelimit.Quota
+	}{
+		{ // 0
+			Advance: 0, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 3, Window: 300 * time.Millisecond, Remaining: 2, Reset: 100 * time.Millisecond},
+		},
+		{ // 1
+			Advance: 0, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 3, Window: 300 * time.Millisecond, Remaining: 1, Reset: 200 * time.Millisecond},
+		},
+		{ // 2
+			Advance: 0, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 3, Window: 300 * time.Millisecond, Remaining: 0, Reset: 300 * time.Millisecond, RetryAfter: 100 * time.Millisecond},
+		},
+		{ // 3
+			Advance: 0, Want: false,
+			WantQuota: ratelimit.Quota{Limit: 3, Window: 300 * time.Millisecond, Remaining: 0, Reset: 300 * time.Millisecond, RetryAfter: 100 * time.Millisecond},
+		},
+		{ // 4
+			Advance: 50 * time.Millisecond, Want: false,
+			WantQuota: ratelimit.Quota{Limit: 3, Window: 300 * time.Millisecond, Remaining: 0, Reset: 250 * time.Millisecond, RetryAfter: 50 * time.Millisecond},
+		},
+	}
+
+	for k, test := range tests {
+		now = now.Add(test.Advance)
+
+		got, quota := limiter.LimitQuota()
+
+		if got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+
+		if quota != test.WantQuota {
+			t.Errorf("%v: got quota %+v but wanted %+v", k, quota, test.WantQuota)
+		}
+	}
+}
+
+func TestGCRAQuotaN(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	limiter := helper.Must(gcra.New(gcra.WithRate(10), gcra.WithBurst(100)))
+	gcra.TSetNow(limiter, func() time.Time { return now })
+
+	tests := []struct {
+		Advance   time.Duration
+		N         int64
+		Want      bool
+		WantQuota ratelimit.Quota
+	}{
+		{ // 0
+			Advance: 0, N: 100, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 100, Window: 10 * time.Second, Remaining: 0, Reset: 10 * time.Second, RetryAfter: 10 * time.Second},
+		},
+		{ // 1 the retry time is that of the whole cost
+			Advance: 0, N: 100, Want: false,
+			WantQuota: ratelimit.Quota{Limit: 100, Window: 10 * time.Second, Remaining: 0, Reset: 10 * time.Second, RetryAfter: 10 * time.Second},
+		},
+		{ // 2
+			Advance: 5 * time.Second, N: 50, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 100, Window: 10 * time.Second, Remaining: 0, Reset: 10 * time.Second, RetryAfter: 5 * time.Second},
+		},
+		{ // 3 more than the limit never passes
+			Advance: 0, N: 101, Want: false,
+			WantQuota: ratelimit.Quota{Limit: 100, Window: 10 * time.Second, Remaining: 0, Reset: 10 * time.Second, RetryAfter: 0},
+		},
+	}
+
+	for k, test := range tests {
+		now = now.Add(test.Advance)
+
+		got, quota := limiter.LimitNQuota(test.N)
+
+		if got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+
+		if quota != test.WantQuota {
+			t.Errorf("%v: got quota %+v but wanted %+v", k, quota, test.WantQuota)
+		}
+	}
+}
+
+func TestGCRALargeCost(t *testing.T) {
+	t.Parallel()
+
+	limiter := helper.Must(gcra.New(gcra.WithRate(1), gcra.WithBurst(1)))
+
+	tests := []struct {
+		N    int64
+		Want bool
+	}{
+		{N: math.MaxInt64/int64(time.Second) + 9, Want: false}, // 0 would overflow the time
+		{N: math.MaxInt64, Want: false},                        // 1
+		{N: 1, Want: true},                                     // 2
+		{N: 1, Want: false},                                    // 3
+	}
+
+	for k, test := range tests {
+		if got := limiter.LimitN(test.N); got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/AlphaOne1/midgard/defs"
+)
+
+// KeyFunc extracts the key of a request, that determines which limit applies. It gives
+// an empty string, if the request does not contain the information to build the key.
+// The keys are prefixed by their kind, e.g. "ip:" or "principal:", so that keys of
+// different kinds cannot collide.
+type KeyFunc func(r *http.Request) string
+
+// ipv6PrefixBits is the prefix length IPv6 addresses are grouped by. Clients usually get
+// a whole /64 network, so limiting single addresses could be circumvented easily.
+const ipv6PrefixBits = 64
+
+// addrKey gives the key of an address, grouping IPv6 addresses by their /64 network.
+func addrKey(addr netip.Addr) string {
+	addr = addr.Unmap()
+
+	if addr.Is6() {
+		prefix, _ := addr.Prefix(ipv6PrefixBits)
+
+		return "ip:" + prefix.String()
+	}
+
+	return "ip:" + addr.String()
+}
+
+// remoteAddr gives the address of the direct peer of the connection.
+func remoteAddr(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+
+	return addr, err == nil
+}
+
+// KeyByIP uses the address of the client connection as key. IPv6 addresses are grouped
+// by their /64 network.
+func KeyByIP() KeyFunc {
+	return func(r *http.Request) string {
+		if addr, ok := remoteAddr(r); ok {
+			return addrKey(addr)
+		}
+
+		return ""
+	}
+}
+
+// KeyByForwardedIP uses the client address given in the X-Forwarded-For header, if the
+// request comes from one of the trusted proxies. The header is evaluated from right to
+// left, the first address not belonging to a trusted proxy is used. Requests not coming
+// from a trusted proxy are keyed by their connection address.
+func KeyByForwardedIP(trusted ...netip.Prefix) KeyFunc {
+	isTrusted := func(addr netip.Addr) bool {
+		return slices.ContainsFunc(trusted, func(p netip.Prefix) bool { return p.Contains(addr.Unmap()) })
+	}
+
+	return func(r *http.Request) string {
+		addr, ok := remoteAddr(r)
+
+		if !ok {
+			return ""
+		}
+
+		if !isTrusted(addr) {
+			return addrKey(addr)
+		}
+
+		hops := make([]string, 0, 4)
+
+		for _, v := range r.Header.Values("X-Forwarded-For") {
+			hops = append(hops, strings.Split(v, ",")...)
+		}
+
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+
+			if err != nil {
+				break
+			}
+
+			addr = hop
+
+			if !isTrusted(hop) {
+				break
+			}
+		}
+
+		return addrKey(addr)
+	}
+}
+
+// KeyByPrincipal uses the name of the authenticated principal as key.
+func KeyByPrincipal() KeyFunc {
+	return func(r *http.Request) string {
+		if p, ok := defs.PrincipalFromContext(r.Context()); ok && p.Name != "" {
+			return "principal:" + p.Name
+		}
+
+		return ""
+	}
+}
+
+// KeyByHeader uses the value of the given header as key, e.g. an API key. As the value
+// is set by the client, each new value gets a fresh limit, so a client could bypass the
+// limit and evict the limiters of other clients by sending a new value with every
+// request. It is only to be used for headers set by a trusted proxy, or wrapped in a
+// KeyFunc giving the key only for values of an allowlist, as policylimit does for its
+// API keys, and combined with a trusted key like KeyByIP using KeyFirst.
+func KeyByHeader(name string) KeyFunc {
+	name = http.CanonicalHeaderKey(name)
+
+	return func(r *http.Request) string {
+		if v := r.Header.Get(name); v != "" {
+			return "header:" + name + ":" + v
+		}
+
+		return ""
+	}
+}
+
+// KeyByRoute uses the pattern of the route matched by http.ServeMux as key. The pattern
+// is only known to middlewares registered for single routes, not to those wrapping the
+// whole ServeMux.
+func KeyByRoute() KeyFunc {
+	return func(r *http.Request) string {
+		if r.Pattern != "" {
+			return "route:" + r.Pattern
+		}
+
+		return ""
+	}
+}
+
+// KeyFirst uses the first non-empty key of the given functions, e.g. the principal for
+// authenticated requests and the client address otherwise.
+func KeyFirst(keys ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		for _, key := range keys {
+			if k := key(r); k != "" {
+				return k
+			}
+		}
+
+		return ""
+	}
+}
+
+// KeyAll combines the keys of all given functions, e.g. to limit each principal per
+// route. If any of the keys is empty, the combined key is empty.
+func KeyAll(keys ...KeyFunc) KeyFunc {
+	return func(r *http.Request) string {
+		parts := make([]string, 0, len(keys))
+
+		for _, key := range keys {
+			k := key(r)
+
+			if k == "" {
+				return ""
+			}
+
+			parts = append(parts, k)
+		}
+
+		return strings.Join(parts, "|")
+	}
+}
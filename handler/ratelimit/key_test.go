@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package ratelimit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+)
+
+func TestKeyFuncs(t *testing.T) {
+	t.Parallel()
+
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")}
+
+	tests := []struct {
+		Name    string
+		Key     ratelimit.KeyFunc
+		Remote  string
+		Headers map[string][]string
+		User    string
+		Pattern string
+		Want    string
+	}{
+		{Name: "ip v4", Key: ratelimit.KeyByIP(), Remote: "192.0.2.1:1234", Want: "ip:192.0.2.1"},
+		{Name: "ip v6 grouped", Key: ratelimit.KeyByIP(), Remote: "[2001:db8:1:2:3:4:5:6]:1234", Want: "ip:2001:db8:1:2::/64"},
+		{Name: "ip v4 mapped", Key: ratelimit.KeyByIP(), Remote: "[::ffff:192.0.2.1]:1234", Want: "ip:192.0.2.1"},
+		{Name: "ip without port", Key: ratelimit.KeyByIP(), Remote: "192.0.2.1", Want: "ip:192.0.2.1"},
+		{Name: "ip invalid", Key: ratelimit.KeyByIP(), Remote: "somewhere", Want: ""},
+		{
+			Name: "forwarded untrusted", Key: ratelimit.KeyByForwardedIP(proxies...), Remote: "192.0.2.1:1234",
+			Headers: map[string][]string{"X-Forwarded-For": {"198.51.100.7"}},
+			Want:    "ip:192.0.2.1",
+		},
+		{
+			Name: "forwarded trusted", Key: ratelimit.KeyByForwardedIP(proxies...), Remote: "10.0.0.1:1234",
+			Headers: map[string][]string{"X-Forwarded-For": {"203.0.113.9, 198.51.100.7, 10.1.1.1"}},
+			Want:    "ip:198.51.100.7",
+		},
+		{
+			Name: "forwarded multiple headers", Key: ratelimit.KeyByForwardedIP(proxies...), Remote: "10.0.0.1:1234",
+			Headers: map[string][]string{"X-Forwarded-For": {"203.0.113.9", "198.51.100.7"}},
+			Want:    "ip:198.51.100.7",
+		},
+		{
+			Name: "forwarded garbage", Key: ratelimit.KeyByForwardedIP(proxies...), Remote: "10.0.0.1:1234",
+			Headers: map[string][]string{"X-Forwarded-For": {"198.51.100.7, garbage, 10.1.1.1"}},
+			Want:    "ip:10.1.1.1",
+		},
+		{
+			Name: "forwarded only proxies", Key: ratelimit.KeyByForwardedIP(proxies...), Remote: "[fd00::1]:1234",
+			Headers: map[string][]string{"X-Forwarded-For": {"10.1.1.1"}},
+			Want:    "ip:10.1.1.1",
+		},
+		{Name: "principal", Key: ratelimit.KeyByPrincipal(), User: "alice", Want: "principal:alice"},
+		{Name: "no principal", Key: ratelimit.KeyByPrincipal(), Want: ""},
+		{
+			Name: "header", Key: ratelimit.KeyByHeader("x-api-key"),
+			Headers: map[string][]string{"X-Api-Key": {"k1"}},
+			Want:    "header:X-Api-Key:k1",
+		},
+		{Name: "no header", Key: ratelimit.KeyByHeader("X-Api-Key"), Want: ""},
+		{Name: "route", Key: ratelimit.KeyByRoute(), Pattern: "GET /items/{id}", Want: "route:GET /items/{id}"},
+		{Name: "no route", Key: ratelimit.KeyByRoute(), Want: ""},
+		{
+			Name: "first principal", Key: ratelimit.KeyFirst(ratelimit.KeyByPrincipal(), ratelimit.KeyByIP()),
+			Remote: "192.0.2.1:1234", User: "alice", Want: "principal:alice",
+		},
+		{
+			Name: "first fallback", Key: ratelimit.KeyFirst(ratelimit.KeyByPrincipal(), ratelimit.KeyByIP()),
+			Remote: "192.0.2.1:1234", Want: "ip:192.0.2.1",
+		},
+		{
+			Name: "all", Key: ratelimit.KeyAll(ratelimit.KeyByPrincipal(), ratelimit.KeyByRoute()),
+			User: "alice", Pattern: "/export", Want: "principal:alice|route:/export",
+		},
+		{
+			Name: "all incomplete", Key: ratelimit.KeyAll(ratelimit.KeyByPrincipal(), ratelimit.KeyByRoute()),
+			User: "alice", Want: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+			req.RemoteAddr = test.Remote
+			req.Pattern = test.Pattern
+
+			for k, values := range test.Headers {
+				for _, v := range values {
+					req.Header.Add(k, v)
+				}
+			}
+
+			if test.User != "" {
+				req = req.WithContext(defs.ContextWithPrincipal(req.Context(), &defs.Principal{Name: test.User}))
+			}
+
+			if got := test.Key(req); got != test.Want {
+				t.Errorf("got key %q but wanted %q", got, test.Want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrNilFactory is returned when the limiter factory is nil.
+var ErrNilFactory = errors.New("limiter factory cannot be nil")
+
+// ErrNilKeyFunc is returned when a key function is nil.
+var ErrNilKeyFunc = errors.New("key function cannot be nil")
+
+// ErrInvalidMaxKeys is returned when the maximum number of keys is not greater than 0.
+var ErrInvalidMaxKeys = errors.New("maximum number of keys must be greater than 0")
+
+const (
+	// DefaultMaxKeys is the default maximum number of keys with their own limiter.
+	DefaultMaxKeys = 10_000
+	// DefaultIdleTTL is the default time after which the limiter of an unused key is
+	// dropped.
+	DefaultIdleTTL = 10 * time.Minute
+)
+
+// RequestLimiter is the interface of limiters that decide based on the request, e.g. to
+// apply separate limits per client.
+type RequestLimiter interface {
+	// LimitRequest gives true, if the request may pass, otherwise false.
+	LimitRequest(r *http.Request) bool
+}
+
+// stopper is implemented by limiters that have to be stopped when no longer used, like
+// locallimit.LocalLimit.
+type stopper interface {
+	Stop()
+}
+
+// KeyedLimiter is a RequestLimiter keeping a separate Limiter per key. The limiters are
+// created on first use of a key. To keep the memory bounded, e.g. if clients use lots
+// of different keys, only a maximum number of limiters is kept, evicting the least
+// recently used ones, and limiters of keys not used for a while are dropped.
+type KeyedLimiter struct {
+	key     KeyFunc                           // key extracts the key of the requests
+	factory func(key string) (Limiter, error) // factory creates the limiter of a key
+	maxKeys int                               // maxKeys is the maximum number of limiters
+	idleTTL time.Duration                     // idleTTL is the time after which unused limiters are dropped
+	store   *lruStore[Limiter]                // store holds the limiters
+	now     func() time.Time                  // now gives the current time
+}
+
+// LimitRequest gives true, if the limiter of the request key allows the request. Limiters
+// that cannot be created reject all requests of their key.
+func (l *KeyedLimiter) LimitRequest(r *http.Request) bool {
+	return l.limiter(r).Limit()
+}
+
+// LimitRequestQuota gives true, if the limiter of the request key allows the request,
+// and the quota of the key, if its limiter reports one.
+func (l *KeyedLimiter) LimitRequestQuota(r *http.Request) (bool, Quota, bool) {
+	return limitCost(l.limiter(r), 1)
+}
+
+// LimitRequestCost gives true, if the limiter of the request key allows the request of
+// the given cost, and the quota of the key, if its limiter reports one. Requests costing
+// other than 1 are rejected, if the limiters cannot account for costs.
+func (l *KeyedLimiter) LimitRequestCost(r *http.Request, cost int64) (bool, Quota, bool) {
+	return limitCost(l.limiter(r), cost)
+}
+
+// limiter gives the limiter of the request key, creating it on first use.
+func (l *KeyedLimiter) limiter(r *http.Request) Limiter {
+	key := l.key(r)
+
+	return l.store.get(key, l.now(), func() Limiter {
+		limiter, err := l.factory(key)
+
+		if err != nil || limiter == nil {
+			return rejectAll{}
+		}
+
+		return limiter
+	})
+}
+
+// Keys gives the number of keys with a limiter.
+func (l *KeyedLimiter) Keys() int {
+	return l.store.len()
+}
+
+// rejectAll is a Limiter rejecting all requests.
+type rejectAll struct{}
+
+// Limit always gives false.
+func (rejectAll) Limit() bool {
+	return false
+}
+
+// WithKeyFunc sets the function extracting the key of the requests. Without it, the
+// requests are keyed by their client address. Requests without key share one limiter.
+func WithKeyFunc(key KeyFunc) func(l *KeyedLimiter) error {
+	return func(l *KeyedLimiter) error {
+		if key == nil {
+			return ErrNilKeyFunc
+		}
+
+		l.key = key
+
+		return nil
+	}
+}
+
+// WithMaxKeys sets the maximum number of keys with their own limiter.
+func WithMaxKeys(n int) func(l *KeyedLimiter) error {
+	return func(l *KeyedLimiter) error {
+		if n <= 0 {
+			return ErrInvalidMaxKeys
+		}
+
+		l.maxKeys = n
+
+		return nil
+	}
+}
+
+// WithIdleTTL sets the time after which the limiter of an unused key is dropped. A value
+// of 0 keeps the limiters until they are evicted by newer ones.
+func WithIdleTTL(d time.Duration) func(l *KeyedLimiter) error {
+	return func(l *KeyedLimiter) error {
+		l.idleTTL = max(0, d)
+
+		return nil
+	}
+}
+
+// NewKeyed creates a new KeyedLimiter, using factory to create the limiter of each key.
+// The factory is called once on creation to check its configuration.
+func NewKeyed(factory func(key string) (Limiter, error), options ...func(*KeyedLimiter) error) (*KeyedLimiter, error) {
+	if factory == nil {
+		return nil, ErrNilFactory
+	}
+
+	limiter := KeyedLimiter{
+		key:     KeyByIP(),
+		factory: factory,
+		maxKeys: DefaultMaxKeys,
+		idleTTL: DefaultIdleTTL,
+		now:     time.Now,
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&limiter); err != nil {
+			return nil, err
+		}
+	}
+
+	probe, err := factory("")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if probe == nil {
+		return nil, ErrInvalidLimiter
+	}
+
+	stop := func(l Limiter) {
+		if s, ok := l.(stopper); ok {
+			s.Stop()
+		}
+	}
+
+	stop(probe)
+	limiter.store = newLRUStore(limiter.maxKeys, limiter.idleTTL, stop)
+
+	return &limiter, nil
+}
@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package ratelimit_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard"
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// budgetLimit allows a fixed number of requests and records if it was stopped.
+type budgetLimit struct {
+	budget  atomic.Int64
+	stopped atomic.Bool
+}
+
+func (b *budgetLimit) Limit() bool {
+	return b.budget.Add(-1) >= 0
+}
+
+func (b *budgetLimit) Stop() {
+	b.stopped.Store(true)
+}
+
+// budgetFactory creates budgetLimits with the given budget and remembers them by key.
+func budgetFactory(budget int64, created map[string]*budgetLimit) func(string) (ratelimit.Limiter, error) {
+	return func(key string) (ratelimit.Limiter, error) {
+		l := &budgetLimit{}
+		l.budget.Store(budget)
+
+		if created != nil && key != "" {
+			created[key] = l
+		}
+
+		return l, nil
+	}
+}
+
+func TestKeyedLimiterPerKey(t *testing.T) {
+	t.Parallel()
+
+	limiter := helper.Must(ratelimit.NewKeyed(budgetFactory(2, nil)))
+	handler := midgard.StackMiddlewareHandler(
+		[]defs.Middleware{helper.Must(ratelimit.New(ratelimit.WithRequestLimiter(limiter)))},
+		http.HandlerFunc(helper.DummyHandler))
+
+	tests := []struct {
+		Remote     string
+		WantStatus int
+	}{
+		{Remote: "192.0.2.1:1000", WantStatus: http.StatusOK},              // 0
+		{Remote: "192.0.2.1:1001", WantStatus: http.StatusOK},              // 1
+		{Remote: "192.0.2.1:1002", WantStatus: http.StatusTooManyRequests}, // 2
+		{Remote: "192.0.2.2:1000", WantStatus: http.StatusOK},              // 3
+		{Remote: "192.0.2.2:1000", WantStatus: http.StatusOK},              // 4
+		{Remote: "192.0.2.2:1000", WantStatus: http.StatusTooManyRequests}, // 5
+	}
+
+	for k, test := range tests {
+		req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+		req.RemoteAddr = test.Remote
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.WantStatus {
+			t.Errorf("%v: got status %v but wanted %v", k, rec.Code, test.WantStatus)
+		}
+	}
+
+	if limiter.Keys() != 2 {
+		t.Errorf("got %v keys but wanted 2", limiter.Keys())
+	}
+}
+
+func TestKeyedLimiterEviction(t *testing.T) {
+	t.Parallel()
+
+	created := make(map[string]*budgetLimit)
+	limiter := helper.Must(ratelimit.NewKeyed(budgetFactory(1, created),
+		ratelimit.WithKeyFunc(ratelimit.KeyByHeader("X-Api-Key")),
+		ratelimit.WithMaxKeys(2),
+		ratelimit.WithIdleTTL(0)))
+
+	request := func(key string) bool {
+		req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+		req.Header.Set("X-Api-Key", key)
+
+		return limiter.LimitRequest(req)
+	}
+
+	tests := []struct {
+		Key  string
+		Want bool
+	}{
+		{Key: "a", Want: true},  // 0
+		{Key: "b", Want: true},  // 1
+		{Key: "a", Want: false}, // 2 a is now more recently used than b
+		{Key: "c", Want: true},  // 3 evicts b
+		{Key: "a", Want: false}, // 4
+		{Key: "b", Want: true},  // 5 b got a new limiter, evicts c
+	}
+
+	for k, test := range tests {
+		if got := request(test.Key); got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+	}
+
+	if limiter.Keys() != 2 {
+		t.Errorf("got %v keys but wanted 2", limiter.Keys())
+	}
+
+	if !created["header:X-Api-Key:c"].stopped.Load() || created["header:X-Api-Key:a"].stopped.Load() {
+		t.Errorf("evicted limiters must be stopped, others not")
+	}
+}
+
+func TestKeyedLimiterIdleTTL(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	limiter := helper.Must(ratelimit.NewKeyed(budgetFactory(1, nil), ratelimit.WithIdleTTL(time.Minute)))
+	ratelimit.TSetKeyedNow(limiter, func() time.Time { return now })
+
+	request := func(remote string) bool {
+		req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+		req.RemoteAddr = remote
+
+		return limiter.LimitRequest(req)
+	}
+
+	if !request("192.0.2.1:1") || request("192.0.2.1:1") {
+		t.Errorf("budget of one request expected")
+	}
+
+	now = now.Add(30 * time.Second)
+	request("192.0.2.2:1")
+
+	now = now.Add(45 * time.Second)
+
+	if !request("192.0.2.1:1") {
+		t.Errorf("idle limiter should have been dropped")
+	}
+
+	if limiter.Keys() != 2 {
+		t.Errorf("got %v keys but wanted 2", limiter.Keys())
+	}
+}
+
+func TestKeyedLimiterFactoryError(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	factory := func(string) (ratelimit.Limiter, error) {
+		calls++
+
+		if calls > 1 {
+			return nil, errors.New("out of limiters")
+		}
+
+		return &budgetLimit{}, nil
+	}
+
+	limiter := helper.Must(ratelimit.NewKeyed(factory))
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+
+	if limiter.LimitRequest(req) {
+		t.Errorf("requests must be rejected if their limiter cannot be created")
+	}
+}
+
+func TestKeyedLimiterOptionErrors(t *testing.T) {
+	t.Parallel()
+
+	factory := budgetFactory(1, nil)
+
+	tests := []struct {
+		Factory func(string) (ratelimit.Limiter, error)
+		Options []func(*ratelimit.KeyedLimiter) error
+		WantErr error
+	}{
+		{Factory: nil, WantErr: ratelimit.ErrNilFactory},                                                                                   // 0
+		{Factory: factory, Options: []func(*ratelimit.KeyedLimiter) error{nil}, WantErr: ratelimit.ErrNilOption},                           // 1
+		{Factory: factory, Options: []func(*ratelimit.KeyedLimiter) error{ratelimit.WithKeyFunc(nil)}, WantErr: ratelimit.ErrNilKeyFunc},   // 2
+		{Factory: factory, Options: []func(*ratelimit.KeyedLimiter) error{ratelimit.WithMaxKeys(0)}, WantErr: ratelimit.ErrInvalidMaxKeys}, // 3
+		{ // 4
+			Factory: func(string) (ratelimit.Limiter, error) { return nil, nil },
+			WantErr: ratelimit.ErrInvalidLimiter,
+		},
+		{ // 5
+			Factory: func(string) (ratelimit.Limiter, error) { return nil, ratelimit.ErrInvalidLimiter },
+			WantErr: ratelimit.ErrInvalidLimiter,
+		},
+	}
+
+	for k, test := range tests {
+		_, err := ratelimit.NewKeyed(test.Factory, test.Options...)
+
+		if !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+}
+
+func TestNilRequestLimiter(t *testing.T) {
+	t.Parallel()
+
+	_, err := ratelimit.New(ratelimit.WithRequestLimiter(nil))
+
+	if !errors.Is(err, ratelimit.ErrInvalidLimiter) {
+		t.Errorf("expected middleware creation to fail")
+	}
+}
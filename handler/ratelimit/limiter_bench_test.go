@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+	"github.com/AlphaOne1/midgard/handler/ratelimit/gcra"
+	"github.com/AlphaOne1/midgard/handler/ratelimit/locallimit"
+	"github.com/AlphaOne1/midgard/handler/ratelimit/slidingwindow"
+	"github.com/AlphaOne1/midgard/handler/ratelimit/tokenbucket"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// the goroutine-free limiters are drop-in replacements for LocalLimit.
+var (
+	_ ratelimit.Limiter = (*locallimit.LocalLimit)(nil)
+	_ ratelimit.Limiter = (*tokenbucket.TokenBucket)(nil)
+	_ ratelimit.Limiter = (*gcra.GCRA)(nil)
+	_ ratelimit.Limiter = (*slidingwindow.SlidingWindow)(nil)
+
+	_ ratelimit.CostQuotaLimiter   = (*tokenbucket.TokenBucket)(nil)
+	_ ratelimit.CostQuotaLimiter   = (*gcra.GCRA)(nil)
+	_ ratelimit.CostQuotaLimiter   = (*slidingwindow.SlidingWindow)(nil)
+	_ ratelimit.RequestCostLimiter = (*ratelimit.KeyedLimiter)(nil)
+)
+
+// benchRate is the rate of the benchmarked limiters, so that LocalLimit mostly has
+// drops available and does not wait for its drop timeout.
+const benchRate = 10_000_000
+
+func benchmarkLimiter(b *testing.B, limiter ratelimit.Limiter) {
+	b.Helper()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			limiter.Limit()
+		}
+	})
+}
+
+func BenchmarkLimiters(b *testing.B) {
+	b.Run("LocalLimit", func(b *testing.B) {
+		limiter := helper.Must(locallimit.New(
+			locallimit.WithTargetRate(benchRate),
+			locallimit.WithSleepInterval(time.Millisecond),
+			locallimit.WithMaxDropsAbsolute(benchRate)))
+		b.Cleanup(limiter.Stop)
+
+		benchmarkLimiter(b, limiter)
+	})
+
+	b.Run("TokenBucket", func(b *testing.B) {
+		benchmarkLimiter(b, helper.Must(tokenbucket.New(
+			tokenbucket.WithRate(benchRate),
+			tokenbucket.WithBurst(benchRate))))
+	})
+
+	b.Run("GCRA", func(b *testing.B) {
+		benchmarkLimiter(b, helper.Must(gcra.New(
+			gcra.WithRate(benchRate),
+			gcra.WithBurst(benchRate))))
+	})
+
+	b.Run("SlidingWindow", func(b *testing.B) {
+		benchmarkLimiter(b, helper.Must(slidingwindow.New(
+			slidingwindow.WithRequests(benchRate),
+			slidingwindow.WithWindow(time.Second))))
+	})
+}
@@ -41,8 +41,11 @@ type LocalLimit struct {
 	SleepInterval time.Duration
 	// dropStarted signalizes if the drop generation has been started.
 	dropStarted atomic.Bool
-	// stop signals the internal drop generator to stop working
-	stop atomic.Bool
+	// done is closed to stop the internal drop generator, even if it waits to deliver
+	// a drop.
+	done chan struct{}
+	// stopOnce cares that done is just closed once.
+	stopOnce sync.Once
 	// overflow stores the fractional drops, especially with a low TargetRate this
 	// guarantees no lost drops.
 	overflow float64
@@ -52,9 +55,10 @@ type LocalLimit struct {
 	lastIter time.Time
 }
 
-// Stop sets the stop marker, so the drop generator can stop eventually.
+// Stop stops the drop generator. Afterwards, Limit only gives the drops already
+// delivered.
 func (l *LocalLimit) Stop() {
-	l.stop.Store(true)
+	l.stopOnce.Do(func() { close(l.done) })
 }
 
 // Limit gives true, if the rate limit is not yet exceeded, otherwise false.
@@ -85,15 +89,24 @@ func (l *LocalLimit) run() {
 	var drops float64
 	var fillDrops int64
 
-	for !l.stop.Load() {
-		time.Sleep(l.SleepInterval)
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-time.After(l.SleepInterval):
+		}
+
 		iterTime = time.Since(l.lastIter)
 		drops = iterTime.Seconds()*l.TargetRate + l.overflow
 
 		fillDrops = min(l.MaxDrops-int64(len(l.drops)), int64(drops))
 
 		for range fillDrops {
-			l.drops <- drop{}
+			select {
+			case l.drops <- drop{}:
+			case <-l.done:
+				return
+			}
 		}
 
 		if fillDrops == int64(drops) {
@@ -180,6 +193,7 @@ func New(options ...func(*LocalLimit) error) (*LocalLimit, error) {
 		SleepInterval: DefaultSleepInterval,
 		DropTimeout:   DefaultDropTimeout,
 		drops:         make(chan drop),
+		done:          make(chan struct{}),
 		MaxDrops:      DefaultMaxDrops,
 		dropStarted:   atomic.Bool{},
 	}
@@ -5,6 +5,7 @@ package locallimit_test
 
 import (
 	"fmt"
+	"runtime"
 	"testing"
 	"time"
 
@@ -198,3 +199,28 @@ func TestWithDropTimeout(t *testing.T) {
 		})
 	}
 }
+
+//nolint:paralleltest // counting the go routines of the process
+func TestStop(t *testing.T) {
+	const limiters = 200
+
+	before := runtime.NumGoroutine()
+
+	for range limiters {
+		limiter := helper.Must(locallimit.New(
+			locallimit.WithTargetRate(1_000),
+			locallimit.WithSleepInterval(time.Millisecond),
+			locallimit.WithDropTimeout(10*time.Millisecond)))
+
+		// start the drop generator, it then blocks delivering the next drops
+		limiter.Limit()
+		limiter.Stop()
+		limiter.Stop()
+	}
+
+	for start := time.Now(); runtime.NumGoroutine() > before+limiters/10; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("got %v go routines after stopping, wanted about %v", runtime.NumGoroutine(), before)
+		}
+	}
+}
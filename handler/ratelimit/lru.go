@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package ratelimit
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lruEntry is an entry of the lruStore.
+type lruEntry[V any] struct {
+	key      string
+	value    V
+	lastUsed time.Time
+}
+
+// lruStore holds values per key. It is bounded in size, evicting the least recently used
+// entries, and drops entries not used for longer than the ttl. Removed values are passed
+// to the evict function.
+type lruStore[V any] struct {
+	mtx     sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // order has the most recently used entry at the front
+	maxSize int
+	ttl     time.Duration
+	evict   func(V)
+}
+
+// newLRUStore creates a new store for at most maxSize entries.
+func newLRUStore[V any](maxSize int, ttl time.Duration, evict func(V)) *lruStore[V] {
+	return &lruStore[V]{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		maxSize: maxSize,
+		ttl:     ttl,
+		evict:   evict,
+	}
+}
+
+// get returns the value of the key, creating it if not yet present or expired.
+func (s *lruStore[V]) get(key string, now time.Time, create func() V) V {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.expire(now)
+
+	if elem, found := s.entries[key]; found {
+		entry := elem.Value.(*lruEntry[V]) //nolint:forcetypeassert // only entries are stored
+		entry.lastUsed = now
+		s.order.MoveToFront(elem)
+
+		return entry.value
+	}
+
+	for len(s.entries) >= s.maxSize {
+		s.remove(s.order.Back())
+	}
+
+	entry := &lruEntry[V]{key: key, value: create(), lastUsed: now}
+	s.entries[key] = s.order.PushFront(entry)
+
+	return entry.value
+}
+
+// len gives the number of entries.
+func (s *lruStore[V]) len() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return len(s.entries)
+}
+
+// expire removes the entries not used within the ttl. As the entries are ordered by
+// their last use, only the back of the list has to be checked.
+func (s *lruStore[V]) expire(now time.Time) {
+	if s.ttl <= 0 {
+		return
+	}
+
+	for elem := s.order.Back(); elem != nil; elem = s.order.Back() {
+		if now.Sub(elem.Value.(*lruEntry[V]).lastUsed) < s.ttl { //nolint:forcetypeassert // only entries are stored
+			return
+		}
+
+		s.remove(elem)
+	}
+}
+
+// remove removes the element from the store.
+func (s *lruStore[V]) remove(elem *list.Element) {
+	entry := elem.Value.(*lruEntry[V]) //nolint:forcetypeassert // only entries are stored
+
+	s.order.Remove(elem)
+	delete(s.entries, entry.key)
+
+	if s.evict != nil {
+		s.evict(entry.value)
+	}
+}
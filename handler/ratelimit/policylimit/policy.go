@@ -0,0 +1,208 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package policylimit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// ErrNoTiers is returned when a policy defines no tiers.
+var ErrNoTiers = errors.New("policy needs at least one tier")
+
+// ErrUnknownTier is returned when a tier is referenced that the policy does not define.
+var ErrUnknownTier = errors.New("unknown tier")
+
+// ErrInvalidLimit is returned when a limit is neither unlimited nor has a positive rate
+// and burst.
+var ErrInvalidLimit = errors.New("limit needs a rate and burst greater than 0 or to be unlimited")
+
+// ErrNoPatterns is returned when a route has no patterns.
+var ErrNoPatterns = errors.New("route needs at least one pattern")
+
+// ErrInvalidNetwork is returned when a network of the allowlist cannot be parsed.
+var ErrInvalidNetwork = errors.New("invalid network")
+
+// ErrUnknownFormat is returned when the format of a policy file cannot be determined.
+var ErrUnknownFormat = helper.ErrUnknownFormat
+
+// DefaultTierClaim is the default claim of the principal naming its tier.
+const DefaultTierClaim = "tier"
+
+// Format is the format of a policy description.
+type Format = helper.ConfigFormat
+
+const (
+	// FormatJSON is the JSON format.
+	FormatJSON = helper.ConfigJSON
+	// FormatYAML is the YAML format.
+	FormatYAML = helper.ConfigYAML
+)
+
+// Limit is the rate limit of a tier.
+type Limit struct {
+	// Rate is the number of requests, or cost units, allowed per second.
+	Rate float64 `json:"rate,omitempty" yaml:"rate,omitempty"`
+	// Burst is the number of requests, or cost units, that may pass at once.
+	Burst int64 `json:"burst,omitempty" yaml:"burst,omitempty"`
+	// Unlimited lets all requests pass.
+	Unlimited bool `json:"unlimited,omitempty" yaml:"unlimited,omitempty"`
+}
+
+// Route overrides the limits of the tiers for the requests of its routes.
+type Route struct {
+	// Patterns of the routes as registered with http.ServeMux, e.g. "GET /export".
+	Patterns []string `json:"patterns" yaml:"patterns"`
+	// Tiers are the limits per tier. Tiers not given keep their general limit.
+	Tiers map[string]Limit `json:"tiers" yaml:"tiers"`
+}
+
+// Allowlist describes the requests bypassing the limits.
+type Allowlist struct {
+	// Networks of the clients, e.g. "10.0.0.0/8" or "192.0.2.1".
+	Networks []string `json:"networks,omitempty" yaml:"networks,omitempty"`
+	// Principals by their names.
+	Principals []string `json:"principals,omitempty" yaml:"principals,omitempty"`
+	// Roles of which the principal needs at least one.
+	Roles []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+}
+
+// Policy describes the limits per tier and route. The tier of a request is given by a
+// claim of its principal or by the metadata of its API key, otherwise the default tier
+// applies. The first route matching the pattern of the request and defining a limit for
+// the tier decides, if none does, the general limit of the tier applies.
+type Policy struct {
+	// DefaultTier is the tier of requests without a known tier.
+	DefaultTier string `json:"defaultTier" yaml:"defaultTier"`
+	// TierClaim is the claim of the principal naming its tier. If empty, "tier" is used.
+	TierClaim string `json:"tierClaim,omitempty" yaml:"tierClaim,omitempty"`
+	// APIKeyHeader is the header carrying the API key of the request.
+	APIKeyHeader string `json:"apiKeyHeader,omitempty" yaml:"apiKeyHeader,omitempty"`
+	// APIKeys maps API keys to their tier.
+	APIKeys map[string]string `json:"apiKeys,omitempty" yaml:"apiKeys,omitempty"`
+	// Tiers are the general limits per tier.
+	Tiers map[string]Limit `json:"tiers" yaml:"tiers"`
+	// Routes override the limits for single routes, evaluated in order.
+	Routes []Route `json:"routes,omitempty" yaml:"routes,omitempty"`
+	// Allow describes the requests bypassing the limits.
+	Allow Allowlist `json:"allow,omitempty" yaml:"allow,omitempty"`
+}
+
+// validate checks that the limit is either unlimited or has a rate and burst.
+func (l Limit) validate() error {
+	if !l.Unlimited && (l.Rate <= 0 || l.Burst <= 0) {
+		return ErrInvalidLimit
+	}
+
+	return nil
+}
+
+// validate checks the limits, tier references and networks of the policy.
+func (p *Policy) validate() error {
+	if len(p.Tiers) == 0 {
+		return ErrNoTiers
+	}
+
+	for name, limit := range p.Tiers {
+		if err := limit.validate(); err != nil {
+			return fmt.Errorf("tier %v: %w", name, err)
+		}
+	}
+
+	if _, ok := p.Tiers[p.DefaultTier]; !ok {
+		return fmt.Errorf("%w: default tier %q", ErrUnknownTier, p.DefaultTier)
+	}
+
+	for _, tier := range p.APIKeys {
+		if _, ok := p.Tiers[tier]; !ok {
+			return fmt.Errorf("%w: api key tier %q", ErrUnknownTier, tier)
+		}
+	}
+
+	for i, route := range p.Routes {
+		if len(route.Patterns) == 0 {
+			return fmt.Errorf("route %v: %w", i, ErrNoPatterns)
+		}
+
+		for name, limit := range route.Tiers {
+			if _, ok := p.Tiers[name]; !ok {
+				return fmt.Errorf("route %v: %w: %q", i, ErrUnknownTier, name)
+			}
+
+			if err := limit.validate(); err != nil {
+				return fmt.Errorf("route %v tier %v: %w", i, name, err)
+			}
+		}
+	}
+
+	_, err := p.Allow.networks()
+
+	return err
+}
+
+// networks gives the parsed networks of the allowlist. Single addresses are taken as
+// networks of their full length.
+func (a *Allowlist) networks() ([]netip.Prefix, error) {
+	result := make([]netip.Prefix, 0, len(a.Networks))
+
+	for _, n := range a.Networks {
+		if !strings.Contains(n, "/") {
+			addr, err := netip.ParseAddr(n)
+
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidNetwork, n)
+			}
+
+			result = append(result, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(n)
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidNetwork, n)
+		}
+
+		result = append(result, prefix.Masked())
+	}
+
+	return result, nil
+}
+
+// ReadPolicy reads a policy in the given format.
+func ReadPolicy(r io.Reader, format Format) (*Policy, error) {
+	policy, err := helper.ReadConfig[Policy](r, format)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// ReadPolicyFile reads a policy from the given file. The format is determined by the
+// file extension, .json for JSON and .yaml or .yml for YAML.
+func ReadPolicyFile(fileName string) (*Policy, error) {
+	policy, err := helper.ReadConfigFile[Policy](fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := policy.validate(); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
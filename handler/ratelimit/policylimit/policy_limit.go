@@ -0,0 +1,297 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package policylimit provides a request limiter applying different rate limits per
+// tier of the clients and per route, configurable by policy files.
+package policylimit
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+	"github.com/AlphaOne1/midgard/handler/ratelimit/gcra"
+)
+
+// ErrNilOption is returned when an option is nil.
+var ErrNilOption = errors.New("option cannot be nil")
+
+// ErrNilPolicy is returned when no policy is given.
+var ErrNilPolicy = errors.New("policy cannot be nil")
+
+// ErrNilTierFunc is returned when the tier function is nil.
+var ErrNilTierFunc = errors.New("tier function cannot be nil")
+
+// TierFunc gives the tier of a request. It gives an empty string, if the tier is not
+// known, so the default tier applies.
+type TierFunc func(r *http.Request) string
+
+// limitRef references the limit of a tier, either the general one or that of a route.
+type limitRef struct {
+	route int // route is the index of the route, -1 for the general limit
+	tier  string
+}
+
+// PolicyLimiter is a ratelimit.RequestLimiter applying the limits of a policy. Each limit
+// is kept per client by a ratelimit.KeyedLimiter, the limits of a route separate from
+// the general ones. Unlimited tiers and allowlisted requests bypass the limits.
+type PolicyLimiter struct {
+	policy   *Policy                               // policy holds the limits
+	networks []netip.Prefix                        // networks are the allowlisted client networks
+	tier     TierFunc                              // tier gives the tier of a request
+	keyed    []func(*ratelimit.KeyedLimiter) error // keyed are the options of the keyed limiters
+	limiters map[limitRef]*ratelimit.KeyedLimiter  // limiters hold the limiters, none for unlimited tiers
+}
+
+// LimitRequest gives true, if the request may pass, otherwise false.
+func (l *PolicyLimiter) LimitRequest(r *http.Request) bool {
+	limiter := l.limiter(r)
+
+	return limiter == nil || limiter.LimitRequest(r)
+}
+
+// LimitRequestQuota gives true, if the request may pass, and the quota of the applied
+// limit. Requests bypassing the limits have no quota.
+func (l *PolicyLimiter) LimitRequestQuota(r *http.Request) (bool, ratelimit.Quota, bool) {
+	return l.LimitRequestCost(r, 1)
+}
+
+// LimitRequestCost gives true, if the request of the given cost may pass, and the quota
+// of the applied limit. Requests bypassing the limits have no quota.
+func (l *PolicyLimiter) LimitRequestCost(r *http.Request, cost int64) (bool, ratelimit.Quota, bool) {
+	limiter := l.limiter(r)
+
+	if limiter == nil {
+		return true, ratelimit.Quota{}, false
+	}
+
+	return limiter.LimitRequestCost(r, cost)
+}
+
+// limiter gives the limiter applying to the request, nil if it bypasses the limits.
+func (l *PolicyLimiter) limiter(r *http.Request) *ratelimit.KeyedLimiter {
+	if l.allowlisted(r) {
+		return nil
+	}
+
+	tier := l.tier(r)
+
+	if _, ok := l.policy.Tiers[tier]; !ok {
+		tier = l.policy.DefaultTier
+	}
+
+	for i, route := range l.policy.Routes {
+		if _, ok := route.Tiers[tier]; ok && slices.Contains(route.Patterns, r.Pattern) {
+			return l.limiters[limitRef{route: i, tier: tier}]
+		}
+	}
+
+	return l.limiters[limitRef{route: -1, tier: tier}]
+}
+
+// allowlisted checks, if the request bypasses the limits.
+func (l *PolicyLimiter) allowlisted(r *http.Request) bool {
+	if p, ok := defs.PrincipalFromContext(r.Context()); ok && p != nil {
+		if p.Name != "" && slices.Contains(l.policy.Allow.Principals, p.Name) {
+			return true
+		}
+
+		if slices.ContainsFunc(l.policy.Allow.Roles, func(role string) bool {
+			return slices.Contains(p.Roles, role)
+		}) {
+			return true
+		}
+	}
+
+	if len(l.networks) == 0 {
+		return false
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+
+	if err != nil {
+		return false
+	}
+
+	return slices.ContainsFunc(l.networks, func(n netip.Prefix) bool {
+		return n.Contains(addr.Unmap())
+	})
+}
+
+// policyTier gives the tier named by the tier claim of the principal or, failing that,
+// the tier of the API key of the request.
+func (l *PolicyLimiter) policyTier(r *http.Request) string {
+	if p, ok := defs.PrincipalFromContext(r.Context()); ok && p != nil {
+		claim := l.policy.TierClaim
+
+		if claim == "" {
+			claim = DefaultTierClaim
+		}
+
+		if tier, ok := p.Claims[claim].(string); ok && tier != "" {
+			return tier
+		}
+	}
+
+	if l.policy.APIKeyHeader != "" {
+		if key := r.Header.Get(l.policy.APIKeyHeader); key != "" {
+			return l.policy.APIKeys[key]
+		}
+	}
+
+	return ""
+}
+
+// keyByAPIKey uses the API key of the request as key, if it is one of the policy. Unknown
+// keys give no key, as clients could otherwise get a fresh limit with every request by
+// changing their key.
+func (l *PolicyLimiter) keyByAPIKey() ratelimit.KeyFunc {
+	byHeader := ratelimit.KeyByHeader(l.policy.APIKeyHeader)
+
+	return func(r *http.Request) string {
+		if _, known := l.policy.APIKeys[r.Header.Get(l.policy.APIKeyHeader)]; !known {
+			return ""
+		}
+
+		return byHeader(r)
+	}
+}
+
+// WithPolicy sets the policy to apply.
+func WithPolicy(policy *Policy) func(l *PolicyLimiter) error {
+	return func(l *PolicyLimiter) error {
+		if policy == nil {
+			return ErrNilPolicy
+		}
+
+		if err := policy.validate(); err != nil {
+			return err
+		}
+
+		l.policy = policy
+
+		return nil
+	}
+}
+
+// WithPolicyFile reads the policy from the given JSON or YAML file and applies it like
+// WithPolicy.
+func WithPolicyFile(fileName string) func(l *PolicyLimiter) error {
+	return func(l *PolicyLimiter) error {
+		policy, err := ReadPolicyFile(fileName)
+
+		if err != nil {
+			return err
+		}
+
+		return WithPolicy(policy)(l)
+	}
+}
+
+// WithTierFunc sets the function giving the tier of a request, e.g. looking up the
+// metadata of an API key in a database. It replaces the tier claim and API keys of the
+// policy.
+func WithTierFunc(tier TierFunc) func(l *PolicyLimiter) error {
+	return func(l *PolicyLimiter) error {
+		if tier == nil {
+			return ErrNilTierFunc
+		}
+
+		l.tier = tier
+
+		return nil
+	}
+}
+
+// WithKeyedOptions sets options of the keyed limiters keeping the limits per client,
+// e.g. ratelimit.WithKeyFunc. By default, the clients are keyed by their principal,
+// their API key, if it is one of the policy, or their address, in this order.
+func WithKeyedOptions(options ...func(*ratelimit.KeyedLimiter) error) func(l *PolicyLimiter) error {
+	return func(l *PolicyLimiter) error {
+		l.keyed = append(l.keyed, options...)
+
+		return nil
+	}
+}
+
+// newKeyed creates the keyed limiter of a limit.
+func (l *PolicyLimiter) newKeyed(limit Limit) (*ratelimit.KeyedLimiter, error) {
+	keys := []ratelimit.KeyFunc{ratelimit.KeyByPrincipal()}
+
+	if l.policy.APIKeyHeader != "" {
+		keys = append(keys, l.keyByAPIKey())
+	}
+
+	keys = append(keys, ratelimit.KeyByIP())
+
+	return ratelimit.NewKeyed(
+		func(string) (ratelimit.Limiter, error) {
+			return gcra.New(gcra.WithRate(limit.Rate), gcra.WithBurst(limit.Burst))
+		},
+		append([]func(*ratelimit.KeyedLimiter) error{ratelimit.WithKeyFunc(ratelimit.KeyFirst(keys...))}, l.keyed...)...)
+}
+
+// New creates a new PolicyLimiter. A policy has to be given.
+func New(options ...func(*PolicyLimiter) error) (*PolicyLimiter, error) {
+	limiter := PolicyLimiter{
+		limiters: make(map[limitRef]*ratelimit.KeyedLimiter),
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&limiter); err != nil {
+			return nil, err
+		}
+	}
+
+	if limiter.policy == nil {
+		return nil, ErrNilPolicy
+	}
+
+	if limiter.tier == nil {
+		limiter.tier = limiter.policyTier
+	}
+
+	limiter.networks, _ = limiter.policy.Allow.networks()
+
+	limits := make(map[limitRef]Limit, len(limiter.policy.Tiers))
+
+	for tier, limit := range limiter.policy.Tiers {
+		limits[limitRef{route: -1, tier: tier}] = limit
+	}
+
+	for i, route := range limiter.policy.Routes {
+		for tier, limit := range route.Tiers {
+			limits[limitRef{route: i, tier: tier}] = limit
+		}
+	}
+
+	for ref, limit := range limits {
+		if limit.Unlimited {
+			continue
+		}
+
+		keyed, err := limiter.newKeyed(limit)
+
+		if err != nil {
+			return nil, err
+		}
+
+		limiter.limiters[ref] = keyed
+	}
+
+	return &limiter, nil
+}
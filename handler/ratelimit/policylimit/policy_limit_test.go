@@ -0,0 +1,267 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package policylimit_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/AlphaOne1/midgard"
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+	"github.com/AlphaOne1/midgard/handler/ratelimit/policylimit"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// newRequest creates a request from the given client to the given route.
+func newRequest(t *testing.T, remoteAddr string, principal *defs.Principal, apiKey, pattern string) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	req.Pattern = pattern
+
+	if principal != nil {
+		req = req.WithContext(defs.ContextWithPrincipal(req.Context(), principal))
+	}
+
+	if apiKey != "" {
+		req.Header.Set("X-Api-Key", apiKey)
+	}
+
+	return req
+}
+
+func TestPolicyLimit(t *testing.T) {
+	t.Parallel()
+
+	limiter := helper.Must(policylimit.New(policylimit.WithPolicyFile("testpolicy.yaml")))
+
+	tests := []struct {
+		RemoteAddr string
+		Principal  *defs.Principal
+		APIKey     string
+		Pattern    string
+		Requests   int
+		WantPassed int
+	}{
+		{RemoteAddr: "192.0.2.1:1234", Pattern: "GET /", Requests: 3, WantPassed: 2},       // 0 free tier
+		{RemoteAddr: "192.0.2.1:1234", Pattern: "GET /export", Requests: 2, WantPassed: 1}, // 1 route limit of the free tier
+		{RemoteAddr: "192.0.2.2:1234", Pattern: "GET /", Requests: 3, WantPassed: 2},       // 2 limited per client
+		{RemoteAddr: "192.0.2.1:1234", APIKey: "pro-key", Requests: 6, WantPassed: 5},      // 3 tier of the api key
+		{RemoteAddr: "192.0.2.3:1234", APIKey: "unknown", Requests: 3, WantPassed: 2},      // 4 unknown api key
+		{ // 5 tier of the principal
+			RemoteAddr: "192.0.2.1:1234",
+			Principal:  &defs.Principal{Name: "svc", Claims: map[string]any{"tier": "internal"}},
+			Requests:   20, WantPassed: 20,
+		},
+		{ // 6 unknown tier of the principal
+			RemoteAddr: "192.0.2.1:1234",
+			Principal:  &defs.Principal{Name: "bob", Claims: map[string]any{"tier": "gold"}},
+			Requests:   3, WantPassed: 2,
+		},
+		{ // 7 route without limit for the tier
+			RemoteAddr: "192.0.2.1:1234",
+			Principal:  &defs.Principal{Name: "alice", Claims: map[string]any{"tier": "pro"}},
+			Pattern:    "GET /export",
+			Requests:   6, WantPassed: 5,
+		},
+		{RemoteAddr: "10.1.2.3:1234", Requests: 20, WantPassed: 20},                                                                  // 8 allowlisted network
+		{RemoteAddr: "192.0.2.100:1234", Requests: 20, WantPassed: 20},                                                               // 9 allowlisted address
+		{RemoteAddr: "192.0.2.1:1234", Principal: &defs.Principal{Name: "monitoring"}, Requests: 20, WantPassed: 20},                 // 10
+		{RemoteAddr: "192.0.2.1:1234", Principal: &defs.Principal{Name: "op", Roles: []string{"ops"}}, Requests: 20, WantPassed: 20}, // 11
+	}
+
+	for k, test := range tests {
+		passed := 0
+
+		for range test.Requests {
+			if limiter.LimitRequest(newRequest(t, test.RemoteAddr, test.Principal, test.APIKey, test.Pattern)) {
+				passed++
+			}
+		}
+
+		if passed != test.WantPassed {
+			t.Errorf("%v: got %v requests passed but wanted %v", k, passed, test.WantPassed)
+		}
+	}
+}
+
+func TestPolicyUnknownAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	limiter := helper.Must(policylimit.New(policylimit.WithPolicyFile("testpolicy.yaml")))
+	passed := 0
+
+	// changing the key must not give a fresh limit
+	for i := range 100 {
+		if limiter.LimitRequest(newRequest(t, "192.0.2.1:1234", nil, "bogus-"+strconv.Itoa(i), "GET /")) {
+			passed++
+		}
+	}
+
+	if passed > 3 {
+		t.Errorf("got %v requests with unknown api keys passed, wanted at most the burst", passed)
+	}
+}
+
+func TestPolicyQuotaHeaders(t *testing.T) {
+	t.Parallel()
+
+	limiter := helper.Must(policylimit.New(policylimit.WithPolicyFile("testpolicy.json")))
+
+	handler := midgard.StackMiddlewareHandler(
+		[]defs.Middleware{helper.Must(ratelimit.New(
+			ratelimit.WithRequestLimiter(limiter),
+			ratelimit.WithCostFunc(ratelimit.CostByRoute(map[string]int64{"GET /search": 3}, 1))))},
+		http.HandlerFunc(helper.DummyHandler))
+
+	tests := []struct {
+		RemoteAddr string
+		APIKey     string
+		Pattern    string
+		WantStatus int
+		WantPolicy string
+	}{
+		{RemoteAddr: "192.0.2.1:1234", WantStatus: http.StatusOK, WantPolicy: `"default";q=2;w=2`},                                      // 0
+		{RemoteAddr: "192.0.2.1:1234", APIKey: "pro-key", WantStatus: http.StatusOK, WantPolicy: `"default";q=5;w=1`},                   // 1
+		{RemoteAddr: "192.0.2.2:1234", Pattern: "GET /search", WantStatus: http.StatusTooManyRequests, WantPolicy: `"default";q=2;w=2`}, // 2 too expensive
+		{RemoteAddr: "10.0.0.1:1234", Pattern: "GET /search", WantStatus: http.StatusOK},                                                // 3 bypass without quota
+	}
+
+	for k, test := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, newRequest(t, test.RemoteAddr, nil, test.APIKey, test.Pattern))
+
+		if rec.Code != test.WantStatus {
+			t.Errorf("%v: got status %v but wanted %v", k, rec.Code, test.WantStatus)
+		}
+
+		if got := rec.Header().Get("RateLimit-Policy"); got != test.WantPolicy {
+			t.Errorf("%v: got RateLimit-Policy %q but wanted %q", k, got, test.WantPolicy)
+		}
+	}
+}
+
+func TestTierFunc(t *testing.T) {
+	t.Parallel()
+
+	limiter := helper.Must(policylimit.New(
+		policylimit.WithPolicyFile("testpolicy.yaml"),
+		policylimit.WithTierFunc(func(r *http.Request) string { return r.Header.Get("X-Tier") }),
+		policylimit.WithKeyedOptions(ratelimit.WithKeyFunc(ratelimit.KeyByHeader("X-Tenant")))))
+
+	passed := 0
+
+	for i := range 10 {
+		req := newRequest(t, "192.0.2.1:1234", nil, "pro-key", "")
+		req.Header.Set("X-Tenant", "acme")
+
+		if i%2 == 0 {
+			req.Header.Set("X-Tier", "pro")
+		}
+
+		if limiter.LimitRequest(req) {
+			passed++
+		}
+	}
+
+	// the api key is ignored, 5 requests of the pro tier and 2 of the free tier pass
+	if passed != 7 {
+		t.Errorf("got %v requests passed but wanted 7", passed)
+	}
+}
+
+func TestReadPolicy(t *testing.T) {
+	t.Parallel()
+
+	fromYAML := helper.Must(policylimit.ReadPolicyFile("testpolicy.yaml"))
+	fromJSON := helper.Must(policylimit.ReadPolicyFile("testpolicy.json"))
+
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Errorf("policies differ:\n%+v\n%+v", fromYAML, fromJSON)
+	}
+
+	tests := []struct {
+		Policy  string
+		WantErr error
+	}{
+		{Policy: `{"defaultTier": "free"}`, WantErr: policylimit.ErrNoTiers},                                                 // 0
+		{Policy: `{"defaultTier": "gold", "tiers": {"free": {"rate": 1, "burst": 1}}}`, WantErr: policylimit.ErrUnknownTier}, // 1
+		{Policy: `{"defaultTier": "free", "tiers": {"free": {"rate": 1}}}`, WantErr: policylimit.ErrInvalidLimit},            // 2
+		{ // 3
+			Policy:  `{"defaultTier": "free", "tiers": {"free": {"unlimited": true}}, "apiKeys": {"k": "pro"}}`,
+			WantErr: policylimit.ErrUnknownTier,
+		},
+		{ // 4
+			Policy:  `{"defaultTier": "free", "tiers": {"free": {"unlimited": true}}, "routes": [{"tiers": {}}]}`,
+			WantErr: policylimit.ErrNoPatterns,
+		},
+		{ // 5
+			Policy:  `{"defaultTier": "free", "tiers": {"free": {"unlimited": true}}, "routes": [{"patterns": ["/"], "tiers": {"pro": {"unlimited": true}}}]}`,
+			WantErr: policylimit.ErrUnknownTier,
+		},
+		{ // 6
+			Policy:  `{"defaultTier": "free", "tiers": {"free": {"unlimited": true}}, "routes": [{"patterns": ["/"], "tiers": {"free": {"burst": 1}}}]}`,
+			WantErr: policylimit.ErrInvalidLimit,
+		},
+		{ // 7
+			Policy:  `{"defaultTier": "free", "tiers": {"free": {"unlimited": true}}, "allow": {"networks": ["10.0.0.0/33"]}}`,
+			WantErr: policylimit.ErrInvalidNetwork,
+		},
+		{ // 8
+			Policy:  `{"defaultTier": "free", "tiers": {"free": {"unlimited": true}}, "allow": {"networks": ["localhost"]}}`,
+			WantErr: policylimit.ErrInvalidNetwork,
+		},
+		{Policy: `{"defaultTier": "free", "tiers": {"free": {"unlimited": true}}}`, WantErr: nil}, // 9
+	}
+
+	for k, test := range tests {
+		if _, err := policylimit.ReadPolicy(strings.NewReader(test.Policy), policylimit.FormatJSON); !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+
+	if _, err := policylimit.ReadPolicy(strings.NewReader(`{"unknown": 1}`), policylimit.FormatJSON); err == nil {
+		t.Errorf("expected error for unknown field")
+	}
+
+	if _, err := policylimit.ReadPolicy(strings.NewReader(""), "toml"); !errors.Is(err, policylimit.ErrUnknownFormat) {
+		t.Errorf("got error %v but wanted %v", err, policylimit.ErrUnknownFormat)
+	}
+}
+
+func TestOptionErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options []func(*policylimit.PolicyLimiter) error
+		WantErr error
+	}{
+		{Options: nil, WantErr: policylimit.ErrNilPolicy},                                                                                         // 0
+		{Options: []func(*policylimit.PolicyLimiter) error{nil}, WantErr: policylimit.ErrNilOption},                                               // 1
+		{Options: []func(*policylimit.PolicyLimiter) error{policylimit.WithPolicy(nil)}, WantErr: policylimit.ErrNilPolicy},                       // 2
+		{Options: []func(*policylimit.PolicyLimiter) error{policylimit.WithTierFunc(nil)}, WantErr: policylimit.ErrNilTierFunc},                   // 3
+		{Options: []func(*policylimit.PolicyLimiter) error{policylimit.WithPolicyFile("testpolicy.toml")}, WantErr: policylimit.ErrUnknownFormat}, // 4
+		{Options: []func(*policylimit.PolicyLimiter) error{policylimit.WithPolicy(&policylimit.Policy{})}, WantErr: policylimit.ErrNoTiers},       // 5
+		{ // 6
+			Options: []func(*policylimit.PolicyLimiter) error{
+				policylimit.WithPolicyFile("testpolicy.yaml"),
+				policylimit.WithKeyedOptions(ratelimit.WithMaxKeys(0)),
+			},
+			WantErr: ratelimit.ErrInvalidMaxKeys,
+		},
+	}
+
+	for k, test := range tests {
+		if _, err := policylimit.New(test.Options...); !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidPolicyName is returned when the policy name is empty or contains characters
+// not allowed in a structured field string.
+var ErrInvalidPolicyName = errors.New("invalid policy name")
+
+// DefaultPolicyName is the default name of the policy in the RateLimit headers.
+const DefaultPolicyName = "default"
+
+// Quota describes the state of a limit at the time of a request.
+type Quota struct {
+	// Limit is the number of requests allowed within the Window.
+	Limit int64
+	// Window is the time in which the Limit is restored completely.
+	Window time.Duration
+	// Remaining is the number of requests that may currently pass.
+	Remaining int64
+	// Reset is the time until the quota is restored completely.
+	Reset time.Duration
+	// RetryAfter is the time until the next request may pass, 0 if it may pass now or
+	// if it exceeds the Limit and will never pass.
+	RetryAfter time.Duration
+}
+
+// QuotaLimiter is a Limiter that also reports its quota. The handler uses it to inform
+// the clients about the limits via the RateLimit headers.
+type QuotaLimiter interface {
+	Limiter
+
+	// LimitQuota gives true, if the rate limit is not yet exceeded, and the quota after
+	// the decision.
+	LimitQuota() (bool, Quota)
+}
+
+// RequestQuotaLimiter is a RequestLimiter that may also report its quota.
+type RequestQuotaLimiter interface {
+	RequestLimiter
+
+	// LimitRequestQuota gives true, if the request may pass, and the quota after the
+	// decision. If no quota is known for the request, ok is false.
+	LimitRequestQuota(r *http.Request) (allowed bool, quota Quota, ok bool)
+}
+
+// seconds gives the duration in whole seconds, rounded up.
+func seconds(d time.Duration) int64 {
+	return int64((max(0, d) + time.Second - 1) / time.Second)
+}
+
+// validPolicyName checks that the name can be written as structured field string
+// without escaping, i.e. consists of printable ASCII characters excluding the quote
+// and the backslash.
+func validPolicyName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, c := range []byte(name) {
+		if c < 0x20 || c > 0x7e || c == '"' || c == '\\' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// writeQuotaHeaders sets the RateLimit-Policy and RateLimit headers following
+// draft-ietf-httpapi-ratelimit-headers. If the request is rejected and retry is set,
+// also the Retry-After header is set.
+func writeQuotaHeaders(header http.Header, policy string, quota Quota, allowed, retry bool) {
+	var b strings.Builder
+
+	b.WriteString(`"` + policy + `";q=`)
+	b.WriteString(strconv.FormatInt(quota.Limit, 10))
+
+	if quota.Window > 0 {
+		b.WriteString(";w=")
+		b.WriteString(strconv.FormatInt(seconds(quota.Window), 10))
+	}
+
+	header.Set("RateLimit-Policy", b.String())
+
+	header.Set("RateLimit", `"`+policy+`";r=`+strconv.FormatInt(max(0, quota.Remaining), 10)+
+		";t="+strconv.FormatInt(seconds(quota.Reset), 10))
+
+	if !allowed && retry {
+		header.Set("Retry-After", strconv.FormatInt(max(1, seconds(quota.RetryAfter)), 10))
+	}
+}
@@ -0,0 +1,174 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package ratelimit_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard"
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+	"github.com/AlphaOne1/midgard/handler/ratelimit/gcra"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// fixedQuota is a QuotaLimiter giving a fixed decision and quota.
+type fixedQuota struct {
+	allowed bool
+	quota   ratelimit.Quota
+}
+
+func (f fixedQuota) Limit() bool {
+	return f.allowed
+}
+
+func (f fixedQuota) LimitQuota() (bool, ratelimit.Quota) {
+	return f.allowed, f.quota
+}
+
+func TestQuotaHeaders(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Limiter        ratelimit.Limiter
+		Options        []func(*ratelimit.Handler) error
+		WantStatus     int
+		WantPolicy     string
+		WantRateLimit  string
+		WantRetryAfter string
+	}{
+		{ // 0
+			Limiter: fixedQuota{
+				allowed: true,
+				quota:   ratelimit.Quota{Limit: 100, Window: time.Minute, Remaining: 42, Reset: 1500 * time.Millisecond},
+			},
+			WantStatus:    http.StatusOK,
+			WantPolicy:    `"default";q=100;w=60`,
+			WantRateLimit: `"default";r=42;t=2`,
+		},
+		{ // 1
+			Limiter: fixedQuota{
+				allowed: false,
+				quota: ratelimit.Quota{
+					Limit: 10, Window: time.Second, Reset: time.Second, RetryAfter: 2100 * time.Millisecond,
+				},
+			},
+			Options:        []func(*ratelimit.Handler) error{ratelimit.WithPolicyName("burst")},
+			WantStatus:     http.StatusTooManyRequests,
+			WantPolicy:     `"burst";q=10;w=1`,
+			WantRateLimit:  `"burst";r=0;t=1`,
+			WantRetryAfter: "3",
+		},
+		{ // 2 retry at least after a second
+			Limiter:        fixedQuota{allowed: false, quota: ratelimit.Quota{Limit: 5}},
+			WantStatus:     http.StatusTooManyRequests,
+			WantPolicy:     `"default";q=5`,
+			WantRateLimit:  `"default";r=0;t=0`,
+			WantRetryAfter: "1",
+		},
+		{ // 3
+			Limiter:    fixedQuota{allowed: false, quota: ratelimit.Quota{Limit: 5}},
+			Options:    []func(*ratelimit.Handler) error{ratelimit.WithQuotaHeaders(false)},
+			WantStatus: http.StatusTooManyRequests,
+		},
+		{ // 4 limiters without quota give no headers
+			Limiter:    budgetFactoryLimit(1),
+			WantStatus: http.StatusOK,
+		},
+	}
+
+	for k, test := range tests {
+		options := append([]func(*ratelimit.Handler) error{ratelimit.WithLimiter(test.Limiter)}, test.Options...)
+		handler := midgard.StackMiddlewareHandler(
+			[]defs.Middleware{helper.Must(ratelimit.New(options...))},
+			http.HandlerFunc(helper.DummyHandler))
+
+		req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.WantStatus {
+			t.Errorf("%v: got status %v but wanted %v", k, rec.Code, test.WantStatus)
+		}
+
+		for name, want := range map[string]string{
+			"RateLimit-Policy": test.WantPolicy,
+			"RateLimit":        test.WantRateLimit,
+			"Retry-After":      test.WantRetryAfter,
+		} {
+			if got := rec.Header().Get(name); got != want {
+				t.Errorf("%v: got %v header %q but wanted %q", k, name, got, want)
+			}
+		}
+	}
+}
+
+// budgetFactoryLimit gives a limiter without quota reporting allowing n requests.
+func budgetFactoryLimit(n int64) ratelimit.Limiter {
+	return helper.Must(budgetFactory(n, nil)(""))
+}
+
+func TestKeyedQuotaHeaders(t *testing.T) {
+	t.Parallel()
+
+	limiter := helper.Must(ratelimit.NewKeyed(func(string) (ratelimit.Limiter, error) {
+		return gcra.New(gcra.WithRate(1), gcra.WithBurst(2))
+	}))
+	handler := midgard.StackMiddlewareHandler(
+		[]defs.Middleware{helper.Must(ratelimit.New(ratelimit.WithRequestLimiter(limiter)))},
+		http.HandlerFunc(helper.DummyHandler))
+
+	tests := []struct {
+		Remote         string
+		WantStatus     int
+		WantRateLimit  string
+		WantRetryAfter string
+	}{
+		{Remote: "192.0.2.1:1", WantStatus: http.StatusOK, WantRateLimit: `"default";r=1;t=1`},                                   // 0
+		{Remote: "192.0.2.1:1", WantStatus: http.StatusOK, WantRateLimit: `"default";r=0;t=2`},                                   // 1
+		{Remote: "192.0.2.1:1", WantStatus: http.StatusTooManyRequests, WantRateLimit: `"default";r=0;t=2`, WantRetryAfter: "1"}, // 2
+		{Remote: "192.0.2.2:1", WantStatus: http.StatusOK, WantRateLimit: `"default";r=1;t=1`},                                   // 3
+	}
+
+	for k, test := range tests {
+		req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+		req.RemoteAddr = test.Remote
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != test.WantStatus {
+			t.Errorf("%v: got status %v but wanted %v", k, rec.Code, test.WantStatus)
+		}
+
+		if got := rec.Header().Get("RateLimit-Policy"); got != `"default";q=2;w=2` {
+			t.Errorf("%v: got policy %q", k, got)
+		}
+
+		if got := rec.Header().Get("RateLimit"); got != test.WantRateLimit {
+			t.Errorf("%v: got RateLimit %q but wanted %q", k, got, test.WantRateLimit)
+		}
+
+		if got := rec.Header().Get("Retry-After"); got != test.WantRetryAfter {
+			t.Errorf("%v: got Retry-After %q but wanted %q", k, got, test.WantRetryAfter)
+		}
+	}
+}
+
+func TestInvalidPolicyName(t *testing.T) {
+	t.Parallel()
+
+	for k, name := range []string{"", `with "quote"`, "back\\slash", "new\nline"} {
+		_, err := ratelimit.New(ratelimit.WithLimiter(budgetFactoryLimit(1)), ratelimit.WithPolicyName(name))
+
+		if !errors.Is(err, ratelimit.ErrInvalidPolicyName) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, ratelimit.ErrInvalidPolicyName)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package quotalimit
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// adminCounter is the representation of a counter in the admin API.
+type adminCounter struct {
+	Key       string    `json:"key"`
+	Window    time.Time `json:"window"`
+	Limit     int64     `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+// AdminHandler gives an http.Handler to inspect and reset the counters:
+//
+//   - GET lists the counters of all keys in the current windows,
+//   - GET with the query parameter key gives the counter of that key,
+//   - DELETE with the query parameter key resets the counter of that key.
+//
+// The handler does not check any permissions, so it has to be protected, e.g. by the
+// authz middleware, and should not be exposed publicly.
+func (l *QuotaLimit) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, hasKey := r.URL.Query()["key"]
+
+		switch {
+		case r.Method == http.MethodGet && hasKey:
+			l.writeJSON(w, l.adminCounter(key[0]))
+		case r.Method == http.MethodGet:
+			counters := l.Counters()
+			result := make([]adminCounter, 0, len(counters))
+
+			for _, c := range counters {
+				result = append(result, l.adminCounter(c.Key))
+			}
+
+			l.writeJSON(w, result)
+		case r.Method == http.MethodDelete && hasKey:
+			if err := l.Reset(key[0]); err != nil {
+				l.log.Warn("could not reset quota counter",
+					slog.String("key", key[0]),
+					slog.String("error", err.Error()))
+				helper.WriteState(w, l.log, http.StatusInternalServerError)
+
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodDelete:
+			helper.WriteState(w, l.log, http.StatusBadRequest)
+		default:
+			w.Header().Set("Allow", "GET, DELETE")
+			helper.WriteState(w, l.log, http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// adminCounter gives the counter of the key in the current window.
+func (l *QuotaLimit) adminCounter(key string) adminCounter {
+	now := l.now()
+	quota := l.Quota(key)
+	start, _ := l.window(now.In(l.Location))
+
+	return adminCounter{
+		Key:       key,
+		Window:    start,
+		Limit:     quota.Limit,
+		Used:      quota.Limit - quota.Remaining,
+		Remaining: quota.Remaining,
+		Reset:     now.Add(quota.Reset).In(l.Location),
+	}
+}
+
+// writeJSON writes the value as JSON response.
+func (l *QuotaLimit) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		l.log.Debug("could not write response", slog.String("error", err.Error()))
+	}
+}
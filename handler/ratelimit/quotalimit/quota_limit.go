@@ -0,0 +1,420 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package quotalimit provides a request limiter enforcing daily or monthly quotas,
+// persisting its counters so they survive restarts.
+package quotalimit
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+)
+
+// ErrNilOption is returned when an option is nil.
+var ErrNilOption = errors.New("option cannot be nil")
+
+// ErrZeroLimit is returned when the quota is not greater than 0.
+var ErrZeroLimit = errors.New("quota must be greater than 0")
+
+// ErrInvalidPeriod is returned when the period is unknown.
+var ErrInvalidPeriod = errors.New("invalid period")
+
+// ErrNilLocation is returned when the location is nil.
+var ErrNilLocation = errors.New("location cannot be nil")
+
+// ErrNilStore is returned when the store is nil.
+var ErrNilStore = errors.New("store cannot be nil")
+
+// DefaultMaxKeys is the default maximum number of keys with their own counter.
+const DefaultMaxKeys = 100_000
+
+// Period is the length of the calendar-aligned windows of a quota.
+type Period int
+
+const (
+	// Day windows start at midnight.
+	Day Period = iota
+	// Month windows start at midnight of the first day of the month.
+	Month
+)
+
+// QuotaLimit is a ratelimit.RequestLimiter allowing each key a quota of requests, or cost
+// units, per calendar day or month. The windows are aligned to the calendar of the
+// configured location, so a daily quota of a client in Berlin resets at midnight in
+// Berlin. The counters are kept in a Store, so they survive restarts. The number of
+// keys with a counter is bounded, requests of further keys pass until the next window.
+type QuotaLimit struct {
+	// Limit is the quota of each key per window.
+	Limit int64
+	// Period is the length of the windows.
+	Period Period
+	// Location is the time zone the windows are aligned to.
+	Location *time.Location
+
+	mtx      sync.Mutex
+	key      ratelimit.KeyFunc   // key extracts the key of the requests
+	store    Store               // store persists the counters
+	counters map[string]*Counter // counters hold the counters of the current window
+	current  time.Time           // current is the start of the window of the counters
+	maxKeys  int                 // maxKeys is the maximum number of keys with a counter
+	full     bool                // full is set, if the maximum number of keys was reported
+	log      *slog.Logger        // log is used to report failures of the store
+	now      func() time.Time
+}
+
+// LimitRequest gives true, if the quota of the request key is not yet used up.
+func (l *QuotaLimit) LimitRequest(r *http.Request) bool {
+	allowed, _, _ := l.LimitRequestCost(r, 1)
+
+	return allowed
+}
+
+// LimitRequestQuota gives true, if the quota of the request key is not yet used up, and
+// the quota after the decision.
+func (l *QuotaLimit) LimitRequestQuota(r *http.Request) (bool, ratelimit.Quota, bool) {
+	return l.LimitRequestCost(r, 1)
+}
+
+// LimitRequestCost gives true, if the quota of the request key suffices for the given
+// cost, and the quota after the decision.
+func (l *QuotaLimit) LimitRequestCost(r *http.Request, cost int64) (bool, ratelimit.Quota, bool) {
+	now := l.now().In(l.Location)
+	key := l.key(r)
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	c := l.counter(key, now)
+
+	if c == nil {
+		return true, ratelimit.Quota{}, false
+	}
+
+	allowed := cost <= 0 || c.Count+cost <= l.Limit
+
+	if allowed && cost > 0 {
+		c.Count += cost
+		l.save(*c)
+	}
+
+	quota := l.quota(c, now)
+
+	if !allowed {
+		// the remaining quota may not suffice for the cost until the next window
+		quota.RetryAfter = quota.Reset
+	}
+
+	return allowed, quota, true
+}
+
+// window gives the start of the window containing t and the start of the next one.
+func (l *QuotaLimit) window(t time.Time) (time.Time, time.Time) {
+	if l.Period == Month {
+		start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, l.Location)
+
+		return start, start.AddDate(0, 1, 0)
+	}
+
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, l.Location)
+
+	return start, start.AddDate(0, 0, 1)
+}
+
+// rollover drops the counters of past windows, if the window containing now has not yet
+// been seen.
+func (l *QuotaLimit) rollover(now time.Time) time.Time {
+	start, _ := l.window(now)
+
+	if !start.Equal(l.current) {
+		l.current = start
+		l.full = false
+		clear(l.counters)
+	}
+
+	return start
+}
+
+// counter gives the counter of the key in the current window, starting a new one if
+// there is none. If the maximum number of keys is reached, no new counter is started
+// and nil is returned. This is reported once per window.
+func (l *QuotaLimit) counter(key string, now time.Time) *Counter {
+	start := l.rollover(now)
+
+	if c, found := l.counters[key]; found {
+		return c
+	}
+
+	if len(l.counters) >= l.maxKeys {
+		if !l.full {
+			l.full = true
+			l.log.Warn("maximum number of quota keys reached, further keys pass unlimited",
+				slog.Int("maxKeys", l.maxKeys))
+		}
+
+		return nil
+	}
+
+	c := &Counter{Key: key, Window: start}
+	l.counters[key] = c
+
+	return c
+}
+
+// quota gives the quota of the counter at the given time.
+func (l *QuotaLimit) quota(c *Counter, now time.Time) ratelimit.Quota {
+	start, next := l.window(now)
+	quota := ratelimit.Quota{
+		Limit:     l.Limit,
+		Window:    next.Sub(start),
+		Remaining: max(0, l.Limit-c.Count),
+		Reset:     next.Sub(now),
+	}
+
+	if quota.Remaining == 0 {
+		quota.RetryAfter = quota.Reset
+	}
+
+	return quota
+}
+
+// save persists the counter, logging failures. The counting continues in memory.
+func (l *QuotaLimit) save(c Counter) {
+	if err := l.store.Save(c); err != nil {
+		l.log.Warn("could not save quota counter",
+			slog.String("key", c.Key),
+			slog.String("error", err.Error()))
+	}
+}
+
+// Counters gives the counters of all keys in their current windows.
+func (l *QuotaLimit) Counters() []Counter {
+	now := l.now().In(l.Location)
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.rollover(now)
+
+	result := make([]Counter, 0, len(l.counters))
+
+	for key, c := range l.counters {
+		if c.Count == 0 {
+			// drop the counters of keys not seen in the current window
+			delete(l.counters, key)
+
+			continue
+		}
+
+		result = append(result, *c)
+	}
+
+	slices.SortFunc(result, func(a, b Counter) int { return strings.Compare(a.Key, b.Key) })
+
+	return result
+}
+
+// Quota gives the quota of the key in the current window.
+func (l *QuotaLimit) Quota(key string) ratelimit.Quota {
+	now := l.now().In(l.Location)
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	start, _ := l.window(now)
+
+	if c, found := l.counters[key]; found && c.Window.Equal(start) {
+		return l.quota(c, now)
+	}
+
+	return l.quota(&Counter{Key: key, Window: start}, now)
+}
+
+// Reset restores the full quota of the key in the current window.
+func (l *QuotaLimit) Reset(key string) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	c, found := l.counters[key]
+
+	if !found {
+		return nil
+	}
+
+	delete(l.counters, key)
+	c.Count = 0
+
+	return l.store.Save(*c)
+}
+
+// Close closes the store.
+func (l *QuotaLimit) Close() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.store.Close()
+}
+
+// WithLimit sets the quota of each key per window.
+func WithLimit(n int64) func(l *QuotaLimit) error {
+	return func(l *QuotaLimit) error {
+		if n <= 0 {
+			return ErrZeroLimit
+		}
+
+		l.Limit = n
+
+		return nil
+	}
+}
+
+// WithPeriod sets the length of the windows, Day or Month.
+func WithPeriod(p Period) func(l *QuotaLimit) error {
+	return func(l *QuotaLimit) error {
+		if p != Day && p != Month {
+			return ErrInvalidPeriod
+		}
+
+		l.Period = p
+
+		return nil
+	}
+}
+
+// WithLocation sets the time zone the windows are aligned to. Without it, UTC is used.
+func WithLocation(loc *time.Location) func(l *QuotaLimit) error {
+	return func(l *QuotaLimit) error {
+		if loc == nil {
+			return ErrNilLocation
+		}
+
+		l.Location = loc
+
+		return nil
+	}
+}
+
+// WithKeyFunc sets the function extracting the key of the requests. Without it, the
+// requests are keyed by their client address. Requests without key share one quota.
+func WithKeyFunc(key ratelimit.KeyFunc) func(l *QuotaLimit) error {
+	return func(l *QuotaLimit) error {
+		if key == nil {
+			return ratelimit.ErrNilKeyFunc
+		}
+
+		l.key = key
+
+		return nil
+	}
+}
+
+// WithStore sets the store persisting the counters, e.g. a FileStore. Without it, the
+// counters are kept in memory only.
+func WithStore(s Store) func(l *QuotaLimit) error {
+	return func(l *QuotaLimit) error {
+		if s == nil {
+			return ErrNilStore
+		}
+
+		l.store = s
+
+		return nil
+	}
+}
+
+// WithMaxKeys sets the maximum number of keys with a counter per window. Requests of
+// further keys pass without limit until the next window, logging a warning once. This
+// fails open, as a client flooding the limiter with new keys, e.g. addresses, could
+// otherwise use up a quota shared by all further keys or reset the quotas of others by
+// evicting their counters. The maximum should exceed the number of expected clients.
+func WithMaxKeys(n int) func(l *QuotaLimit) error {
+	return func(l *QuotaLimit) error {
+		if n <= 0 {
+			return ratelimit.ErrInvalidMaxKeys
+		}
+
+		l.maxKeys = n
+
+		return nil
+	}
+}
+
+// WithLogger sets the logger used to report failures of the store.
+func WithLogger(log *slog.Logger) func(l *QuotaLimit) error {
+	return func(l *QuotaLimit) error {
+		if log == nil {
+			log = slog.Default()
+		}
+
+		l.log = log
+
+		return nil
+	}
+}
+
+// New creates a new quota limiter, loading the counters of the current windows from
+// the store. The quota has to be given.
+func New(options ...func(*QuotaLimit) error) (*QuotaLimit, error) {
+	limiter := QuotaLimit{
+		Period:   Day,
+		Location: time.UTC,
+		key:      ratelimit.KeyByIP(),
+		store:    memoryStore{},
+		counters: make(map[string]*Counter),
+		maxKeys:  DefaultMaxKeys,
+		log:      slog.Default(),
+		now:      time.Now,
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&limiter); err != nil {
+			return nil, err
+		}
+	}
+
+	if limiter.Limit <= 0 {
+		return nil, ErrZeroLimit
+	}
+
+	counters, err := limiter.store.Load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	start := limiter.rollover(limiter.now().In(limiter.Location))
+
+	for _, c := range counters {
+		if c.Window.Equal(start) {
+			limiter.counters[c.Key] = &c
+		} else {
+			// counters of past windows are no longer needed
+			c.Count = 0
+			limiter.save(c)
+		}
+	}
+
+	return &limiter, nil
+}
+
+// memoryStore is the Store used without persistence.
+type memoryStore struct{}
+
+// Load gives no counters, as none are stored.
+func (memoryStore) Load() ([]Counter, error) { return nil, nil }
+
+// Save does nothing, the counters are only kept by the limiter.
+func (memoryStore) Save(Counter) error { return nil }
+
+// Close does nothing.
+func (memoryStore) Close() error { return nil }
@@ -0,0 +1,422 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package quotalimit_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard"
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+	"github.com/AlphaOne1/midgard/handler/ratelimit/quotalimit"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// zone is a time zone 2 hours ahead of UTC.
+var zone = time.FixedZone("UTC+2", 2*60*60)
+
+// clock is a time source advanced manually.
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time { return c.now }
+
+func (c *clock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newLimiter creates a limiter keyed by the API key using the given clock.
+func newLimiter(t *testing.T, c *clock, options ...func(*quotalimit.QuotaLimit) error) *quotalimit.QuotaLimit {
+	t.Helper()
+
+	limiter := helper.Must(quotalimit.New(append([]func(*quotalimit.QuotaLimit) error{
+		quotalimit.WithKeyFunc(ratelimit.KeyByHeader("X-Api-Key")),
+		quotalimit.WithLocation(zone),
+		quotalimit.TWithNow(c.Now),
+	}, options...)...))
+
+	return limiter
+}
+
+func newRequest(t *testing.T, apiKey string) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+	req.Header.Set("X-Api-Key", apiKey)
+
+	return req
+}
+
+func TestQuotaWindows(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Period   quotalimit.Period
+		Start    time.Time
+		Advances []time.Duration
+		Want     []bool
+		// WantQuota is the quota after the last request
+		WantQuota ratelimit.Quota
+	}{
+		{ // 0 the day ends at midnight of the zone
+			Period:   quotalimit.Day,
+			Start:    time.Date(2026, 10, 19, 21, 30, 0, 0, time.UTC),
+			Advances: []time.Duration{0, 0, 0, 29 * time.Minute, time.Minute},
+			Want:     []bool{true, true, false, false, true},
+			WantQuota: ratelimit.Quota{
+				Limit: 2, Window: 24 * time.Hour, Remaining: 1, Reset: 24 * time.Hour,
+			},
+		},
+		{ // 1 the month resets on its first day
+			Period:   quotalimit.Month,
+			Start:    time.Date(2026, 10, 2, 0, 0, 0, 0, zone),
+			Advances: []time.Duration{0, 10 * 24 * time.Hour, 10 * 24 * time.Hour, 10 * 24 * time.Hour},
+			Want:     []bool{true, true, false, true},
+			WantQuota: ratelimit.Quota{
+				Limit: 2, Window: 30 * 24 * time.Hour, Remaining: 1, Reset: 30 * 24 * time.Hour,
+			},
+		},
+	}
+
+	for k, test := range tests {
+		c := clock{now: test.Start}
+		limiter := newLimiter(t, &c, quotalimit.WithLimit(2), quotalimit.WithPeriod(test.Period))
+
+		var quota ratelimit.Quota
+
+		for i, want := range test.Want {
+			c.advance(test.Advances[i])
+
+			var got bool
+
+			got, quota, _ = limiter.LimitRequestQuota(newRequest(t, "a"))
+
+			if got != want {
+				t.Errorf("%v/%v: got %v but wanted %v", k, i, got, want)
+			}
+		}
+
+		if quota != test.WantQuota {
+			t.Errorf("%v: got quota %+v but wanted %+v", k, quota, test.WantQuota)
+		}
+	}
+}
+
+func TestQuotaCost(t *testing.T) {
+	t.Parallel()
+
+	c := clock{now: time.Date(2026, 10, 19, 12, 0, 0, 0, zone)}
+	limiter := newLimiter(t, &c, quotalimit.WithLimit(10))
+
+	tests := []struct {
+		Cost          int64
+		Want          bool
+		WantRemaining int64
+		WantRetry     time.Duration
+	}{
+		{Cost: 7, Want: true, WantRemaining: 3},                             // 0
+		{Cost: 4, Want: false, WantRemaining: 3, WantRetry: 12 * time.Hour}, // 1
+		{Cost: 0, Want: true, WantRemaining: 3},                             // 2
+		{Cost: 3, Want: true, WantRemaining: 0, WantRetry: 12 * time.Hour},  // 3
+	}
+
+	for k, test := range tests {
+		got, quota, _ := limiter.LimitRequestCost(newRequest(t, "a"), test.Cost)
+
+		if got != test.Want || quota.Remaining != test.WantRemaining || quota.RetryAfter != test.WantRetry {
+			t.Errorf("%v: got %v with quota %+v but wanted %v, %v remaining and retry after %v",
+				k, got, quota, test.Want, test.WantRemaining, test.WantRetry)
+		}
+	}
+}
+
+func TestQuotaMaxKeys(t *testing.T) {
+	t.Parallel()
+
+	c := clock{now: time.Date(2026, 10, 19, 12, 0, 0, 0, zone)}
+	limiter := newLimiter(t, &c, quotalimit.WithLimit(1), quotalimit.WithMaxKeys(2))
+
+	tests := []struct {
+		Advance   time.Duration
+		Key       string
+		Want      bool
+		WantQuota bool
+	}{
+		{Key: "a", Want: true, WantQuota: true},                          // 0
+		{Key: "b", Want: true, WantQuota: true},                          // 1
+		{Key: "c", Want: true, WantQuota: false},                         // 2 further keys pass
+		{Key: "c", Want: true, WantQuota: false},                         // 3
+		{Key: "a", Want: false, WantQuota: true},                         // 4
+		{Advance: 12 * time.Hour, Key: "c", Want: true, WantQuota: true}, // 5 the counters of the past window are dropped
+		{Key: "d", Want: true, WantQuota: true},                          // 6
+		{Key: "c", Want: false, WantQuota: true},                         // 7
+		{Key: "a", Want: true, WantQuota: false},                         // 8
+	}
+
+	for k, test := range tests {
+		c.advance(test.Advance)
+
+		got, _, hasQuota := limiter.LimitRequestQuota(newRequest(t, test.Key))
+
+		if got != test.Want || hasQuota != test.WantQuota {
+			t.Errorf("%v: got %v with quota %v but wanted %v with quota %v", k, got, hasQuota, test.Want, test.WantQuota)
+		}
+	}
+
+	if got := len(limiter.Counters()); got != 2 {
+		t.Errorf("got %v counters but wanted 2", got)
+	}
+}
+
+func TestQuotaPersistence(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "quota.log")
+	c := clock{now: time.Date(2026, 10, 19, 12, 0, 0, 0, zone)}
+
+	open := func() *quotalimit.QuotaLimit {
+		return newLimiter(t, &c,
+			quotalimit.WithLimit(3),
+			quotalimit.WithStore(helper.Must(quotalimit.NewFileStore(path))))
+	}
+
+	limiter := open()
+
+	limiter.LimitRequest(newRequest(t, "a"))
+	limiter.LimitRequest(newRequest(t, "a"))
+	limiter.LimitRequest(newRequest(t, "b"))
+
+	if err := limiter.Close(); err != nil {
+		t.Fatalf("could not close limiter: %v", err)
+	}
+
+	// the counters survive the restart
+	limiter = open()
+
+	if got := limiter.Quota("header:X-Api-Key:a").Remaining; got != 1 {
+		t.Errorf("got %v remaining but wanted 1", got)
+	}
+
+	if got := limiter.Quota("header:X-Api-Key:b").Remaining; got != 2 {
+		t.Errorf("got %v remaining but wanted 2", got)
+	}
+
+	_ = limiter.Close()
+
+	// counters of past windows are dropped
+	c.advance(24 * time.Hour)
+	limiter = open()
+
+	if got := limiter.Counters(); len(got) != 0 {
+		t.Errorf("got counters %+v but wanted none", got)
+	}
+
+	_ = limiter.Close()
+
+	if content := helper.Must(os.ReadFile(path)); len(content) != 0 {
+		t.Errorf("got file content %q but wanted it empty", content)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "quota.log")
+	window := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	// a broken last line, e.g. from a crash while writing, is ignored
+	content := `{"key":"a","window":"2026-10-19T00:00:00Z","count":1}` + "\n" +
+		`{"key":"a","window":"2026-10-19T00:00:00Z","count":2}` + "\n" +
+		`{"key":"b","wind`
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("could not write file: %v", err)
+	}
+
+	store := helper.Must(quotalimit.NewFileStore(path, quotalimit.WithCompactAfter(3)))
+
+	if got := helper.Must(store.Load()); len(got) != 1 || got[0].Count != 2 {
+		t.Errorf("got counters %+v but wanted a with 2", got)
+	}
+
+	lines := func() int {
+		return strings.Count(string(helper.Must(os.ReadFile(path))), "\n")
+	}
+
+	if got := lines(); got != 1 {
+		t.Errorf("got %v lines after opening but wanted 1", got)
+	}
+
+	for i := range 2 {
+		if err := store.Save(quotalimit.Counter{Key: "b", Window: window, Count: int64(i + 1)}); err != nil {
+			t.Errorf("could not save: %v", err)
+		}
+	}
+
+	if got := lines(); got != 3 {
+		t.Errorf("got %v lines but wanted 3", got)
+	}
+
+	// the third record compacts the file, dropping a without count
+	if err := store.Save(quotalimit.Counter{Key: "a", Window: window}); err != nil {
+		t.Errorf("could not save: %v", err)
+	}
+
+	if got := lines(); got != 1 {
+		t.Errorf("got %v lines after compaction but wanted 1", got)
+	}
+
+	// a counter of the next window drops those of the past one
+	if err := store.Save(quotalimit.Counter{Key: "c", Window: window.AddDate(0, 0, 1), Count: 1}); err != nil {
+		t.Errorf("could not save: %v", err)
+	}
+
+	if got := helper.Must(store.Load()); len(got) != 1 || got[0].Key != "c" {
+		t.Errorf("got counters %+v but wanted only c", got)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Errorf("could not close: %v", err)
+	}
+
+	if got := lines(); got != 1 {
+		t.Errorf("got %v lines after closing but wanted 1", got)
+	}
+
+	if err := store.Save(quotalimit.Counter{Key: "a"}); !errors.Is(err, quotalimit.ErrStoreClosed) {
+		t.Errorf("got error %v but wanted %v", err, quotalimit.ErrStoreClosed)
+	}
+
+	if _, err := store.Load(); !errors.Is(err, quotalimit.ErrStoreClosed) {
+		t.Errorf("got error %v but wanted %v", err, quotalimit.ErrStoreClosed)
+	}
+}
+
+func TestQuotaHeaders(t *testing.T) {
+	t.Parallel()
+
+	c := clock{now: time.Date(2026, 10, 19, 18, 0, 0, 0, zone)}
+	limiter := newLimiter(t, &c, quotalimit.WithLimit(1000))
+
+	handler := midgard.StackMiddlewareHandler(
+		[]defs.Middleware{helper.Must(ratelimit.New(
+			ratelimit.WithRequestLimiter(limiter),
+			ratelimit.WithPolicyName("daily")))},
+		http.HandlerFunc(helper.DummyHandler))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, newRequest(t, "a"))
+
+	if got := rec.Header().Get("RateLimit-Policy"); got != `"daily";q=1000;w=86400` {
+		t.Errorf("got RateLimit-Policy %q", got)
+	}
+
+	if got := rec.Header().Get("RateLimit"); got != `"daily";r=999;t=21600` {
+		t.Errorf("got RateLimit %q", got)
+	}
+}
+
+func TestAdminHandler(t *testing.T) {
+	t.Parallel()
+
+	c := clock{now: time.Date(2026, 10, 19, 18, 0, 0, 0, zone)}
+	limiter := newLimiter(t, &c, quotalimit.WithLimit(5))
+
+	limiter.LimitRequest(newRequest(t, "a"))
+	limiter.LimitRequest(newRequest(t, "a"))
+	limiter.LimitRequest(newRequest(t, "b"))
+
+	admin := limiter.AdminHandler()
+
+	tests := []struct {
+		Method     string
+		Target     string
+		WantStatus int
+		WantBody   string
+	}{
+		{ // 0
+			Method: http.MethodGet, Target: "/", WantStatus: http.StatusOK,
+			WantBody: `[{"key":"header:X-Api-Key:a","window":"2026-10-19T00:00:00+02:00","limit":5,"used":2,"remaining":3,"reset":"2026-10-20T00:00:00+02:00"},` +
+				`{"key":"header:X-Api-Key:b","window":"2026-10-19T00:00:00+02:00","limit":5,"used":1,"remaining":4,"reset":"2026-10-20T00:00:00+02:00"}]`,
+		},
+		{ // 1
+			Method: http.MethodGet, Target: "/?key=header:X-Api-Key:a", WantStatus: http.StatusOK,
+			WantBody: `{"key":"header:X-Api-Key:a","window":"2026-10-19T00:00:00+02:00","limit":5,"used":2,"remaining":3,"reset":"2026-10-20T00:00:00+02:00"}`,
+		},
+		{Method: http.MethodDelete, Target: "/?key=header:X-Api-Key:a", WantStatus: http.StatusNoContent}, // 2
+		{ // 3
+			Method: http.MethodGet, Target: "/", WantStatus: http.StatusOK,
+			WantBody: `[{"key":"header:X-Api-Key:b","window":"2026-10-19T00:00:00+02:00","limit":5,"used":1,"remaining":4,"reset":"2026-10-20T00:00:00+02:00"}]`,
+		},
+		{Method: http.MethodDelete, Target: "/", WantStatus: http.StatusBadRequest},     // 4
+		{Method: http.MethodPost, Target: "/", WantStatus: http.StatusMethodNotAllowed}, // 5
+	}
+
+	for k, test := range tests {
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, httptest.NewRequestWithContext(t.Context(), test.Method, test.Target, nil))
+
+		if rec.Code != test.WantStatus {
+			t.Errorf("%v: got status %v but wanted %v", k, rec.Code, test.WantStatus)
+		}
+
+		if test.WantBody == "" {
+			continue
+		}
+
+		if got := strings.TrimSpace(rec.Body.String()); got != test.WantBody {
+			t.Errorf("%v: got body\n%v\nbut wanted\n%v", k, got, test.WantBody)
+		}
+
+		if !json.Valid(rec.Body.Bytes()) {
+			t.Errorf("%v: got invalid json", k)
+		}
+	}
+}
+
+func TestOptionErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options []func(*quotalimit.QuotaLimit) error
+		WantErr error
+	}{
+		{Options: nil, WantErr: quotalimit.ErrZeroLimit},                                                                   // 0
+		{Options: []func(*quotalimit.QuotaLimit) error{nil}, WantErr: quotalimit.ErrNilOption},                             // 1
+		{Options: []func(*quotalimit.QuotaLimit) error{quotalimit.WithLimit(0)}, WantErr: quotalimit.ErrZeroLimit},         // 2
+		{Options: []func(*quotalimit.QuotaLimit) error{quotalimit.WithPeriod(2)}, WantErr: quotalimit.ErrInvalidPeriod},    // 3
+		{Options: []func(*quotalimit.QuotaLimit) error{quotalimit.WithLocation(nil)}, WantErr: quotalimit.ErrNilLocation},  // 4
+		{Options: []func(*quotalimit.QuotaLimit) error{quotalimit.WithStore(nil)}, WantErr: quotalimit.ErrNilStore},        // 5
+		{Options: []func(*quotalimit.QuotaLimit) error{quotalimit.WithKeyFunc(nil)}, WantErr: ratelimit.ErrNilKeyFunc},     // 6
+		{Options: []func(*quotalimit.QuotaLimit) error{quotalimit.WithLimit(1), quotalimit.WithLogger(nil)}, WantErr: nil}, // 7
+		{Options: []func(*quotalimit.QuotaLimit) error{quotalimit.WithMaxKeys(0)}, WantErr: ratelimit.ErrInvalidMaxKeys},   // 8
+	}
+
+	for k, test := range tests {
+		if _, err := quotalimit.New(test.Options...); !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "quota.log")
+
+	if _, err := quotalimit.NewFileStore(path, nil); !errors.Is(err, quotalimit.ErrNilOption) {
+		t.Errorf("got error %v but wanted %v", err, quotalimit.ErrNilOption)
+	}
+
+	if _, err := quotalimit.NewFileStore(path, quotalimit.WithCompactAfter(0)); !errors.Is(err, quotalimit.ErrInvalidCompactAfter) {
+		t.Errorf("got error %v but wanted %v", err, quotalimit.ErrInvalidCompactAfter)
+	}
+
+	if _, err := quotalimit.NewFileStore(filepath.Join(path, "missing", "quota.log")); err == nil {
+		t.Errorf("expected error for unusable path")
+	}
+}
@@ -0,0 +1,18 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package quotalimit
+
+import "time"
+
+// The following functions are used for internal testing and are not visible to normal library users.
+
+// TWithNow is an option replacing the time source of the limiter. It is already used
+// when loading the counters.
+func TWithNow(now func() time.Time) func(l *QuotaLimit) error {
+	return func(l *QuotaLimit) error {
+		l.now = now
+
+		return nil
+	}
+}
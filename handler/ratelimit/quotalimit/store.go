@@ -0,0 +1,287 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package quotalimit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidCompactAfter is returned when the number of records before compaction is not
+// greater than 0.
+var ErrInvalidCompactAfter = errors.New("records before compaction must be greater than 0")
+
+// ErrStoreClosed is returned when a closed store is used.
+var ErrStoreClosed = errors.New("store closed")
+
+// DefaultCompactAfter is the default number of records appended before the file of a
+// FileStore is compacted.
+const DefaultCompactAfter = 10_000
+
+// Counter is the consumed quota of a key within a window.
+type Counter struct {
+	// Key identifies the client, e.g. "principal:alice".
+	Key string `json:"key"`
+	// Window is the start of the window the count belongs to.
+	Window time.Time `json:"window"`
+	// Count is the consumed quota.
+	Count int64 `json:"count"`
+}
+
+// Store persists the counters, so they survive restarts.
+type Store interface {
+	// Load gives all stored counters.
+	Load() ([]Counter, error)
+	// Save stores the counter, replacing the one of the same key. Counters with a count
+	// of 0 may be dropped.
+	Save(c Counter) error
+	// Close releases the resources of the store.
+	Close() error
+}
+
+// FileStore is a Store keeping the counters in an append-only file. Each change of a
+// counter appends a JSON line, so the last line of a key holds its current state. When
+// enough records were appended, the file is compacted to one line per key. Only the
+// counters of the latest window are kept, so a FileStore is to be used by a single
+// limiter.
+//
+// The records are written without syncing, so they survive a restart of the process,
+// but may get lost on a crash of the system.
+type FileStore struct {
+	// CompactAfter is the number of records appended before the file is compacted.
+	CompactAfter int
+
+	mtx      sync.Mutex
+	path     string
+	file     *os.File
+	records  int                // records appended since the last compaction
+	counters map[string]Counter // counters hold the current state of all keys
+	window   time.Time          // window is the latest window of the counters
+}
+
+// Load gives all stored counters.
+func (s *FileStore) Load() ([]Counter, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.file == nil {
+		return nil, ErrStoreClosed
+	}
+
+	result := make([]Counter, 0, len(s.counters))
+
+	for _, c := range s.counters {
+		result = append(result, c)
+	}
+
+	slices.SortFunc(result, func(a, b Counter) int { return strings.Compare(a.Key, b.Key) })
+
+	return result, nil
+}
+
+// Save appends the counter to the file.
+func (s *FileStore) Save(c Counter) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.file == nil {
+		return ErrStoreClosed
+	}
+
+	line, err := json.Marshal(c)
+
+	if err != nil {
+		return fmt.Errorf("could not encode counter: %w", err)
+	}
+
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("could not write counter: %w", err)
+	}
+
+	s.set(c)
+	s.records++
+
+	if s.records >= s.CompactAfter {
+		return s.compact()
+	}
+
+	return nil
+}
+
+// Close compacts and closes the file.
+func (s *FileStore) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.compact()
+
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	s.file = nil
+
+	return err
+}
+
+// set updates the state of the key, dropping counters without count. A counter of a new
+// window drops those of the past ones.
+func (s *FileStore) set(c Counter) {
+	if c.Window.After(s.window) {
+		s.window = c.Window
+		clear(s.counters)
+	}
+
+	if c.Count == 0 || c.Window.Before(s.window) {
+		delete(s.counters, c.Key)
+
+		return
+	}
+
+	s.counters[c.Key] = c
+}
+
+// read reads the records of the file. A broken last line, e.g. from an interrupted
+// write, is ignored.
+func (s *FileStore) read() error {
+	file, err := os.Open(s.path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("could not open quota file: %w", err)
+	}
+
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		var c Counter
+
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			continue
+		}
+
+		s.set(c)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read quota file: %w", err)
+	}
+
+	return nil
+}
+
+// compact rewrites the file with one record per key. The new file replaces the old one
+// atomically, so a crash while compacting keeps the old one.
+func (s *FileStore) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+
+	if err != nil {
+		return fmt.Errorf("could not compact quota file: %w", err)
+	}
+
+	w := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(w)
+
+	for _, c := range s.counters {
+		if err = encoder.Encode(c); err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		err = w.Flush()
+	}
+
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+
+		return fmt.Errorf("could not compact quota file: %w", err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o600)
+
+	if err != nil {
+		return fmt.Errorf("could not open quota file: %w", err)
+	}
+
+	if s.file != nil {
+		_ = s.file.Close()
+	}
+
+	s.file = file
+	s.records = 0
+
+	return nil
+}
+
+// WithCompactAfter sets the number of records appended before the file is compacted.
+func WithCompactAfter(n int) func(s *FileStore) error {
+	return func(s *FileStore) error {
+		if n <= 0 {
+			return ErrInvalidCompactAfter
+		}
+
+		s.CompactAfter = n
+
+		return nil
+	}
+}
+
+// NewFileStore opens the store in the given file, creating it if necessary.
+func NewFileStore(path string, options ...func(*FileStore) error) (*FileStore, error) {
+	store := FileStore{
+		CompactAfter: DefaultCompactAfter,
+		path:         path,
+		counters:     make(map[string]Counter),
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&store); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := store.read(); err != nil {
+		return nil, err
+	}
+
+	if err := store.compact(); err != nil {
+		return nil, err
+	}
+
+	return &store, nil
+}
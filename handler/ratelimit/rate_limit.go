@@ -29,7 +29,18 @@ type Limiter interface {
 type Handler struct {
 	defs.MWBase
 
-	Limit Limiter
+	Limit        Limiter
+	RequestLimit RequestLimiter
+
+	// QuotaHeaders enables the RateLimit headers, if the limiter reports its quota.
+	QuotaHeaders bool
+	// PolicyName is the name of the policy given in the RateLimit headers.
+	PolicyName string
+	// Cost gives the cost of a request. Without it, each request costs 1.
+	Cost CostFunc
+	// MaxCost is the maximum cost of a request. Requests costing more are rejected
+	// without asking the limiter.
+	MaxCost int64
 }
 
 // GetMWBase returns the MWBase instance of the handler.
@@ -41,13 +52,28 @@ func (h *Handler) GetMWBase() *defs.MWBase {
 	return &h.MWBase
 }
 
-// ServeHTTP limits the requests using the internal Limiter.
+// ServeHTTP limits the requests using the internal RequestLimiter or Limiter.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if !helper.IntroCheck(h, w, r) {
 		return
 	}
 
-	if !h.Limit.Limit() {
+	cost, valid := h.cost(r)
+
+	if !valid {
+		helper.WriteState(w, h.Log(), http.StatusTooManyRequests)
+
+		return
+	}
+
+	allowed, quota, hasQuota := h.limit(r, cost)
+
+	if hasQuota && h.QuotaHeaders {
+		// requests costing more than the limit will never pass, so no retry is suggested
+		writeQuotaHeaders(w.Header(), h.PolicyName, quota, allowed, cost <= quota.Limit)
+	}
+
+	if !allowed {
 		helper.WriteState(w, h.Log(), http.StatusTooManyRequests)
 
 		return
@@ -56,6 +82,49 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.Next().ServeHTTP(w, r)
 }
 
+// cost gives the cost of the request, 1 if no CostFunc is configured. Costs above the
+// maximum are not valid, as such requests could never pass.
+func (h *Handler) cost(r *http.Request) (int64, bool) {
+	if h.Cost == nil {
+		return 1, true
+	}
+
+	cost := max(0, h.Cost(r))
+
+	return cost, cost <= h.MaxCost
+}
+
+// limit asks the configured limiter, if the request of the given cost may pass. If the
+// limiter reports its quota, it is returned as well.
+func (h *Handler) limit(r *http.Request, cost int64) (bool, Quota, bool) {
+	if h.RequestLimit != nil {
+		if l, ok := h.RequestLimit.(RequestCostLimiter); ok {
+			return l.LimitRequestCost(r, cost)
+		}
+
+		if l, ok := h.RequestLimit.(RequestQuotaLimiter); ok {
+			return l.LimitRequestQuota(r)
+		}
+
+		return h.RequestLimit.LimitRequest(r), Quota{}, false
+	}
+
+	return limitCost(h.Limit, cost)
+}
+
+// supportsCost checks, if the configured limiter can account for costs.
+func (h *Handler) supportsCost() bool {
+	if h.RequestLimit != nil {
+		_, ok := h.RequestLimit.(RequestCostLimiter)
+
+		return ok
+	}
+
+	_, ok := h.Limit.(CostLimiter)
+
+	return ok
+}
+
 // WithLimiter sets the Limiter to use.
 func WithLimiter(l Limiter) func(h *Handler) error {
 	return func(h *Handler) error {
@@ -69,6 +138,74 @@ func WithLimiter(l Limiter) func(h *Handler) error {
 	}
 }
 
+// WithRequestLimiter sets the RequestLimiter to use, e.g. a KeyedLimiter. It takes
+// precedence over a Limiter set with WithLimiter.
+func WithRequestLimiter(l RequestLimiter) func(h *Handler) error {
+	return func(h *Handler) error {
+		if l == nil {
+			return ErrInvalidLimiter
+		}
+
+		h.RequestLimit = l
+
+		return nil
+	}
+}
+
+// WithQuotaHeaders enables or disables the RateLimit-Policy and RateLimit headers on
+// all responses and the Retry-After header on rejections. They are enabled by default,
+// but only written if the limiter reports its quota.
+func WithQuotaHeaders(enabled bool) func(h *Handler) error {
+	return func(h *Handler) error {
+		h.QuotaHeaders = enabled
+
+		return nil
+	}
+}
+
+// WithPolicyName sets the name of the policy given in the RateLimit headers.
+func WithPolicyName(name string) func(h *Handler) error {
+	return func(h *Handler) error {
+		if !validPolicyName(name) {
+			return ErrInvalidPolicyName
+		}
+
+		h.PolicyName = name
+
+		return nil
+	}
+}
+
+// WithCostFunc sets the function giving the cost of a request, e.g. CostByRoute. The
+// limits are then expressed in cost units instead of requests. The limiter has to be a
+// CostLimiter or RequestCostLimiter.
+func WithCostFunc(cost CostFunc) func(h *Handler) error {
+	return func(h *Handler) error {
+		if cost == nil {
+			return ErrNilCostFunc
+		}
+
+		h.Cost = cost
+
+		return nil
+	}
+}
+
+// WithMaxCost sets the maximum cost of a request. Requests costing more are rejected
+// without asking the limiter. It should not exceed the limit, e.g. the burst of the
+// limiter.
+func WithMaxCost(n int64) func(h *Handler) error {
+	return func(h *Handler) error {
+		if n <= 0 {
+			return ErrInvalidMaxCost
+		}
+
+		h.MaxCost = n
+
+		return nil
+	}
+}
+
 // WithLogger configures the logger to use.
 func WithLogger(log *slog.Logger) func(h *Handler) error {
 	return defs.WithLogger[*Handler](log)
@@ -81,7 +218,11 @@ func WithLogLevel(level slog.Level) func(h *Handler) error {
 
 // New creates a new rate limiter middleware.
 func New(options ...func(*Handler) error) (defs.Middleware, error) {
-	handler := Handler{}
+	handler := Handler{
+		QuotaHeaders: true,
+		PolicyName:   DefaultPolicyName,
+		MaxCost:      DefaultMaxCost,
+	}
 
 	for _, opt := range options {
 		if opt == nil {
@@ -93,10 +234,14 @@ func New(options ...func(*Handler) error) (defs.Middleware, error) {
 		}
 	}
 
-	if handler.Limit == nil {
+	if handler.Limit == nil && handler.RequestLimit == nil {
 		return nil, ErrInvalidLimiter
 	}
 
+	if handler.Cost != nil && !handler.supportsCost() {
+		return nil, ErrNoCostLimiter
+	}
+
 	return func(next http.Handler) http.Handler {
 		if err := handler.SetNext(next); err != nil {
 			return nil
@@ -0,0 +1,13 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package ratelimit
+
+import "time"
+
+// The following functions are used for internal testing and are not visible to normal library users.
+
+// TSetKeyedNow replaces the time source of the given keyed limiter.
+func TSetKeyedNow(l *KeyedLimiter, now func() time.Time) {
+	l.now = now
+}
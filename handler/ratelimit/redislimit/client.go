@@ -0,0 +1,288 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package redislimit
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNoAddress is returned when no server address is given.
+var ErrNoAddress = errors.New("server address cannot be empty")
+
+// ErrInvalidPoolSize is returned when the pool size is not greater than 0.
+var ErrInvalidPoolSize = errors.New("pool size must be greater than 0")
+
+// ErrInvalidTimeout is returned when the timeout is not greater than 0.
+var ErrInvalidTimeout = errors.New("timeout must be greater than 0")
+
+// ErrClientClosed is returned when the client is used after being closed.
+var ErrClientClosed = errors.New("client closed")
+
+const (
+	// DefaultPoolSize is the default maximum number of idle connections.
+	DefaultPoolSize = 16
+	// DefaultTimeout is the default timeout of a command including connection setup.
+	DefaultTimeout = 100 * time.Millisecond
+)
+
+// conn is a connection to the server.
+type conn struct {
+	net.Conn
+
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// Client is a minimal client for servers speaking the Redis protocol (RESP), e.g.
+// Redis or Valkey. It keeps a pool of idle connections and is safe for concurrent use.
+// Several limiters should share one client.
+type Client struct {
+	addr      string
+	username  string
+	password  string
+	database  int
+	tlsConfig *tls.Config
+	timeout   time.Duration
+
+	mtx    sync.Mutex
+	idle   []*conn
+	size   int
+	closed bool
+}
+
+// Do sends the command to the server and gives its reply. Error replies of the server
+// are returned as Error.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	cn, err := c.get(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := cn.do(ctx, args...)
+
+	if err != nil {
+		_ = cn.Close()
+
+		return nil, err
+	}
+
+	c.put(cn)
+
+	if e, isErr := reply.(Error); isErr {
+		return nil, e
+	}
+
+	return reply, nil
+}
+
+// do sends the command on the connection and reads the reply.
+func (cn *conn) do(ctx context.Context, args ...string) (any, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := cn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writeCommand(cn.w, args...); err != nil {
+		return nil, err
+	}
+
+	return readReply(cn.r)
+}
+
+// get gives an idle connection or dials a new one.
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	c.mtx.Lock()
+
+	if c.closed {
+		c.mtx.Unlock()
+
+		return nil, ErrClientClosed
+	}
+
+	if n := len(c.idle); n > 0 {
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mtx.Unlock()
+
+		return cn, nil
+	}
+
+	c.mtx.Unlock()
+
+	return c.dial(ctx)
+}
+
+// put returns the connection to the pool, closing it if the pool is full.
+func (c *Client) put(cn *conn) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.closed || len(c.idle) >= c.size {
+		_ = cn.Close()
+
+		return
+	}
+
+	c.idle = append(c.idle, cn)
+}
+
+// dial opens a new connection, authenticating and selecting the database if configured.
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	var dialer interface {
+		DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+	} = &net.Dialer{}
+
+	if c.tlsConfig != nil {
+		dialer = &tls.Dialer{Config: c.tlsConfig}
+	}
+
+	nc, err := dialer.DialContext(ctx, "tcp", c.addr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	var setup [][]string
+
+	if c.password != "" {
+		if c.username != "" {
+			setup = append(setup, []string{"AUTH", c.username, c.password})
+		} else {
+			setup = append(setup, []string{"AUTH", c.password})
+		}
+	}
+
+	if c.database != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.database)})
+	}
+
+	for _, cmd := range setup {
+		reply, err := cn.do(ctx, cmd...)
+
+		if e, isErr := reply.(Error); isErr && err == nil {
+			err = e
+		}
+
+		if err != nil {
+			_ = cn.Close()
+
+			return nil, err
+		}
+	}
+
+	return cn, nil
+}
+
+// Close closes the idle connections. Connections in use are closed when returned.
+func (c *Client) Close() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.closed = true
+
+	var errs []error
+
+	for _, cn := range c.idle {
+		errs = append(errs, cn.Close())
+	}
+
+	c.idle = nil
+
+	return errors.Join(errs...)
+}
+
+// WithAuth sets the credentials to authenticate with. The username may be empty for
+// servers not supporting access control lists.
+func WithAuth(username, password string) func(c *Client) error {
+	return func(c *Client) error {
+		c.username = username
+		c.password = password
+
+		return nil
+	}
+}
+
+// WithDatabase sets the number of the database to use.
+func WithDatabase(n int) func(c *Client) error {
+	return func(c *Client) error {
+		c.database = n
+
+		return nil
+	}
+}
+
+// WithTLSConfig enables TLS using the given configuration.
+func WithTLSConfig(cfg *tls.Config) func(c *Client) error {
+	return func(c *Client) error {
+		c.tlsConfig = cfg
+
+		return nil
+	}
+}
+
+// WithPoolSize sets the maximum number of idle connections kept.
+func WithPoolSize(n int) func(c *Client) error {
+	return func(c *Client) error {
+		if n <= 0 {
+			return ErrInvalidPoolSize
+		}
+
+		c.size = n
+
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout of a command including the connection setup.
+func WithTimeout(d time.Duration) func(c *Client) error {
+	return func(c *Client) error {
+		if d <= 0 {
+			return ErrInvalidTimeout
+		}
+
+		c.timeout = d
+
+		return nil
+	}
+}
+
+// NewClient creates a new client for the server at the given address. Connections are
+// opened on demand.
+func NewClient(addr string, options ...func(*Client) error) (*Client, error) {
+	if addr == "" {
+		return nil, ErrNoAddress
+	}
+
+	client := Client{
+		addr:    addr,
+		size:    DefaultPoolSize,
+		timeout: DefaultTimeout,
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&client); err != nil {
+			return nil, err
+		}
+	}
+
+	return &client, nil
+}
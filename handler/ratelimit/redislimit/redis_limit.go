@@ -0,0 +1,409 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package redislimit provides a rate limiter shared by all instances of a service, that
+// keeps its state in a server speaking the Redis protocol.
+package redislimit
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec // SHA1 is mandated by EVALSHA, not used for security
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+	"github.com/AlphaOne1/midgard/handler/ratelimit/gcra"
+)
+
+// ErrNilOption is returned when an option is nil.
+var ErrNilOption = errors.New("option cannot be nil")
+
+// ErrNoClient is returned when no client is given.
+var ErrNoClient = errors.New("client cannot be nil")
+
+// ErrNoKey is returned when the key is empty.
+var ErrNoKey = errors.New("key cannot be empty")
+
+// ErrZeroRate is returned when the rate is 0.
+var ErrZeroRate = errors.New("rate must be greater than 0")
+
+// ErrZeroBurst is returned when the burst is 0.
+var ErrZeroBurst = errors.New("burst must be greater than 0")
+
+// ErrInvalidFailMode is returned when the fail mode is unknown.
+var ErrInvalidFailMode = errors.New("invalid fail mode")
+
+// ErrInvalidReply is returned when the script gives an unexpected reply.
+var ErrInvalidReply = errors.New("invalid script reply")
+
+// FailMode determines how requests are handled, if the server cannot be reached.
+type FailMode int
+
+const (
+	// FailLocal limits the requests using the local fallback limiter.
+	FailLocal FailMode = iota
+	// FailOpen lets all requests pass.
+	FailOpen
+	// FailClosed rejects all requests.
+	FailClosed
+)
+
+const (
+	// DefaultKey is the default key of the limiter state on the server.
+	DefaultKey = "midgard:ratelimit"
+	// DefaultRetryInterval is the default time the server is not asked after a failure.
+	DefaultRetryInterval = time.Second
+)
+
+// gcraScript implements the generic cell rate algorithm on the server. It uses the time
+// of the server, so the clocks of the instances do not have to be synchronized. The
+// theoretical arrival time is stored in microseconds and expires, when it is reached.
+// The arguments are the emission interval in microseconds, the burst and the cost of
+// the request. It returns if the request is allowed, the remaining requests, the time
+// until the quota is restored and the time until a request of the same cost may pass,
+// 0 if its cost exceeds the burst.
+const gcraScript = `local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local tolerance = interval * burst
+local tat = tonumber(redis.call('GET', KEYS[1])) or now
+if tat < now then
+	tat = now
+end
+local allowed = 0
+local next_tat = tat + interval * cost
+if next_tat - now <= tolerance then
+	allowed = 1
+	tat = next_tat
+	redis.call('SET', KEYS[1], string.format('%d', tat), 'PX', math.ceil((tat - now) / 1000))
+end
+local retry = 0
+if cost <= burst then
+	retry = math.max(0, tat + interval * cost - tolerance - now)
+end
+return {allowed, math.floor((now + tolerance - tat) / interval), tat - now, retry}
+`
+
+// gcraScriptSHA is the SHA1 digest of the script, used to call it via EVALSHA.
+var gcraScriptSHA = func() string {
+	sum := sha1.Sum([]byte(gcraScript)) //nolint:gosec // see import
+
+	return hex.EncodeToString(sum[:])
+}()
+
+// RedisLimit is a request limiter shared by all instances of a service using the same
+// server and key. The decision is made atomically on the server by a script. If the
+// server cannot be reached, the FailMode determines how the requests are handled and
+// the server is not asked again for the RetryInterval.
+type RedisLimit struct {
+	// Key is the key of the limiter state on the server.
+	Key string
+
+	// Rate is the number of requests allowed per second.
+	Rate float64
+
+	// Burst is the number of requests that may pass at once.
+	Burst int64
+
+	// FailMode determines how requests are handled, if the server cannot be reached.
+	FailMode FailMode
+
+	// RetryInterval is the time the server is not asked after a failure.
+	RetryInterval time.Duration
+
+	// client is used to talk to the server.
+	client *Client
+	// fallback is the limiter used in FailLocal mode.
+	fallback ratelimit.Limiter
+	// interval is the emission interval in microseconds.
+	interval int64
+	// downUntil is the time in Unix nanoseconds until which the server is not asked.
+	downUntil atomic.Int64
+	// log is used to report failures of the server.
+	log *slog.Logger
+}
+
+// Limit gives true, if the rate limit is not yet exceeded, otherwise false.
+func (l *RedisLimit) Limit() bool {
+	return l.LimitN(1)
+}
+
+// LimitN gives true, if n requests may pass, otherwise false.
+func (l *RedisLimit) LimitN(n int64) bool {
+	if n <= 0 {
+		return true
+	}
+
+	allowed, _ := l.limit(n)
+
+	return allowed
+}
+
+// LimitQuota gives true, if the rate limit is not yet exceeded, and the quota after
+// the decision.
+func (l *RedisLimit) LimitQuota() (bool, ratelimit.Quota) {
+	return l.limit(1)
+}
+
+// LimitNQuota gives true, if n requests may pass, and the quota after the decision. For
+// n <= 0 the server is not asked, so the quota only gives the limit.
+func (l *RedisLimit) LimitNQuota(n int64) (bool, ratelimit.Quota) {
+	if n <= 0 {
+		return true, ratelimit.Quota{
+			Limit:  l.Burst,
+			Window: time.Duration(l.Burst*l.interval) * time.Microsecond,
+		}
+	}
+
+	return l.limit(n)
+}
+
+// limit asks the server, if n requests may pass, failing over as configured.
+func (l *RedisLimit) limit(n int64) (bool, ratelimit.Quota) {
+	now := time.Now()
+
+	if now.UnixNano() < l.downUntil.Load() {
+		return l.fail(n)
+	}
+
+	reply, err := l.eval(n)
+
+	if err != nil {
+		if l.downUntil.Swap(now.Add(l.RetryInterval).UnixNano()) < now.UnixNano() {
+			l.log.Warn("rate limit server failed, using fail mode",
+				slog.String("key", l.Key),
+				slog.Int("failMode", int(l.FailMode)),
+				slog.String("error", err.Error()))
+		}
+
+		return l.fail(n)
+	}
+
+	return reply[0] == 1, ratelimit.Quota{
+		Limit:      l.Burst,
+		Window:     time.Duration(l.Burst*l.interval) * time.Microsecond,
+		Remaining:  reply[1],
+		Reset:      time.Duration(reply[2]) * time.Microsecond,
+		RetryAfter: time.Duration(reply[3]) * time.Microsecond,
+	}
+}
+
+// eval runs the script on the server, loading it if it is not yet known there.
+func (l *RedisLimit) eval(n int64) ([4]int64, error) {
+	var result [4]int64
+
+	args := []string{
+		"1", l.Key,
+		strconv.FormatInt(l.interval, 10),
+		strconv.FormatInt(l.Burst, 10),
+		strconv.FormatInt(n, 10),
+	}
+
+	reply, err := l.client.Do(context.Background(), append([]string{"EVALSHA", gcraScriptSHA}, args...)...)
+
+	if e, isErr := errors.AsType[Error](err); isErr && strings.HasPrefix(string(e), "NOSCRIPT") {
+		reply, err = l.client.Do(context.Background(), append([]string{"EVAL", gcraScript}, args...)...)
+	}
+
+	if err != nil {
+		return result, err
+	}
+
+	values, ok := reply.([]any)
+
+	if !ok || len(values) != len(result) {
+		return result, fmt.Errorf("%w: %v", ErrInvalidReply, reply)
+	}
+
+	for i, v := range values {
+		if result[i], ok = v.(int64); !ok {
+			return result, fmt.Errorf("%w: %v", ErrInvalidReply, reply)
+		}
+	}
+
+	return result, nil
+}
+
+// fail decides on n requests according to the FailMode.
+func (l *RedisLimit) fail(n int64) (bool, ratelimit.Quota) {
+	quota := ratelimit.Quota{
+		Limit:  l.Burst,
+		Window: time.Duration(l.Burst*l.interval) * time.Microsecond,
+	}
+
+	switch l.FailMode {
+	case FailOpen:
+		quota.Remaining = l.Burst
+
+		return true, quota
+	case FailClosed:
+		quota.RetryAfter = time.Until(time.Unix(0, l.downUntil.Load()))
+
+		return false, quota
+	default:
+		if q, ok := l.fallback.(ratelimit.CostQuotaLimiter); ok {
+			return q.LimitNQuota(n)
+		}
+
+		if q, ok := l.fallback.(ratelimit.QuotaLimiter); ok && n == 1 {
+			return q.LimitQuota()
+		}
+
+		if f, ok := l.fallback.(ratelimit.CostLimiter); ok {
+			return f.LimitN(n), quota
+		}
+
+		return l.fallback.Limit(), quota
+	}
+}
+
+// WithClient sets the client used to talk to the server.
+func WithClient(c *Client) func(l *RedisLimit) error {
+	return func(l *RedisLimit) error {
+		if c == nil {
+			return ErrNoClient
+		}
+
+		l.client = c
+
+		return nil
+	}
+}
+
+// WithKey sets the key of the limiter state on the server. Limiters using the same key
+// share their limit.
+func WithKey(key string) func(l *RedisLimit) error {
+	return func(l *RedisLimit) error {
+		if key == "" {
+			return ErrNoKey
+		}
+
+		l.Key = key
+
+		return nil
+	}
+}
+
+// WithRate sets the number of requests allowed per second.
+func WithRate(r float64) func(l *RedisLimit) error {
+	return func(l *RedisLimit) error {
+		if r <= 0 {
+			return ErrZeroRate
+		}
+
+		l.Rate = r
+
+		return nil
+	}
+}
+
+// WithBurst sets the number of requests that may pass at once.
+func WithBurst(n int64) func(l *RedisLimit) error {
+	return func(l *RedisLimit) error {
+		if n <= 0 {
+			return ErrZeroBurst
+		}
+
+		l.Burst = n
+
+		return nil
+	}
+}
+
+// WithFailMode sets how requests are handled, if the server cannot be reached.
+func WithFailMode(mode FailMode) func(l *RedisLimit) error {
+	return func(l *RedisLimit) error {
+		if mode < FailLocal || mode > FailClosed {
+			return ErrInvalidFailMode
+		}
+
+		l.FailMode = mode
+
+		return nil
+	}
+}
+
+// WithFallback sets the limiter used in FailLocal mode. Without it, a local GCRA limiter
+// with the same rate and burst is used.
+func WithFallback(f ratelimit.Limiter) func(l *RedisLimit) error {
+	return func(l *RedisLimit) error {
+		if f == nil {
+			return ratelimit.ErrInvalidLimiter
+		}
+
+		l.fallback = f
+
+		return nil
+	}
+}
+
+// WithRetryInterval sets the time the server is not asked after a failure.
+func WithRetryInterval(d time.Duration) func(l *RedisLimit) error {
+	return func(l *RedisLimit) error {
+		l.RetryInterval = max(0, d)
+
+		return nil
+	}
+}
+
+// WithLogger sets the logger used to report failures of the server.
+func WithLogger(log *slog.Logger) func(l *RedisLimit) error {
+	return func(l *RedisLimit) error {
+		if log == nil {
+			log = slog.Default()
+		}
+
+		l.log = log
+
+		return nil
+	}
+}
+
+// New creates a new rate limiter keeping its state on the server of the given client.
+func New(options ...func(*RedisLimit) error) (*RedisLimit, error) {
+	limiter := RedisLimit{
+		Key:           DefaultKey,
+		Rate:          1,
+		Burst:         1,
+		FailMode:      FailLocal,
+		RetryInterval: DefaultRetryInterval,
+		log:           slog.Default(),
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&limiter); err != nil {
+			return nil, err
+		}
+	}
+
+	if limiter.client == nil {
+		return nil, ErrNoClient
+	}
+
+	limiter.interval = max(1, int64(math.Round(float64(time.Second/time.Microsecond)/limiter.Rate)))
+
+	if limiter.fallback == nil {
+		fallback, err := gcra.New(gcra.WithRate(limiter.Rate), gcra.WithBurst(limiter.Burst))
+
+		if err != nil {
+			return nil, err
+		}
+
+		limiter.fallback = fallback
+	}
+
+	return &limiter, nil
+}
@@ -0,0 +1,373 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package redislimit_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+	"github.com/AlphaOne1/midgard/handler/ratelimit/redislimit"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+var _ ratelimit.CostQuotaLimiter = (*redislimit.RedisLimit)(nil)
+
+// neverLimit is a fallback limiter rejecting all requests.
+type neverLimit struct{}
+
+func (neverLimit) Limit() bool { return false }
+
+// closedAddr gives an address nobody listens on.
+func closedAddr(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	return addr
+}
+
+func TestRedisLimitShared(t *testing.T) {
+	t.Parallel()
+
+	server := newStandIn(t)
+	client := helper.Must(redislimit.NewClient(server.addr()))
+	t.Cleanup(func() { _ = client.Close() })
+
+	// two instances of a service share the limit
+	instances := []*redislimit.RedisLimit{
+		helper.Must(redislimit.New(redislimit.WithClient(client), redislimit.WithRate(10), redislimit.WithBurst(3))),
+		helper.Must(redislimit.New(redislimit.WithClient(client), redislimit.WithRate(10), redislimit.WithBurst(3))),
+	}
+
+	tests := []struct {
+		Instance  int
+		Advance   time.Duration
+		Want      bool
+		WantQuota ratelimit.Quota
+	}{
+		{ // 0
+			Instance: 0, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 3, Window: 300 * time.Millisecond, Remaining: 2, Reset: 100 * time.Millisecond},
+		},
+		{ // 1
+			Instance: 1, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 3, Window: 300 * time.Millisecond, Remaining: 1, Reset: 200 * time.Millisecond},
+		},
+		{ // 2
+			Instance: 0, Want: true,
+			WantQuota: ratelimit.Quota{
+				Limit: 3, Window: 300 * time.Millisecond, Remaining: 0,
+				Reset: 300 * time.Millisecond, RetryAfter: 100 * time.Millisecond,
+			},
+		},
+		{ // 3
+			Instance: 1, Want: false,
+			WantQuota: ratelimit.Quota{
+				Limit: 3, Window: 300 * time.Millisecond, Remaining: 0,
+				Reset: 300 * time.Millisecond, RetryAfter: 100 * time.Millisecond,
+			},
+		},
+		{ // 4
+			Instance: 1, Advance: 100 * time.Millisecond, Want: true,
+			WantQuota: ratelimit.Quota{
+				Limit: 3, Window: 300 * time.Millisecond, Remaining: 0,
+				Reset: 300 * time.Millisecond, RetryAfter: 100 * time.Millisecond,
+			},
+		},
+	}
+
+	for k, test := range tests {
+		server.advance(test.Advance)
+
+		got, quota := instances[test.Instance].LimitQuota()
+
+		if got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+
+		if quota != test.WantQuota {
+			t.Errorf("%v: got quota %+v but wanted %+v", k, quota, test.WantQuota)
+		}
+	}
+
+	if server.count("EVAL") != 1 || server.count("EVALSHA") != len(tests) {
+		t.Errorf("script should be loaded once, got %v EVAL and %v EVALSHA",
+			server.count("EVAL"), server.count("EVALSHA"))
+	}
+}
+
+func TestRedisLimitN(t *testing.T) {
+	t.Parallel()
+
+	server := newStandIn(t)
+	client := helper.Must(redislimit.NewClient(server.addr()))
+	t.Cleanup(func() { _ = client.Close() })
+
+	limiter := helper.Must(redislimit.New(
+		redislimit.WithClient(client),
+		redislimit.WithKey("test:n"),
+		redislimit.WithBurst(5)))
+
+	tests := []struct {
+		N    int64
+		Want bool
+	}{
+		{N: 3, Want: true},  // 0
+		{N: 3, Want: false}, // 1
+		{N: 2, Want: true},  // 2
+		{N: 0, Want: true},  // 3
+		{N: 1, Want: false}, // 4
+	}
+
+	for k, test := range tests {
+		if got := limiter.LimitN(test.N); got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+	}
+}
+
+func TestRedisLimitNQuota(t *testing.T) {
+	t.Parallel()
+
+	server := newStandIn(t)
+	client := helper.Must(redislimit.NewClient(server.addr()))
+	t.Cleanup(func() { _ = client.Close() })
+
+	limiter := helper.Must(redislimit.New(
+		redislimit.WithClient(client),
+		redislimit.WithKey("test:nquota"),
+		redislimit.WithRate(10),
+		redislimit.WithBurst(100)))
+
+	// requests without cost do not ask the server
+	if got, _ := limiter.LimitNQuota(0); !got || server.count("EVAL")+server.count("EVALSHA") != 0 {
+		t.Errorf("got %v for a request without cost, wanted it to pass without asking the server", got)
+	}
+
+	tests := []struct {
+		Advance   time.Duration
+		N         int64
+		Want      bool
+		WantRetry time.Duration
+	}{
+		{N: 100, Want: true, WantRetry: 10 * time.Second},                         // 0
+		{N: 100, Want: false, WantRetry: 10 * time.Second},                        // 1 the retry time is that of the whole cost
+		{Advance: 5 * time.Second, N: 50, Want: true, WantRetry: 5 * time.Second}, // 2
+		{N: 101, Want: false, WantRetry: 0},                                       // 3 more than the burst never passes
+	}
+
+	for k, test := range tests {
+		server.advance(test.Advance)
+
+		got, quota := limiter.LimitNQuota(test.N)
+
+		if got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+
+		if quota.RetryAfter != test.WantRetry {
+			t.Errorf("%v: got retry after %v but wanted %v", k, quota.RetryAfter, test.WantRetry)
+		}
+	}
+}
+
+func TestRedisLimitFailModes(t *testing.T) {
+	t.Parallel()
+
+	addr := closedAddr(t)
+
+	tests := []struct {
+		Options []func(*redislimit.RedisLimit) error
+		Want    []bool
+	}{
+		{ // 0 local fallback with the same burst
+			Options: []func(*redislimit.RedisLimit) error{redislimit.WithBurst(2)},
+			Want:    []bool{true, true, false},
+		},
+		{ // 1
+			Options: []func(*redislimit.RedisLimit) error{redislimit.WithFallback(neverLimit{})},
+			Want:    []bool{false, false},
+		},
+		{ // 2
+			Options: []func(*redislimit.RedisLimit) error{redislimit.WithFailMode(redislimit.FailOpen)},
+			Want:    []bool{true, true, true},
+		},
+		{ // 3
+			Options: []func(*redislimit.RedisLimit) error{redislimit.WithFailMode(redislimit.FailClosed)},
+			Want:    []bool{false, false},
+		},
+	}
+
+	for k, test := range tests {
+		client := helper.Must(redislimit.NewClient(addr))
+		limiter := helper.Must(redislimit.New(append(test.Options, redislimit.WithClient(client))...))
+
+		for i, want := range test.Want {
+			if got := limiter.Limit(); got != want {
+				t.Errorf("%v/%v: got %v but wanted %v", k, i, got, want)
+			}
+		}
+
+		_ = client.Close()
+	}
+}
+
+func TestRedisLimitRetryInterval(t *testing.T) {
+	t.Parallel()
+
+	server := newStandIn(t)
+	client := helper.Must(redislimit.NewClient(server.addr()))
+	t.Cleanup(func() { _ = client.Close() })
+
+	limiter := helper.Must(redislimit.New(
+		redislimit.WithClient(client),
+		redislimit.WithRate(1000),
+		redislimit.WithBurst(1000),
+		redislimit.WithFailMode(redislimit.FailClosed),
+		redislimit.WithRetryInterval(50*time.Millisecond)))
+
+	if !limiter.Limit() {
+		t.Fatalf("first request should pass")
+	}
+
+	server.setBroken(true)
+
+	for range 3 {
+		if limiter.Limit() {
+			t.Errorf("requests should be rejected while the server fails")
+		}
+	}
+
+	if got := server.count("EVALSHA"); got != 2 {
+		t.Errorf("server should not be asked within the retry interval, got %v calls", got)
+	}
+
+	server.setBroken(false)
+	time.Sleep(60 * time.Millisecond)
+
+	if !limiter.Limit() {
+		t.Errorf("request should pass after the server recovered")
+	}
+}
+
+func TestClientAuth(t *testing.T) {
+	t.Parallel()
+
+	server := newStandIn(t)
+	server.password = "secret"
+
+	tests := []struct {
+		Options []func(*redislimit.Client) error
+		Want    bool
+	}{
+		{Options: []func(*redislimit.Client) error{redislimit.WithAuth("", "secret"), redislimit.WithDatabase(2)}, Want: true}, // 0
+		{Options: []func(*redislimit.Client) error{redislimit.WithAuth("user", "secret")}, Want: true},                         // 1
+		{Options: []func(*redislimit.Client) error{redislimit.WithAuth("", "wrong")}, Want: false},                             // 2
+		{Options: nil, Want: false}, // 3
+	}
+
+	for k, test := range tests {
+		client := helper.Must(redislimit.NewClient(server.addr(), test.Options...))
+		limiter := helper.Must(redislimit.New(
+			redislimit.WithClient(client),
+			redislimit.WithKey("test:auth"),
+			redislimit.WithRate(1000),
+			redislimit.WithBurst(1000),
+			redislimit.WithFailMode(redislimit.FailClosed)))
+
+		if got := limiter.Limit(); got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+
+		_ = client.Close()
+	}
+
+	server.mtx.Lock()
+	defer server.mtx.Unlock()
+
+	if server.database != "2" {
+		t.Errorf("database should have been selected")
+	}
+}
+
+func TestClientClosed(t *testing.T) {
+	t.Parallel()
+
+	server := newStandIn(t)
+	client := helper.Must(redislimit.NewClient(server.addr()))
+
+	if _, err := client.Do(t.Context(), "PING"); err != nil {
+		t.Errorf("got unexpected error %v", err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Errorf("got unexpected error closing client: %v", err)
+	}
+
+	if _, err := client.Do(t.Context(), "PING"); !errors.Is(err, redislimit.ErrClientClosed) {
+		t.Errorf("got error %v but wanted %v", err, redislimit.ErrClientClosed)
+	}
+
+	if _, err := helper.Must(redislimit.NewClient(server.addr())).Do(t.Context(), "FLUSHALL"); err == nil {
+		t.Errorf("expected error reply for unknown command")
+	}
+}
+
+func TestRedisLimitOptionErrors(t *testing.T) {
+	t.Parallel()
+
+	client := helper.Must(redislimit.NewClient("127.0.0.1:6379"))
+
+	tests := []struct {
+		Options []func(*redislimit.RedisLimit) error
+		WantErr error
+	}{
+		{Options: nil, WantErr: redislimit.ErrNoClient},                                                                     // 0
+		{Options: []func(*redislimit.RedisLimit) error{nil}, WantErr: redislimit.ErrNilOption},                              // 1
+		{Options: []func(*redislimit.RedisLimit) error{redislimit.WithClient(nil)}, WantErr: redislimit.ErrNoClient},        // 2
+		{Options: []func(*redislimit.RedisLimit) error{redislimit.WithKey("")}, WantErr: redislimit.ErrNoKey},               // 3
+		{Options: []func(*redislimit.RedisLimit) error{redislimit.WithRate(0)}, WantErr: redislimit.ErrZeroRate},            // 4
+		{Options: []func(*redislimit.RedisLimit) error{redislimit.WithBurst(0)}, WantErr: redislimit.ErrZeroBurst},          // 5
+		{Options: []func(*redislimit.RedisLimit) error{redislimit.WithFailMode(7)}, WantErr: redislimit.ErrInvalidFailMode}, // 6
+		{Options: []func(*redislimit.RedisLimit) error{redislimit.WithFallback(nil)}, WantErr: ratelimit.ErrInvalidLimiter}, // 7
+		{Options: []func(*redislimit.RedisLimit) error{redislimit.WithClient(client)}, WantErr: nil},                        // 8
+	}
+
+	for k, test := range tests {
+		_, err := redislimit.New(test.Options...)
+
+		if !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+
+	clientTests := []struct {
+		Addr    string
+		Options []func(*redislimit.Client) error
+		WantErr error
+	}{
+		{Addr: "", WantErr: redislimit.ErrNoAddress},                                                                                            // 0
+		{Addr: "localhost:6379", Options: []func(*redislimit.Client) error{nil}, WantErr: redislimit.ErrNilOption},                              // 1
+		{Addr: "localhost:6379", Options: []func(*redislimit.Client) error{redislimit.WithPoolSize(0)}, WantErr: redislimit.ErrInvalidPoolSize}, // 2
+		{Addr: "localhost:6379", Options: []func(*redislimit.Client) error{redislimit.WithTimeout(0)}, WantErr: redislimit.ErrInvalidTimeout},   // 3
+	}
+
+	for k, test := range clientTests {
+		_, err := redislimit.NewClient(test.Addr, test.Options...)
+
+		if !errors.Is(err, test.WantErr) {
+			t.Errorf("client %v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package redislimit
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ErrProtocol is returned when the server reply does not follow the RESP protocol.
+var ErrProtocol = errors.New("redis protocol error")
+
+// maxBulkLength is the maximum length of bulk strings and arrays accepted in replies.
+const maxBulkLength = 1 << 20
+
+// Error is an error reply sent by the server.
+type Error string
+
+// Error gives the error message sent by the server.
+func (e Error) Error() string {
+	return "redis: " + string(e)
+}
+
+// writeCommand writes the command as an array of bulk strings.
+func writeCommand(w *bufio.Writer, args ...string) error {
+	buf := w.AvailableBuffer()
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+// readLine reads a line terminated by CRLF, without the terminator.
+func readLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("%w: line not terminated by CRLF", ErrProtocol)
+	}
+
+	return line[:len(line)-2], nil
+}
+
+// readReply reads a reply of the server. Simple and bulk strings are given as string,
+// integers as int64, arrays as []any and null values as nil. Error replies are given
+// as Error value, not as error, so that the connection is known to be still usable.
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := readLine(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 {
+		return nil, fmt.Errorf("%w: empty reply", ErrProtocol)
+	}
+
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		return parseInt(line[1:])
+	case '$':
+		return readBulk(r, line[1:])
+	case '*':
+		return readArray(r, line[1:])
+	default:
+		return nil, fmt.Errorf("%w: unknown reply type %q", ErrProtocol, line[0])
+	}
+}
+
+// parseInt parses the integer of a reply line.
+func parseInt(b []byte) (int64, error) {
+	n, err := strconv.ParseInt(string(b), 10, 64)
+
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid integer %q", ErrProtocol, b)
+	}
+
+	return n, nil
+}
+
+// readBulk reads a bulk string of the given length.
+func readBulk(r *bufio.Reader, length []byte) (any, error) {
+	n, err := parseInt(length)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 0 {
+		return nil, nil //nolint:nilnil // null bulk string
+	}
+
+	if n > maxBulkLength {
+		return nil, fmt.Errorf("%w: bulk string too long", ErrProtocol)
+	}
+
+	buf := make([]byte, n+2)
+
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	if buf[n] != '\r' || buf[n+1] != '\n' {
+		return nil, fmt.Errorf("%w: bulk string not terminated by CRLF", ErrProtocol)
+	}
+
+	return string(buf[:n]), nil
+}
+
+// readArray reads an array of the given length.
+func readArray(r *bufio.Reader, length []byte) (any, error) {
+	n, err := parseInt(length)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if n < 0 {
+		return nil, nil //nolint:nilnil // null array
+	}
+
+	if n > maxBulkLength {
+		return nil, fmt.Errorf("%w: array too long", ErrProtocol)
+	}
+
+	result := make([]any, n)
+
+	for i := range result {
+		if result[i], err = readReply(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,241 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package redislimit_test
+
+import (
+	"bufio"
+	"crypto/sha1" //nolint:gosec // SHA1 is mandated by EVALSHA
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// standIn is an in-process server speaking the Redis protocol. It knows just the
+// commands used by the limiter and runs the scripts sent to it as the GCRA script of the
+// limiter, implemented natively in Go.
+type standIn struct {
+	ln net.Listener
+
+	mtx      sync.Mutex
+	now      time.Time
+	tat      map[string]int64 // tat holds the theoretical arrival time per key in µs
+	scripts  map[string]bool
+	commands map[string]int
+	password string
+	database string
+	broken   bool
+}
+
+// newStandIn starts a new stand-in server, that is stopped at the end of the test.
+func newStandIn(t *testing.T) *standIn {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+
+	s := &standIn{
+		ln:       ln,
+		now:      time.Unix(1_700_000_000, 0),
+		tat:      make(map[string]int64),
+		scripts:  make(map[string]bool),
+		commands: make(map[string]int),
+	}
+
+	go s.serve()
+
+	t.Cleanup(func() { _ = ln.Close() })
+
+	return s
+}
+
+func (s *standIn) addr() string {
+	return s.ln.Addr().String()
+}
+
+// advance moves the clock of the server forward.
+func (s *standIn) advance(d time.Duration) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.now = s.now.Add(d)
+}
+
+// count gives the number of times the command was received.
+func (s *standIn) count(cmd string) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.commands[cmd]
+}
+
+func (s *standIn) setBroken(broken bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.broken = broken
+}
+
+func (s *standIn) serve() {
+	for {
+		c, err := s.ln.Accept()
+
+		if err != nil {
+			return
+		}
+
+		go s.handle(c)
+	}
+}
+
+func (s *standIn) handle(c net.Conn) {
+	defer func() { _ = c.Close() }()
+
+	r := bufio.NewReader(c)
+	authorized := false
+
+	for {
+		args, err := readCommand(r)
+
+		if err != nil {
+			return
+		}
+
+		reply := s.exec(args, &authorized)
+
+		if _, err := io.WriteString(c, reply); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads a command sent as array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(line, "*") {
+		return nil, errors.New("no array")
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+
+	for i := range args {
+		if line, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+
+		length, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+
+		if err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, length+2)
+
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+
+		args[i] = string(buf[:length])
+	}
+
+	return args, nil
+}
+
+func (s *standIn) exec(args []string, authorized *bool) string {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if len(args) == 0 {
+		return "-ERR empty command\r\n"
+	}
+
+	cmd := strings.ToUpper(args[0])
+	s.commands[cmd]++
+
+	if cmd == "AUTH" {
+		if args[len(args)-1] != s.password {
+			return "-WRONGPASS invalid username-password pair\r\n"
+		}
+
+		*authorized = true
+
+		return "+OK\r\n"
+	}
+
+	if s.password != "" && !*authorized {
+		return "-NOAUTH Authentication required.\r\n"
+	}
+
+	switch cmd {
+	case "PING":
+		return "+PONG\r\n"
+	case "SELECT":
+		s.database = args[1]
+
+		return "+OK\r\n"
+	case "EVAL":
+		sum := sha1.Sum([]byte(args[1])) //nolint:gosec // see import
+		s.scripts[hex.EncodeToString(sum[:])] = true
+
+		return s.gcra(args[3], args[4:])
+	case "EVALSHA":
+		if !s.scripts[args[1]] {
+			return "-NOSCRIPT No matching script. Please use EVAL.\r\n"
+		}
+
+		return s.gcra(args[3], args[4:])
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+// gcra does what the script of the limiter does.
+func (s *standIn) gcra(key string, args []string) string {
+	if s.broken {
+		return "-ERR broken\r\n"
+	}
+
+	interval, _ := strconv.ParseInt(args[0], 10, 64)
+	burst, _ := strconv.ParseInt(args[1], 10, 64)
+	cost, _ := strconv.ParseInt(args[2], 10, 64)
+
+	now := s.now.UnixMicro()
+	tolerance := interval * burst
+	tat := max(s.tat[key], now)
+	allowed := 0
+
+	if next := tat + interval*cost; next-now <= tolerance {
+		allowed = 1
+		tat = next
+		s.tat[key] = tat
+	}
+
+	retry := int64(0)
+
+	if cost <= burst {
+		retry = max(0, tat+interval*cost-tolerance-now)
+	}
+
+	return fmt.Sprintf("*4\r\n:%d\r\n:%d\r\n:%d\r\n:%d\r\n", allowed, (now+tolerance-tat)/interval, tat-now, retry)
+}
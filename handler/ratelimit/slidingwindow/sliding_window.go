@@ -0,0 +1,216 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package slidingwindow provides a process-local sliding window counter rate limiter,
+// that works without background go routines.
+package slidingwindow
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+)
+
+// ErrZeroRequests is returned when the number of requests per window is 0.
+var ErrZeroRequests = errors.New("requests per window must be greater than 0")
+
+// ErrZeroWindow is returned when the window is 0.
+var ErrZeroWindow = errors.New("window must be greater than 0")
+
+// windowState is the immutable state of the limiter. It is replaced as a whole, so
+// that the counters and their window are always consistent.
+type windowState struct {
+	window   int64 // window is the number of the current window since start
+	previous int64 // previous is the number of requests in the window before
+	current  int64 // current is the number of requests in the current window
+}
+
+// SlidingWindow is an instance local request limiter, allowing a number of Requests
+// per Window. It counts the requests in fixed windows and estimates the number of
+// requests in the sliding window, weighting the count of the previous window by the
+// part of it still covered by the sliding window.
+type SlidingWindow struct {
+	// Requests is the number of requests allowed per window.
+	Requests int64
+
+	// Window is the duration of the window.
+	Window time.Duration
+
+	// state holds the current counters.
+	state atomic.Pointer[windowState]
+	// start is the reference time of the windows.
+	start time.Time
+	// now gives the current time.
+	now func() time.Time
+}
+
+// elapsed gives the nanoseconds passed since the start of the limiter.
+func (s *SlidingWindow) elapsed() int64 {
+	return s.now().Sub(s.start).Nanoseconds()
+}
+
+// estimate gives the estimated number of requests in the sliding window ending at now
+// and the state advanced to the window of now.
+func (s *SlidingWindow) estimate(state *windowState, now int64) (float64, windowState) {
+	window := now / int64(s.Window)
+	next := windowState{window: window}
+
+	switch window {
+	case state.window:
+		next = *state
+	case state.window + 1:
+		next.previous = state.current
+	}
+
+	covered := 1 - float64(now%int64(s.Window))/float64(s.Window)
+
+	return float64(next.previous)*covered + float64(next.current), next
+}
+
+// Limit gives true, if the rate limit is not yet exceeded, otherwise false.
+func (s *SlidingWindow) Limit() bool {
+	return s.LimitN(1)
+}
+
+// LimitN gives true, if n requests may pass, otherwise false. If they may not pass,
+// none of them is counted.
+func (s *SlidingWindow) LimitN(n int64) bool {
+	return s.limitN(s.elapsed(), n)
+}
+
+// LimitQuota gives true, if the rate limit is not yet exceeded, and the quota after
+// the decision.
+func (s *SlidingWindow) LimitQuota() (bool, ratelimit.Quota) {
+	return s.LimitNQuota(1)
+}
+
+// LimitNQuota gives true, if n requests may pass, and the quota after the decision.
+// The RetryAfter of the quota is the time until n requests may pass.
+func (s *SlidingWindow) LimitNQuota(n int64) (bool, ratelimit.Quota) {
+	now := s.elapsed()
+	allowed := s.limitN(now, n)
+
+	return allowed, s.quota(now, n)
+}
+
+// limitN counts n requests at the given time, if they may pass.
+func (s *SlidingWindow) limitN(now, n int64) bool {
+	if n <= 0 {
+		return true
+	}
+
+	if n > s.Requests {
+		// never passes, also keeps the following calculations from overflowing
+		return false
+	}
+
+	for {
+		state := s.state.Load()
+		count, next := s.estimate(state, now)
+
+		if count+float64(n) > float64(s.Requests) {
+			return false
+		}
+
+		next.current += n
+
+		if s.state.CompareAndSwap(state, &next) {
+			return true
+		}
+	}
+}
+
+// Remaining gives the number of requests that may currently pass.
+func (s *SlidingWindow) Remaining() int64 {
+	count, _ := s.estimate(s.state.Load(), s.elapsed())
+
+	return max(0, s.Requests-int64(math.Ceil(count)))
+}
+
+// quota gives the quota of the limiter at the given time, with the time until n
+// requests may pass. The weighted count of the previous window drops to 0 at the end
+// of the current window, the count of the current window at the end of the next one.
+// If n exceeds the requests per window, they never pass and no retry time is given.
+func (s *SlidingWindow) quota(now, n int64) ratelimit.Quota {
+	count, state := s.estimate(s.state.Load(), now)
+	window := float64(s.Window)
+	offset := float64(now % int64(s.Window))
+	toEnd := window - offset
+
+	quota := ratelimit.Quota{
+		Limit:     s.Requests,
+		Window:    s.Window,
+		Remaining: max(0, s.Requests-int64(math.Ceil(count))),
+	}
+
+	switch {
+	case state.current > 0:
+		quota.Reset = time.Duration(toEnd + window)
+	case state.previous > 0:
+		quota.Reset = time.Duration(toEnd)
+	}
+
+	if n > s.Requests || count+float64(n) <= float64(s.Requests) {
+		return quota
+	}
+
+	if budget := float64(s.Requests - n - state.current); budget >= 0 {
+		// the previous window has to be weighted low enough
+		quota.RetryAfter = time.Duration(math.Ceil(window*(1-budget/float64(state.previous)) - offset))
+	} else {
+		// the current window becomes the previous one and has to be weighted low enough
+		quota.RetryAfter = time.Duration(math.Ceil(
+			toEnd + window*(1-float64(s.Requests-n)/float64(state.current))))
+	}
+
+	return quota
+}
+
+// WithRequests sets the number of requests allowed per window.
+func WithRequests(n int64) func(s *SlidingWindow) error {
+	return func(s *SlidingWindow) error {
+		if n <= 0 {
+			return ErrZeroRequests
+		}
+
+		s.Requests = n
+
+		return nil
+	}
+}
+
+// WithWindow sets the duration of the window.
+func WithWindow(d time.Duration) func(s *SlidingWindow) error {
+	return func(s *SlidingWindow) error {
+		if d <= 0 {
+			return ErrZeroWindow
+		}
+
+		s.Window = d
+
+		return nil
+	}
+}
+
+// New creates a new sliding window rate limiter.
+func New(options ...func(*SlidingWindow) error) (*SlidingWindow, error) {
+	limiter := SlidingWindow{
+		Requests: 1,
+		Window:   time.Second,
+		start:    time.Now(),
+		now:      time.Now,
+	}
+
+	for _, opt := range options {
+		if err := opt(&limiter); err != nil {
+			return nil, err
+		}
+	}
+
+	limiter.state.Store(&windowState{})
+
+	return &limiter, nil
+}
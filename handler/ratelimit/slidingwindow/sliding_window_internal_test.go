@@ -0,0 +1,15 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package slidingwindow
+
+import "time"
+
+// The following functions are used for internal testing and are not visible to normal library users.
+
+// TSetNow replaces the time source of the given limiter and restarts it at the current
+// time of the new source.
+func TSetNow(s *SlidingWindow, now func() time.Time) {
+	s.now = now
+	s.start = now()
+}
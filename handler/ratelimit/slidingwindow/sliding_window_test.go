@@ -0,0 +1,244 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package slidingwindow_test
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+	"github.com/AlphaOne1/midgard/handler/ratelimit/slidingwindow"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+func TestSlidingWindow(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	limiter := helper.Must(slidingwindow.New(
+		slidingwindow.WithRequests(4),
+		slidingwindow.WithWindow(time.Second)))
+	slidingwindow.TSetNow(limiter, func() time.Time { return now })
+
+	tests := []struct {
+		Advance       time.Duration
+		N             int64
+		Want          bool
+		WantRemaining int64
+	}{
+		{Advance: 0, N: 3, Want: true, WantRemaining: 1},                       // 0
+		{Advance: 500 * time.Millisecond, N: 1, Want: true, WantRemaining: 0},  // 1
+		{Advance: 400 * time.Millisecond, N: 1, Want: false, WantRemaining: 0}, // 2
+		{Advance: 100 * time.Millisecond, N: 1, Want: false, WantRemaining: 0}, // 3 previous window weighs 4
+		{Advance: 500 * time.Millisecond, N: 2, Want: true, WantRemaining: 0},  // 4 previous window weighs 2
+		{Advance: 250 * time.Millisecond, N: 1, Want: true, WantRemaining: 0},  // 5 previous window weighs 1
+		{Advance: 500 * time.Millisecond, N: 1, Want: true, WantRemaining: 0},  // 6 previous 3 weigh 2.25
+		{Advance: 0, N: 1, Want: false, WantRemaining: 0},                      // 7
+		{Advance: 3 * time.Second, N: 5, Want: false, WantRemaining: 4},        // 8 all windows passed
+		{Advance: 0, N: 4, Want: true, WantRemaining: 0},                       // 9
+		{Advance: 0, N: 0, Want: true, WantRemaining: 0},                       // 10
+	}
+
+	for k, test := range tests {
+		now = now.Add(test.Advance)
+
+		if got := limiter.LimitN(test.N); got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+
+		if got := limiter.Remaining(); got != test.WantRemaining {
+			t.Errorf("%v: got %v remaining but wanted %v", k, got, test.WantRemaining)
+		}
+	}
+}
+
+func TestSlidingWindowConcurrent(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	limiter := helper.Must(slidingwindow.New(slidingwindow.WithRequests(100)))
+	slidingwindow.TSetNow(limiter, func() time.Time { return now })
+
+	var passed atomic.Int64
+	var wg sync.WaitGroup
+
+	for range 16 {
+		wg.Go(func() {
+			for range 50 {
+				if limiter.Limit() {
+					passed.Add(1)
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if passed.Load() != 100 {
+		t.Errorf("got %v passed requests but wanted 100", passed.Load())
+	}
+}
+
+func TestSlidingWindowOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options      []func(*slidingwindow.SlidingWindow) error
+		WantErr      error
+		WantRequests int64
+		WantWindow   time.Duration
+	}{
+		{Options: nil, WantRequests: 1, WantWindow: time.Second}, // 0
+		{ // 1
+			Options: []func(*slidingwindow.SlidingWindow) error{
+				slidingwindow.WithRequests(60),
+				slidingwindow.WithWindow(time.Minute),
+			},
+			WantRequests: 60,
+			WantWindow:   time.Minute,
+		},
+		{ // 2
+			Options: []func(*slidingwindow.SlidingWindow) error{slidingwindow.WithRequests(0)},
+			WantErr: slidingwindow.ErrZeroRequests,
+		},
+		{ // 3
+			Options: []func(*slidingwindow.SlidingWindow) error{slidingwindow.WithWindow(0)},
+			WantErr: slidingwindow.ErrZeroWindow,
+		},
+	}
+
+	for k, test := range tests {
+		limiter, err := slidingwindow.New(test.Options...)
+
+		if !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+
+		if err == nil && (limiter.Requests != test.WantRequests || limiter.Window != test.WantWindow) {
+			t.Errorf("%v: got %v requests per %v but wanted %v per %v",
+				k, limiter.Requests, limiter.Window, test.WantRequests, test.WantWindow)
+		}
+	}
+}
+
+func TestSlidingWindowQuota(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	limiter := helper.Must(slidingwindow.New(slidingwindow.WithRequests(2)))
+	slidingwindow.TSetNow(limiter, func() time.Time { return now })
+
+	tests := []struct {
+		Advance   time.Duration
+		Want      bool
+		WantQuota ratelimit.Quota
+	}{
+		{ // 0
+			Advance: 0, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 2, Window: time.Second, Remaining: 1, Reset: 2 * time.Second},
+		},
+		{ // 1
+			Advance: 0, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 2, Window: time.Second, Remaining: 0, Reset: 2 * time.Second, RetryAfter: 1500 * time.Millisecond},
+		},
+		{ // 2
+			Advance: 0, Want: false,
+			WantQuota: ratelimit.Quota{Limit: 2, Window: time.Second, Remaining: 0, Reset: 2 * time.Second, RetryAfter: 1500 * time.Millisecond},
+		},
+		{ // 3
+			Advance: 1250 * time.Millisecond, Want: false,
+			WantQuota: ratelimit.Quota{Limit: 2, Window: time.Second, Remaining: 0, Reset: 750 * time.Millisecond, RetryAfter: 250 * time.Millisecond},
+		},
+		{ // 4
+			Advance: 250 * time.Millisecond, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 2, Window: time.Second, Remaining: 0, Reset: 1500 * time.Millisecond, RetryAfter: 500 * time.Millisecond},
+		},
+	}
+
+	for k, test := range tests {
+		now = now.Add(test.Advance)
+
+		got, quota := limiter.LimitQuota()
+
+		if got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+
+		if quota != test.WantQuota {
+			t.Errorf("%v: got quota %+v but wanted %+v", k, quota, test.WantQuota)
+		}
+	}
+}
+
+func TestSlidingWindowQuotaN(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	limiter := helper.Must(slidingwindow.New(slidingwindow.WithRequests(100), slidingwindow.WithWindow(10*time.Second)))
+	slidingwindow.TSetNow(limiter, func() time.Time { return now })
+
+	tests := []struct {
+		Advance   time.Duration
+		N         int64
+		Want      bool
+		WantQuota ratelimit.Quota
+	}{
+		{ // 0
+			Advance: 0, N: 100, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 100, Window: 10 * time.Second, Remaining: 0, Reset: 20 * time.Second, RetryAfter: 20 * time.Second},
+		},
+		{ // 1 the retry time is that of the whole cost
+			Advance: 0, N: 100, Want: false,
+			WantQuota: ratelimit.Quota{Limit: 100, Window: 10 * time.Second, Remaining: 0, Reset: 20 * time.Second, RetryAfter: 20 * time.Second},
+		},
+		{ // 2
+			Advance: 15 * time.Second, N: 50, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 100, Window: 10 * time.Second, Remaining: 0, Reset: 15 * time.Second, RetryAfter: 5 * time.Second},
+		},
+		{ // 3 more than the limit never passes
+			Advance: 0, N: 101, Want: false,
+			WantQuota: ratelimit.Quota{Limit: 100, Window: 10 * time.Second, Remaining: 0, Reset: 15 * time.Second, RetryAfter: 0},
+		},
+	}
+
+	for k, test := range tests {
+		now = now.Add(test.Advance)
+
+		got, quota := limiter.LimitNQuota(test.N)
+
+		if got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+
+		if quota != test.WantQuota {
+			t.Errorf("%v: got quota %+v but wanted %+v", k, quota, test.WantQuota)
+		}
+	}
+}
+
+func TestSlidingWindowLargeCost(t *testing.T) {
+	t.Parallel()
+
+	limiter := helper.Must(slidingwindow.New(slidingwindow.WithRequests(1), slidingwindow.WithWindow(time.Second)))
+
+	tests := []struct {
+		N    int64
+		Want bool
+	}{
+		{N: math.MaxInt64/int64(time.Second) + 9, Want: false}, // 0 would overflow the time
+		{N: math.MaxInt64, Want: false},                        // 1
+		{N: 1, Want: true},                                     // 2
+		{N: 1, Want: false},                                    // 3
+	}
+
+	for k, test := range tests {
+		if got := limiter.LimitN(test.N); got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package tokenbucket provides a process-local token bucket rate limiter, that works
+// without background go routines.
+package tokenbucket
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+)
+
+// ErrZeroRate is returned when the rate is 0.
+var ErrZeroRate = errors.New("rate must be greater than 0")
+
+// ErrZeroBurst is returned when the burst is 0.
+var ErrZeroBurst = errors.New("burst must be greater than 0")
+
+// TokenBucket is an instance local request limiter. The bucket holds up to Burst tokens
+// and is refilled with Rate tokens per second. Every request takes one token. The
+// tokens are not added by a background go routine, instead they are calculated from
+// the time passed on each request.
+type TokenBucket struct {
+	// Rate is the number of tokens added per second.
+	Rate float64
+
+	// Burst is the maximum number of tokens in the bucket, i.e. the number of requests
+	// that may pass at once after a period without requests.
+	Burst int64
+
+	// interval is the time in nanoseconds it takes to add one token.
+	interval int64
+	// empty is the time in nanoseconds since start, at which the bucket was or will
+	// be empty, if no further tokens are taken. This single value represents both the
+	// number of tokens and the time of the last refill, so it can be updated atomically.
+	empty atomic.Int64
+	// start is the reference time of empty.
+	start time.Time
+	// now gives the current time.
+	now func() time.Time
+}
+
+// elapsed gives the nanoseconds passed since the start of the bucket.
+func (b *TokenBucket) elapsed() int64 {
+	return b.now().Sub(b.start).Nanoseconds()
+}
+
+// Limit gives true, if a token could be taken from the bucket, otherwise false.
+func (b *TokenBucket) Limit() bool {
+	return b.LimitN(1)
+}
+
+// LimitN gives true, if n tokens could be taken from the bucket, otherwise false. If
+// there are not enough tokens, none are taken.
+func (b *TokenBucket) LimitN(n int64) bool {
+	return b.limitN(b.elapsed(), n)
+}
+
+// LimitQuota gives true, if a token could be taken from the bucket, and the quota
+// after taking it.
+func (b *TokenBucket) LimitQuota() (bool, ratelimit.Quota) {
+	return b.LimitNQuota(1)
+}
+
+// LimitNQuota gives true, if n tokens could be taken from the bucket, and the quota
+// after taking them. The RetryAfter of the quota is the time until n tokens are
+// available.
+func (b *TokenBucket) LimitNQuota(n int64) (bool, ratelimit.Quota) {
+	now := b.elapsed()
+	allowed := b.limitN(now, n)
+
+	return allowed, b.quota(now, n)
+}
+
+// limitN takes n tokens at the given time, if available.
+func (b *TokenBucket) limitN(now, n int64) bool {
+	if n <= 0 {
+		return true
+	}
+
+	if n > b.Burst {
+		// never passes, also keeps the following calculations from overflowing
+		return false
+	}
+
+	full := now - b.Burst*b.interval
+
+	for {
+		empty := b.empty.Load()
+		next := max(empty, full) + n*b.interval
+
+		if next > now {
+			return false
+		}
+
+		if b.empty.CompareAndSwap(empty, next) {
+			return true
+		}
+	}
+}
+
+// Tokens gives the number of tokens currently available in the bucket.
+func (b *TokenBucket) Tokens() float64 {
+	now := b.elapsed()
+	empty := max(b.empty.Load(), now-b.Burst*b.interval)
+
+	return float64(now-empty) / float64(b.interval)
+}
+
+// quota gives the quota of the bucket at the given time, with the time until n tokens
+// are available. If n exceeds the burst, they never are and no retry time is given.
+func (b *TokenBucket) quota(now, n int64) ratelimit.Quota {
+	empty := max(b.empty.Load(), now-b.Burst*b.interval)
+	quota := ratelimit.Quota{
+		Limit:     b.Burst,
+		Window:    time.Duration(b.Burst * b.interval),
+		Remaining: (now - empty) / b.interval,
+		Reset:     time.Duration(empty + b.Burst*b.interval - now),
+	}
+
+	if n <= b.Burst {
+		quota.RetryAfter = time.Duration(max(0, empty+n*b.interval-now))
+	}
+
+	return quota
+}
+
+// WithRate sets the number of tokens added per second.
+func WithRate(r float64) func(b *TokenBucket) error {
+	return func(b *TokenBucket) error {
+		if r <= 0 {
+			return ErrZeroRate
+		}
+
+		b.Rate = r
+
+		return nil
+	}
+}
+
+// WithBurst sets the maximum number of tokens in the bucket.
+func WithBurst(n int64) func(b *TokenBucket) error {
+	return func(b *TokenBucket) error {
+		if n <= 0 {
+			return ErrZeroBurst
+		}
+
+		b.Burst = n
+
+		return nil
+	}
+}
+
+// New creates a new token bucket rate limiter. The bucket starts full.
+func New(options ...func(*TokenBucket) error) (*TokenBucket, error) {
+	bucket := TokenBucket{
+		Rate:  1,
+		Burst: 1,
+		start: time.Now(),
+		now:   time.Now,
+	}
+
+	for _, opt := range options {
+		if err := opt(&bucket); err != nil {
+			return nil, err
+		}
+	}
+
+	bucket.interval = max(1, int64(math.Round(float64(time.Second)/bucket.Rate)))
+	bucket.empty.Store(-bucket.Burst * bucket.interval)
+
+	return &bucket, nil
+}
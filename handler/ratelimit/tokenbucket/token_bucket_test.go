@@ -0,0 +1,232 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package tokenbucket_test
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/handler/ratelimit"
+	"github.com/AlphaOne1/midgard/handler/ratelimit/tokenbucket"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+func TestTokenBucket(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	bucket := helper.Must(tokenbucket.New(tokenbucket.WithRate(10), tokenbucket.WithBurst(3)))
+	tokenbucket.TSetNow(bucket, func() time.Time { return now })
+
+	tests := []struct {
+		Advance    time.Duration
+		N          int64
+		Want       bool
+		WantTokens float64
+	}{
+		{Advance: 0, N: 1, Want: true, WantTokens: 2},                         // 0
+		{Advance: 0, N: 2, Want: true, WantTokens: 0},                         // 1
+		{Advance: 0, N: 1, Want: false, WantTokens: 0},                        // 2
+		{Advance: 50 * time.Millisecond, N: 1, Want: false, WantTokens: 0.5},  // 3
+		{Advance: 50 * time.Millisecond, N: 1, Want: true, WantTokens: 0},     // 4
+		{Advance: 150 * time.Millisecond, N: 2, Want: false, WantTokens: 1.5}, // 5 not enough, nothing taken
+		{Advance: 0, N: 1, Want: true, WantTokens: 0.5},                       // 6
+		{Advance: time.Hour, N: 4, Want: false, WantTokens: 3},                // 7 capped at burst
+		{Advance: 0, N: 3, Want: true, WantTokens: 0},                         // 8
+		{Advance: 0, N: 0, Want: true, WantTokens: 0},                         // 9
+	}
+
+	for k, test := range tests {
+		now = now.Add(test.Advance)
+
+		if got := bucket.LimitN(test.N); got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+
+		if got := bucket.Tokens(); got != test.WantTokens {
+			t.Errorf("%v: got %v tokens but wanted %v", k, got, test.WantTokens)
+		}
+	}
+}
+
+func TestTokenBucketConcurrent(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	bucket := helper.Must(tokenbucket.New(tokenbucket.WithRate(1), tokenbucket.WithBurst(100)))
+	tokenbucket.TSetNow(bucket, func() time.Time { return now })
+
+	var passed atomic.Int64
+	var wg sync.WaitGroup
+
+	for range 16 {
+		wg.Go(func() {
+			for range 50 {
+				if bucket.Limit() {
+					passed.Add(1)
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+
+	if passed.Load() != 100 {
+		t.Errorf("got %v passed requests but wanted 100", passed.Load())
+	}
+}
+
+func TestTokenBucketOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options   []func(*tokenbucket.TokenBucket) error
+		WantErr   error
+		WantRate  float64
+		WantBurst int64
+	}{
+		{Options: nil, WantRate: 1, WantBurst: 1}, // 0
+		{ // 1
+			Options:   []func(*tokenbucket.TokenBucket) error{tokenbucket.WithRate(2.5), tokenbucket.WithBurst(7)},
+			WantRate:  2.5,
+			WantBurst: 7,
+		},
+		{Options: []func(*tokenbucket.TokenBucket) error{tokenbucket.WithRate(0)}, WantErr: tokenbucket.ErrZeroRate},   // 2
+		{Options: []func(*tokenbucket.TokenBucket) error{tokenbucket.WithBurst(0)}, WantErr: tokenbucket.ErrZeroBurst}, // 3
+	}
+
+	for k, test := range tests {
+		bucket, err := tokenbucket.New(test.Options...)
+
+		if !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+
+		if err == nil && (bucket.Rate != test.WantRate || bucket.Burst != test.WantBurst) {
+			t.Errorf("%v: got rate %v burst %v but wanted %v and %v",
+				k, bucket.Rate, bucket.Burst, test.WantRate, test.WantBurst)
+		}
+	}
+}
+
+func TestTokenBucketQuota(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	limiter := helper.Must(tokenbucket.New(tokenbucket.WithRate(10), tokenbucket.WithBurst(3)))
+	tokenbucket.TSetNow(limiter, func() time.Time { return now })
+
+	tests := []struct {
+		Advance   time.Duration
+		Want      bool
+		WantQuota ratelimit.Quota
+	}{
+		{ // 0
+			Advance: 0, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 3, Window: 300 * time.Millisecond, Remaining: 2, Reset: 100 * time.Millisecond},
+		},
+		{ // 1
+			Advance: 0, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 3, Window: 300 * time.Millisecond, Remaining: 1, Reset: 200 * time.Millisecond},
+		},
+		{ // 2
+			Advance: 0, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 3, Window: 300 * time.Millisecond, Remaining: 0, Reset: 300 * time.Millisecond, RetryAfter: 100 * time.Millisecond},
+		},
+		{ // 3
+			Advance: 0, Want: false,
+			WantQuota: ratelimit.Quota{Limit: 3, Window: 300 * time.Millisecond, Remaining: 0, Reset: 300 * time.Millisecond, RetryAfter: 100 * time.Millisecond},
+		},
+		{ // 4
+			Advance: 50 * time.Millisecond, Want: false,
+			WantQuota: ratelimit.Quota{Limit: 3, Window: 300 * time.Millisecond, Remaining: 0, Reset: 250 * time.Millisecond, RetryAfter: 50 * time.Millisecond},
+		},
+	}
+
+	for k, test := range tests {
+		now = now.Add(test.Advance)
+
+		got, quota := limiter.LimitQuota()
+
+		if got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+
+		if quota != test.WantQuota {
+			t.Errorf("%v: got quota %+v but wanted %+v", k, quota, test.WantQuota)
+		}
+	}
+}
+
+func TestTokenBucketQuotaN(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1_700_000_000, 0)
+	limiter := helper.Must(tokenbucket.New(tokenbucket.WithRate(10), tokenbucket.WithBurst(100)))
+	tokenbucket.TSetNow(limiter, func() time.Time { return now })
+
+	tests := []struct {
+		Advance   time.Duration
+		N         int64
+		Want      bool
+		WantQuota ratelimit.Quota
+	}{
+		{ // 0
+			Advance: 0, N: 100, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 100, Window: 10 * time.Second, Remaining: 0, Reset: 10 * time.Second, RetryAfter: 10 * time.Second},
+		},
+		{ // 1 the retry time is that of the whole cost
+			Advance: 0, N: 100, Want: false,
+			WantQuota: ratelimit.Quota{Limit: 100, Window: 10 * time.Second, Remaining: 0, Reset: 10 * time.Second, RetryAfter: 10 * time.Second},
+		},
+		{ // 2
+			Advance: 5 * time.Second, N: 50, Want: true,
+			WantQuota: ratelimit.Quota{Limit: 100, Window: 10 * time.Second, Remaining: 0, Reset: 10 * time.Second, RetryAfter: 5 * time.Second},
+		},
+		{ // 3 more than the limit never passes
+			Advance: 0, N: 101, Want: false,
+			WantQuota: ratelimit.Quota{Limit: 100, Window: 10 * time.Second, Remaining: 0, Reset: 10 * time.Second, RetryAfter: 0},
+		},
+	}
+
+	for k, test := range tests {
+		now = now.Add(test.Advance)
+
+		got, quota := limiter.LimitNQuota(test.N)
+
+		if got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+
+		if quota != test.WantQuota {
+			t.Errorf("%v: got quota %+v but wanted %+v", k, quota, test.WantQuota)
+		}
+	}
+}
+
+func TestTokenBucketLargeCost(t *testing.T) {
+	t.Parallel()
+
+	limiter := helper.Must(tokenbucket.New(tokenbucket.WithRate(1), tokenbucket.WithBurst(1)))
+
+	tests := []struct {
+		N    int64
+		Want bool
+	}{
+		{N: math.MaxInt64/int64(time.Second) + 9, Want: false}, // 0 would overflow the time
+		{N: math.MaxInt64, Want: false},                        // 1
+		{N: 1, Want: true},                                     // 2
+		{N: 1, Want: false},                                    // 3
+	}
+
+	for k, test := range tests {
+		if got := limiter.LimitN(test.N); got != test.Want {
+			t.Errorf("%v: got %v but wanted %v", k, got, test.Want)
+		}
+	}
+}
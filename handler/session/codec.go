@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+)
+
+// ErrInvalidKey is returned when a key does not have the length of KeySize.
+var ErrInvalidKey = errors.New("session key must be 32 bytes long")
+
+// ErrInvalidSession is returned when a session cookie cannot be decrypted or decoded.
+var ErrInvalidSession = errors.New("invalid session")
+
+// KeySize is the size of the session keys. The keys are used for AES-256-GCM.
+const KeySize = 32
+
+// keyIDSize is the size of the key identifier prefixed to the cookie values.
+const keyIDSize = 4
+
+// sessionData is the content of a session cookie.
+type sessionData struct {
+	Name     string   `json:"n"`
+	Method   string   `json:"m,omitempty"`
+	Roles    []string `json:"r,omitempty"`
+	Scopes   []string `json:"s,omitempty"`
+	Issued   int64    `json:"i"` // Issued is the login time, the base of the absolute expiry
+	LastSeen int64    `json:"l"` // LastSeen is the time of the last refresh, the base of the idle expiry
+}
+
+// principal creates the principal of the session owner.
+func (d *sessionData) principal() *defs.Principal {
+	return &defs.Principal{
+		Name:   d.Name,
+		Method: "session",
+		Roles:  d.Roles,
+		Scopes: d.Scopes,
+		Claims: map[string]any{
+			"auth_method": d.Method,
+			"issued":      time.Unix(d.Issued, 0),
+		},
+	}
+}
+
+// sessionKey is a key together with its cipher and identifier.
+type sessionKey struct {
+	id   [keyIDSize]byte
+	aead cipher.AEAD
+}
+
+// codec encrypts and decrypts the session data. The first key is used for encryption,
+// all keys are tried for decryption, allowing keys to be rotated.
+type codec struct {
+	keys []sessionKey
+}
+
+// newCodec creates a codec for the given keys.
+func newCodec(keys [][]byte) (*codec, error) {
+	result := codec{keys: make([]sessionKey, 0, len(keys))}
+
+	for _, k := range keys {
+		if len(k) != KeySize {
+			return nil, ErrInvalidKey
+		}
+
+		block, err := aes.NewCipher(k)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not create cipher: %w", err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not create cipher: %w", err)
+		}
+
+		sum := sha256.Sum256(k)
+		key := sessionKey{aead: aead}
+		copy(key.id[:], sum[:])
+
+		result.keys = append(result.keys, key)
+	}
+
+	return &result, nil
+}
+
+// encode encrypts the session data with the primary key. The cookie name is used as
+// additional data, so that a value cannot be used in another cookie.
+func (c *codec) encode(name string, data *sessionData) (string, error) {
+	plain, err := json.Marshal(data)
+
+	if err != nil {
+		return "", fmt.Errorf("could not encode session: %w", err)
+	}
+
+	key := c.keys[0]
+	nonce := make([]byte, key.aead.NonceSize())
+	_, _ = rand.Read(nonce)
+
+	out := make([]byte, 0, keyIDSize+len(nonce)+len(plain)+key.aead.Overhead())
+	out = append(out, key.id[:]...)
+	out = append(out, nonce...)
+	out = key.aead.Seal(out, nonce, plain, []byte(name))
+
+	return base64.RawURLEncoding.EncodeToString(out), nil
+}
+
+// decode decrypts the session data. The second return value tells if the primary key
+// was used, otherwise the session should be encoded again.
+func (c *codec) decode(name, value string) (*sessionData, bool, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+
+	if err != nil || len(raw) < keyIDSize {
+		return nil, false, ErrInvalidSession
+	}
+
+	for i, key := range c.keys {
+		if [keyIDSize]byte(raw[:keyIDSize]) != key.id {
+			continue
+		}
+
+		rest := raw[keyIDSize:]
+
+		if len(rest) < key.aead.NonceSize() {
+			return nil, false, ErrInvalidSession
+		}
+
+		plain, openErr := key.aead.Open(nil, rest[:key.aead.NonceSize()], rest[key.aead.NonceSize():], []byte(name))
+
+		if openErr != nil {
+			return nil, false, ErrInvalidSession
+		}
+
+		var data sessionData
+
+		if err := json.Unmarshal(plain, &data); err != nil {
+			return nil, false, ErrInvalidSession
+		}
+
+		return &data, i == 0, nil
+	}
+
+	return nil, false, ErrInvalidSession
+}
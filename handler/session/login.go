@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package session
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/AlphaOne1/midgard/handler/basicauth"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// ErrNilAuthenticator is returned when the authenticator of the login handler is nil.
+var ErrNilAuthenticator = errors.New("authenticator cannot be nil")
+
+// maxFormSize limits the size of the login form.
+const maxFormSize = 64 << 10
+
+// LoginHandler checks the credentials posted by a login form and starts a session.
+type LoginHandler struct {
+	config *Handler
+	auth   basicauth.Authenticator
+}
+
+// NewLoginHandler creates a handler for the login form. It expects a POST request with
+// the form fields username, password and optionally return_to. The credentials are
+// checked using the given authenticator. On success, the session cookie is set and the
+// client is redirected to the return_to address. Otherwise, it is redirected to the
+// login page with the additional query parameter error=invalid_credentials, or, if no
+// login page is configured, rejected with 401 Unauthorized.
+func NewLoginHandler(auth basicauth.Authenticator, options ...func(handler *Handler) error) (*LoginHandler, error) {
+	if auth == nil {
+		return nil, ErrNilAuthenticator
+	}
+
+	config, err := newHandler(options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginHandler{config: config, auth: auth}, nil
+}
+
+// ServeHTTP implements the login.
+func (l *LoginHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if l == nil || l.config == nil {
+		helper.WriteState(w, slog.Default(), http.StatusInternalServerError)
+
+		return
+	}
+
+	h := l.config
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		helper.WriteState(w, h.Log(), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+
+	if err := r.ParseForm(); err != nil {
+		helper.WriteState(w, h.Log(), http.StatusBadRequest)
+
+		return
+	}
+
+	username := r.PostForm.Get("username")
+	returnTo := h.safeReturn(r.PostForm.Get(ReturnToParam))
+
+	principal, ok, authErr := basicauth.AuthenticatePrincipal(l.auth, username, r.PostForm.Get("password"))
+
+	if authErr != nil {
+		h.Log().Error("authentication error",
+			slog.String("error", authErr.Error()),
+			slog.String("user", username))
+	}
+
+	if !ok || authErr != nil || username == "" {
+		h.Log().Info("login failed",
+			slog.String("user", username),
+			slog.String("client", r.RemoteAddr))
+
+		if h.loginURL == "" {
+			helper.WriteState(w, h.Log(), http.StatusUnauthorized)
+
+			return
+		}
+
+		http.Redirect(w, r, h.loginRedirect(returnTo, "error", "invalid_credentials"), http.StatusSeeOther)
+
+		return
+	}
+
+	now := h.now().Unix()
+
+	if err := h.setCookie(w, &sessionData{
+		Name:     principal.Name,
+		Method:   principal.Method,
+		Roles:    principal.Roles,
+		Scopes:   principal.Scopes,
+		Issued:   now,
+		LastSeen: now,
+	}); err != nil {
+		h.Log().Error("could not start session", slog.String("error", err.Error()))
+		helper.WriteState(w, h.Log(), http.StatusInternalServerError)
+
+		return
+	}
+
+	http.Redirect(w, r, returnTo, http.StatusSeeOther)
+}
+
+// LogoutHandler ends the session.
+type LogoutHandler struct {
+	config *Handler
+}
+
+// NewLogoutHandler creates a handler that removes the session cookie on POST requests
+// and redirects the client to the return_to address.
+func NewLogoutHandler(options ...func(handler *Handler) error) (*LogoutHandler, error) {
+	config, err := newHandler(options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &LogoutHandler{config: config}, nil
+}
+
+// ServeHTTP implements the logout.
+func (l *LogoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if l == nil || l.config == nil {
+		helper.WriteState(w, slog.Default(), http.StatusInternalServerError)
+
+		return
+	}
+
+	h := l.config
+
+	// only POST, so that foreign sites cannot log users out using links or images
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		helper.WriteState(w, h.Log(), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFormSize)
+	_ = r.ParseForm()
+
+	h.clearCookie(w)
+	http.Redirect(w, r, h.safeReturn(r.PostForm.Get(ReturnToParam)), http.StatusSeeOther)
+}
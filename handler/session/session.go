@@ -0,0 +1,408 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package session implements the authentication of requests using encrypted session
+// cookies, together with handlers to log in and out.
+package session
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// ErrNilOption is returned when an option is nil.
+var ErrNilOption = errors.New("option cannot be nil")
+
+// ErrNoKeys is returned when no session keys are configured.
+var ErrNoKeys = errors.New("no session keys configured")
+
+// ErrInvalidTimeout is returned when a timeout is not positive.
+var ErrInvalidTimeout = errors.New("timeout must be greater than 0")
+
+// ErrInvalidCookieName is returned when the cookie name is empty or contains invalid characters.
+var ErrInvalidCookieName = errors.New("invalid cookie name")
+
+// ErrInvalidURL is returned when a configured URL is not a local path.
+var ErrInvalidURL = errors.New("url must be a local path")
+
+// DefaultCookieName is the name of the session cookie, if not configured otherwise.
+const DefaultCookieName = "midgard_session"
+
+// DefaultIdleTimeout is the time a session stays valid without requests, if not configured otherwise.
+const DefaultIdleTimeout = 30 * time.Minute
+
+// DefaultMaxAge is the time a session stays valid at most, if not configured otherwise.
+const DefaultMaxAge = 12 * time.Hour
+
+// ReturnToParam is the name of the query and form parameter carrying the address to
+// return to after the login.
+const ReturnToParam = "return_to"
+
+// Handler holds the internal data of the session middleware.
+type Handler struct {
+	defs.MWBase
+
+	keys          [][]byte         // keys are the session keys, the first one is used for new cookies
+	cookieName    string           // cookieName is the name of the session cookie
+	cookiePath    string           // cookiePath is the path attribute of the session cookie
+	cookieDomain  string           // cookieDomain is the domain attribute of the session cookie
+	insecure      bool             // insecure omits the secure attribute of the session cookie
+	idleTimeout   time.Duration    // idleTimeout is the sliding expiry
+	maxAge        time.Duration    // maxAge is the absolute expiry, counted from the login
+	loginURL      string           // loginURL is where requests without session are redirected to
+	defaultReturn string           // defaultReturn is the address to go to after login, if none given
+	now           func() time.Time // now gives the current time, replaceable for testing
+
+	codec *codec
+}
+
+// GetMWBase returns the MWBase instance of the handler.
+func (h *Handler) GetMWBase() *defs.MWBase {
+	if h == nil {
+		return nil
+	}
+
+	return &h.MWBase
+}
+
+// expires gives the time the session expires, the earlier of the idle and the
+// absolute expiry.
+func (h *Handler) expires(data *sessionData) time.Time {
+	idle := time.Unix(data.LastSeen, 0).Add(h.idleTimeout)
+	absolute := time.Unix(data.Issued, 0).Add(h.maxAge)
+
+	if absolute.Before(idle) {
+		return absolute
+	}
+
+	return idle
+}
+
+// load gets the valid session of the request. The second return value tells if the
+// cookie has to be issued again, because it was encrypted with an old key.
+func (h *Handler) load(r *http.Request) (*sessionData, bool) {
+	cookie, cookieErr := r.Cookie(h.cookieName)
+
+	if cookieErr != nil {
+		return nil, false
+	}
+
+	data, primary, decodeErr := h.codec.decode(h.cookieName, cookie.Value)
+
+	if decodeErr != nil {
+		h.Log().Debug("invalid session cookie",
+			slog.String("error", decodeErr.Error()),
+			slog.String("client", r.RemoteAddr))
+
+		return nil, false
+	}
+
+	if !h.now().Before(h.expires(data)) {
+		return nil, false
+	}
+
+	return data, !primary
+}
+
+// setCookie issues the session cookie for the given session.
+func (h *Handler) setCookie(w http.ResponseWriter, data *sessionData) error {
+	value, err := h.codec.encode(h.cookieName, data)
+
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.cookieName,
+		Value:    value,
+		Path:     h.cookiePath,
+		Domain:   h.cookieDomain,
+		MaxAge:   max(1, int(h.expires(data).Sub(h.now()).Seconds())),
+		Secure:   !h.insecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return nil
+}
+
+// clearCookie removes the session cookie from the client.
+func (h *Handler) clearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.cookieName,
+		Value:    "",
+		Path:     h.cookiePath,
+		Domain:   h.cookieDomain,
+		MaxAge:   -1,
+		Secure:   !h.insecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// loginRedirect gives the address of the login page with the given return address and
+// additional parameters.
+func (h *Handler) loginRedirect(returnTo string, params ...string) string {
+	target, _ := url.Parse(h.loginURL) // validated when configured
+	query := target.Query()
+	query.Set(ReturnToParam, returnTo)
+
+	for i := 0; i+1 < len(params); i += 2 {
+		query.Set(params[i], params[i+1])
+	}
+
+	target.RawQuery = query.Encode()
+
+	return target.String()
+}
+
+// safeReturn gives the address to return to after the login. Only local paths are
+// accepted, to prevent redirects to foreign sites.
+func (h *Handler) safeReturn(returnTo string) string {
+	if isLocalPath(returnTo) {
+		return returnTo
+	}
+
+	return h.defaultReturn
+}
+
+// isLocalPath checks that the address is a path on the same site. Addresses like
+// "//evil.example" or "/\evil.example" are interpreted as hosts by browsers.
+func isLocalPath(address string) bool {
+	if !strings.HasPrefix(address, "/") || strings.HasPrefix(address, "//") || strings.HasPrefix(address, `/\`) {
+		return false
+	}
+
+	u, err := url.Parse(address)
+
+	return err == nil && u.Scheme == "" && u.Host == ""
+}
+
+// ServeHTTP implements the session authentication.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !helper.IntroCheck(h, w, r) {
+		return
+	}
+
+	data, rekey := h.load(r)
+
+	if data == nil {
+		h.sendNoSession(w, r)
+
+		return
+	}
+
+	// sliding expiry: the cookie is refreshed after half of the idle timeout
+	if now := h.now(); rekey || now.Sub(time.Unix(data.LastSeen, 0)) >= h.idleTimeout/2 {
+		data.LastSeen = now.Unix()
+
+		if err := h.setCookie(w, data); err != nil {
+			h.Log().Error("could not refresh session", slog.String("error", err.Error()))
+		}
+	}
+
+	r = r.WithContext(defs.ContextWithPrincipal(r.Context(), data.principal()))
+
+	h.Next().ServeHTTP(w, r)
+}
+
+// sendNoSession redirects requests without valid session to the login page. Requests
+// that cannot be repeated after the login, and all requests if no login page is
+// configured, are rejected.
+func (h *Handler) sendNoSession(w http.ResponseWriter, r *http.Request) {
+	if _, err := r.Cookie(h.cookieName); err == nil {
+		h.clearCookie(w)
+	}
+
+	if h.loginURL != "" && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+		http.Redirect(w, r, h.loginRedirect(r.URL.RequestURI()), http.StatusFound)
+
+		return
+	}
+
+	helper.WriteState(w, h.Log(), http.StatusUnauthorized)
+}
+
+// WithKeys sets the keys used to encrypt the session cookies. Each key has to be
+// KeySize bytes long. New cookies are encrypted using the first key, the other keys are
+// only used to decrypt existing cookies. This allows to rotate keys by adding a new key
+// in front and removing the oldest one after the maximum session age.
+func WithKeys(keys ...[]byte) func(h *Handler) error {
+	return func(h *Handler) error {
+		if len(keys) == 0 {
+			return ErrNoKeys
+		}
+
+		for _, k := range keys {
+			if len(k) != KeySize {
+				return ErrInvalidKey
+			}
+		}
+
+		h.keys = keys
+
+		return nil
+	}
+}
+
+// WithCookieName sets the name of the session cookie.
+func WithCookieName(name string) func(h *Handler) error {
+	return func(h *Handler) error {
+		if name == "" || strings.ContainsAny(name, " \t;,=\"") {
+			return ErrInvalidCookieName
+		}
+
+		h.cookieName = name
+
+		return nil
+	}
+}
+
+// WithCookieScope sets the path and domain attributes of the session cookie. Without
+// it, the cookie is sent for all paths of the issuing host.
+func WithCookieScope(path, domain string) func(h *Handler) error {
+	return func(h *Handler) error {
+		h.cookiePath = path
+		h.cookieDomain = domain
+
+		return nil
+	}
+}
+
+// WithInsecureCookies omits the secure attribute of the session cookie, so that it is
+// also sent over unencrypted connections. Only use it for development.
+func WithInsecureCookies() func(h *Handler) error {
+	return func(h *Handler) error {
+		h.insecure = true
+
+		return nil
+	}
+}
+
+// WithIdleTimeout sets the time after which a session expires without requests.
+func WithIdleTimeout(d time.Duration) func(h *Handler) error {
+	return func(h *Handler) error {
+		if d <= 0 {
+			return ErrInvalidTimeout
+		}
+
+		h.idleTimeout = d
+
+		return nil
+	}
+}
+
+// WithMaxAge sets the time after the login, after which a session expires regardless
+// of its use.
+func WithMaxAge(d time.Duration) func(h *Handler) error {
+	return func(h *Handler) error {
+		if d <= 0 {
+			return ErrInvalidTimeout
+		}
+
+		h.maxAge = d
+
+		return nil
+	}
+}
+
+// WithLoginURL sets the login page that requests without valid session are redirected
+// to. The original address is passed in the return_to query parameter. Without it,
+// these requests are rejected with 401 Unauthorized.
+func WithLoginURL(loginURL string) func(h *Handler) error {
+	return func(h *Handler) error {
+		if !isLocalPath(loginURL) {
+			return ErrInvalidURL
+		}
+
+		h.loginURL = loginURL
+
+		return nil
+	}
+}
+
+// WithDefaultReturn sets the address the login and logout handlers redirect to, if no
+// valid return_to parameter was given.
+func WithDefaultReturn(address string) func(h *Handler) error {
+	return func(h *Handler) error {
+		if !isLocalPath(address) {
+			return ErrInvalidURL
+		}
+
+		h.defaultReturn = address
+
+		return nil
+	}
+}
+
+// WithLogger configures the logger to use.
+func WithLogger(log *slog.Logger) func(h *Handler) error {
+	return defs.WithLogger[*Handler](log)
+}
+
+// WithLogLevel configures the log level to use with the logger.
+func WithLogLevel(level slog.Level) func(h *Handler) error {
+	return defs.WithLogLevel[*Handler](level)
+}
+
+// newHandler creates the handler configuration shared by the middleware and the login
+// and logout handlers.
+func newHandler(options []func(handler *Handler) error) (*Handler, error) {
+	handler := Handler{
+		cookieName:    DefaultCookieName,
+		cookiePath:    "/",
+		idleTimeout:   DefaultIdleTimeout,
+		maxAge:        DefaultMaxAge,
+		defaultReturn: "/",
+		now:           time.Now,
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&handler); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(handler.keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	c, err := newCodec(handler.keys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	handler.codec = c
+
+	return &handler, nil
+}
+
+// New generates a new session middleware. The login and logout handlers have to be
+// configured with the same options.
+func New(options ...func(handler *Handler) error) (defs.Middleware, error) {
+	handler, err := newHandler(options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		if err := handler.SetNext(next); err != nil {
+			return nil
+		}
+
+		return handler
+	}, nil
+}
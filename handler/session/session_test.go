@@ -0,0 +1,470 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package session_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/basicauth/mapauth"
+	"github.com/AlphaOne1/midgard/handler/session"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+var (
+	oldKey = bytes.Repeat([]byte{1}, session.KeySize)
+	newKey = bytes.Repeat([]byte{2}, session.KeySize)
+)
+
+// testClock is a manually advanced time source.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+// testSetup contains the handlers under test, sharing one configuration.
+type testSetup struct {
+	clock     *testClock
+	protected http.Handler
+	login     *session.LoginHandler
+	logout    *session.LogoutHandler
+	principal *defs.Principal
+}
+
+func newSetup(t *testing.T, options ...func(*session.Handler) error) *testSetup {
+	t.Helper()
+
+	s := &testSetup{clock: &testClock{now: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)}}
+
+	options = append([]func(*session.Handler) error{
+		session.WithKeys(newKey),
+		session.WithLoginURL("/login?lang=en"),
+		session.WithIdleTimeout(30 * time.Minute),
+		session.WithMaxAge(2 * time.Hour),
+	}, options...)
+
+	protected := helper.Must(session.New(options...))(
+		http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			s.principal, _ = defs.PrincipalFromContext(r.Context())
+		}))
+
+	handler, isHandler := protected.(*session.Handler)
+
+	if !isHandler {
+		t.Fatalf("wrong handler type")
+	}
+
+	session.TSetNow(handler, s.clock.Now)
+	s.protected = handler
+
+	s.login = helper.Must(session.NewLoginHandler(
+		helper.Must(mapauth.New(mapauth.WithAuths(map[string]string{"alice": "secret"}))),
+		options...))
+	session.TSetLoginNow(s.login, s.clock.Now)
+
+	s.logout = helper.Must(session.NewLogoutHandler(options...))
+
+	return s
+}
+
+// doLogin posts the login form and gives the response.
+func (s *testSetup) doLogin(t *testing.T, user, pass, returnTo string) *http.Response {
+	t.Helper()
+
+	form := url.Values{"username": {user}, "password": {pass}, session.ReturnToParam: {returnTo}}
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+
+	s.login.ServeHTTP(rec, req)
+
+	return rec.Result()
+}
+
+// get requests a protected resource with the given cookie.
+func (s *testSetup) get(t *testing.T, method string, cookie *http.Cookie) *http.Response {
+	t.Helper()
+
+	s.principal = nil
+	req := httptest.NewRequestWithContext(t.Context(), method, "/app/page?x=1", nil)
+
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	s.protected.ServeHTTP(rec, req)
+
+	return rec.Result()
+}
+
+// sessionCookie gets the session cookie set by the response.
+func sessionCookie(resp *http.Response) *http.Cookie {
+	for _, c := range resp.Cookies() {
+		if c.Name == session.DefaultCookieName {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func TestLogin(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		User         string
+		Pass         string
+		ReturnTo     string
+		WantLocation string
+		WantCookie   bool
+	}{
+		{User: "alice", Pass: "secret", ReturnTo: "/app/page?x=1", WantLocation: "/app/page?x=1", WantCookie: true}, // 0
+		{User: "alice", Pass: "secret", ReturnTo: "", WantLocation: "/", WantCookie: true},                          // 1
+		{User: "alice", Pass: "secret", ReturnTo: "https://evil.example/", WantLocation: "/", WantCookie: true},     // 2
+		{User: "alice", Pass: "secret", ReturnTo: "//evil.example/", WantLocation: "/", WantCookie: true},           // 3
+		{User: "alice", Pass: "secret", ReturnTo: `/\evil.example/`, WantLocation: "/", WantCookie: true},           // 4
+		{ // 5
+			User: "alice", Pass: "wrong", ReturnTo: "/app",
+			WantLocation: "/login?error=invalid_credentials&lang=en&return_to=%2Fapp", WantCookie: false,
+		},
+		{ // 6
+			User: "", Pass: "", ReturnTo: "/app",
+			WantLocation: "/login?error=invalid_credentials&lang=en&return_to=%2Fapp", WantCookie: false,
+		},
+	}
+
+	for k, test := range tests {
+		s := newSetup(t)
+		resp := s.doLogin(t, test.User, test.Pass, test.ReturnTo)
+
+		if resp.StatusCode != http.StatusSeeOther {
+			t.Errorf("%v: got status %v but wanted %v", k, resp.StatusCode, http.StatusSeeOther)
+		}
+
+		if got := resp.Header.Get("Location"); got != test.WantLocation {
+			t.Errorf("%v: got location %q but wanted %q", k, got, test.WantLocation)
+		}
+
+		cookie := sessionCookie(resp)
+
+		if (cookie != nil) != test.WantCookie {
+			t.Errorf("%v: got cookie %v but wanted cookie %v", k, cookie, test.WantCookie)
+		}
+
+		if cookie != nil && (!cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode) {
+			t.Errorf("%v: cookie attributes not set correctly: %v", k, cookie)
+		}
+	}
+}
+
+func TestSession(t *testing.T) {
+	t.Parallel()
+
+	s := newSetup(t)
+	cookie := sessionCookie(s.doLogin(t, "alice", "secret", "/"))
+
+	if resp := s.get(t, http.MethodGet, cookie); resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %v but wanted %v", resp.StatusCode, http.StatusOK)
+	}
+
+	if s.principal == nil || s.principal.Name != "alice" || s.principal.Method != "session" {
+		t.Errorf("got unexpected principal %+v", s.principal)
+	}
+
+	if s.principal != nil && s.principal.Claims["auth_method"] != "basic" {
+		t.Errorf("got unexpected claims %v", s.principal.Claims)
+	}
+}
+
+func TestNoSession(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Method       string
+		Cookie       *http.Cookie
+		WantStatus   int
+		WantLocation string
+		WantClear    bool
+	}{
+		{ // 0
+			Method: http.MethodGet, Cookie: nil,
+			WantStatus: http.StatusFound, WantLocation: "/login?lang=en&return_to=%2Fapp%2Fpage%3Fx%3D1",
+		},
+		{ // 1
+			Method: http.MethodPost, Cookie: nil,
+			WantStatus: http.StatusUnauthorized,
+		},
+		{ // 2
+			Method: http.MethodGet, Cookie: &http.Cookie{Name: session.DefaultCookieName, Value: "garbage"},
+			WantStatus: http.StatusFound, WantLocation: "/login?lang=en&return_to=%2Fapp%2Fpage%3Fx%3D1",
+			WantClear: true,
+		},
+	}
+
+	for k, test := range tests {
+		s := newSetup(t)
+		resp := s.get(t, test.Method, test.Cookie)
+
+		if resp.StatusCode != test.WantStatus {
+			t.Errorf("%v: got status %v but wanted %v", k, resp.StatusCode, test.WantStatus)
+		}
+
+		if got := resp.Header.Get("Location"); got != test.WantLocation {
+			t.Errorf("%v: got location %q but wanted %q", k, got, test.WantLocation)
+		}
+
+		if c := sessionCookie(resp); (c != nil && c.MaxAge < 0) != test.WantClear {
+			t.Errorf("%v: got cookie %v but wanted clearing %v", k, c, test.WantClear)
+		}
+	}
+}
+
+func TestNoLoginURL(t *testing.T) {
+	t.Parallel()
+
+	handler := helper.Must(session.New(session.WithKeys(newKey)))(http.HandlerFunc(helper.DummyHandler))
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("got status %v but wanted %v", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Steps       []time.Duration // Steps are the pauses between the requests
+		WantOK      bool            // WantOK is the result of the last request
+		WantRefresh bool            // WantRefresh tells if the last successful request refreshed the cookie
+	}{
+		{Steps: []time.Duration{time.Minute}, WantOK: true, WantRefresh: false},     // 0
+		{Steps: []time.Duration{20 * time.Minute}, WantOK: true, WantRefresh: true}, // 1
+		{Steps: []time.Duration{31 * time.Minute}, WantOK: false},                   // 2
+		{Steps: []time.Duration{20 * time.Minute, 20 * time.Minute}, WantOK: true},  // 3
+		{Steps: []time.Duration{10 * time.Minute, 25 * time.Minute}, WantOK: false}, // 4
+		{ // 5: sliding expiry does not extend beyond the maximum age
+			Steps:  []time.Duration{25 * time.Minute, 25 * time.Minute, 25 * time.Minute, 25 * time.Minute, 25 * time.Minute},
+			WantOK: false,
+		},
+	}
+
+	for k, test := range tests {
+		s := newSetup(t)
+		cookie := sessionCookie(s.doLogin(t, "alice", "secret", "/"))
+
+		var (
+			resp      *http.Response
+			refreshed bool
+		)
+
+		for _, step := range test.Steps {
+			s.clock.now = s.clock.now.Add(step)
+			resp = s.get(t, http.MethodGet, cookie)
+			refreshed = false
+
+			if c := sessionCookie(resp); c != nil && c.MaxAge > 0 {
+				cookie = c
+				refreshed = true
+			}
+		}
+
+		if (resp.StatusCode == http.StatusOK) != test.WantOK {
+			t.Errorf("%v: got status %v but wanted success %v", k, resp.StatusCode, test.WantOK)
+		}
+
+		if test.WantOK && refreshed != test.WantRefresh && len(test.Steps) == 1 {
+			t.Errorf("%v: got refresh %v but wanted %v", k, refreshed, test.WantRefresh)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	t.Parallel()
+
+	old := newSetup(t, session.WithKeys(oldKey))
+	cookie := sessionCookie(old.doLogin(t, "alice", "secret", "/"))
+
+	// the new key is primary, the old one is still accepted and the cookie re-encrypted
+	rotated := newSetup(t, session.WithKeys(newKey, oldKey))
+	resp := rotated.get(t, http.MethodGet, cookie)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %v but wanted %v", resp.StatusCode, http.StatusOK)
+	}
+
+	reissued := sessionCookie(resp)
+
+	if reissued == nil || reissued.Value == cookie.Value {
+		t.Fatalf("expected cookie to be issued again with the new key")
+	}
+
+	// after removing the old key, only the reissued cookie is accepted
+	current := newSetup(t, session.WithKeys(newKey))
+
+	if resp := current.get(t, http.MethodGet, cookie); resp.StatusCode == http.StatusOK {
+		t.Errorf("cookie of removed key must not be accepted")
+	}
+
+	if resp := current.get(t, http.MethodGet, reissued); resp.StatusCode != http.StatusOK {
+		t.Errorf("reissued cookie must be accepted, but got %v", resp.StatusCode)
+	}
+}
+
+func TestTampering(t *testing.T) {
+	t.Parallel()
+
+	s := newSetup(t)
+	cookie := sessionCookie(s.doLogin(t, "alice", "secret", "/"))
+
+	value := []byte(cookie.Value)
+
+	for i := range value {
+		tampered := slices.Clone(value)
+		tampered[i] ^= 1
+
+		resp := s.get(t, http.MethodGet, &http.Cookie{Name: cookie.Name, Value: string(tampered)})
+
+		if resp.StatusCode == http.StatusOK {
+			// flipping the lowest bit of the last base64 character may leave the bytes unchanged
+			if i == len(value)-1 {
+				continue
+			}
+
+			t.Errorf("tampered cookie at %v accepted", i)
+		}
+	}
+
+	// the value of another cookie name cannot be used as session
+	other := newSetup(t, session.WithCookieName("other"))
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+	req.AddCookie(&http.Cookie{Name: "other", Value: cookie.Value})
+	rec := httptest.NewRecorder()
+	other.protected.ServeHTTP(rec, req)
+
+	if rec.Code == http.StatusOK {
+		t.Errorf("cookie value accepted under other name")
+	}
+}
+
+func TestLogout(t *testing.T) {
+	t.Parallel()
+
+	s := newSetup(t)
+
+	tests := []struct {
+		Method       string
+		ReturnTo     string
+		WantStatus   int
+		WantLocation string
+	}{
+		{Method: http.MethodPost, ReturnTo: "/bye", WantStatus: http.StatusSeeOther, WantLocation: "/bye"},   // 0
+		{Method: http.MethodPost, ReturnTo: "http://x/", WantStatus: http.StatusSeeOther, WantLocation: "/"}, // 1
+		{Method: http.MethodGet, WantStatus: http.StatusMethodNotAllowed},                                    // 2
+	}
+
+	for k, test := range tests {
+		form := url.Values{session.ReturnToParam: {test.ReturnTo}}
+		req := httptest.NewRequestWithContext(t.Context(), test.Method, "/logout", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		s.logout.ServeHTTP(rec, req)
+
+		resp := rec.Result()
+
+		if resp.StatusCode != test.WantStatus {
+			t.Errorf("%v: got status %v but wanted %v", k, resp.StatusCode, test.WantStatus)
+		}
+
+		if got := resp.Header.Get("Location"); got != test.WantLocation {
+			t.Errorf("%v: got location %q but wanted %q", k, got, test.WantLocation)
+		}
+
+		if c := sessionCookie(resp); (c != nil && c.MaxAge < 0) != (test.WantStatus == http.StatusSeeOther) {
+			t.Errorf("%v: cookie not cleared correctly: %v", k, c)
+		}
+	}
+}
+
+func TestLoginMethod(t *testing.T) {
+	t.Parallel()
+
+	s := newSetup(t)
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/login", nil)
+	rec := httptest.NewRecorder()
+	s.login.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("got status %v, allow %q but wanted %v, POST", rec.Code, rec.Header().Get("Allow"),
+			http.StatusMethodNotAllowed)
+	}
+}
+
+func TestNilHandlers(t *testing.T) {
+	t.Parallel()
+
+	var (
+		login  *session.LoginHandler
+		logout *session.LogoutHandler
+	)
+
+	for k, h := range []http.Handler{login, logout} {
+		req := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/", nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("%v: got status %v but wanted %v", k, rec.Code, http.StatusInternalServerError)
+		}
+	}
+}
+
+func TestOptionErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Options []func(*session.Handler) error
+		WantErr error
+	}{
+		{Options: nil, WantErr: session.ErrNoKeys},                                                                                            // 0
+		{Options: []func(*session.Handler) error{session.WithKeys()}, WantErr: session.ErrNoKeys},                                             // 1
+		{Options: []func(*session.Handler) error{session.WithKeys([]byte("short"))}, WantErr: session.ErrInvalidKey},                          // 2
+		{Options: []func(*session.Handler) error{testKeys, session.WithIdleTimeout(0)}, WantErr: session.ErrInvalidTimeout},                   // 3
+		{Options: []func(*session.Handler) error{testKeys, session.WithMaxAge(-1)}, WantErr: session.ErrInvalidTimeout},                       // 4
+		{Options: []func(*session.Handler) error{testKeys, session.WithCookieName("a b")}, WantErr: session.ErrInvalidCookieName},             // 5
+		{Options: []func(*session.Handler) error{testKeys, session.WithLoginURL("https://x/login")}, WantErr: session.ErrInvalidURL},          // 6
+		{Options: []func(*session.Handler) error{testKeys, session.WithDefaultReturn("//x")}, WantErr: session.ErrInvalidURL},                 // 7
+		{Options: []func(*session.Handler) error{testKeys, session.WithCookieScope("/app", ""), session.WithInsecureCookies()}, WantErr: nil}, // 8
+	}
+
+	for k, test := range tests {
+		_, err := session.New(test.Options...)
+
+		if !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+
+	if _, err := session.NewLoginHandler(nil, testKeys); !errors.Is(err, session.ErrNilAuthenticator) {
+		t.Errorf("got error %v but wanted %v", err, session.ErrNilAuthenticator)
+	}
+
+	if _, err := session.NewLogoutHandler(); !errors.Is(err, session.ErrNoKeys) {
+		t.Errorf("got error %v but wanted %v", err, session.ErrNoKeys)
+	}
+}
@@ -0,0 +1,288 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package webhook
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrInvalidScheme is returned for schemes lacking the information to find the signature.
+var ErrInvalidScheme = errors.New("invalid scheme")
+
+// ErrMissingSignature is returned by the parsing of a scheme if the request carries no
+// signature.
+var ErrMissingSignature = errors.New("missing signature")
+
+// Encoding is the encoding of the signature in its header.
+type Encoding int
+
+const (
+	// EncodingHex is the lower or upper case hexadecimal encoding.
+	EncodingHex Encoding = iota
+	// EncodingBase64 is the standard base64 encoding with padding.
+	EncodingBase64
+)
+
+// decode decodes the signature.
+func (e Encoding) decode(s string) ([]byte, error) {
+	var (
+		result []byte
+		err    error
+	)
+
+	switch e {
+	case EncodingHex:
+		result, err = hex.DecodeString(s)
+	case EncodingBase64:
+		result, err = base64.StdEncoding.DecodeString(s)
+	default:
+		err = fmt.Errorf("%w: unknown encoding %v", ErrInvalidScheme, e)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not decode signature: %w", err)
+	}
+
+	return result, nil
+}
+
+// Scheme describes how a webhook provider signs its requests. Simple schemes are
+// described by the header carrying the signature, its prefix and encoding. Schemes not
+// fitting this model provide a Parse function.
+type Scheme struct {
+	// Name identifies the scheme, it is used as name of the principal of verified requests.
+	Name string
+	// SignatureHeader is the header carrying the signature. Multiple values are each
+	// treated as a signature.
+	SignatureHeader string
+	// Prefix precedes the encoded signature in the header, e.g. "sha256=".
+	Prefix string
+	// Encoding is the encoding of the signature.
+	Encoding Encoding
+	// TimestampHeader is the optional header carrying the time of sending in Unix seconds.
+	TimestampHeader string
+	// Parse extracts the timestamp and the decoded signatures from the request. If set,
+	// SignatureHeader, Prefix, Encoding and TimestampHeader are not used.
+	Parse func(r *http.Request) (timestamp string, signatures [][]byte, err error)
+	// Payload creates the signed content from the request, its timestamp and body. If nil,
+	// the body is signed.
+	Payload func(r *http.Request, timestamp string, body []byte) []byte
+	// Hash is the hash function used for the HMAC. If nil, SHA-256 is used.
+	Hash func() hash.Hash
+	// Tolerance is the maximum difference between the timestamp and the current time.
+	// If 0, the timestamp is not checked.
+	Tolerance time.Duration
+}
+
+// validate checks that the scheme can be used.
+func (s *Scheme) validate() error {
+	if s.Parse == nil && s.SignatureHeader == "" {
+		return fmt.Errorf("%w: needs a signature header or a parse function", ErrInvalidScheme)
+	}
+
+	if s.Parse == nil && s.Tolerance > 0 && s.TimestampHeader == "" {
+		return fmt.Errorf("%w: tolerance needs a timestamp header", ErrInvalidScheme)
+	}
+
+	if s.Tolerance < 0 {
+		return fmt.Errorf("%w: negative tolerance", ErrInvalidScheme)
+	}
+
+	if s.Hash == nil {
+		s.Hash = sha256.New
+	}
+
+	if s.Name == "" {
+		s.Name = "webhook"
+	}
+
+	return nil
+}
+
+// parse extracts the timestamp and the signatures from the request.
+func (s *Scheme) parse(r *http.Request) (string, [][]byte, error) {
+	if s.Parse != nil {
+		return s.Parse(r)
+	}
+
+	values := r.Header.Values(s.SignatureHeader)
+
+	if len(values) == 0 {
+		return "", nil, ErrMissingSignature
+	}
+
+	signatures := make([][]byte, 0, len(values))
+
+	for _, v := range values {
+		encoded, found := strings.CutPrefix(strings.TrimSpace(v), s.Prefix)
+
+		if !found {
+			return "", nil, fmt.Errorf("%w: prefix %q", ErrMissingSignature, s.Prefix)
+		}
+
+		signature, err := s.Encoding.decode(encoded)
+
+		if err != nil {
+			return "", nil, err
+		}
+
+		signatures = append(signatures, signature)
+	}
+
+	timestamp := ""
+
+	if s.TimestampHeader != "" {
+		timestamp = r.Header.Get(s.TimestampHeader)
+	}
+
+	return timestamp, signatures, nil
+}
+
+// payload gives the signed content of the request.
+func (s *Scheme) payload(r *http.Request, timestamp string, body []byte) []byte {
+	if s.Payload != nil {
+		return s.Payload(r, timestamp, body)
+	}
+
+	return body
+}
+
+// DefaultTolerance is the timestamp tolerance used by the presets of providers sending
+// timestamps.
+const DefaultTolerance = 5 * time.Minute
+
+// GitHub is the scheme used by GitHub, signing the body with HMAC-SHA256 in the
+// X-Hub-Signature-256 header. GitHub does not send a timestamp.
+func GitHub() Scheme {
+	return Scheme{
+		Name:            "github",
+		SignatureHeader: "X-Hub-Signature-256",
+		Prefix:          "sha256=",
+		Encoding:        EncodingHex,
+	}
+}
+
+// Slack is the scheme used by Slack, signing the version, timestamp and body in the
+// X-Slack-Signature header.
+func Slack() Scheme {
+	return Scheme{
+		Name:            "slack",
+		SignatureHeader: "X-Slack-Signature",
+		Prefix:          "v0=",
+		Encoding:        EncodingHex,
+		TimestampHeader: "X-Slack-Request-Timestamp",
+		Payload: func(_ *http.Request, timestamp string, body []byte) []byte {
+			return bytes.Join([][]byte{[]byte("v0"), []byte(timestamp), body}, []byte(":"))
+		},
+		Tolerance: DefaultTolerance,
+	}
+}
+
+// Shopify is the scheme used by Shopify, signing the body in the X-Shopify-Hmac-Sha256
+// header. Shopify does not send a timestamp.
+func Shopify() Scheme {
+	return Scheme{
+		Name:            "shopify",
+		SignatureHeader: "X-Shopify-Hmac-Sha256",
+		Encoding:        EncodingBase64,
+	}
+}
+
+// Stripe is the scheme used by Stripe, signing the timestamp and body in the
+// Stripe-Signature header. Multiple v1 signatures are accepted, as sent during the
+// rotation of secrets.
+func Stripe() Scheme {
+	return Scheme{
+		Name:  "stripe",
+		Parse: parseStripe,
+		Payload: func(_ *http.Request, timestamp string, body []byte) []byte {
+			return bytes.Join([][]byte{[]byte(timestamp), body}, []byte("."))
+		},
+		Tolerance: DefaultTolerance,
+	}
+}
+
+// parseStripe parses the Stripe-Signature header of the form t=123,v1=abc,v1=def.
+func parseStripe(r *http.Request) (string, [][]byte, error) {
+	header := r.Header.Get("Stripe-Signature")
+	timestamp := ""
+	signatures := make([][]byte, 0, 1)
+
+	for part := range strings.SplitSeq(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			if signature, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+	}
+
+	if len(signatures) == 0 {
+		return "", nil, ErrMissingSignature
+	}
+
+	return timestamp, signatures, nil
+}
+
+// StandardWebhooks is the scheme of the Standard Webhooks specification, used e.g. by
+// Svix. It signs the message id, timestamp and body. The secrets are usually given with
+// a whsec_ prefix, use StandardWebhooksSecret to decode them.
+func StandardWebhooks() Scheme {
+	return Scheme{
+		Name:  "standard-webhooks",
+		Parse: parseStandardWebhooks,
+		Payload: func(r *http.Request, timestamp string, body []byte) []byte {
+			return bytes.Join([][]byte{[]byte(r.Header.Get("Webhook-Id")), []byte(timestamp), body}, []byte("."))
+		},
+		Tolerance: DefaultTolerance,
+	}
+}
+
+// parseStandardWebhooks parses the space separated signatures of the form v1,base64.
+func parseStandardWebhooks(r *http.Request) (string, [][]byte, error) {
+	if r.Header.Get("Webhook-Id") == "" {
+		return "", nil, ErrMissingSignature
+	}
+
+	signatures := make([][]byte, 0, 1)
+
+	for part := range strings.FieldsSeq(r.Header.Get("Webhook-Signature")) {
+		if encoded, found := strings.CutPrefix(part, "v1,"); found {
+			if signature, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+	}
+
+	if len(signatures) == 0 {
+		return "", nil, ErrMissingSignature
+	}
+
+	return r.Header.Get("Webhook-Timestamp"), signatures, nil
+}
+
+// StandardWebhooksSecret decodes a Standard Webhooks secret given as whsec_ followed by
+// the base64 encoded key.
+func StandardWebhooksSecret(secret string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "whsec_"))
+
+	if err != nil {
+		return nil, fmt.Errorf("could not decode secret: %w", err)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,232 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+// Package webhook implements the verification of HMAC signed webhook requests, with
+// presets for common providers.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// ErrNilOption is returned when an option is nil.
+var ErrNilOption = errors.New("option cannot be nil")
+
+// ErrNoScheme is returned when there is no scheme configured.
+var ErrNoScheme = errors.New("no scheme configured")
+
+// ErrNoSecrets is returned when there are no secrets configured.
+var ErrNoSecrets = errors.New("no secrets configured")
+
+// ErrInvalidSize is returned for sizes that are not greater than 0.
+var ErrInvalidSize = errors.New("size must be greater than 0")
+
+// DefaultMaxBodySize is the default maximum size of webhook bodies.
+const DefaultMaxBodySize = 1 << 20
+
+// Handler holds the internal data of the webhook verification middleware.
+type Handler struct {
+	defs.MWBase
+
+	scheme      *Scheme          // scheme describes the signature of the provider
+	secrets     [][]byte         // secrets are the accepted signing secrets
+	maxBodySize int64            // maxBodySize is the maximum size of the bodies
+	now         func() time.Time // now gives the current time
+}
+
+// GetMWBase returns the MWBase of the handler.
+func (h *Handler) GetMWBase() *defs.MWBase {
+	if h == nil {
+		return nil
+	}
+
+	return &h.MWBase
+}
+
+// reject logs the reason and sends it to the client as problem details.
+func (h *Handler) reject(w http.ResponseWriter, r *http.Request, status int, reason string) {
+	h.Log().Info("webhook rejected",
+		slog.String("scheme", h.scheme.Name),
+		slog.String("reason", reason),
+		slog.String("client", r.RemoteAddr))
+	helper.WriteProblem(w, h.Log(), status, reason)
+}
+
+// validTimestamp checks the timestamp against the tolerance of the scheme.
+func (h *Handler) validTimestamp(timestamp string) bool {
+	if h.scheme.Tolerance == 0 {
+		return true
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+
+	if err != nil {
+		return false
+	}
+
+	sent := time.Unix(unix, 0)
+	now := h.now()
+
+	return !sent.Before(now.Add(-h.scheme.Tolerance)) && !sent.After(now.Add(h.scheme.Tolerance))
+}
+
+// validSignature checks if any of the signatures was created with any of the secrets.
+func (h *Handler) validSignature(payload []byte, signatures [][]byte) bool {
+	for _, secret := range h.secrets {
+		mac := hmac.New(h.scheme.Hash, secret)
+		mac.Write(payload)
+		expected := mac.Sum(nil)
+
+		for _, signature := range signatures {
+			if hmac.Equal(expected, signature) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ServeHTTP implements the webhook verification.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !helper.IntroCheck(h, w, r) {
+		return
+	}
+
+	timestamp, signatures, parseErr := h.scheme.parse(r)
+
+	if parseErr != nil {
+		h.reject(w, r, http.StatusUnauthorized, "missing or malformed signature")
+
+		return
+	}
+
+	if !h.validTimestamp(timestamp) {
+		h.reject(w, r, http.StatusUnauthorized, "timestamp outside of tolerance")
+
+		return
+	}
+
+	body, complete, bodyErr := helper.ReadBody(r, h.maxBodySize)
+
+	if bodyErr != nil {
+		h.reject(w, r, http.StatusBadRequest, "could not read body")
+
+		return
+	}
+
+	if !complete {
+		h.reject(w, r, http.StatusRequestEntityTooLarge, "body too large")
+
+		return
+	}
+
+	if !h.validSignature(h.scheme.payload(r, timestamp, body), signatures) {
+		h.reject(w, r, http.StatusUnauthorized, "invalid signature")
+
+		return
+	}
+
+	r = r.WithContext(defs.ContextWithPrincipal(r.Context(), &defs.Principal{
+		Name:   h.scheme.Name,
+		Method: "webhook",
+	}))
+
+	h.Next().ServeHTTP(w, r)
+}
+
+// WithScheme sets the signature scheme of the provider, e.g. GitHub() or a custom one.
+func WithScheme(scheme Scheme) func(h *Handler) error {
+	return func(h *Handler) error {
+		if err := scheme.validate(); err != nil {
+			return err
+		}
+
+		h.scheme = &scheme
+
+		return nil
+	}
+}
+
+// WithSecrets adds signing secrets. Requests signed with any of them are accepted, so
+// that secrets can be rotated.
+func WithSecrets(secrets ...[]byte) func(h *Handler) error {
+	return func(h *Handler) error {
+		for _, secret := range secrets {
+			if len(secret) == 0 {
+				return ErrNoSecrets
+			}
+
+			h.secrets = append(h.secrets, bytes.Clone(secret))
+		}
+
+		return nil
+	}
+}
+
+// WithMaxBodySize sets the maximum size of webhook bodies. Larger requests are rejected,
+// as the body has to be held in memory for the verification.
+func WithMaxBodySize(size int64) func(h *Handler) error {
+	return func(h *Handler) error {
+		if size <= 0 {
+			return ErrInvalidSize
+		}
+
+		h.maxBodySize = size
+
+		return nil
+	}
+}
+
+// WithLogger configures the logger to use.
+func WithLogger(log *slog.Logger) func(h *Handler) error {
+	return defs.WithLogger[*Handler](log)
+}
+
+// WithLogLevel configures the log level to use with the logger.
+func WithLogLevel(level slog.Level) func(h *Handler) error {
+	return defs.WithLogLevel[*Handler](level)
+}
+
+// New generates a new webhook verification middleware.
+func New(options ...func(handler *Handler) error) (defs.Middleware, error) {
+	handler := Handler{
+		maxBodySize: DefaultMaxBodySize,
+		now:         time.Now,
+	}
+
+	for _, opt := range options {
+		if opt == nil {
+			return nil, ErrNilOption
+		}
+
+		if err := opt(&handler); err != nil {
+			return nil, err
+		}
+	}
+
+	if handler.scheme == nil {
+		return nil, ErrNoScheme
+	}
+
+	if len(handler.secrets) == 0 {
+		return nil, ErrNoSecrets
+	}
+
+	return func(next http.Handler) http.Handler {
+		if err := handler.SetNext(next); err != nil {
+			return nil
+		}
+
+		return &handler
+	}, nil
+}
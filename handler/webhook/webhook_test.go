@@ -0,0 +1,350 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package webhook_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec // used by legacy webhook providers
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlphaOne1/midgard/defs"
+	"github.com/AlphaOne1/midgard/handler/webhook"
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// slackBody is the example request body of the Slack documentation.
+const slackBody = "token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&" +
+	"channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&" +
+	"command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2F" +
+	"T1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&" +
+	"trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c"
+
+// sign calculates the hex encoded HMAC of the payload.
+func sign(h func() hash.Hash, secret, payload string) string {
+	mac := hmac.New(h, []byte(secret))
+	mac.Write([]byte(payload))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// received records what the next handler got.
+type received struct {
+	body      string
+	principal *defs.Principal
+}
+
+func newHandler(t *testing.T, rec *received, now time.Time,
+	options ...func(*webhook.Handler) error) *webhook.Handler {
+
+	t.Helper()
+
+	h := helper.Must(webhook.New(options...))(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.body = string(body)
+		rec.principal, _ = defs.PrincipalFromContext(r.Context())
+	}))
+
+	handler, isHandler := h.(*webhook.Handler)
+
+	if !isHandler {
+		t.Fatalf("wrong handler type")
+	}
+
+	webhook.TSetNow(handler, func() time.Time { return now })
+
+	return handler
+}
+
+func TestPresets(t *testing.T) {
+	t.Parallel()
+
+	standardSecret := helper.Must(webhook.StandardWebhooksSecret("whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"))
+	stripeSignature := func(secret, timestamp, body string) string {
+		return sign(sha256.New, secret, timestamp+"."+body)
+	}
+
+	tests := []struct {
+		Name       string
+		Scheme     webhook.Scheme
+		Secrets    [][]byte
+		Now        time.Time
+		Body       string
+		Headers    map[string]string
+		WantStatus int
+	}{
+		{
+			Name: "github", Scheme: webhook.GitHub(), Secrets: [][]byte{[]byte("It's a Secret to Everybody")},
+			Body: "Hello, World!",
+			Headers: map[string]string{
+				"X-Hub-Signature-256": "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17",
+			},
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "github wrong secret", Scheme: webhook.GitHub(), Secrets: [][]byte{[]byte("It's a Secret")},
+			Body: "Hello, World!",
+			Headers: map[string]string{
+				"X-Hub-Signature-256": "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17",
+			},
+			WantStatus: http.StatusUnauthorized,
+		},
+		{
+			Name: "github rotated secret", Scheme: webhook.GitHub(),
+			Secrets: [][]byte{[]byte("new secret"), []byte("It's a Secret to Everybody")},
+			Body:    "Hello, World!",
+			Headers: map[string]string{
+				"X-Hub-Signature-256": "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17",
+			},
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "github missing signature", Scheme: webhook.GitHub(), Secrets: [][]byte{[]byte("secret")},
+			Body:       "Hello, World!",
+			WantStatus: http.StatusUnauthorized,
+		},
+		{
+			Name: "github wrong prefix", Scheme: webhook.GitHub(), Secrets: [][]byte{[]byte("It's a Secret to Everybody")},
+			Body: "Hello, World!",
+			Headers: map[string]string{
+				"X-Hub-Signature-256": "sha1=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17",
+			},
+			WantStatus: http.StatusUnauthorized,
+		},
+		{
+			Name: "custom sha1", Secrets: [][]byte{[]byte("secret")},
+			Scheme: webhook.Scheme{
+				Name:            "legacy",
+				SignatureHeader: "X-Hub-Signature",
+				Prefix:          "sha1=",
+				Hash:            sha1.New,
+			},
+			Body:       "payload",
+			Headers:    map[string]string{"X-Hub-Signature": "sha1=" + sign(sha1.New, "secret", "payload")},
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "custom with timestamp", Secrets: [][]byte{[]byte("secret")},
+			Scheme: webhook.Scheme{
+				SignatureHeader: "X-Signature",
+				Encoding:        webhook.EncodingHex,
+				TimestampHeader: "X-Timestamp",
+				Tolerance:       time.Minute,
+			},
+			Now:  time.Unix(1700000000, 0).Add(time.Minute),
+			Body: "payload",
+			Headers: map[string]string{
+				"X-Timestamp": "1700000000",
+				"X-Signature": sign(sha256.New, "secret", "payload"),
+			},
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "custom malformed timestamp", Secrets: [][]byte{[]byte("secret")},
+			Scheme: webhook.Scheme{
+				SignatureHeader: "X-Signature",
+				TimestampHeader: "X-Timestamp",
+				Tolerance:       time.Minute,
+			},
+			Body: "payload",
+			Headers: map[string]string{
+				"X-Timestamp": "yesterday",
+				"X-Signature": sign(sha256.New, "secret", "payload"),
+			},
+			WantStatus: http.StatusUnauthorized,
+		},
+		{
+			Name: "slack", Scheme: webhook.Slack(), Secrets: [][]byte{[]byte("8f742231b10e8888abcd99yyyzzz85a5")},
+			Now: time.Unix(1531420618, 0), Body: slackBody,
+			Headers: map[string]string{
+				"X-Slack-Request-Timestamp": "1531420618",
+				"X-Slack-Signature":         "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503",
+			},
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "slack replayed later", Scheme: webhook.Slack(), Secrets: [][]byte{[]byte("8f742231b10e8888abcd99yyyzzz85a5")},
+			Now: time.Unix(1531420618, 0).Add(6 * time.Minute), Body: slackBody,
+			Headers: map[string]string{
+				"X-Slack-Request-Timestamp": "1531420618",
+				"X-Slack-Signature":         "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503",
+			},
+			WantStatus: http.StatusUnauthorized,
+		},
+		{
+			Name: "slack tampered timestamp", Scheme: webhook.Slack(), Secrets: [][]byte{[]byte("8f742231b10e8888abcd99yyyzzz85a5")},
+			Now: time.Unix(1531420618, 0), Body: slackBody,
+			Headers: map[string]string{
+				"X-Slack-Request-Timestamp": "1531420619",
+				"X-Slack-Signature":         "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503",
+			},
+			WantStatus: http.StatusUnauthorized,
+		},
+		{
+			Name: "standard webhooks", Scheme: webhook.StandardWebhooks(), Secrets: [][]byte{standardSecret},
+			Now: time.Unix(1614265330, 0), Body: `{"test": 2432232314}`,
+			Headers: map[string]string{
+				"Webhook-Id":        "msg_p5jXN8AQM9LWM0D4loKWxJek",
+				"Webhook-Timestamp": "1614265330",
+				"Webhook-Signature": "v1,bm9wZQ== v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=",
+			},
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "standard webhooks other id", Scheme: webhook.StandardWebhooks(), Secrets: [][]byte{standardSecret},
+			Now: time.Unix(1614265330, 0), Body: `{"test": 2432232314}`,
+			Headers: map[string]string{
+				"Webhook-Id":        "msg_other",
+				"Webhook-Timestamp": "1614265330",
+				"Webhook-Signature": "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE=",
+			},
+			WantStatus: http.StatusUnauthorized,
+		},
+		{
+			Name: "shopify", Scheme: webhook.Shopify(), Secrets: [][]byte{[]byte("It's a Secret to Everybody")},
+			Body: "Hello, World!",
+			Headers: map[string]string{
+				"X-Shopify-Hmac-Sha256": "dXEH6g6yUJ/CESIczphLijdXC211hsIsRvQ3nIsEPhc=",
+			},
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "stripe", Scheme: webhook.Stripe(), Secrets: [][]byte{[]byte("whsec_test")},
+			Now: time.Unix(1700000000, 0), Body: `{"id":"evt_1"}`,
+			Headers: map[string]string{
+				"Stripe-Signature": "t=1700000000,v1=" + stripeSignature("old", "1700000000", `{"id":"evt_1"}`) +
+					",v1=" + stripeSignature("whsec_test", "1700000000", `{"id":"evt_1"}`) + ",v0=abc",
+			},
+			WantStatus: http.StatusOK,
+		},
+		{
+			Name: "stripe missing timestamp", Scheme: webhook.Stripe(), Secrets: [][]byte{[]byte("whsec_test")},
+			Now: time.Unix(1700000000, 0), Body: `{"id":"evt_1"}`,
+			Headers: map[string]string{
+				"Stripe-Signature": "v1=" + stripeSignature("whsec_test", "1700000000", `{"id":"evt_1"}`),
+			},
+			WantStatus: http.StatusUnauthorized,
+		},
+		{
+			Name: "stripe only v0", Scheme: webhook.Stripe(), Secrets: [][]byte{[]byte("whsec_test")},
+			Now: time.Unix(1700000000, 0), Body: `{"id":"evt_1"}`,
+			Headers: map[string]string{
+				"Stripe-Signature": "t=1700000000,v0=" + stripeSignature("whsec_test", "1700000000", `{"id":"evt_1"}`),
+			},
+			WantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			t.Parallel()
+
+			var rec received
+
+			handler := newHandler(t, &rec, test.Now,
+				webhook.WithScheme(test.Scheme),
+				webhook.WithSecrets(test.Secrets...))
+
+			req := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/hook", strings.NewReader(test.Body))
+
+			for k, v := range test.Headers {
+				req.Header.Set(k, v)
+			}
+
+			resp := httptest.NewRecorder()
+			handler.ServeHTTP(resp, req)
+
+			if resp.Code != test.WantStatus {
+				t.Errorf("got status %v but wanted %v: %v", resp.Code, test.WantStatus, resp.Body.String())
+			}
+
+			if test.WantStatus != http.StatusOK {
+				return
+			}
+
+			if rec.body != test.Body {
+				t.Errorf("downstream got body %q but wanted %q", rec.body, test.Body)
+			}
+
+			wantName := test.Scheme.Name
+
+			if wantName == "" {
+				wantName = "webhook"
+			}
+
+			if rec.principal == nil || rec.principal.Name != wantName || rec.principal.Method != "webhook" {
+				t.Errorf("got unexpected principal %+v", rec.principal)
+			}
+		})
+	}
+}
+
+func TestBodyTooLarge(t *testing.T) {
+	t.Parallel()
+
+	var rec received
+
+	handler := newHandler(t, &rec, time.Now(),
+		webhook.WithScheme(webhook.GitHub()),
+		webhook.WithSecrets([]byte("secret")),
+		webhook.WithMaxBodySize(4))
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/hook", strings.NewReader("12345"))
+	req.Header.Set("X-Hub-Signature-256", "sha256="+sign(sha256.New, "secret", "12345"))
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("got status %v but wanted %v", resp.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestOptionErrors(t *testing.T) {
+	t.Parallel()
+
+	secret := webhook.WithSecrets([]byte("secret"))
+	github := webhook.WithScheme(webhook.GitHub())
+
+	tests := []struct {
+		Options []func(*webhook.Handler) error
+		WantErr error
+	}{
+		{Options: []func(*webhook.Handler) error{secret}, WantErr: webhook.ErrNoScheme},                                            // 0
+		{Options: []func(*webhook.Handler) error{github}, WantErr: webhook.ErrNoSecrets},                                           // 1
+		{Options: []func(*webhook.Handler) error{github, webhook.WithSecrets(nil)}, WantErr: webhook.ErrNoSecrets},                 // 2
+		{Options: []func(*webhook.Handler) error{secret, webhook.WithScheme(webhook.Scheme{})}, WantErr: webhook.ErrInvalidScheme}, // 3
+		{ // 4
+			Options: []func(*webhook.Handler) error{secret, webhook.WithScheme(webhook.Scheme{
+				SignatureHeader: "X-Signature",
+				Tolerance:       time.Minute,
+			})},
+			WantErr: webhook.ErrInvalidScheme,
+		},
+		{ // 5
+			Options: []func(*webhook.Handler) error{secret, webhook.WithScheme(webhook.Scheme{
+				SignatureHeader: "X-Signature",
+				Tolerance:       -time.Minute,
+			})},
+			WantErr: webhook.ErrInvalidScheme,
+		},
+		{Options: []func(*webhook.Handler) error{secret, github, webhook.WithMaxBodySize(0)}, WantErr: webhook.ErrInvalidSize}, // 6
+	}
+
+	for k, test := range tests {
+		_, err := webhook.New(test.Options...)
+
+		if !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ErrUnknownFormat is returned when the format of a configuration cannot be determined.
+var ErrUnknownFormat = errors.New("unknown configuration format")
+
+// ConfigFormat is the format of a configuration, e.g. a policy file.
+type ConfigFormat string
+
+const (
+	// ConfigJSON is the JSON format.
+	ConfigJSON ConfigFormat = "json"
+	// ConfigYAML is the YAML format.
+	ConfigYAML ConfigFormat = "yaml"
+)
+
+// ConfigFormatOf determines the format of a configuration file by its extension, .json
+// for JSON and .yaml or .yml for YAML.
+func ConfigFormatOf(fileName string) (ConfigFormat, error) {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json":
+		return ConfigJSON, nil
+	case ".yaml", ".yml":
+		return ConfigYAML, nil
+	default:
+		return "", fmt.Errorf("%w: %v", ErrUnknownFormat, fileName)
+	}
+}
+
+// ReadConfig reads a configuration in the given format. Unknown fields are rejected, so
+// that misspelled settings do not go unnoticed. An empty YAML document gives the zero
+// value.
+func ReadConfig[T any](r io.Reader, format ConfigFormat) (*T, error) {
+	var config T
+
+	switch format {
+	case ConfigJSON:
+		decoder := json.NewDecoder(r)
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(&config); err != nil {
+			return nil, fmt.Errorf("could not read json configuration: %w", err)
+		}
+	case ConfigYAML:
+		decoder := yaml.NewDecoder(r)
+		decoder.KnownFields(true)
+
+		if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("could not read yaml configuration: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnknownFormat, format)
+	}
+
+	return &config, nil
+}
+
+// ReadConfigFile reads a configuration from the given file, determining its format by
+// the file extension as ConfigFormatOf does.
+func ReadConfigFile[T any](fileName string) (*T, error) {
+	format, err := ConfigFormatOf(fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(fileName)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not read configuration file: %w", err)
+	}
+
+	return ReadConfig[T](bytes.NewReader(content), format)
+}
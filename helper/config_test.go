@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2026 The midgard contributors.
+// SPDX-License-Identifier: MPL-2.0
+
+package helper_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AlphaOne1/midgard/helper"
+)
+
+// testConfig is a configuration used to test the reading.
+type testConfig struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+func TestReadConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Input    string
+		Format   helper.ConfigFormat
+		WantName string
+		WantErr  bool
+	}{
+		{Input: `{"name": "a"}`, Format: helper.ConfigJSON, WantName: "a"}, // 0
+		{Input: "name: b", Format: helper.ConfigYAML, WantName: "b"},       // 1
+		{Input: "", Format: helper.ConfigYAML, WantName: ""},               // 2
+		{Input: `{"nme": "a"}`, Format: helper.ConfigJSON, WantErr: true},  // 3 unknown field
+		{Input: "nme: b", Format: helper.ConfigYAML, WantErr: true},        // 4 unknown field
+		{Input: "name = a", Format: "toml", WantErr: true},                 // 5
+	}
+
+	for k, test := range tests {
+		config, err := helper.ReadConfig[testConfig](strings.NewReader(test.Input), test.Format)
+
+		if (err != nil) != test.WantErr {
+			t.Errorf("%v: got error %v, wanted error: %v", k, err, test.WantErr)
+
+			continue
+		}
+
+		if err == nil && config.Name != test.WantName {
+			t.Errorf("%v: got name %q but wanted %q", k, config.Name, test.WantName)
+		}
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	tests := []struct {
+		FileName string
+		WantErr  error
+	}{
+		{FileName: "config.json"},                                   // 0
+		{FileName: "config.YML"},                                    // 1
+		{FileName: "config.toml", WantErr: helper.ErrUnknownFormat}, // 2
+		{FileName: "missing.yaml", WantErr: os.ErrNotExist},         // 3
+	}
+
+	for _, name := range []string{"config.json", "config.YML", "config.toml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(`{"name": "a"}`), 0o600); err != nil {
+			t.Fatalf("could not write file: %v", err)
+		}
+	}
+
+	for k, test := range tests {
+		config, err := helper.ReadConfigFile[testConfig](filepath.Join(dir, test.FileName))
+
+		if !errors.Is(err, test.WantErr) {
+			t.Errorf("%v: got error %v but wanted %v", k, err, test.WantErr)
+		}
+
+		if err == nil && config.Name != "a" {
+			t.Errorf("%v: got name %q but wanted a", k, config.Name)
+		}
+	}
+}
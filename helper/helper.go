@@ -5,7 +5,10 @@
 package helper
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
@@ -94,6 +97,39 @@ func WriteState(w http.ResponseWriter, log *slog.Logger, httpState int) {
 	}
 }
 
+// Problem is a problem details object as defined in RFC 9457.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// WriteProblem sets the specified HTTP response code and writes a problem details object
+// (RFC 9457) with the given detail as body. Like WriteState, it is intended to give error
+// feedback to clients, but in a machine-readable form.
+func WriteProblem(w http.ResponseWriter, log *slog.Logger, httpState int, detail string) {
+	h := w.Header()
+
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/problem+json")
+	h.Set("X-Content-Type-Options", "nosniff")
+
+	w.WriteHeader(httpState)
+
+	err := json.NewEncoder(w).Encode(Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(httpState),
+		Status: httpState,
+		Detail: detail,
+	})
+
+	if err != nil {
+		log.Error("failed to write response", slog.String("error", err.Error()))
+	}
+}
+
 // IntroCheck is used to facilitate the introductory check in each handler for the basic requirements.
 // It manages the corresponding logging operations and can be used as follows:
 //
@@ -118,6 +154,26 @@ func IntroCheck(h defs.MWBaser, w http.ResponseWriter, r *http.Request) bool {
 	return true
 }
 
+// ReadBody reads the body of the request, at most maxSize bytes, and replaces it by a
+// copy, so that it can be read again by the following handlers. The returned flag is
+// false, if the body is longer than maxSize.
+func ReadBody(r *http.Request, maxSize int64) ([]byte, bool, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSize+1))
+	_ = r.Body.Close()
+
+	if err != nil {
+		return nil, false, fmt.Errorf("could not read body: %w", err)
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, int64(len(body)) <= maxSize, nil
+}
+
 // DummyHandler is a handler used for internal testing.
 // It simply writes the text "dummy" to the given http.ResponseWriter.
 func DummyHandler(w http.ResponseWriter, _ /*r*/ *http.Request) {
@@ -5,8 +5,10 @@ package helper_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
@@ -192,6 +194,44 @@ func TestWriteState(t *testing.T) {
 	}
 }
 
+func TestWriteProblem(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		state  int
+		detail string
+	}{
+		{state: http.StatusUnauthorized, detail: "invalid signature"}, // 0
+		{state: http.StatusTooManyRequests, detail: ""},               // 1
+	}
+
+	for k, test := range tests {
+		rec := httptest.NewRecorder()
+		helper.WriteProblem(rec, slog.Default(), test.state, test.detail)
+
+		var got helper.Problem
+
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("%v: could not decode problem: %v", k, err)
+		}
+
+		want := helper.Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(test.state),
+			Status: test.state,
+			Detail: test.detail,
+		}
+
+		if got != want || rec.Code != test.state {
+			t.Errorf("%v: got %v, %+v but wanted %v, %+v", k, rec.Code, got, test.state, want)
+		}
+
+		if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+			t.Errorf("%v: content type not set correctly, set to %v", k, ct)
+		}
+	}
+}
+
 type MWTest struct {
 	defs.MWBase
 }
@@ -287,3 +327,36 @@ func TestMapKeys(t *testing.T) {
 		})
 	}
 }
+
+func TestReadBody(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		Body         io.Reader
+		WantBody     string
+		WantComplete bool
+	}{
+		{Body: nil, WantBody: "", WantComplete: true},                                // 0
+		{Body: strings.NewReader("hello"), WantBody: "hello", WantComplete: true},    // 1
+		{Body: strings.NewReader("hello!"), WantBody: "hello!", WantComplete: false}, // 2 too long
+	}
+
+	for k, test := range tests {
+		req := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/", test.Body)
+
+		body, complete, err := helper.ReadBody(req, 5)
+
+		if err != nil {
+			t.Fatalf("%v: got error %v", k, err)
+		}
+
+		if string(body) != test.WantBody || complete != test.WantComplete {
+			t.Errorf("%v: got %q, %v, wanted %q, %v", k, body, complete, test.WantBody, test.WantComplete)
+		}
+
+		// the body can be read again
+		if again, _ := io.ReadAll(req.Body); string(again) != test.WantBody {
+			t.Errorf("%v: got %q on reading again, wanted %q", k, again, test.WantBody)
+		}
+	}
+}